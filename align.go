@@ -0,0 +1,264 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+	"math"
+	"unsafe"
+)
+
+/*
+paddingMarker is the record length which marks a padding frame. It is
+followed by the number of padding bytes as 4 byte big endian integer and
+the padding bytes themselves, which are zero.
+*/
+const paddingMarker = math.MaxUint32 - 1
+
+/*
+alignedWriteSize is the amount of data collected by writers using
+WithAlignment() before it is written, rounded to whole blocks.
+*/
+const alignedWriteSize = 64 * 1024
+
+/*
+WithAlignment keeps all reads and writes of the underlying streams aligned
+to blocks of blockSize bytes, in both position and length, from memory
+aligned to blockSize as well. This makes it possible to use files opened
+for direct I/O (O_DIRECT), bypassing the page cache when ingesting large
+amounts of data.
+
+Writers collect the stream in memory and pass it to the output stream in
+whole blocks. When the writer is flushed or closed, the last block is
+filled up with a padding frame, which readers using the same option skip
+over; the footer, if any, is placed at the very end of the last block. The
+output stream must be positioned at a block boundary when the writer is
+created, and it can not be resumed after errors.
+
+Readers request data in whole blocks from the input stream, seeking to
+block boundaries as needed. Streams written with WithAlignment() must be
+read with it as well, so that padding frames are recognized.
+*/
+func WithAlignment(blockSize int) Option {
+	return func(o *options) {
+		o.alignment = blockSize
+	}
+}
+
+/*
+alignedBuffer allocates a buffer of the specified size whose first byte is
+aligned to a multiple of align in memory.
+*/
+func alignedBuffer(size, align int) []byte {
+	var buf = make([]byte, size+align)
+	var offset = int(uintptr(unsafe.Pointer(&buf[0])) % uintptr(align))
+
+	if offset > 0 {
+		offset = align - offset
+	}
+	return buf[offset : offset+size : offset+size]
+}
+
+/*
+alignedWriter collects the data written to it and passes it on to the
+output stream in whole blocks.
+*/
+type alignedWriter struct {
+	writer filesystem.WriteCloser
+	align  int
+	buf    []byte
+	used   int
+	err    error
+}
+
+/*
+aligned wraps writer into an alignedWriter for blocks of the specified
+size, or returns it unchanged if align is not positive.
+*/
+func aligned(writer filesystem.WriteCloser,
+	align int) filesystem.WriteCloser {
+	var size = align
+
+	if align <= 0 {
+		return writer
+	}
+
+	if size < alignedWriteSize {
+		size = alignedWriteSize / align * align
+	}
+	return &alignedWriter{
+		writer: writer,
+		align:  align,
+		buf:    alignedBuffer(size, align),
+	}
+}
+
+func (a *alignedWriter) Write(ctx context.Context, p []byte) (int, error) {
+	var written, n int
+	var err error
+
+	if a.err != nil {
+		return 0, a.err
+	}
+
+	for len(p) > 0 {
+		n = copy(a.buf[a.used:], p)
+		a.used += n
+		written += n
+		p = p[n:]
+
+		if a.used == len(a.buf) {
+			if err = a.writeBlocks(ctx); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	return written, nil
+}
+
+/*
+writeBlocks writes all complete blocks collected so far to the output
+stream. Once writing fails, all further writes fail with the same error,
+since the position of the output stream is no longer aligned.
+*/
+func (a *alignedWriter) writeBlocks(ctx context.Context) error {
+	var whole = a.used - a.used%a.align
+
+	if a.err != nil || whole == 0 {
+		return a.err
+	}
+
+	if a.err = writeFull(ctx, a.writer, a.buf[:whole]); a.err != nil {
+		return a.err
+	}
+	a.used = copy(a.buf, a.buf[whole:a.used])
+	return nil
+}
+
+func (a *alignedWriter) Flush(ctx context.Context) error {
+	if err := a.writeBlocks(ctx); err != nil {
+		return err
+	}
+	if flusher, ok := a.writer.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+func (a *alignedWriter) Sync(ctx context.Context) error {
+	if err := a.Flush(ctx); err != nil {
+		return err
+	}
+	if syncer, ok := a.writer.(Syncer); ok {
+		return syncer.Sync(ctx)
+	}
+	return ErrSyncUnsupported
+}
+
+/*
+Close writes the remaining data, even if it does not fill a whole block,
+and closes the output stream.
+*/
+func (a *alignedWriter) Close(ctx context.Context) error {
+	var err = a.writeBlocks(ctx)
+
+	if err == nil && a.used > 0 {
+		err = writeFull(ctx, a.writer, a.buf[:a.used])
+		a.used = 0
+	}
+	if closeErr := a.writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+/*
+pad writes a padding frame so that the output stream ends at a block
+boundary once trailing more bytes have been written after it. Nothing is
+written if alignment is not enabled or the stream will be aligned anyway.
+*/
+func (w *RecordWriter) pad(ctx context.Context, trailing int) error {
+	var align = int64(w.options.alignment)
+	var frame []byte
+	var size, minSize int64
+	var err error
+
+	if align <= 0 {
+		return nil
+	}
+
+	size = (align - (w.position.Offset+int64(trailing))%align) % align
+	if size == 0 {
+		return nil
+	}
+	minSize = int64(len(w.appendHeader(w.header[:0], paddingMarker,
+		0))) + 4
+	for size < minSize {
+		size += align
+	}
+
+	frame = w.appendHeader(make([]byte, 0, size), paddingMarker, 0)
+	frame = appendUint32(frame, uint32(size-minSize), false)
+	frame = frame[:size]
+
+	err = writeFull(ctx, w.wrappedWriter, frame)
+	w.position.Offset += size
+	return err
+}
+
+/*
+skipPadding skips over the remainder of a padding frame following its
+header.
+*/
+func (r *RecordReader) skipPadding(ctx context.Context) error {
+	var header [4]byte
+	var length uint32
+	var err error
+
+	if _, err = readFull(ctx, r.wrappedReader, header[:]); err != nil {
+		return noEOF(err)
+	}
+	length = binary.BigEndian.Uint32(header[:])
+	if err = r.skipBody(ctx, int64(length)); err != nil {
+		return err
+	}
+
+	r.position.Offset += int64(r.headerLength(paddingMarker)) + 4 +
+		int64(length)
+	return nil
+}
+
+/*
+skipAlignment drops the first bytes of the data read into a bufferedReader
+after seeking to a block boundary before the requested position.
+*/
+func (b *bufferedReader) skipAlignment() {
+	var drop = int64(len(b.data))
+
+	if drop > b.skip {
+		drop = b.skip
+	}
+	b.data = b.data[drop:]
+	b.skip -= drop
+}
+
+/*
+seekAligned seeks the input stream to the block boundary at or before pos,
+remembering how much of the following data to skip.
+*/
+func (b *bufferedSeekReader) seekAligned(ctx context.Context,
+	pos int64) error {
+	var base = pos - pos%int64(b.align)
+	var err error
+
+	if base == pos {
+		return nil
+	}
+	if _, err = b.seeker.Seek(ctx, base, io.SeekStart); err != nil {
+		return err
+	}
+	b.skip = pos - base
+	return nil
+}
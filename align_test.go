@@ -0,0 +1,113 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+	"unsafe"
+)
+
+/*
+blockWriter fails all writes which are not aligned to blocks.
+*/
+type blockWriter struct {
+	bytes.Buffer
+	block int
+}
+
+func (b *blockWriter) Write(p []byte) (int, error) {
+	if len(p)%b.block != 0 || b.Len()%b.block != 0 {
+		return 0, fmt.Errorf("Unaligned write of %d bytes at %d", len(p),
+			b.Len())
+	}
+	return b.Buffer.Write(p)
+}
+
+/*
+blockReader fails all reads which are not aligned to blocks.
+*/
+type blockReader struct {
+	*bytes.Reader
+	block int
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	var pos = b.Size() - int64(b.Len())
+
+	if len(p)%b.block != 0 || pos%int64(b.block) != 0 {
+		return 0, fmt.Errorf("Unaligned read of %d bytes at %d", len(p), pos)
+	}
+	return b.Reader.Read(p)
+}
+
+/*
+Aligned streams must only be written and read in whole blocks, and padding
+frames must be skipped transparently, also when seeking via the footer.
+*/
+func TestAlignment(t *testing.T) {
+	var ctx = context.Background()
+	var out = &blockWriter{block: 512}
+	var opts = []Option{WithAlignment(512), WithChecksum(),
+		WithSparseIndex(10)}
+	var writer = NewIORecordWriter(out, opts...)
+	var reader *RecordReader
+	var rec []byte
+	var i int
+	var err error
+
+	for i = 0; i < 100; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Rec ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+		if i == 41 {
+			if err = writer.Flush(ctx); err != nil {
+				t.Error("Error flushing writer: ", err)
+			}
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if out.Len()%512 != 0 {
+		t.Error("Unexpected stream length: ", out.Len())
+	}
+
+	reader = NewIORecordReader(
+		&blockReader{Reader: bytes.NewReader(out.Bytes()), block: 512},
+		opts...)
+	for i = 0; i < 100; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+			break
+		}
+		if string(rec) != fmt.Sprint("Rec ", i) {
+			t.Errorf("Unexpected data: got %q, expected Rec %d", rec, i)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+
+	if err = reader.SeekToRecord(ctx, 57); err != nil {
+		t.Error("Error seeking: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Rec 57" {
+		t.Errorf("Unexpected record after seeking: %q (%v)", rec, err)
+	}
+}
+
+/*
+Aligned buffers must start at a multiple of the alignment in memory.
+*/
+func TestAlignedBuffer(t *testing.T) {
+	for _, align := range []int{512, 4096} {
+		var buf = alignedBuffer(3*align, align)
+
+		if len(buf) != 3*align ||
+			uintptr(unsafe.Pointer(&buf[0]))%uintptr(align) != 0 {
+			t.Error("Misaligned buffer for alignment ", align)
+		}
+	}
+}
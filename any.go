@@ -0,0 +1,142 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const anyTypeURLPrefix = "type.googleapis.com/"
+
+/*
+TypeRegistry maps fully qualified protocol buffer message names to their Go
+types, so that records written with WriteAny() can be decoded into messages
+of the correct type by ReadAny().
+
+TypeRegistries are safe for concurrent use.
+*/
+type TypeRegistry struct {
+	mtx   sync.RWMutex
+	types map[string]reflect.Type
+}
+
+/*
+NewTypeRegistry creates a new, empty TypeRegistry.
+*/
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		types: make(map[string]reflect.Type),
+	}
+}
+
+/*
+Register adds the types of the specified protocol buffer messages to the
+registry. The messages themselves are only used to determine the types.
+*/
+func (t *TypeRegistry) Register(pbs ...Message) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	for _, pb := range pbs {
+		t.types[messageName(pb)] = reflect.TypeOf(pb)
+	}
+}
+
+/*
+New creates a new, empty message of the type registered under the specified
+fully qualified message name.
+*/
+func (t *TypeRegistry) New(name string) (Message, error) {
+	var typ reflect.Type
+	var ok bool
+
+	t.mtx.RLock()
+	typ, ok = t.types[name]
+	t.mtx.RUnlock()
+
+	if !ok || typ.Kind() != reflect.Ptr {
+		return nil, errors.New("Unknown message type: " + name)
+	}
+
+	return reflect.New(typ.Elem()).Interface().(Message), nil
+}
+
+/*
+WriteAny wraps the specified protocol buffer in a google.protobuf.Any message
+and writes it as a new record to the underlying output stream. This allows
+messages of different types to be stored in the same file.
+
+The same warnings about locking as for Write() apply to this method.
+*/
+func (w *RecordWriter) WriteAny(ctx context.Context, pb Message) error {
+	var wrapper anypb.Any
+	var err error
+
+	wrapper.TypeUrl = anyTypeURLPrefix + messageName(pb)
+	wrapper.Value, err = w.options.marshalMessage(pb)
+	if err != nil {
+		return err
+	}
+
+	return w.WriteMessage(ctx, &wrapper)
+}
+
+/*
+ReadAny reads the next record from the input stream as a google.protobuf.Any
+message and returns the message contained in it. The type of the message is
+looked up in the specified registry; if registry is nil, the types registered
+globally with the protocol buffer library are used instead.
+
+If the type of the message is unknown or the message cannot be parsed, an
+error will be returned but the reader will be advanced by a record.
+
+All warnings from the ReadRecord() method apply here as well.
+*/
+func (r *RecordReader) ReadAny(ctx context.Context, registry *TypeRegistry) (
+	Message, error) {
+	var wrapper anypb.Any
+	var name string
+	var pb Message
+	var mt protoreflect.MessageType
+	var err error
+
+	if err = r.ReadMessage(ctx, &wrapper); err != nil {
+		return nil, err
+	}
+
+	name = wrapper.TypeUrl
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	if registry != nil {
+		pb, err = registry.New(name)
+	} else if mt, err = protoregistry.GlobalTypes.FindMessageByName(
+		protoreflect.FullName(name)); err == nil {
+		pb = protoadapt.MessageV1Of(mt.New().Interface())
+	} else {
+		err = errors.New("Unknown message type: " + name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.options.unmarshalMessage(wrapper.Value, pb); err != nil {
+		return nil, err
+	}
+	return pb, nil
+}
+
+/*
+messageName returns the fully qualified name of the protocol buffer type.
+*/
+func messageName(pb Message) string {
+	return string(proto.MessageName(protoadapt.MessageV2Of(pb)))
+}
@@ -0,0 +1,74 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Write a message wrapped in Any and read it back through a registry and the
+global protocol buffer types.
+*/
+func TestWriteAndReadAny(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf)
+	var reader *RecordReader
+	var registry = NewTypeRegistry()
+	var data MessageForTest
+	var pb interface{}
+	var err error
+
+	registry.Register(&MessageForTest{})
+
+	data.Message = "Test data"
+	if err = writer.WriteAny(ctx, &data); err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+	data.Message = "Toast Data"
+	if err = writer.WriteAny(ctx, &data); err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+
+	// Reset position
+	writer.Close(ctx)
+	reader = NewRecordReader(buf)
+
+	pb, err = reader.ReadAny(ctx, registry)
+	if err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+	if m, ok := pb.(*MessageForTest); !ok || m.Message != "Test data" {
+		t.Error("Expected MessageForTest with Test data, got ", pb)
+	}
+
+	pb, err = reader.ReadAny(ctx, nil)
+	if err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+	if m, ok := pb.(*MessageForTest); !ok || m.Message != "Toast Data" {
+		t.Error("Expected MessageForTest with Toast Data, got ", pb)
+	}
+}
+
+/*
+Reading a message of a type not in the registry must fail.
+*/
+func TestReadAnyUnknownType(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf)
+	var err error
+
+	if err = writer.WriteAny(ctx, &MessageForTest{Message: "x"}); err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+
+	// Reset position
+	writer.Close(ctx)
+
+	if _, err = NewRecordReader(buf).ReadAny(ctx, NewTypeRegistry()); err == nil {
+		t.Error("Expected error reading unregistered message type")
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Package arrow stores Apache Arrow record batches as records in recordio
+files.
+
+Every record holds a complete Arrow IPC stream consisting of the schema and a
+single record batch. This duplicates the schema in every record, but keeps
+every record independently decodable, so record files containing Arrow data
+can be split, sharded and accessed randomly like any other record file.
+*/
+package arrow
+
+import (
+	"bytes"
+	"errors"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+)
+
+/*
+WriteRecordBatch serializes the specified record batch in the Arrow IPC
+stream format and writes it as a new record to the RecordWriter.
+
+The same warnings about locking as for RecordWriter.Write() apply to this
+function.
+*/
+func WriteRecordBatch(ctx context.Context, w *recordio.RecordWriter,
+	batch arrow.Record) error {
+	var buf bytes.Buffer
+	var iw = ipc.NewWriter(&buf, ipc.WithSchema(batch.Schema()))
+	var err error
+
+	if err = iw.Write(batch); err != nil {
+		iw.Close()
+		return err
+	}
+	if err = iw.Close(); err != nil {
+		return err
+	}
+
+	_, err = w.Write(ctx, buf.Bytes())
+	return err
+}
+
+/*
+ReadRecordBatch reads the next record from the RecordReader and decodes the
+Arrow record batch stored in it, allocating memory from mem. If mem is nil,
+memory.DefaultAllocator is used. The caller must Release() the returned
+record batch.
+
+All warnings from RecordReader.ReadRecord() apply here as well.
+*/
+func ReadRecordBatch(ctx context.Context, r *recordio.RecordReader,
+	mem memory.Allocator) (arrow.Record, error) {
+	var ir *ipc.Reader
+	var batch arrow.Record
+	var buf []byte
+	var err error
+
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return nil, err
+	}
+
+	if ir, err = ipc.NewReader(bytes.NewReader(buf),
+		ipc.WithAllocator(mem)); err != nil {
+		return nil, err
+	}
+	defer ir.Release()
+
+	if !ir.Next() {
+		if err = ir.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("Record contains no Arrow record batch")
+	}
+
+	// The record batch is owned by the IPC reader, which releases it along
+	// with itself.
+	batch = ir.Record()
+	batch.Retain()
+	return batch, nil
+}
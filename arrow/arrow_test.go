@@ -0,0 +1,57 @@
+package arrow
+
+import (
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Write an Arrow record batch as a record and read it back.
+*/
+func TestWriteAndReadRecordBatch(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = recordio.NewRecordWriter(buf)
+	var mem = memory.NewGoAllocator()
+	var schema = arrow.NewSchema(
+		[]arrow.Field{{Name: "count", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	var builder = array.NewRecordBuilder(mem, schema)
+	var batch, rbatch arrow.Record
+	var values []int64
+	var err error
+
+	defer builder.Release()
+	builder.Field(0).(*array.Int64Builder).AppendValues([]int64{1, 2, 3}, nil)
+	batch = builder.NewRecord()
+	defer batch.Release()
+
+	if err = WriteRecordBatch(ctx, writer, batch); err != nil {
+		t.Error("Error writing record batch: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	rbatch, err = ReadRecordBatch(ctx, recordio.NewRecordReader(buf), mem)
+	if err != nil {
+		t.Fatal("Error reading record batch: ", err)
+	}
+	defer rbatch.Release()
+
+	if rbatch.Schema().Field(0).Name != "count" {
+		t.Error("Unexpected field name: ", rbatch.Schema().Field(0).Name)
+	}
+	if rbatch.NumRows() != 3 {
+		t.Error("Expected 3 rows, got ", rbatch.NumRows())
+	}
+
+	values = rbatch.Column(0).(*array.Int64).Int64Values()
+	if len(values) != 3 || values[0] != 1 || values[2] != 3 {
+		t.Error("Unexpected values: ", values)
+	}
+}
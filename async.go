@@ -0,0 +1,235 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"sync"
+)
+
+/*
+AsyncRecordWriter writes records like a RecordWriter, but on a background
+goroutine: Write() only copies the record into a bounded queue and returns
+immediately unless the queue is full, so that producers which are sensitive
+to latency are not blocked by slow storage.
+
+Since records are written after Write() has returned, errors are deferred:
+the first error writing a record is returned by the next call to any method,
+and all records queued after it are discarded. Flush(), Sync(), Barrier()
+and Close() wait for all records queued before them to be written, so they
+surface any error which occurred. Records are written using a background
+context, so canceling the context passed to Write() does not affect records
+which have already been queued.
+
+As with RecordWriter, AsyncRecordWriters are not thread safe.
+*/
+type AsyncRecordWriter struct {
+	writer *RecordWriter
+	queue  chan asyncRequest
+	done   chan struct{}
+	closed bool
+
+	// mtx protects err, the first error writing a record.
+	mtx sync.Mutex
+	err error
+}
+
+/*
+asyncRequest is an entry of the queue of an AsyncRecordWriter: either a
+record to write, or a call to make on the background goroutine, whose result
+is sent to result.
+*/
+type asyncRequest struct {
+	rec    []byte
+	call   func() error
+	result chan error
+}
+
+/*
+NewAsyncRecordWriter creates a new AsyncRecordWriter writing to the
+specified output stream with the specified options, queueing up to
+queueLength records. The background goroutine is started right away; the
+writer must be closed to stop it.
+*/
+func NewAsyncRecordWriter(writer filesystem.WriteCloser, queueLength int,
+	opts ...Option) *AsyncRecordWriter {
+	var w = &AsyncRecordWriter{
+		writer: NewRecordWriter(writer, opts...),
+		queue:  make(chan asyncRequest, queueLength),
+		done:   make(chan struct{}),
+	}
+
+	go w.run()
+	return w
+}
+
+/*
+run services the queue until it is closed.
+*/
+func (w *AsyncRecordWriter) run() {
+	var ctx = context.Background()
+	var err error
+
+	defer close(w.done)
+
+	for req := range w.queue {
+		if err = w.failure(); req.call != nil {
+			if err == nil {
+				err = req.call()
+			}
+			req.result <- err
+		} else if err == nil {
+			if _, err = w.writer.Write(ctx, req.rec); err != nil {
+				w.mtx.Lock()
+				w.err = err
+				w.mtx.Unlock()
+			}
+		}
+	}
+}
+
+/*
+failure returns the first error writing a record, if any.
+*/
+func (w *AsyncRecordWriter) failure() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.err
+}
+
+/*
+Write queues a copy of the record for writing, waiting for space in the
+queue if it is full, and returns the length of the record. If writing a
+previous record failed, its error is returned instead. If the context is
+canceled while waiting, the record is not queued.
+*/
+func (w *AsyncRecordWriter) Write(ctx context.Context, rec []byte) (int,
+	error) {
+	var err error
+
+	if err = w.enqueue(ctx, asyncRequest{
+		rec: append(make([]byte, 0, len(rec)), rec...),
+	}); err != nil {
+		return 0, err
+	}
+	return len(rec), nil
+}
+
+/*
+WriteMessage serializes the protocol buffer and queues it for writing as
+described for Write().
+*/
+func (w *AsyncRecordWriter) WriteMessage(ctx context.Context,
+	pb Message) error {
+	var b []byte
+	var err error
+
+	if b, err = w.writer.options.marshalMessage(pb); err != nil {
+		return err
+	}
+
+	return w.enqueue(ctx, asyncRequest{rec: b})
+}
+
+/*
+Flush waits for all records queued so far to be written, and flushes the
+RecordWriter as described for RecordWriter.Flush(). The first error writing
+any of the records is returned, if there was one.
+*/
+func (w *AsyncRecordWriter) Flush(ctx context.Context) error {
+	return w.wait(ctx, func() error {
+		return w.writer.Flush(ctx)
+	})
+}
+
+/*
+Sync waits for all records queued so far to be written, and syncs them to
+stable storage as described for RecordWriter.Sync(). The first error
+writing any of the records is returned, if there was one.
+*/
+func (w *AsyncRecordWriter) Sync(ctx context.Context) error {
+	return w.wait(ctx, func() error {
+		return w.writer.Sync(ctx)
+	})
+}
+
+/*
+Barrier waits for all records queued so far to be written, and makes them
+durable as described for RecordWriter.Barrier(). The first error writing
+any of the records is returned, if there was one.
+*/
+func (w *AsyncRecordWriter) Barrier(ctx context.Context) error {
+	return w.wait(ctx, func() error {
+		return w.writer.Barrier(ctx)
+	})
+}
+
+/*
+Close waits for all queued records to be written, stops the background
+goroutine and closes the RecordWriter. The first error writing any of the
+records is returned, if there was one; the RecordWriter is closed
+regardless. Closing an AsyncRecordWriter more than once has no effect;
+afterwards, all writes fail with ErrClosed.
+*/
+func (w *AsyncRecordWriter) Close(ctx context.Context) error {
+	var err error
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	close(w.queue)
+	<-w.done
+
+	err = w.failure()
+	if closeErr := w.writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+/*
+enqueue adds the request to the queue, unless writing has failed or the
+context is canceled while waiting for space.
+*/
+func (w *AsyncRecordWriter) enqueue(ctx context.Context,
+	req asyncRequest) error {
+	var err error
+
+	if w.closed {
+		return ErrClosed
+	}
+	if err = w.failure(); err != nil {
+		return err
+	}
+
+	select {
+	case w.queue <- req:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*
+wait queues a call to fn, and returns its result once all requests before
+it have been handled, or the error of the context if it is canceled first.
+*/
+func (w *AsyncRecordWriter) wait(ctx context.Context, fn func() error) error {
+	var result = make(chan error, 1)
+	var err error
+
+	if err = w.enqueue(ctx, asyncRequest{
+		call:   fn,
+		result: result,
+	}); err != nil {
+		return err
+	}
+
+	select {
+	case err = <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
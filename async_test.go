@@ -0,0 +1,124 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+gatedWriter blocks every write until it is released through its gate.
+*/
+type gatedWriter struct {
+	bytes.Buffer
+	gate chan struct{}
+}
+
+func (g *gatedWriter) Write(p []byte) (int, error) {
+	<-g.gate
+	return g.Buffer.Write(p)
+}
+
+/*
+Writes must return before the records have been written, and Close must
+write all queued records.
+*/
+func TestAsyncRecordWriter(t *testing.T) {
+	var ctx = context.Background()
+	var out = &gatedWriter{gate: make(chan struct{})}
+	var writer = NewAsyncRecordWriter(NewIOWriteCloser(out), 10)
+	var reader *RecordReader
+	var rec []byte
+	var i int
+	var err error
+
+	// Nothing can be written until the gate is closed, so these writes
+	// can only succeed if they are queued.
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Rec ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	close(out.gate)
+
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("Late")); err != ErrClosed {
+		t.Error("Expected ErrClosed after closing, got: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(out.Bytes()))
+	for i = 0; ; i++ {
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		if string(rec) != fmt.Sprint("Rec ", i) {
+			t.Error("Unexpected record: ", string(rec))
+		}
+	}
+	if i != 10 {
+		t.Error("Unexpected number of records: ", i)
+	}
+}
+
+/*
+An error writing a record in the background must be returned by Flush and
+by all later calls.
+*/
+func TestAsyncRecordWriterFailure(t *testing.T) {
+	var ctx = context.Background()
+	var out = &failingWriter{limit: 20}
+	var writer = NewAsyncRecordWriter(NewIOWriteCloser(out), 10)
+	var err error
+
+	for i := 0; i < 5; i++ {
+		if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+			t.Error("Error queueing record: ", err)
+		}
+	}
+	if err = writer.Flush(ctx); err == nil {
+		t.Error("Expected write error from Flush")
+	}
+	if _, err = writer.Write(ctx, []byte("Hello")); err == nil {
+		t.Error("Expected deferred error from Write")
+	}
+	if err = writer.Close(ctx); err == nil {
+		t.Error("Expected deferred error from Close")
+	}
+}
+
+/*
+Writes must give up waiting for space in a full queue once the context is
+canceled.
+*/
+func TestAsyncRecordWriterCanceled(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var out = &gatedWriter{gate: make(chan struct{})}
+	var writer = NewAsyncRecordWriter(NewIOWriteCloser(out), 1)
+	var err error
+
+	// The first record is being written and the second one fills the
+	// queue.
+	for i := 0; i < 2; i++ {
+		if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+			t.Error("Error queueing record: ", err)
+		}
+	}
+	cancel()
+	if _, err = writer.Write(ctx, []byte("Hello")); err != context.Canceled {
+		t.Error("Expected cancellation, got: ", err)
+	}
+
+	close(out.gate)
+	if err = writer.Close(context.Background()); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if out.Len() != 18 {
+		t.Error("Unexpected amount of data written: ", out.Len())
+	}
+}
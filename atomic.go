@@ -0,0 +1,134 @@
+package recordio
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+)
+
+/*
+AtomicRecordWriter writes a record file under a temporary name and only
+renames it to its final name once it has been closed successfully, so that
+readers never observe a half-written file: the file either does not exist
+yet or is complete, including its footer. All methods of RecordWriter are
+available; only Close() is different.
+
+If writing fails, or Abort() is called, the file is left behind under its
+temporary name, which TempName() returns, so it can be removed.
+*/
+type AtomicRecordWriter struct {
+	*RecordWriter
+
+	name     string
+	tempName string
+	rename   func(ctx context.Context, from, to string) error
+	done     bool
+}
+
+/*
+NewAtomicRecordWriter creates the file name with a random suffix using the
+open function, and wraps it into an AtomicRecordWriter with the specified
+options. The rename function is used to move the file to name when the
+writer is closed; it must replace an existing file of that name, and should
+do so atomically for readers to be safe.
+*/
+func NewAtomicRecordWriter(ctx context.Context, name string,
+	open func(context.Context, string) (filesystem.WriteCloser, error),
+	rename func(ctx context.Context, from, to string) error,
+	opts ...Option) (*AtomicRecordWriter, error) {
+	var suffix [8]byte
+	var writer filesystem.WriteCloser
+	var tempName string
+	var err error
+
+	if _, err = rand.Read(suffix[:]); err != nil {
+		return nil, err
+	}
+	tempName = name + ".tmp-" + hex.EncodeToString(suffix[:])
+
+	if writer, err = open(ctx, tempName); err != nil {
+		return nil, err
+	}
+
+	return &AtomicRecordWriter{
+		RecordWriter: NewRecordWriter(writer, opts...),
+		name:         name,
+		tempName:     tempName,
+		rename:       rename,
+	}, nil
+}
+
+/*
+TempName returns the temporary name the file is written under.
+*/
+func (w *AtomicRecordWriter) TempName() string {
+	return w.tempName
+}
+
+/*
+Close writes the footer, if any, syncs the file to stable storage, closes
+the RecordWriter as usual and then renames the file to its final name, so
+that a crash can not leave a renamed file with missing data behind. The
+output stream must therefore implement Syncer; otherwise,
+ErrSyncUnsupported is returned. The file is not renamed if syncing or
+closing fails, or if it ends in an incomplete record, in which case
+ErrIncompleteRecord is returned. Closing an AtomicRecordWriter more than
+once has no effect.
+*/
+func (w *AtomicRecordWriter) Close(ctx context.Context) error {
+	var err error
+
+	if w.done {
+		return nil
+	}
+	w.done = true
+
+	if err = w.finish(ctx); err == nil && w.incomplete == 0 {
+		err = w.syncOutput(ctx)
+	}
+	if closeErr := w.RecordWriter.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if w.incomplete > 0 {
+		return ErrIncompleteRecord
+	}
+
+	return w.rename(ctx, w.tempName, w.name)
+}
+
+/*
+syncOutput flushes the output stream and syncs it to stable storage. Unlike
+RecordWriter.Sync(), it does not pad the stream, since the stream has
+already been finished.
+*/
+func (w *AtomicRecordWriter) syncOutput(ctx context.Context) error {
+	var syncer Syncer
+	var ok bool
+
+	if flusher, ok := w.wrappedWriter.(Flusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	if syncer, ok = w.wrappedWriter.(Syncer); !ok {
+		return ErrSyncUnsupported
+	}
+	return syncer.Sync(ctx)
+}
+
+/*
+Abort closes the RecordWriter without renaming the file, e.g. because
+producing its contents failed. Subsequent calls to Close() have no effect.
+*/
+func (w *AtomicRecordWriter) Abort(ctx context.Context) error {
+	if w.done {
+		return nil
+	}
+	w.done = true
+
+	return w.RecordWriter.Close(ctx)
+}
@@ -0,0 +1,125 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"os"
+	"testing"
+)
+
+/*
+Atomically written files must only appear under their final name once they
+have been closed, and never if writing was aborted.
+*/
+func TestAtomicRecordWriter(t *testing.T) {
+	var ctx = context.Background()
+	var dir = t.TempDir()
+	var name = dir + "/data"
+	var writer *AtomicRecordWriter
+	var file *os.File
+	var rec []byte
+	var err error
+
+	var open = func(ctx context.Context, name string) (
+		filesystem.WriteCloser, error) {
+		var f, err = os.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		return NewIOWriteCloser(f), nil
+	}
+	var rename = func(ctx context.Context, from, to string) error {
+		return os.Rename(from, to)
+	}
+
+	if writer, err = NewAtomicRecordWriter(ctx, name, open, rename,
+		WithFooter()); err != nil {
+		t.Fatal("Error creating writer: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if _, err = os.Stat(name); !os.IsNotExist(err) {
+		t.Error("File visible before closing: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if _, err = os.Stat(writer.TempName()); !os.IsNotExist(err) {
+		t.Error("Temporary file left behind: ", err)
+	}
+
+	if file, err = os.Open(name); err != nil {
+		t.Fatal("Error opening file: ", err)
+	}
+	rec, err = NewIORecordReader(file, WithFooter()).ReadRecord(ctx)
+	if err != nil || string(rec) != "Hello" {
+		t.Errorf("Unexpected record: %q (%v)", rec, err)
+	}
+	file.Close()
+
+	if writer, err = NewAtomicRecordWriter(ctx, dir+"/aborted", open,
+		rename); err != nil {
+		t.Fatal("Error creating writer: ", err)
+	}
+	if err = writer.Abort(ctx); err != nil {
+		t.Error("Error aborting writer: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing aborted writer: ", err)
+	}
+	if _, err = os.Stat(dir + "/aborted"); !os.IsNotExist(err) {
+		t.Error("Aborted file was renamed: ", err)
+	}
+}
+
+/*
+failingSyncWriter is an output stream whose Sync() always fails.
+*/
+type failingSyncWriter struct {
+	ioWriteCloser
+}
+
+func (f *failingSyncWriter) Sync(ctx context.Context) error {
+	return errors.New("Sync failed")
+}
+
+/*
+Files must only be renamed once they have been synced, and not at all if
+they can't be synced.
+*/
+func TestAtomicRecordWriterSync(t *testing.T) {
+	var ctx = context.Background()
+	var renamed bool
+	var writer *AtomicRecordWriter
+	var err error
+
+	var rename = func(ctx context.Context, from, to string) error {
+		renamed = true
+		return nil
+	}
+
+	for _, stream := range []filesystem.WriteCloser{
+		NewIOWriteCloser(&bytes.Buffer{}),
+		&failingSyncWriter{ioWriteCloser{writer: &bytes.Buffer{}}},
+	} {
+		var open = func(ctx context.Context, name string) (
+			filesystem.WriteCloser, error) {
+			return stream, nil
+		}
+
+		if writer, err = NewAtomicRecordWriter(ctx, "data", open,
+			rename); err != nil {
+			t.Fatal("Error creating writer: ", err)
+		}
+		writer.Write(ctx, []byte("Hello"))
+		if err = writer.Close(ctx); err == nil {
+			t.Error("Expected error closing writer which can't be synced")
+		}
+		if renamed {
+			t.Error("File was renamed without being synced")
+		}
+	}
+}
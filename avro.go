@@ -0,0 +1,355 @@
+package recordio
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+Codecs for the data blocks of Avro object container files, as named in the
+avro.codec metadata entry of the file header. Only the codecs mandated by the
+Avro specification are supported.
+*/
+const (
+	AvroCodecNull    = "null"
+	AvroCodecDeflate = "deflate"
+)
+
+var avroMagic = []byte{'O', 'b', 'j', 1}
+
+/*
+AvroReader reads an Avro Object Container File and returns each of its data
+blocks as a record. The schema and other metadata from the file header are
+exposed so that callers can decode the serialized objects contained in the
+blocks.
+
+Avro blocks may hold more than one object. ReadBlock() returns the number of
+objects along with the data; ReadRecord() only returns the data, which is the
+concatenation of the serialized objects. Files written by AvroWriter.Write()
+contain exactly one object per block.
+
+The size of blocks, both compressed and decompressed, and of metadata
+entries is limited by WithMaxRecordSize(), so that corrupted sizes are
+rejected rather than allocated. Other options have no effect.
+*/
+type AvroReader struct {
+	wrappedReader filesystem.ReadCloser
+	options       options
+	metadata      map[string][]byte
+	syncMarker    []byte
+}
+
+/*
+NewAvroReader creates a new AvroReader wrapped around the specified input
+stream. The file header is read immediately, so the schema is available as
+soon as this function returns.
+*/
+func NewAvroReader(ctx context.Context, reader filesystem.ReadCloser,
+	opts ...Option) (*AvroReader, error) {
+	var r = &AvroReader{
+		wrappedReader: reader,
+		options:       applyOptions(opts),
+		metadata:      make(map[string][]byte),
+		syncMarker:    make([]byte, 16),
+	}
+	var magic = make([]byte, len(avroMagic))
+	var br = &byteReader{ctx: ctx, reader: reader}
+	var count int64
+	var key, value []byte
+	var err error
+
+	if _, err = readFull(ctx, reader, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, avroMagic) {
+		return nil, errors.New("Not an Avro object container file")
+	}
+
+	// The metadata is encoded as an Avro map of bytes, which is a series of
+	// blocks of key/value pairs terminated by an empty block.
+	for {
+		if count, err = binary.ReadVarint(br); err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			break
+		}
+		if count < 0 {
+			// Negative counts are followed by the size of the block in
+			// bytes, which we don't need.
+			count = -count
+			if _, err = binary.ReadVarint(br); err != nil {
+				return nil, err
+			}
+		}
+		for ; count > 0; count-- {
+			if key, err = readAvroBytes(ctx, br,
+				r.options.maxRecordSize); err != nil {
+				return nil, err
+			}
+			if value, err = readAvroBytes(ctx, br,
+				r.options.maxRecordSize); err != nil {
+				return nil, err
+			}
+			r.metadata[string(key)] = value
+		}
+	}
+
+	if _, err = readFull(ctx, reader, r.syncMarker); err != nil {
+		return nil, err
+	}
+
+	if codec := r.Codec(); codec != AvroCodecNull && codec != AvroCodecDeflate {
+		return nil, errors.New("Unsupported Avro codec: " + codec)
+	}
+
+	return r, nil
+}
+
+/*
+Schema returns the JSON encoded writer schema from the file header.
+*/
+func (r *AvroReader) Schema() []byte {
+	return r.metadata["avro.schema"]
+}
+
+/*
+Codec returns the name of the codec the data blocks are compressed with.
+*/
+func (r *AvroReader) Codec() string {
+	if codec, ok := r.metadata["avro.codec"]; ok {
+		return string(codec)
+	}
+	return AvroCodecNull
+}
+
+/*
+Metadata returns the value of the specified key from the file header, or nil
+if the key is not set.
+*/
+func (r *AvroReader) Metadata(key string) []byte {
+	return r.metadata[key]
+}
+
+/*
+ReadBlock reads the next data block from the input stream, decompresses it
+and returns the number of objects in the block along with their serialized
+data. io.EOF is returned once the end of the file has been reached, and
+ErrRecordTooLarge for blocks exceeding the maximum record size.
+*/
+func (r *AvroReader) ReadBlock(ctx context.Context) (int64, []byte, error) {
+	var br = &byteReader{ctx: ctx, reader: r.wrappedReader}
+	var marker = make([]byte, len(r.syncMarker))
+	var count, size int64
+	var data []byte
+	var err error
+
+	if count, err = binary.ReadVarint(br); err != nil {
+		return 0, nil, err
+	}
+	if size, err = binary.ReadVarint(br); err != nil {
+		return 0, nil, noEOF(err)
+	}
+	if count < 0 || size < 0 {
+		return 0, nil, errors.New("Invalid Avro block header")
+	}
+	if size > int64(r.options.maxRecordSize) {
+		return 0, nil, ErrRecordTooLarge
+	}
+
+	data = make([]byte, size)
+	if _, err = readFull(ctx, r.wrappedReader, data); err != nil {
+		return 0, nil, noEOF(err)
+	}
+	if _, err = readFull(ctx, r.wrappedReader, marker); err != nil {
+		return 0, nil, noEOF(err)
+	}
+	if !bytes.Equal(marker, r.syncMarker) {
+		return 0, nil, errors.New("Avro sync marker mismatch")
+	}
+
+	if r.Codec() == AvroCodecDeflate {
+		if data, err = DeflateCodec.Decompress(data,
+			r.options.maxRecordSize); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return count, data, nil
+}
+
+/*
+ReadRecord reads the next data block from the input stream and returns the
+serialized objects contained in it.
+*/
+func (r *AvroReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var data []byte
+	var err error
+
+	_, data, err = r.ReadBlock(ctx)
+	return data, err
+}
+
+/*
+Close just delegates to the close function of the underlying reader.
+*/
+func (r *AvroReader) Close(ctx context.Context) error {
+	return r.wrappedReader.Close(ctx)
+}
+
+/*
+AvroWriter writes records as data blocks of an Avro Object Container File.
+Every record passed to Write() must be a single object serialized according to
+the schema passed to NewAvroWriter().
+
+As with RecordWriter, AvroWriters are not thread safe.
+*/
+type AvroWriter struct {
+	wrappedWriter filesystem.WriteCloser
+	codec         string
+	syncMarker    []byte
+}
+
+/*
+NewAvroWriter creates a new AvroWriter wrapped around the specified output
+stream and immediately writes the file header containing the given schema.
+codec must be one of AvroCodecNull or AvroCodecDeflate.
+*/
+func NewAvroWriter(ctx context.Context, writer filesystem.WriteCloser,
+	schema []byte, codec string) (*AvroWriter, error) {
+	var w = &AvroWriter{
+		wrappedWriter: writer,
+		codec:         codec,
+		syncMarker:    make([]byte, 16),
+	}
+	var header bytes.Buffer
+	var err error
+
+	if codec != AvroCodecNull && codec != AvroCodecDeflate {
+		return nil, errors.New("Unsupported Avro codec: " + codec)
+	}
+
+	if _, err = rand.Read(w.syncMarker); err != nil {
+		return nil, err
+	}
+
+	header.Write(avroMagic)
+	writeAvroLong(&header, 2)
+	writeAvroBytes(&header, []byte("avro.schema"))
+	writeAvroBytes(&header, schema)
+	writeAvroBytes(&header, []byte("avro.codec"))
+	writeAvroBytes(&header, []byte(codec))
+	writeAvroLong(&header, 0)
+	header.Write(w.syncMarker)
+
+	if err = writeFull(ctx, writer, header.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+/*
+WriteBlock writes a data block containing count serialized objects to the
+output stream, compressing it with the configured codec.
+*/
+func (w *AvroWriter) WriteBlock(ctx context.Context, count int64,
+	data []byte) error {
+	var block bytes.Buffer
+	var err error
+
+	if w.codec == AvroCodecDeflate {
+		var compressed bytes.Buffer
+		var fw *flate.Writer
+
+		if fw, err = flate.NewWriter(&compressed, flate.DefaultCompression); err != nil {
+			return err
+		}
+		if _, err = fw.Write(data); err != nil {
+			return err
+		}
+		if err = fw.Close(); err != nil {
+			return err
+		}
+		data = compressed.Bytes()
+	}
+
+	writeAvroLong(&block, count)
+	writeAvroLong(&block, int64(len(data)))
+	block.Write(data)
+	block.Write(w.syncMarker)
+
+	return writeFull(ctx, w.wrappedWriter, block.Bytes())
+}
+
+/*
+Write writes a single serialized object to the output stream as a new data
+block.
+*/
+func (w *AvroWriter) Write(ctx context.Context, rec []byte) (int, error) {
+	var err error
+
+	if err = w.WriteBlock(ctx, 1, rec); err != nil {
+		return 0, err
+	}
+	return len(rec), nil
+}
+
+/*
+Close just delegates to the close function of the underlying writer.
+*/
+func (w *AvroWriter) Close(ctx context.Context) error {
+	return w.wrappedWriter.Close(ctx)
+}
+
+/*
+readAvroBytes reads a byte string of at most limit bytes.
+*/
+func readAvroBytes(ctx context.Context, br *byteReader, limit uint32) (
+	[]byte, error) {
+	var length int64
+	var data []byte
+	var err error
+
+	if length, err = binary.ReadVarint(br); err != nil {
+		return nil, noEOF(err)
+	}
+	if length < 0 {
+		return nil, errors.New("Invalid Avro string length")
+	}
+	if length > int64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+	data = make([]byte, length)
+	if _, err = readFull(ctx, br.reader, data); err != nil {
+		return nil, noEOF(err)
+	}
+	return data, nil
+}
+
+func writeAvroLong(buf *bytes.Buffer, v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutVarint(tmp[:], v)])
+}
+
+func writeAvroBytes(buf *bytes.Buffer, data []byte) {
+	writeAvroLong(buf, int64(len(data)))
+	buf.Write(data)
+}
+
+/*
+noEOF converts an io.EOF encountered in the middle of a structure into
+io.ErrUnexpectedEOF.
+*/
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
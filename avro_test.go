@@ -0,0 +1,145 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Write two objects to an Avro container file using the specified codec and
+read them back, checking the schema and codec from the header.
+*/
+func testAvroRoundTrip(t *testing.T, codec string) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var schema = []byte("{\"type\": \"string\"}")
+	var writer *AvroWriter
+	var reader *AvroReader
+	var rec []byte
+	var count int64
+	var err error
+
+	writer, err = NewAvroWriter(ctx, buf, schema, codec)
+	if err != nil {
+		t.Fatal("Error creating Avro writer: ", err)
+	}
+
+	if _, err = writer.Write(ctx, []byte("\x0aHello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.WriteBlock(ctx, 2, []byte("\x0aWorld\x02!")); err != nil {
+		t.Error("Error writing block: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	reader, err = NewAvroReader(ctx, buf)
+	if err != nil {
+		t.Fatal("Error creating Avro reader: ", err)
+	}
+
+	if string(reader.Schema()) != string(schema) {
+		t.Error("Unexpected schema: ", string(reader.Schema()))
+	}
+	if reader.Codec() != codec {
+		t.Error("Unexpected codec: ", reader.Codec(), ", expected ", codec)
+	}
+
+	rec, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "\x0aHello" {
+		t.Errorf("Unexpected data: got %q, expected \"\\nHello\"", rec)
+	}
+
+	count, rec, err = reader.ReadBlock(ctx)
+	if err != nil {
+		t.Error("Error reading block: ", err)
+	}
+	if count != 2 {
+		t.Error("Expected 2 objects in block, got ", count)
+	}
+	if string(rec) != "\x0aWorld\x02!" {
+		t.Errorf("Unexpected data: got %q", rec)
+	}
+
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+}
+
+func TestAvroNullCodec(t *testing.T) {
+	testAvroRoundTrip(t, AvroCodecNull)
+}
+
+func TestAvroDeflateCodec(t *testing.T) {
+	testAvroRoundTrip(t, AvroCodecDeflate)
+}
+
+/*
+Check that reading a file which is not an Avro container fails right away.
+*/
+func TestAvroBadMagic(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var err error
+
+	buf.Write(ctx, []byte("Not avro"))
+	buf.Close(ctx)
+
+	if _, err = NewAvroReader(ctx, buf); err == nil {
+		t.Error("Expected error reading non-Avro data")
+	}
+}
+
+/*
+Blocks exceeding the maximum record size must be rejected before their data
+is allocated, as must negative sizes.
+*/
+func TestAvroBlockSizeLimit(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer *AvroWriter
+	var reader *AvroReader
+	var err error
+
+	writer, err = NewAvroWriter(ctx, buf, []byte("\"bytes\""), AvroCodecNull)
+	if err != nil {
+		t.Fatal("Error creating Avro writer: ", err)
+	}
+	writer.Write(ctx, make([]byte, 100))
+	writer.Close(ctx)
+
+	if reader, err = NewAvroReader(ctx, buf,
+		WithMaxRecordSize(50)); err != nil {
+		t.Fatal("Error creating Avro reader: ", err)
+	}
+	if _, err = reader.ReadRecord(ctx); err != ErrRecordTooLarge {
+		t.Error("Expected ErrRecordTooLarge, got ", err)
+	}
+
+	buf = internal.NewAnonymousFile()
+	NewAvroWriter(ctx, buf, []byte("\"bytes\""), AvroCodecNull)
+	buf.Write(ctx, []byte{2, 1})
+	buf.Close(ctx)
+
+	if reader, err = NewAvroReader(ctx, buf); err != nil {
+		t.Fatal("Error creating Avro reader: ", err)
+	}
+	if _, err = reader.ReadRecord(ctx); err == nil {
+		t.Error("Expected error for negative block size")
+	}
+
+	buf = internal.NewAnonymousFile()
+	NewAvroWriter(ctx, buf, make([]byte, 100), AvroCodecNull)
+	buf.Close(ctx)
+
+	if _, err = NewAvroReader(ctx, buf,
+		WithMaxRecordSize(50)); err != ErrRecordTooLarge {
+		t.Error("Expected ErrRecordTooLarge for metadata, got ", err)
+	}
+}
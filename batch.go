@@ -0,0 +1,233 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+)
+
+const (
+	/*
+		DefaultReadAllMaxRecords is the default maximum number of records
+		read by ReadAll().
+	*/
+	DefaultReadAllMaxRecords = 1000000
+
+	/*
+		DefaultReadAllMaxBytes is the default maximum total size of the
+		records read by ReadAll().
+	*/
+	DefaultReadAllMaxBytes = 256 << 20
+)
+
+/*
+ErrReadAllLimit is returned by ReadAll() if the stream contains more records
+or more data than permitted by the limits of the reader.
+*/
+var ErrReadAllLimit = errors.New("Stream exceeds ReadAll limits")
+
+/*
+ErrNegativeCount is returned by ReadRecords() if it is asked for a negative
+number of records.
+*/
+var ErrNegativeCount = errors.New("Negative number of records requested")
+
+/*
+WriteAll writes all of the specified records to the output stream. The
+records are framed into a single buffer first, which is then passed to the
+underlying output stream in a single call, avoiding the overhead of two
+calls per record when loading large numbers of small records.
+
+If any of the records cannot be encoded, nothing is written. Otherwise, the
+same rules as for Write() apply; if writing the buffer fails, a
+*PartialWriteError may be returned and the output stream may end in the
+middle of any of the records. The number of bytes added to the stream is
+returned.
+*/
+func (w *RecordWriter) WriteAll(ctx context.Context, recs [][]byte) (
+	int, error) {
+	var pos = w.position
+	var pooled = getBuffer(0)
+	var buf = *pooled
+	var body []byte
+	var written = make([]RecordInfo, len(recs))
+	var n int
+	var err error
+
+	defer func() {
+		*pooled = buf
+		putBuffer(pooled)
+	}()
+
+	if w.closed {
+		return 0, recordError(pos, ErrClosed)
+	}
+	if w.incomplete > 0 {
+		return 0, recordError(pos, ErrIncompleteRecord)
+	}
+
+	for i, rec := range recs {
+		written[i].Index = pos.Index + int64(i)
+		written[i].Offset = pos.Offset + int64(len(buf))
+		buf, body, written[i].Checksum, err = w.encodeRecord(buf, rec)
+		if err != nil {
+			return 0, recordError(Position{
+				Offset: written[i].Offset,
+				Index:  written[i].Index,
+			}, err)
+		}
+		written[i].Length = uint32(len(body))
+		buf = append(buf, body...)
+	}
+
+	if err = ctx.Err(); err != nil {
+		return 0, recordError(pos, err)
+	}
+	if err = w.limiter.wait(ctx, dataLength(recs), len(recs)); err != nil {
+		return 0, recordError(pos, err)
+	}
+	if err = w.flushBuffer(ctx); err != nil {
+		return 0, err
+	}
+
+	n, err = w.wrappedWriter.Write(ctx, buf)
+	w.position.Offset += int64(n)
+	if err == nil && n < len(buf) {
+		err = errors.New("Short write")
+	}
+	if err != nil {
+		return n, recordError(pos, w.partialWrite(n, err))
+	}
+
+	for _, info := range written {
+		w.recordWritten(Position{Offset: info.Offset, Index: info.Index},
+			info.Length, info.Checksum)
+	}
+	w.position.Index += int64(len(recs))
+	return n, nil
+}
+
+/*
+dataLength returns the total length of the specified records.
+*/
+func dataLength(recs [][]byte) int64 {
+	var total int64
+
+	for _, rec := range recs {
+		total += int64(len(rec))
+	}
+	return total
+}
+
+/*
+WriteMessages serializes all of the specified protocol buffers and writes
+them to the output stream as described for WriteAll().
+*/
+func (w *RecordWriter) WriteMessages(ctx context.Context,
+	pbs []Message) error {
+	var recs = make([][]byte, len(pbs))
+	var err error
+
+	for i, pb := range pbs {
+		if recs[i], err = w.options.marshalMessage(pb); err != nil {
+			return recordError(Position{
+				Offset: w.position.Offset,
+				Index:  w.position.Index + int64(i),
+			}, err)
+		}
+	}
+
+	_, err = w.WriteAll(ctx, recs)
+	return err
+}
+
+/*
+ReadAll reads all remaining records from the input stream until its end is
+reached. This is convenient for tests and small files, but holds all records
+in memory; to protect against unexpectedly large input, ErrReadAllLimit is
+returned along with the records read so far if the stream contains more
+records or data than permitted by WithReadAllLimits(). The limits are
+checked against the header of every record before its data is read; if a
+codec is used, the decompressed data is checked again once it has been read.
+*/
+func (r *RecordReader) ReadAll(ctx context.Context) ([][]byte, error) {
+	var recs [][]byte
+	var total int64
+	var pos Position
+	var length uint32
+	var rec []byte
+	var err error
+
+	for {
+		pos = r.position
+		if length, err = r.Peek(ctx); err == io.EOF {
+			return recs, nil
+		} else if err != nil && r.options.corruptionPolicy == FailFast {
+			return recs, err
+		} else if err == nil && (len(recs) >= r.options.readAllMaxRecords ||
+			int64(length) > r.options.readAllMaxBytes-total) {
+			return recs, recordError(pos, ErrReadAllLimit)
+		}
+
+		// Corrupted headers are skipped by ReadRecord() when salvaging.
+		if rec, err = r.ReadRecord(ctx); err == io.EOF {
+			return recs, nil
+		} else if err != nil {
+			return recs, err
+		}
+
+		total += int64(len(rec))
+		if len(recs) >= r.options.readAllMaxRecords ||
+			total > r.options.readAllMaxBytes {
+			return recs, recordError(pos, ErrReadAllLimit)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+/*
+ReadRecords reads up to n records from the input stream. On its first call,
+the reader switches to requesting data from the input stream in large
+chunks, from which this and all later reads are served, so that the cost of
+every call to the input stream is shared by many records. The size of the
+chunks follows the average size of the records read. This pays off
+for high-latency input streams, such as network file systems, but delays
+the detection of errors by up to the size of a chunk.
+
+Fewer than n records are returned at the end of the stream; if no records
+are left at all, io.EOF is returned. If reading a record fails, the records
+read before it are returned along with the error. If n is 0, an empty slice
+is returned without reading anything; ErrNegativeCount is returned if n is
+negative.
+*/
+func (r *RecordReader) ReadRecords(ctx context.Context, n int) ([][]byte,
+	error) {
+	var recs [][]byte
+	var rec []byte
+	var err error
+
+	if r.closed {
+		return nil, recordError(r.position, ErrClosed)
+	}
+	if n < 0 {
+		return nil, recordError(r.position, ErrNegativeCount)
+	}
+	if n == 0 {
+		return [][]byte{}, nil
+	}
+
+	recs = make([][]byte, 0, n)
+	r.wrappedReader = buffered(r.wrappedReader, &r.sizes,
+		r.options.alignment)
+
+	for len(recs) < n {
+		if rec, err = r.ReadRecord(ctx); err == io.EOF && len(recs) > 0 {
+			return recs, nil
+		} else if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+
+	return recs, nil
+}
@@ -0,0 +1,200 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+countingWriter counts the calls to its Write method.
+*/
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.writes++
+	return c.Buffer.Write(p)
+}
+
+/*
+WriteAll must write all records in a single call, and the records must read
+back individually.
+*/
+func TestWriteAll(t *testing.T) {
+	var ctx = context.Background()
+	var out countingWriter
+	var writer = NewIORecordWriter(&out, WithChecksum())
+	var reader *RecordReader
+	var msg MessageForTest
+	var rec []byte
+	var n int
+	var err error
+
+	n, err = writer.WriteAll(ctx, [][]byte{
+		[]byte("One"), []byte("Two"), []byte("Three")})
+	if err != nil {
+		t.Error("Error writing records: ", err)
+	}
+	if n != out.Len() || out.writes != 1 {
+		t.Error("Unexpected write: ", n, " bytes in ", out.writes, " calls")
+	}
+	if writer.Tell().Index != 3 {
+		t.Error("Unexpected record index: ", writer.Tell().Index)
+	}
+
+	err = writer.WriteMessages(ctx, []Message{
+		&MessageForTest{Message: "Four"}})
+	if err != nil {
+		t.Error("Error writing messages: ", err)
+	}
+
+	reader = NewIORecordReader(&out.Buffer, WithChecksum())
+	for _, expected := range []string{"One", "Two", "Three"} {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != expected {
+			t.Errorf("Unexpected data: got %q, expected %s", rec, expected)
+		}
+	}
+
+	if err = reader.ReadMessage(ctx, &msg); err != nil || msg.Message != "Four" {
+		t.Error("Unexpected message ", msg.Message, ", error: ", err)
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+ReadAll must return all records, and stop before reading a record which
+would exceed the limits.
+*/
+func TestReadAll(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var reader *RecordReader
+	var recs [][]byte
+	var rec []byte
+	var err error
+
+	NewIORecordWriter(&buf).WriteAll(ctx, [][]byte{
+		[]byte("One"), []byte("Two"), []byte("Three")})
+
+	recs, err = NewIORecordReader(bytes.NewReader(buf.Bytes())).ReadAll(ctx)
+	if err != nil {
+		t.Error("Error reading records: ", err)
+	}
+	if len(recs) != 3 || string(recs[2]) != "Three" {
+		t.Errorf("Unexpected records: %q", recs)
+	}
+
+	recs, err = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithReadAllLimits(2, 100)).ReadAll(ctx)
+	if !errors.Is(err, ErrReadAllLimit) || len(recs) != 2 {
+		t.Errorf("Unexpected result %q, error: %v", recs, err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithReadAllLimits(10, 6))
+	recs, err = reader.ReadAll(ctx)
+	if !errors.Is(err, ErrReadAllLimit) || len(recs) != 2 {
+		t.Errorf("Unexpected result %q, error: %v", recs, err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil ||
+		string(rec) != "Three" {
+		t.Errorf("Record exceeding the limits was consumed: %q, %v", rec, err)
+	}
+
+	recs, err = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithReadAllLimits(3, 11)).ReadAll(ctx)
+	if err != nil || len(recs) != 3 {
+		t.Errorf("Unexpected result %q, error: %v", recs, err)
+	}
+}
+
+/*
+countingReader counts the calls to its Read method.
+*/
+type countingReader struct {
+	*bytes.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.Reader.Read(p)
+}
+
+/*
+ReadRecords must return the records in batches while reading the input
+stream in few calls, and the reader must stay usable for seeking.
+*/
+func TestReadRecords(t *testing.T) {
+	var ctx = context.Background()
+	var in = &countingReader{Reader: bytes.NewReader(writeNumbered(t, 100))}
+	var reader = NewIORecordReader(in)
+	var recs [][]byte
+	var rec []byte
+	var total int
+	var err error
+
+	for _, expected := range []int{30, 30, 30, 10} {
+		if recs, err = reader.ReadRecords(ctx, 30); err != nil {
+			t.Error("Error reading records: ", err)
+		}
+		if len(recs) != expected {
+			t.Error("Unexpected number of records: ", len(recs))
+		}
+		for _, rec = range recs {
+			if string(rec) != fmt.Sprint("Rec ", total) {
+				t.Errorf("Unexpected data: got %q, expected Rec %d", rec, total)
+			}
+			total++
+		}
+	}
+	if recs, err = reader.ReadRecords(ctx, 30); err != io.EOF ||
+		len(recs) != 0 {
+		t.Errorf("Expected EOF, got %q, %v", recs, err)
+	}
+	if in.reads > 3 {
+		t.Error("Too many reads from the input stream: ", in.reads)
+	}
+
+	if err = reader.SeekToRecord(ctx, 42); err != nil {
+		t.Error("Error seeking: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Rec 42" {
+		t.Errorf("Unexpected record after seeking: %q (%v)", rec, err)
+	}
+}
+
+/*
+ReadRecords must reject negative counts and return an empty slice for a
+count of 0 without consuming any records.
+*/
+func TestReadRecordsCount(t *testing.T) {
+	var ctx = context.Background()
+	var reader = NewIORecordReader(bytes.NewReader(writeNumbered(t, 1)))
+	var recs [][]byte
+	var rec []byte
+	var err error
+
+	if _, err = reader.ReadRecords(ctx, -1); !errors.Is(
+		err, ErrNegativeCount) {
+		t.Error("Expected ErrNegativeCount, got: ", err)
+	}
+	if recs, err = reader.ReadRecords(ctx, 0); err != nil || recs == nil ||
+		len(recs) != 0 {
+		t.Errorf("Expected empty slice, got %q, %v", recs, err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Rec 0" {
+		t.Errorf("Unexpected record: %q (%v)", rec, err)
+	}
+}
@@ -0,0 +1,60 @@
+package recordio
+
+/*
+RecordInfo describes a record which has been written to the output stream.
+*/
+type RecordInfo struct {
+	/*
+		Index is the index of the record in the stream.
+	*/
+	Index int64
+
+	/*
+		Offset is the byte offset of the record in the stream, as reported
+		by Tell() before writing it.
+	*/
+	Offset int64
+
+	/*
+		Length is the length of the record data stored in the stream, which
+		is the compressed length if a codec is used.
+	*/
+	Length uint32
+
+	/*
+		Checksum is the CRC-32C checksum of the stored record data, or 0 if
+		checksums are not enabled.
+	*/
+	Checksum uint32
+}
+
+/*
+WithWriteCallback registers a function which writers call after every record
+has been written to the output stream successfully, so that external
+indexes, manifests or offset maps can be maintained while writing. The
+callback is invoked synchronously, so it should return quickly. Records
+written using WriteAll() are reported after the whole batch has been
+written.
+*/
+func WithWriteCallback(callback func(RecordInfo)) Option {
+	return func(o *options) {
+		o.writeCallback = callback
+	}
+}
+
+/*
+recordWritten is called after a record has been written completely, to add
+it to the sparse index and report it to the write callback.
+*/
+func (w *RecordWriter) recordWritten(pos Position, length, crc uint32) {
+	w.indexRecord(pos)
+
+	if w.options.writeCallback != nil {
+		w.options.writeCallback(RecordInfo{
+			Index:    pos.Index,
+			Offset:   pos.Offset,
+			Length:   length,
+			Checksum: crc,
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"strings"
+	"testing"
+)
+
+/*
+The write callback must be invoked for every record written, regardless of
+the method used to write it.
+*/
+func TestWriteCallback(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var infos []RecordInfo
+	var writer = NewIORecordWriter(&buf, WithChecksum(),
+		WithWriteCallback(func(info RecordInfo) {
+			infos = append(infos, info)
+		}))
+	var expected = []RecordInfo{
+		{Index: 0, Offset: 0, Length: 3, Checksum: checksum([]byte("One"))},
+		{Index: 1, Offset: 11, Length: 3, Checksum: checksum([]byte("Two"))},
+		{Index: 2, Offset: 22, Length: 5, Checksum: checksum([]byte("Three"))},
+		{Index: 3, Offset: 35, Length: 4, Checksum: checksum([]byte("Four"))},
+	}
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("One")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if _, err = writer.WriteAll(ctx, [][]byte{
+		[]byte("Two"), []byte("Three")}); err != nil {
+		t.Error("Error writing records: ", err)
+	}
+	if _, err = writer.WriteRecordFrom(ctx, strings.NewReader("Four"),
+		4); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	if len(infos) != len(expected) {
+		t.Fatal("Unexpected number of callbacks: ", infos)
+	}
+	for i := range expected {
+		if infos[i] != expected[i] {
+			t.Error("Unexpected record info: ", infos[i], ", expected ",
+				expected[i])
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Package capnp stores Cap'n Proto messages as records in recordio files.
+
+Cap'n Proto messages are stored in their standard (unpacked) stream encoding,
+one message per record. Reading a message does not copy or decode its
+contents: the returned message refers directly to the record data, so it
+stays valid for as long as the record data itself. Messages can be read from
+any record source; reading them from a recordio.MmapReader avoids copying
+them out of the file altogether.
+*/
+package capnp
+
+import (
+	"capnproto.org/go/capnp/v3"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+)
+
+/*
+WriteMessage serializes the specified Cap'n Proto message and writes it as a
+new record to the RecordWriter.
+
+The same warnings about locking as for RecordWriter.Write() apply to this
+function.
+*/
+func WriteMessage(ctx context.Context, w *recordio.RecordWriter,
+	msg *capnp.Message) error {
+	var b []byte
+	var err error
+
+	b, err = msg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(ctx, b)
+	return err
+}
+
+/*
+ReadMessage reads the next record from the record source and returns it as
+a Cap'n Proto message. The message aliases the record data returned by the
+source; no further copies are made. With a recordio.MmapReader, the message
+therefore points directly into the mapped file, and is only valid until the
+MmapReader is closed.
+
+All warnings from the ReadRecord() method of the source apply here as well.
+*/
+func ReadMessage(ctx context.Context, r recordio.RecordSource) (
+	*capnp.Message, error) {
+	var buf []byte
+	var err error
+
+	buf, err = r.ReadRecord(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return capnp.Unmarshal(buf)
+}
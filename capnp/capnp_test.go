@@ -0,0 +1,45 @@
+package capnp
+
+import (
+	"bytes"
+	"capnproto.org/go/capnp/v3"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Write a Cap'n Proto message as a record and read it back.
+*/
+func TestWriteAndReadMessage(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = recordio.NewRecordWriter(buf)
+	var msg, rmsg *capnp.Message
+	var expected, actual []byte
+	var err error
+
+	msg, _, err = capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal("Error creating message: ", err)
+	}
+
+	if err = WriteMessage(ctx, writer, msg); err != nil {
+		t.Error("Error writing message: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	rmsg, err = ReadMessage(ctx, recordio.NewRecordReader(buf))
+	if err != nil {
+		t.Fatal("Error reading message: ", err)
+	}
+
+	expected, _ = msg.Marshal()
+	actual, _ = rmsg.Marshal()
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Message mismatch: got %v, expected %v", actual, expected)
+	}
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package capnp
+
+import (
+	"bytes"
+	"capnproto.org/go/capnp/v3"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+Messages read from an MmapReader must be decoded from the mapped file.
+*/
+func TestReadMessageMmap(t *testing.T) {
+	var ctx = context.Background()
+	var name = filepath.Join(t.TempDir(), "messages")
+	var file *os.File
+	var writer *recordio.RecordWriter
+	var reader *recordio.MmapReader
+	var msg, rmsg *capnp.Message
+	var expected, actual []byte
+	var err error
+
+	if file, err = os.Create(name); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	writer = recordio.NewIORecordWriter(file)
+
+	msg, _, err = capnp.NewMessage(capnp.SingleSegment(nil))
+	if err != nil {
+		t.Fatal("Error creating message: ", err)
+	}
+	if err = WriteMessage(ctx, writer, msg); err != nil {
+		t.Error("Error writing message: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Fatal("Error closing writer: ", err)
+	}
+
+	if reader, err = recordio.OpenMmap(name); err != nil {
+		t.Fatal("Error mapping file: ", err)
+	}
+	defer reader.Close(ctx)
+
+	if rmsg, err = ReadMessage(ctx, reader); err != nil {
+		t.Fatal("Error reading message: ", err)
+	}
+
+	expected, _ = msg.Marshal()
+	actual, _ = rmsg.Marshal()
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("Message mismatch: got %v, expected %v", actual, expected)
+	}
+}
@@ -0,0 +1,29 @@
+/*
+Package cbor provides a recordio.Encoding which stores values as CBOR
+(RFC 8949), for pipelines which are standardized on CBOR:
+
+	err = writer.WriteEncoded(ctx, cbor.Encoding, &value)
+	err = reader.ReadEncoded(ctx, cbor.Encoding, &value)
+*/
+package cbor
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"github.com/fxamacker/cbor/v2"
+)
+
+/*
+Encoding serializes values using github.com/fxamacker/cbor with its default
+options, honoring its struct tags.
+*/
+var Encoding recordio.Encoding = cborEncoding{}
+
+type cborEncoding struct{}
+
+func (cborEncoding) Marshal(v interface{}) ([]byte, error) {
+	return cbor.Marshal(v)
+}
+
+func (cborEncoding) Unmarshal(data []byte, v interface{}) error {
+	return cbor.Unmarshal(data, v)
+}
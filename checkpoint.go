@@ -0,0 +1,135 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"errors"
+	"golang.org/x/net/context"
+)
+
+/*
+ErrInvalidCheckpoint is returned when decoding a checkpoint fails.
+*/
+var ErrInvalidCheckpoint = errors.New("Invalid checkpoint")
+
+/*
+checkpointVersion is the first byte of encoded checkpoints.
+*/
+const checkpointVersion = 1
+
+/*
+Checkpoint records the progress of a RecordReader, so that a consumer can
+persist it and resume reading exactly where it left off after a restart,
+using ResumeFrom() on a new reader for the same stream. Checkpoints
+implement encoding.BinaryMarshaler and encoding.BinaryUnmarshaler; the
+encoding takes only a few bytes.
+*/
+type Checkpoint struct {
+	/*
+		Position is the position of the next record to be read.
+	*/
+	Position Position
+
+	/*
+		Consumed is the number of bytes of the record at Position which
+		have already been read through NextRecordReader(), or 0 if it
+		has not been started.
+	*/
+	Consumed int64
+}
+
+/*
+Checkpoint returns a checkpoint of the current position of the reader. If
+a record is being streamed using NextRecordReader(), the checkpoint points
+at the beginning of that record and records how much of it has been
+consumed, so it can be continued with ResumeFrom() and NextRecordReader().
+*/
+func (r *RecordReader) Checkpoint() Checkpoint {
+	var cp = Checkpoint{Position: r.position}
+
+	if r.pending != nil && r.pending.remaining > 0 {
+		cp.Position = r.pending.start
+		cp.Consumed = r.pending.length - r.pending.remaining
+	}
+	return cp
+}
+
+/*
+ResumeFrom positions the reader at the checkpoint, which must have been
+taken by a reader of the same stream opened at the same offset with the
+same options. If the input stream implements Seeker, it is seeked directly;
+otherwise the reader can only move forward, and the data up to the
+checkpoint is read and discarded. If the checkpoint lies behind the current
+position of a stream which is not seekable, ErrNotSeekable is returned.
+
+If the checkpoint was taken in the middle of a record, the next call to
+NextRecordReader() continues streaming the rest of that record.
+*/
+func (r *RecordReader) ResumeFrom(ctx context.Context, cp Checkpoint) error {
+	var current = r.streamOffset()
+	var err error
+
+	if r.closed {
+		return recordError(r.position, ErrClosed)
+	}
+
+	if _, ok := r.wrappedReader.(Seeker); ok {
+		err = r.seekTo(ctx, cp.Position)
+	} else if cp.Position.Offset < current {
+		err = ErrNotSeekable
+	} else if err = r.skipBody(ctx, cp.Position.Offset-current); err == nil {
+		r.position = cp.Position
+		r.pending = nil
+		r.peeked = false
+		r.trailing = 0
+	}
+	if err != nil {
+		return recordError(r.position, noEOF(err))
+	}
+
+	r.resumeConsumed = cp.Consumed
+	return nil
+}
+
+/*
+MarshalBinary encodes the checkpoint as a version byte followed by the
+offset, index and consumed bytes as varints.
+*/
+func (cp Checkpoint) MarshalBinary() ([]byte, error) {
+	var buf = make([]byte, 0, 1+3*binary.MaxVarintLen64)
+
+	buf = append(buf, checkpointVersion)
+	buf = binary.AppendUvarint(buf, uint64(cp.Position.Offset))
+	buf = binary.AppendVarint(buf, cp.Position.Index)
+	buf = binary.AppendUvarint(buf, uint64(cp.Consumed))
+	return buf, nil
+}
+
+/*
+UnmarshalBinary decodes a checkpoint encoded by MarshalBinary().
+*/
+func (cp *Checkpoint) UnmarshalBinary(data []byte) error {
+	var offset, consumed uint64
+	var index int64
+	var n int
+
+	if len(data) == 0 || data[0] != checkpointVersion {
+		return ErrInvalidCheckpoint
+	}
+	data = data[1:]
+
+	if offset, n = binary.Uvarint(data); n <= 0 {
+		return ErrInvalidCheckpoint
+	}
+	data = data[n:]
+	if index, n = binary.Varint(data); n <= 0 {
+		return ErrInvalidCheckpoint
+	}
+	data = data[n:]
+	if consumed, n = binary.Uvarint(data); n <= 0 || n != len(data) {
+		return ErrInvalidCheckpoint
+	}
+
+	cp.Position = Position{Offset: int64(offset), Index: index}
+	cp.Consumed = int64(consumed)
+	return nil
+}
@@ -0,0 +1,80 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Readers must resume at the record following the checkpoint after it has
+been encoded and decoded, on seekable and non-seekable streams, and in the
+middle of a streamed record.
+*/
+func TestCheckpoint(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	var cp, decoded Checkpoint
+	var body io.Reader
+	var encoded, rec []byte
+	var length int64
+	var err error
+
+	if _, err = reader.Skip(ctx, 3); err != nil {
+		t.Error("Error skipping records: ", err)
+	}
+	if encoded, err = reader.Checkpoint().MarshalBinary(); err != nil {
+		t.Error("Error encoding checkpoint: ", err)
+	}
+	if err = decoded.UnmarshalBinary(encoded); err != nil {
+		t.Error("Error decoding checkpoint: ", err)
+	}
+	if decoded.Position != (Position{Offset: 39, Index: 3}) {
+		t.Error("Unexpected checkpoint: ", decoded)
+	}
+
+	for _, input := range []io.Reader{bytes.NewReader(data),
+		bytes.NewBuffer(data)} {
+		reader = NewIORecordReader(input, WithChecksum())
+		if err = reader.ResumeFrom(ctx, decoded); err != nil {
+			t.Error("Error resuming: ", err)
+		}
+		if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Rec 3" {
+			t.Errorf("Unexpected record after resuming: %q (%v)", rec, err)
+		}
+		if reader.Tell() != (Position{Offset: 52, Index: 4}) {
+			t.Error("Unexpected position after resuming: ", reader.Tell())
+		}
+	}
+
+	// Take a checkpoint after reading 2 bytes of a streamed record.
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if body, _, err = reader.NextRecordReader(ctx); err != nil {
+		t.Fatal("Error streaming record: ", err)
+	}
+	if _, err = io.ReadFull(body, make([]byte, 2)); err != nil {
+		t.Error("Error reading streamed record: ", err)
+	}
+	cp = reader.Checkpoint()
+	if cp.Position != (Position{}) || cp.Consumed != 2 {
+		t.Error("Unexpected checkpoint of streamed record: ", cp)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if err = reader.ResumeFrom(ctx, cp); err != nil {
+		t.Error("Error resuming: ", err)
+	}
+	if body, length, err = reader.NextRecordReader(ctx); err != nil {
+		t.Fatal("Error streaming record: ", err)
+	}
+	if rec, err = io.ReadAll(body); err != nil || string(rec) != "c 0" ||
+		length != 3 {
+		t.Errorf("Unexpected rest of record: %q, %d (%v)", rec, length, err)
+	}
+
+	if err = decoded.UnmarshalBinary(encoded[:2]); err != ErrInvalidCheckpoint {
+		t.Error("Expected invalid checkpoint, got: ", err)
+	}
+}
@@ -0,0 +1,58 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"hash/crc32"
+)
+
+/*
+ErrChecksumMismatch is returned when the checksum of a record does not match
+its data.
+*/
+var ErrChecksumMismatch = errors.New("Record checksum mismatch")
+
+/*
+crc32cTable is the table for the Castagnoli polynomial used for record
+checksums. hash/crc32 recognizes it and uses hardware acceleration instead
+of the table where the CPU supports it.
+*/
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+/*
+WithStrictChecksums makes readers verify the checksum of every record they
+pass over, not only of the records they return, so that bit rot is caught
+as soon as any part of a stream is consumed in production. This covers
+records passed over by Skip(), Count() and SeekToRecord(), records which do
+not fit the buffer passed to Read(), the unread remainder of records
+returned by NextRecordReader(), and records copied verbatim by
+CopyRecords(). The data of these records is then read rather than seeked
+over. ErrChecksumMismatch is returned for corrupted records; in the case
+of CopyRecords(), after the record has been copied with its checksum.
+
+The option has no effect unless WithChecksum() is used as well.
+*/
+func WithStrictChecksums() Option {
+	return func(o *options) {
+		o.strictChecksums = true
+	}
+}
+
+/*
+skipRecord skips over the data of a record like skipBody(), verifying its
+checksum if WithStrictChecksums() was used.
+*/
+func (r *RecordReader) skipRecord(ctx context.Context, length,
+	crc uint32) error {
+	if r.options.strictChecksums && r.options.checksum {
+		return r.verifyBody(ctx, int64(length), crc)
+	}
+	return r.skipBody(ctx, int64(length))
+}
+
+/*
+checksum computes the checksum of the specified record data.
+*/
+func checksum(data []byte) uint32 {
+	return crc32.Checksum(data, crc32cTable)
+}
@@ -0,0 +1,112 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Checksums must use the Castagnoli polynomial, as specified for CRC-32C.
+*/
+func TestChecksumCastagnoli(t *testing.T) {
+	if crc := checksum([]byte("123456789")); crc != 0xe3069283 {
+		t.Errorf("Unexpected checksum: %08x", crc)
+	}
+}
+
+/*
+With WithStrictChecksums(), corrupted records must be detected when they are
+skipped, counted or copied, whereas they pass unnoticed without it.
+*/
+func TestStrictChecksums(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 3, WithChecksum())
+	var strict = []Option{WithChecksum(), WithStrictChecksums()}
+	var reader *RecordReader
+	var out bytes.Buffer
+	var err error
+
+	// Flip the last byte of the second record.
+	data[2*13-1] ^= 1
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if _, err = reader.Skip(ctx, 3); err != nil {
+		t.Error("Unexpected error skipping records: ", err)
+	}
+	reader = NewIORecordReader(bytes.NewReader(data), strict...)
+	if _, err = reader.Skip(ctx, 3); !errors.Is(err, ErrChecksumMismatch) {
+		t.Error("Expected checksum mismatch skipping records, got ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if _, _, err = reader.Count(ctx); err != nil {
+		t.Error("Unexpected error counting records: ", err)
+	}
+	reader = NewIORecordReader(bytes.NewReader(data), strict...)
+	if _, _, err = reader.Count(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Error("Expected checksum mismatch counting records, got ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if _, err = CopyRecords(ctx, NewIORecordWriter(&out, WithChecksum()),
+		reader, 3); err != nil {
+		t.Error("Unexpected error copying records: ", err)
+	}
+	reader = NewIORecordReader(bytes.NewReader(data), strict...)
+	if _, err = CopyRecords(ctx, NewIORecordWriter(&out, WithChecksum()),
+		reader, 3); !errors.Is(err, ErrChecksumMismatch) {
+		t.Error("Expected checksum mismatch copying records, got ", err)
+	}
+}
+
+/*
+Write and read records of various sizes with and without checksums, to
+measure the overhead of computing and verifying them.
+*/
+func BenchmarkChecksum(b *testing.B) {
+	var ctx = context.Background()
+
+	for _, size := range []struct {
+		name   string
+		length int
+	}{
+		{"64B", 64},
+		{"4KiB", 4 << 10},
+		{"64KiB", 64 << 10},
+	} {
+		for _, opts := range []struct {
+			name    string
+			options []Option
+		}{
+			{"Plain", nil},
+			{"Checksum", []Option{WithChecksum()}},
+		} {
+			b.Run(size.name+"/"+opts.name, func(b *testing.B) {
+				var rec = bytes.Repeat([]byte{'x'}, size.length)
+				var stream = bytes.NewReader(nil)
+				var data bytes.Buffer
+				var writer = NewIORecordWriter(io.Discard, opts.options...)
+				var reader = NewIORecordReader(stream, opts.options...)
+				var buf []byte
+				var err error
+
+				NewIORecordWriter(&data, opts.options...).Write(ctx, rec)
+
+				b.SetBytes(int64(size.length))
+				for i := 0; i < b.N; i++ {
+					if _, err = writer.Write(ctx, rec); err != nil {
+						b.Fatal("Error writing record: ", err)
+					}
+					stream.Reset(data.Bytes())
+					if buf, err = reader.ReadRecordAppend(ctx,
+						buf[:0]); err != nil {
+						b.Fatal("Error reading record: ", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package recordio
+
+import (
+	"errors"
+	"io"
+)
+
+/*
+ErrNotCloneable is returned by Clone() for readers which were not created
+from an io.ReaderAt using NewCloneableRecordReader().
+*/
+var ErrNotCloneable = errors.New("Reader cannot be cloned")
+
+/*
+NewCloneableRecordReader creates a new RecordReader reading the records of
+the first size bytes of the specified io.ReaderAt, such as an os.File, from
+its beginning. Unlike
+other RecordReaders, it can be cloned using Clone(), so that a pool of
+workers can scan different parts of the same file concurrently without
+opening it once per worker. The options must match those used for writing
+the records. Closing the reader does not close the io.ReaderAt.
+*/
+func NewCloneableRecordReader(reader io.ReaderAt, size int64,
+	opts ...Option) *RecordReader {
+	var r = NewIORecordReader(io.NewSectionReader(reader, 0, size), opts...)
+
+	r.source = reader
+	r.sourceSize = size
+	return r
+}
+
+/*
+Clone returns an independent RecordReader over the same io.ReaderAt,
+positioned at the same record as r, which is the record following the one
+being streamed by NextRecordReader(), if any. The clone shares the options
+and the index of r, so each of them can seek to a different part of the
+stream using SeekToRecord() and read it on its own goroutine, as long as
+the io.ReaderAt supports concurrent calls, which os.File does. Rate limits
+apply to every clone separately.
+
+Only readers created using NewCloneableRecordReader(), and their clones,
+can be cloned; ErrNotCloneable is returned for all others.
+*/
+func (r *RecordReader) Clone() (*RecordReader, error) {
+	var section = io.NewSectionReader(r.source, 0, r.sourceSize)
+	var clone *RecordReader
+
+	if r.source == nil {
+		return nil, ErrNotCloneable
+	}
+	if r.closed {
+		return nil, recordError(r.position, ErrClosed)
+	}
+
+	section.Seek(r.position.Offset, io.SeekStart)
+	clone = &RecordReader{
+		options:        r.options,
+		source:         r.source,
+		sourceSize:     r.sourceSize,
+		position:       r.position,
+		index:          r.index,
+		resumeConsumed: r.resumeConsumed,
+	}
+	clone.limiter = newRateLimiter(clone.options)
+	clone.wrappedReader = clone.wrap(NewIOReadCloser(section))
+	return clone, nil
+}
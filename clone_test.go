@@ -0,0 +1,91 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"sync"
+	"testing"
+)
+
+/*
+Clones must read the records following the position of the original reader
+independently of it, and workers must be able to scan different ranges of
+the same stream concurrently using clones.
+*/
+func TestClone(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 100, WithFooter(), WithSparseIndex(10))
+	var reader = NewCloneableRecordReader(bytes.NewReader(data),
+		int64(len(data)), WithFooter(), WithSparseIndex(10))
+	var clone *RecordReader
+	var scanned [4][]string
+	var wg sync.WaitGroup
+	var rec []byte
+	var err error
+
+	if _, err = reader.Skip(ctx, 10); err != nil {
+		t.Fatal("Error skipping records: ", err)
+	}
+	if clone, err = reader.Clone(); err != nil {
+		t.Fatal("Error cloning reader: ", err)
+	}
+	if _, err = reader.Skip(ctx, 5); err != nil {
+		t.Error("Error skipping records: ", err)
+	}
+	if rec, err = clone.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record from clone: ", err)
+	} else if string(rec) != "Rec 10" {
+		t.Error("Unexpected record read from clone: ", string(rec))
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	} else if string(rec) != "Rec 15" {
+		t.Error("Unexpected record read: ", string(rec))
+	}
+
+	for i := range scanned {
+		if clone, err = reader.Clone(); err != nil {
+			t.Fatal("Error cloning reader: ", err)
+		}
+		wg.Add(1)
+		go func(i int, clone *RecordReader) {
+			defer wg.Done()
+			var rec []byte
+			var err error
+
+			if err = clone.SeekToRecord(ctx, int64(i*25)); err != nil {
+				t.Error("Error seeking clone: ", err)
+				return
+			}
+			for j := 0; j < 25; j++ {
+				if rec, err = clone.ReadRecord(ctx); err != nil {
+					t.Error("Error reading record from clone: ", err)
+					return
+				}
+				scanned[i] = append(scanned[i], string(rec))
+			}
+		}(i, clone)
+	}
+	wg.Wait()
+
+	for i := range scanned {
+		for j, rec := range scanned[i] {
+			if rec != fmt.Sprint("Rec ", i*25+j) {
+				t.Error("Unexpected record scanned: ", rec)
+			}
+		}
+	}
+}
+
+/*
+Readers which were not created from an io.ReaderAt must not be cloneable.
+*/
+func TestCloneUnsupported(t *testing.T) {
+	var reader = NewIORecordReader(bytes.NewReader(writeNumbered(t, 1)))
+	var err error
+
+	if _, err = reader.Clone(); err != ErrNotCloneable {
+		t.Error("Expected ErrNotCloneable, got: ", err)
+	}
+}
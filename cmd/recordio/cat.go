@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+)
+
+/*
+dumpSource is a stream of records which can be printed along with their
+positions.
+*/
+type dumpSource interface {
+	ReadRecord(ctx context.Context) ([]byte, error)
+	Tell() recordio.Position
+}
+
+/*
+dumpFlags holds the flags determining how records are printed:
+
+	hex    a hex dump of every record, preceded by a comment line giving
+	       its index and offset, as written by recordio.DumpText()
+	raw    the bytes of every record, each followed by a newline
+	proto  every record parsed as a protocol buffer of the type named by
+	       -type from the descriptor set given by -descriptors, in the text
+	       format and preceded by a comment line as for hex
+*/
+type dumpFlags struct {
+	format      string
+	descriptors string
+	typeName    string
+	desc        protoreflect.MessageDescriptor
+}
+
+/*
+register adds the dump flags to the flag set.
+*/
+func (d *dumpFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&d.format, "format", "hex",
+		"output format: hex, raw or proto")
+	flags.StringVar(&d.descriptors, "descriptors", "",
+		"file containing a FileDescriptorSet, for -format=proto")
+	flags.StringVar(&d.typeName, "type", "",
+		"full name of the message type, for -format=proto")
+}
+
+/*
+prepare checks the dump flags, and loads the message type for -format=proto.
+*/
+func (d *dumpFlags) prepare() error {
+	var err error
+
+	switch d.format {
+	case "hex", "raw":
+	case "proto":
+		if d.descriptors == "" || d.typeName == "" {
+			return &usageError{
+				"-format=proto requires -descriptors and -type"}
+		}
+		if d.desc, err = loadMessageType(d.descriptors,
+			d.typeName); err != nil {
+			return err
+		}
+	default:
+		return &usageError{"unknown output format " + d.format}
+	}
+	return nil
+}
+
+/*
+dump prints all records of the source in the selected format.
+*/
+func (d *dumpFlags) dump(ctx context.Context, w io.Writer,
+	source dumpSource) error {
+	var text = prototext.MarshalOptions{Multiline: true}
+	var pos recordio.Position
+	var msg protoreflect.ProtoMessage
+	var rec []byte
+	var err error
+
+	for {
+		pos = source.Tell()
+		if rec, err = source.ReadRecord(ctx); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch d.format {
+		case "raw":
+			_, err = fmt.Fprintf(w, "%s\n", rec)
+		case "hex":
+			_, err = fmt.Fprintf(w, "# record %d at offset %d\n%s",
+				pos.Index, pos.Offset, hex.Dump(rec))
+		case "proto":
+			if msg, err = parseMessage(d.desc, rec); err != nil {
+				return fmt.Errorf("Error parsing record %d: %w", pos.Index,
+					err)
+			}
+			_, err = fmt.Fprintf(w, "# record %d at offset %d\n%s\n",
+				pos.Index, pos.Offset, text.Format(msg))
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+runCat implements the cat command, which prints all records of the files
+given as arguments, one after the other, in the format selected by the dump
+flags.
+*/
+func runCat(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("cat", "<files...>", stderr)
+	var format formatFlags
+	var output dumpFlags
+	var opts []recordio.Option
+	var err error
+
+	format.register(flags, "")
+	output.register(flags)
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return &usageError{"no files specified"}
+	}
+	if opts, err = format.options(); err != nil {
+		return err
+	}
+	if err = output.prepare(); err != nil {
+		return err
+	}
+
+	return forEachFile(flags.Args(), opts,
+		func(name string, reader *recordio.RecordReader) error {
+			return output.dump(ctx, stdout, reader)
+		})
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"strings"
+	"testing"
+)
+
+/*
+cat must print the records of all files in raw format, one per line.
+*/
+func TestCatRaw(t *testing.T) {
+	var first = writeFile(t, []string{"one", "two"})
+	var second = writeFile(t, []string{"three"})
+	var code int
+	var stdout, stderr string
+
+	code, stdout, stderr = runTool("cat", "-format", "raw", first, second)
+	if code != 0 {
+		t.Error("Error running cat: ", stderr)
+	}
+	if stdout != "one\ntwo\nthree\n" {
+		t.Errorf("Unexpected output: %q", stdout)
+	}
+}
+
+/*
+cat must print hex dumps of the records along with their offsets, using the
+format specified on the command line.
+*/
+func TestCatHex(t *testing.T) {
+	var name = writeFile(t, []string{"Hello", "World"},
+		recordio.WithFraming(recordio.VarintFraming), recordio.WithChecksum())
+	var code int
+	var stdout, stderr string
+
+	code, stdout, stderr = runTool("cat", "-framing", "varint", "-checksum",
+		name)
+	if code != 0 {
+		t.Error("Error running cat: ", stderr)
+	}
+	if !strings.Contains(stdout, "# record 0 at offset 0\n") ||
+		!strings.Contains(stdout, "# record 1 at offset ") {
+		t.Error("Record markers missing from output: ", stdout)
+	}
+	if !strings.Contains(stdout, "48 65 6c 6c 6f") ||
+		!strings.Contains(stdout, "|World|") {
+		t.Error("Records missing from output: ", stdout)
+	}
+}
+
+/*
+Decoding protocol buffers requires a descriptor set and a message type.
+*/
+func TestCatProtoUsage(t *testing.T) {
+	var name = writeFile(t, []string{"one"})
+	var code int
+
+	if code, _, _ = runTool("cat", "-format", "proto", name); code != 2 {
+		t.Error("Unexpected exit code without descriptors: ", code)
+	}
+	code, _, _ = runTool("cat", "-format", "proto", "-descriptors",
+		name+".missing", "-type", "test.Message", name)
+	if code != 1 {
+		t.Error("Unexpected exit code for missing descriptors: ", code)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+)
+
+/*
+convertSource is a stream of records which can be converted.
+*/
+type convertSource interface {
+	recordio.RecordSource
+	Close(ctx context.Context) error
+}
+
+/*
+convertSink receives converted records.
+*/
+type convertSink interface {
+	recordio.RecordSink
+	Close(ctx context.Context) error
+}
+
+/*
+runConvert implements the convert command, which copies all records of the
+input file to a new output file in a different format. The formats of both
+are selected by -from and -to:
+
+	recordio      record files laid out as described by the format flags
+	              for the input and the flags prefixed with out- for the
+	              output
+	delimited     delimited protocol buffers, i.e. record files with
+	              varint framing regardless of the framing flags
+	tfrecord      TFRecord files as used by TensorFlow
+	jsonl         JSON Lines, one JSON document per record
+	jsonl-base64  JSON Lines, one base64 encoded string per record
+	riegeli       Riegeli files, only as input
+
+Records copied between record files with the same framing, checksums and
+codec are copied verbatim, as described for recordio.CopyRecords(). The
+output file is removed if the conversion fails.
+*/
+func runConvert(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("convert", "<input> <output>", stderr)
+	var in, out formatFlags
+	var from, to string
+	var inOpts, outOpts []recordio.Option
+	var source convertSource
+	var sink convertSink
+	var input, output *os.File
+	var copied int64
+	var err error
+
+	in.register(flags, "")
+	out.register(flags, "out-")
+	flags.StringVar(&from, "from", "recordio", "format of the input: "+
+		"recordio, delimited, tfrecord, jsonl, jsonl-base64 or riegeli")
+	flags.StringVar(&to, "to", "recordio", "format of the output: "+
+		"recordio, delimited, tfrecord, jsonl or jsonl-base64")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		return &usageError{"expected an input and an output file"}
+	}
+	if inOpts, err = in.options(); err != nil {
+		return err
+	}
+	if outOpts, err = out.options(); err != nil {
+		return err
+	}
+	switch from {
+	case "recordio", "delimited", "tfrecord", "jsonl", "jsonl-base64",
+		"riegeli":
+	default:
+		return &usageError{"unknown input format " + from}
+	}
+	switch to {
+	case "recordio", "delimited", "tfrecord", "jsonl", "jsonl-base64":
+	default:
+		return &usageError{"unknown output format " + to}
+	}
+
+	if input, err = os.Open(flags.Arg(0)); err != nil {
+		return err
+	}
+	switch from {
+	case "recordio":
+		source = recordio.NewIORecordReader(input, inOpts...)
+	case "delimited":
+		source = recordio.NewIORecordReader(input, append(inOpts,
+			recordio.WithFraming(recordio.VarintFraming))...)
+	case "tfrecord":
+		source = recordio.NewTFRecordReader(recordio.NewIOReadCloser(input))
+	case "jsonl":
+		source = recordio.NewJSONLReader(recordio.NewIOReadCloser(input),
+			recordio.JSONLDocuments)
+	case "jsonl-base64":
+		source = recordio.NewJSONLReader(recordio.NewIOReadCloser(input),
+			recordio.JSONLBase64)
+	case "riegeli":
+		source = recordio.NewRiegeliReader(recordio.NewIOReadCloser(input))
+	}
+	defer source.Close(ctx)
+
+	if output, err = os.Create(flags.Arg(1)); err != nil {
+		return err
+	}
+	switch to {
+	case "recordio":
+		sink = recordio.NewIORecordWriter(output, outOpts...)
+	case "delimited":
+		sink = recordio.NewIORecordWriter(output, append(outOpts,
+			recordio.WithFraming(recordio.VarintFraming))...)
+	case "tfrecord":
+		sink = recordio.NewTFRecordWriter(recordio.NewIOWriteCloser(output))
+	case "jsonl":
+		sink = recordio.NewJSONLWriter(recordio.NewIOWriteCloser(output),
+			recordio.JSONLDocuments)
+	case "jsonl-base64":
+		sink = recordio.NewJSONLWriter(recordio.NewIOWriteCloser(output),
+			recordio.JSONLBase64)
+	}
+
+	copied, err = convertRecords(ctx, sink, source)
+	if closeErr := sink.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(flags.Arg(1))
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Converted %d records\n", copied)
+	return nil
+}
+
+/*
+convertRecords copies all records from source to sink, copying them
+verbatim if both are record files, and returns the number of records copied.
+*/
+func convertRecords(ctx context.Context, sink convertSink,
+	source convertSource) (int64, error) {
+	var copied int
+	var err error
+
+	if reader, ok := source.(*recordio.RecordReader); ok {
+		if writer, ok := sink.(*recordio.RecordWriter); ok {
+			copied, err = recordio.CopyRecords(ctx, writer, reader, -1)
+			return int64(copied), err
+		}
+	}
+
+	return recordio.NewPipeline(source, sink).Run(ctx)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+Records must survive conversion between record file layouts, codecs,
+TFRecord files and JSON Lines in both directions.
+*/
+func TestConvert(t *testing.T) {
+	var dir = t.TempDir()
+	var input = writeFile(t, []string{`{"a": 1}`, `{"b": [2, 3]}`})
+	var compressed = filepath.Join(dir, "compressed")
+	var base64 = filepath.Join(dir, "base64.jsonl")
+	var tfrecord = filepath.Join(dir, "records.tfrecord")
+	var delimited = filepath.Join(dir, "delimited")
+	var zstd = filepath.Join(dir, "zstd")
+	var documents = filepath.Join(dir, "documents.jsonl")
+	var output = filepath.Join(dir, "output")
+	var data []byte
+	var code int
+	var stdout, stderr string
+	var err error
+
+	for _, args := range [][]string{
+		{"-out-framing", "varint", "-out-checksum", "-out-codec", "deflate",
+			"-out-footer", input, compressed},
+		{"-framing", "varint", "-checksum", "-codec", "deflate", "-footer",
+			"-to", "jsonl-base64", compressed, base64},
+		{"-from", "jsonl-base64", "-to", "tfrecord", base64, tfrecord},
+		{"-from", "tfrecord", "-to", "delimited", "-out-codec", "snappy",
+			tfrecord, delimited},
+		{"-from", "delimited", "-codec", "snappy", "-out-codec", "zstd",
+			delimited, zstd},
+		{"-codec", "zstd", "-to", "jsonl", zstd, documents},
+		{"-from", "jsonl", documents, output},
+	} {
+		code, stdout, stderr = runTool(append([]string{"convert"},
+			args...)...)
+		if code != 0 {
+			t.Error("Error converting: ", args, stderr)
+		}
+		if stdout != "Converted 2 records\n" {
+			t.Errorf("Unexpected output: %q", stdout)
+		}
+	}
+
+	if data, err = os.ReadFile(documents); err != nil {
+		t.Error("Error reading JSON Lines: ", err)
+	} else if string(data) != "{\"a\":1}\n{\"b\":[2,3]}\n" {
+		t.Errorf("Unexpected JSON Lines: %q", data)
+	}
+
+	code, stdout, stderr = runTool("cat", "-format", "raw", output)
+	if code != 0 {
+		t.Error("Error running cat: ", stderr)
+	}
+	if stdout != "{\"a\":1}\n{\"b\":[2,3]}\n" {
+		t.Errorf("Unexpected records: %q", stdout)
+	}
+}
+
+/*
+Failed conversions must not leave partial output behind, and unsupported
+formats must be rejected.
+*/
+func TestConvertErrors(t *testing.T) {
+	var input = writeFile(t, []string{"not JSON"})
+	var output = filepath.Join(t.TempDir(), "output.jsonl")
+	var code int
+	var err error
+
+	if code, _, _ = runTool("convert", "-to", "jsonl", input,
+		output); code != 1 {
+		t.Error("Unexpected exit code for invalid JSON: ", code)
+	}
+	if _, err = os.Stat(output); !os.IsNotExist(err) {
+		t.Error("Output of failed conversion not removed: ", err)
+	}
+
+	if code, _, _ = runTool("convert", "-to", "parquet", input,
+		output); code != 2 {
+		t.Error("Unexpected exit code for unknown format: ", code)
+	}
+	if code, _, _ = runTool("convert", input); code != 2 {
+		t.Error("Unexpected exit code without output: ", code)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+/*
+stringList is a flag which can be given several times, collecting all of
+its values.
+*/
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+/*
+fieldPredicate matches messages in which the field reached by following
+path has the specified value. Fields along the path may be repeated, in
+which case any of their values can match.
+*/
+type fieldPredicate struct {
+	path  []protoreflect.FieldDescriptor
+	value string
+}
+
+/*
+parsePredicate parses a predicate of the form field=value, where field is
+the name of a field of the message type, or a path of field names separated
+by dots to reach fields of nested messages, such as user.id=42. Enum fields
+match both the names and the numbers of their values.
+*/
+func parsePredicate(desc protoreflect.MessageDescriptor, where string) (
+	*fieldPredicate, error) {
+	var predicate = new(fieldPredicate)
+	var field protoreflect.FieldDescriptor
+	var path string
+	var ok bool
+
+	if path, predicate.value, ok = strings.Cut(where, "="); !ok {
+		return nil, &usageError{"expected field=value: " + where}
+	}
+
+	for _, name := range strings.Split(path, ".") {
+		if desc == nil {
+			return nil, &usageError{string(field.Name()) +
+				" is not a message: " + where}
+		}
+		if field = desc.Fields().ByName(protoreflect.Name(name)); field == nil {
+			return nil, &usageError{fmt.Sprintf("%s has no field %s",
+				desc.FullName(), name)}
+		}
+		if field.IsMap() {
+			return nil, &usageError{"map fields are not supported: " + where}
+		}
+
+		predicate.path = append(predicate.path, field)
+		desc = nil
+		if field.Kind() == protoreflect.MessageKind ||
+			field.Kind() == protoreflect.GroupKind {
+			desc = field.Message()
+		}
+	}
+
+	if desc != nil {
+		return nil, &usageError{"cannot compare message " + path}
+	}
+	return predicate, nil
+}
+
+/*
+matches returns whether the message satisfies the predicate.
+*/
+func (p *fieldPredicate) matches(msg protoreflect.Message) bool {
+	return p.matchPath(msg, 0)
+}
+
+/*
+matchPath matches the part of the path starting at the specified depth
+against the message.
+*/
+func (p *fieldPredicate) matchPath(msg protoreflect.Message,
+	depth int) bool {
+	var field = p.path[depth]
+	var value = msg.Get(field)
+	var list protoreflect.List
+
+	if !field.IsList() {
+		return p.matchValue(field, value, depth)
+	}
+
+	list = value.List()
+	for i := 0; i < list.Len(); i++ {
+		if p.matchValue(field, list.Get(i), depth) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+matchValue matches a single value of the field at the specified depth of
+the path, descending into it if it is a message.
+*/
+func (p *fieldPredicate) matchValue(field protoreflect.FieldDescriptor,
+	value protoreflect.Value, depth int) bool {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return p.matchPath(value.Message(), depth+1)
+	case protoreflect.BoolKind:
+		var b, err = strconv.ParseBool(p.value)
+		return err == nil && b == value.Bool()
+	case protoreflect.EnumKind:
+		var enum = field.Enum().Values().ByNumber(value.Enum())
+		if enum != nil && string(enum.Name()) == p.value {
+			return true
+		}
+		return p.value == strconv.FormatInt(int64(value.Enum()), 10)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		var i, err = strconv.ParseInt(p.value, 0, 64)
+		return err == nil && i == value.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		var u, err = strconv.ParseUint(p.value, 0, 64)
+		return err == nil && u == value.Uint()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		var f, err = strconv.ParseFloat(p.value, 64)
+		return err == nil && f == value.Float()
+	case protoreflect.StringKind:
+		return p.value == value.String()
+	case protoreflect.BytesKind:
+		return p.value == string(value.Bytes())
+	}
+	return false
+}
+
+/*
+runGrep implements the grep command, which parses the records of the files
+given as arguments as protocol buffers of the type named by -type from the
+descriptor set given by -descriptors, and copies the records matching all
+-where predicates unchanged to a new record file given by -o, with the same
+format flags. The number of matching records is printed.
+*/
+func runGrep(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("grep", "<files or shard sets...>", stderr)
+	var format formatFlags
+	var where stringList
+	var descriptors, typeName, output string
+	var desc protoreflect.MessageDescriptor
+	var predicates []*fieldPredicate
+	var predicate *fieldPredicate
+	var names []string
+	var opts []recordio.Option
+	var writer *recordio.RecordWriter
+	var file *os.File
+	var matched, total int64
+	var err error
+
+	format.register(flags, "")
+	flags.Var(&where, "where",
+		"field=value predicate which records must match; may be repeated")
+	flags.StringVar(&descriptors, "descriptors", "",
+		"file containing a FileDescriptorSet")
+	flags.StringVar(&typeName, "type", "",
+		"full name of the message type of the records")
+	flags.StringVar(&output, "o", "", "file to write the matching records to")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return &usageError{"no files specified"}
+	}
+	if descriptors == "" || typeName == "" {
+		return &usageError{"-descriptors and -type are required"}
+	}
+	if len(where) == 0 {
+		return &usageError{"no -where predicates specified"}
+	}
+	if output == "" {
+		return &usageError{"no output file specified with -o"}
+	}
+	if opts, err = format.options(); err != nil {
+		return err
+	}
+	if names, err = expandNames(flags.Args()); err != nil {
+		return err
+	}
+
+	if desc, err = loadMessageType(descriptors, typeName); err != nil {
+		return err
+	}
+	for _, w := range where {
+		if predicate, err = parsePredicate(desc, w); err != nil {
+			return err
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	if file, err = os.Create(output); err != nil {
+		return err
+	}
+	writer = recordio.NewIORecordWriter(file, opts...)
+
+	err = forEachFile(names, opts,
+		func(name string, reader *recordio.RecordReader) error {
+			var pos recordio.Position
+			var msg protoreflect.ProtoMessage
+			var rec []byte
+			var err error
+
+			for {
+				pos = reader.Tell()
+				if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				total++
+
+				if msg, err = parseMessage(desc, rec); err != nil {
+					return fmt.Errorf("Error parsing record %d: %w",
+						pos.Index, err)
+				}
+				if !matchesAll(msg.ProtoReflect(), predicates) {
+					continue
+				}
+				if _, err = writer.Write(ctx, rec); err != nil {
+					return err
+				}
+				matched++
+			}
+		})
+	if closeErr := writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Matched %d of %d records\n", matched, total)
+	return nil
+}
+
+/*
+matchesAll returns whether the message satisfies all predicates.
+*/
+func matchesAll(msg protoreflect.Message,
+	predicates []*fieldPredicate) bool {
+	for _, predicate := range predicates {
+		if !predicate.matches(msg) {
+			return false
+		}
+	}
+	return true
+}
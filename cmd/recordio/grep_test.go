@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+grep must require a descriptor set, a message type, predicates and an output
+file, and must not create the output file if the descriptors cannot be
+loaded.
+*/
+func TestGrepUsage(t *testing.T) {
+	var input = writeFile(t, []string{"one"})
+	var dir = t.TempDir()
+	var output = filepath.Join(dir, "matches")
+	var descriptors = filepath.Join(dir, "missing.pb")
+	var code int
+	var err error
+
+	for _, args := range [][]string{
+		{"-type", "test.Message", "-where", "id=1", "-o", output, input},
+		{"-descriptors", descriptors, "-where", "id=1", "-o", output, input},
+		{"-descriptors", descriptors, "-type", "test.Message", "-o", output,
+			input},
+		{"-descriptors", descriptors, "-type", "test.Message", "-where",
+			"id=1", input},
+		{"-descriptors", descriptors, "-type", "test.Message", "-where",
+			"id=1", "-o", output},
+	} {
+		code, _, _ = runTool(append([]string{"grep"}, args...)...)
+		if code != 2 {
+			t.Error("Unexpected exit code for ", args, ": ", code)
+		}
+	}
+
+	code, _, _ = runTool("grep", "-descriptors", descriptors, "-type",
+		"test.Message", "-where", "id=1", "-where", "name=x", "-o", output,
+		input)
+	if code != 1 {
+		t.Error("Unexpected exit code for missing descriptors: ", code)
+	}
+	if _, err = os.Stat(output); !os.IsNotExist(err) {
+		t.Error("Output created despite failure: ", err)
+	}
+}
+
+/*
+-where must be collected every time it is given.
+*/
+func TestStringList(t *testing.T) {
+	var list stringList
+
+	list.Set("a=1")
+	list.Set("b=2")
+	if len(list) != 2 || list.String() != "a=1, b=2" {
+		t.Errorf("Unexpected list: %q", list.String())
+	}
+}
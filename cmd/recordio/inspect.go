@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+recordStats accumulates the number and sizes of records.
+*/
+type recordStats struct {
+	records  int64
+	bytes    int64
+	min, max int
+}
+
+/*
+add counts a record of the specified size.
+*/
+func (s *recordStats) add(size int) {
+	if s.records == 0 || size < s.min {
+		s.min = size
+	}
+	if size > s.max {
+		s.max = size
+	}
+	s.records++
+	s.bytes += int64(size)
+}
+
+/*
+merge adds the records counted by other.
+*/
+func (s *recordStats) merge(other recordStats) {
+	if other.records == 0 {
+		return
+	}
+	if s.records == 0 || other.min < s.min {
+		s.min = other.min
+	}
+	if other.max > s.max {
+		s.max = other.max
+	}
+	s.records += other.records
+	s.bytes += other.bytes
+}
+
+/*
+print writes the statistics to w.
+*/
+func (s *recordStats) print(w io.Writer) {
+	var mean float64
+
+	if s.records > 0 {
+		mean = float64(s.bytes) / float64(s.records)
+	}
+	fmt.Fprintf(w, "  records:     %d\n", s.records)
+	fmt.Fprintf(w, "  bytes:       %d\n", s.bytes)
+	fmt.Fprintf(w, "  record size: min %d, mean %.1f, max %d\n", s.min, mean,
+		s.max)
+}
+
+/*
+runInspect implements the inspect command, which reads all records of the
+files given as arguments and reports their number and sizes, along with the
+codec and checksums they were read with and whether they end in a footer.
+Sizes are those of the records after decompression. Shard sets can be
+inspected by passing their base name, in which case the totals across all
+shards are reported as well.
+
+Footers are detected regardless of the -footer flag.
+*/
+func runInspect(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("inspect", "<files or shard sets...>", stderr)
+	var format formatFlags
+	var total recordStats
+	var names []string
+	var opts []recordio.Option
+	var err error
+
+	format.register(flags, "")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return &usageError{"no files specified"}
+	}
+	if opts, err = format.options(); err != nil {
+		return err
+	}
+	if names, err = expandNames(flags.Args()); err != nil {
+		return err
+	}
+
+	// Stop at footers even if they were not announced, rather than failing
+	// on their header.
+	opts = append(opts, recordio.WithFooter())
+
+	err = forEachFile(names, opts,
+		func(name string, reader *recordio.RecordReader) error {
+			var stats recordStats
+			var index *recordio.RecordIndex
+			var rec []byte
+			var err error
+
+			if index, err = reader.FooterIndex(ctx); err != nil {
+				return err
+			}
+			for {
+				if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+					break
+				} else if err != nil {
+					return err
+				}
+				stats.add(len(rec))
+			}
+
+			fmt.Fprintf(stdout, "%s:\n", name)
+			stats.print(stdout)
+			fmt.Fprintf(stdout, "  codec:       %s\n", format.codec)
+			if format.checksum {
+				fmt.Fprintf(stdout, "  checksums:   CRC-32C\n")
+			} else {
+				fmt.Fprintf(stdout, "  checksums:   none\n")
+			}
+			if index == nil {
+				fmt.Fprintf(stdout, "  footer:      none\n")
+			} else if _, ok := index.Record(0); ok {
+				fmt.Fprintf(stdout, "  footer:      %d records, sparse "+
+					"index\n", index.Len())
+			} else {
+				fmt.Fprintf(stdout, "  footer:      %d records, no index\n",
+					index.Len())
+			}
+
+			total.merge(stats)
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	if len(names) > 1 {
+		fmt.Fprintf(stdout, "total (%d files):\n", len(names))
+		total.print(stdout)
+	}
+	return nil
+}
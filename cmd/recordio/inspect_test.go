@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+inspect must report the number and sizes of the records and detect footers.
+*/
+func TestInspect(t *testing.T) {
+	var name = writeFile(t, []string{"a", "bbb", "bb"},
+		recordio.WithChecksum(), recordio.WithSparseIndex(2))
+	var code int
+	var stdout, stderr string
+
+	code, stdout, stderr = runTool("inspect", "-checksum", name)
+	if code != 0 {
+		t.Error("Error running inspect: ", stderr)
+	}
+	for _, expected := range []string{
+		"records:     3\n",
+		"bytes:       6\n",
+		"record size: min 1, mean 2.0, max 3\n",
+		"codec:       none\n",
+		"checksums:   CRC-32C\n",
+		"footer:      3 records, sparse index\n",
+	} {
+		if !strings.Contains(stdout, expected) {
+			t.Errorf("Expected %q in output: %s", expected, stdout)
+		}
+	}
+	if strings.Contains(stdout, "total") {
+		t.Error("Unexpected total for a single file: ", stdout)
+	}
+}
+
+/*
+Shard sets must be inspected shard by shard, followed by the totals, and be
+rejected if shards are missing.
+*/
+func TestInspectShardSet(t *testing.T) {
+	var base = filepath.Join(t.TempDir(), "sharded")
+	var code int
+	var stdout, stderr string
+
+	writeNamed(t, recordio.ShardName(base, 0, 2), []string{"one", "two"})
+	writeNamed(t, recordio.ShardName(base, 1, 2), []string{"three"},
+		recordio.WithFooter())
+
+	code, stdout, stderr = runTool("inspect", base)
+	if code != 0 {
+		t.Error("Error running inspect: ", stderr)
+	}
+	if !strings.Contains(stdout, recordio.ShardName(base, 0, 2)+":\n") ||
+		!strings.Contains(stdout, recordio.ShardName(base, 1, 2)+":\n") {
+		t.Error("Shards missing from output: ", stdout)
+	}
+	if !strings.Contains(stdout, "footer:      none\n") ||
+		!strings.Contains(stdout, "footer:      1 records, no index\n") {
+		t.Error("Unexpected footers in output: ", stdout)
+	}
+	if !strings.Contains(stdout, "total (2 files):\n  records:     3\n"+
+		"  bytes:       11\n  record size: min 3, mean 3.7, max 5\n") {
+		t.Error("Unexpected totals in output: ", stdout)
+	}
+
+	writeNamed(t, recordio.ShardName(base, 2, 4), []string{"four"})
+	if code, _, _ = runTool("inspect", base); code != 1 {
+		t.Error("Unexpected exit code for inconsistent shard set: ", code)
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Command recordio inspects and manipulates record files written by the
+recordio package, so that they can be examined without writing a program
+for it.
+
+Usage:
+
+	recordio <command> [flags] <files...>
+
+The commands are:
+
+	cat      print the records of files as hex, raw bytes or protocol buffers
+	inspect  report the number and sizes of records in files or shard sets
+	verify   check the framing, checksums and footers of files
+	convert  copy records to a file of a different format
+	head     extract the first records of a file
+	tail     extract the last records of a file
+	slice    extract a range of records from a file
+	grep     copy the records matching protocol buffer field values to a
+	         new file
+	split    distribute the records of files across new shards
+	merge    concatenate the records of files into a new file
+
+Run "recordio <command> -h" for the flags of a command. The flags describing
+the layout of the files, such as -framing, -checksum and -codec, must match
+those used when writing them, since the files do not record them.
+*/
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+/*
+command is a subcommand of the recordio tool. run is called with the
+arguments following the name of the command, and writes its output to
+stdout; usage messages and diagnostics go to stderr.
+*/
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, stdout,
+		stderr io.Writer) error
+}
+
+/*
+commands lists all commands of the recordio tool.
+*/
+var commands = []*command{
+	{"cat", "print the records of files as hex, raw bytes or protocol " +
+		"buffers", runCat},
+	{"inspect", "report the number and sizes of records in files or shard " +
+		"sets", runInspect},
+	{"verify", "check the framing, checksums and footers of files",
+		runVerify},
+	{"convert", "copy records to a file of a different format", runConvert},
+	{"head", "extract the first records of a file", runHead},
+	{"tail", "extract the last records of a file", runTail},
+	{"slice", "extract a range of records from a file", runSlice},
+	{"grep", "copy the records matching protocol buffer field values to a " +
+		"new file", runGrep},
+	{"split", "distribute the records of files across new shards", runSplit},
+	{"merge", "concatenate the records of files into a new file", runMerge},
+}
+
+/*
+usageError is returned by commands which have been invoked incorrectly.
+*/
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdout, os.Stderr))
+}
+
+/*
+run executes the command named by the first argument and returns the exit
+code of the tool: 0 on success, 1 if the command failed and 2 if it was
+invoked incorrectly.
+*/
+func run(ctx context.Context, args []string, stdout,
+	stderr io.Writer) int {
+	var usage *usageError
+	var err error
+
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != args[0] {
+			continue
+		}
+
+		if err = cmd.run(ctx, args[1:], stdout, stderr); err == nil {
+			return 0
+		} else if errors.Is(err, flag.ErrHelp) {
+			return 2
+		} else if errors.As(err, &usage) {
+			fmt.Fprintf(stderr, "recordio %s: %v\n", cmd.name, err)
+			return 2
+		}
+		fmt.Fprintf(stderr, "recordio %s: %v\n", cmd.name, err)
+		return 1
+	}
+
+	fmt.Fprintf(stderr, "recordio: unknown command %q\n", args[0])
+	printUsage(stderr)
+	return 2
+}
+
+/*
+printUsage lists the commands of the tool.
+*/
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: recordio <command> [flags] <files...>")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+/*
+newFlagSet creates the flag set of a command, reporting errors to stderr
+rather than exiting.
+*/
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	var flags = flag.NewFlagSet(name, flag.ContinueOnError)
+
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: recordio %s [flags] %s\n\nFlags:\n", name,
+			args)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+/*
+formatFlags holds the flags describing the layout of record files.
+*/
+type formatFlags struct {
+	framing  string
+	checksum bool
+	codec    string
+	footer   bool
+}
+
+/*
+register adds the format flags to the flag set, with names starting with
+prefix, so that the layouts of input and output files can be specified
+separately.
+*/
+func (f *formatFlags) register(flags *flag.FlagSet, prefix string) {
+	flags.StringVar(&f.framing, prefix+"framing", "fixed",
+		"framing of the records: fixed, varint or littleendian")
+	flags.BoolVar(&f.checksum, prefix+"checksum", false,
+		"records carry CRC-32C checksums")
+	flags.StringVar(&f.codec, prefix+"codec", "none",
+		"codec compressing the records: none, deflate, snappy or zstd")
+	flags.BoolVar(&f.footer, prefix+"footer", false, "files end in a footer")
+}
+
+/*
+options returns the recordio options corresponding to the format flags.
+*/
+func (f *formatFlags) options() ([]recordio.Option, error) {
+	var builder = recordio.NewFormatBuilder()
+	var format recordio.Format
+	var opts []recordio.Option
+	var err error
+
+	switch f.framing {
+	case "fixed":
+		builder.Framing(recordio.FixedLengthFraming)
+	case "varint":
+		builder.Framing(recordio.VarintFraming)
+	case "littleendian":
+		builder.Framing(recordio.LittleEndianFraming)
+	default:
+		return nil, &usageError{"unknown framing " + f.framing}
+	}
+
+	if f.checksum {
+		builder.Checksum()
+	}
+
+	switch f.codec {
+	case "none":
+	case "deflate":
+		builder.Codec(recordio.DeflateCodec)
+	case "snappy":
+		builder.Codec(recordio.SnappyCodec)
+	case "zstd":
+		builder.Codec(recordio.ZstdCodec)
+	default:
+		return nil, &usageError{"unknown codec " + f.codec}
+	}
+
+	if format, err = builder.Build(); err != nil {
+		return nil, &usageError{err.Error()}
+	}
+	opts = append(opts, recordio.WithFormat(format))
+	if f.footer {
+		opts = append(opts, recordio.WithFooter())
+	}
+	return opts, nil
+}
+
+/*
+expandNames expands the file arguments of a command into file names. Glob
+patterns are expanded to the files matching them. Names of files which do
+not exist are taken to be the base names of shard sets, and replaced by the
+names of all their shards, which must all exist.
+*/
+func expandNames(args []string) ([]string, error) {
+	var names, matches []string
+	var err error
+
+	for _, arg := range args {
+		if _, err = os.Stat(arg); err == nil {
+			names = append(names, arg)
+			continue
+		}
+
+		if matches, err = filepath.Glob(arg); err != nil {
+			return nil, &usageError{err.Error()}
+		}
+		if len(matches) == 0 {
+			if matches, err = shardSet(arg); err != nil {
+				return nil, err
+			}
+		}
+		names = append(names, matches...)
+	}
+	return names, nil
+}
+
+/*
+shardSet returns the names of all shards of the sharded file with the
+specified base name, as found on disk. An error is returned if there are no
+shards, or if any of them are missing.
+*/
+func shardSet(base string) ([]string, error) {
+	var matches, names []string
+	var numShards int
+	var err error
+
+	if matches, err = filepath.Glob(base + "-*-of-*"); err != nil {
+		return nil, &usageError{err.Error()}
+	}
+	for _, match := range matches {
+		var matchBase string
+		var num int
+
+		if matchBase, _, num, err = recordio.ParseShardName(
+			match); err != nil || matchBase != base {
+			continue
+		}
+		if numShards == 0 {
+			numShards = num
+		} else if num != numShards {
+			return nil, fmt.Errorf("%s: Shards of different sets found",
+				base)
+		}
+		names = append(names, match)
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: No such file or shard set", base)
+	}
+	if len(names) != numShards {
+		return nil, fmt.Errorf("%s: Found %d of %d shards", base, len(names),
+			numShards)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+/*
+forEachFile opens every one of the named files as a RecordReader with the
+specified options and calls fn for it, closing the file afterwards.
+*/
+func forEachFile(names []string, opts []recordio.Option,
+	fn func(name string, reader *recordio.RecordReader) error) error {
+	var file *os.File
+	var err error
+
+	for _, name := range names {
+		if file, err = os.Open(name); err != nil {
+			return err
+		}
+
+		err = fn(name, recordio.NewIORecordReader(file, opts...))
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
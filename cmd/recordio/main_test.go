@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+writeFile writes the records to a new file in a temporary directory with
+the specified options and returns its name.
+*/
+func writeFile(t *testing.T, recs []string, opts ...recordio.Option) string {
+	var name = filepath.Join(t.TempDir(), "records")
+
+	writeNamed(t, name, recs, opts...)
+	return name
+}
+
+/*
+writeNamed writes the records to the named file with the specified options.
+Closing the writer closes the file.
+*/
+func writeNamed(t *testing.T, name string, recs []string,
+	opts ...recordio.Option) {
+	var ctx = context.Background()
+	var writer *recordio.RecordWriter
+	var file *os.File
+	var err error
+
+	if file, err = os.Create(name); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	writer = recordio.NewIORecordWriter(file, opts...)
+	for _, rec := range recs {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+}
+
+/*
+runTool runs the tool with the specified arguments and returns its exit code
+and output.
+*/
+func runTool(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	var code = run(context.Background(), args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+/*
+Unknown commands and missing arguments must be reported as usage errors.
+*/
+func TestRunUsage(t *testing.T) {
+	var code int
+	var stderr string
+
+	if code, _, stderr = runTool(); code != 2 {
+		t.Error("Unexpected exit code without command: ", code)
+	} else if !strings.Contains(stderr, "cat") {
+		t.Error("Commands not listed in usage: ", stderr)
+	}
+
+	if code, _, stderr = runTool("frobnicate"); code != 2 {
+		t.Error("Unexpected exit code for unknown command: ", code)
+	} else if !strings.Contains(stderr, "unknown command") {
+		t.Error("Unexpected error for unknown command: ", stderr)
+	}
+
+	if code, _, _ = runTool("cat"); code != 2 {
+		t.Error("Unexpected exit code without files: ", code)
+	}
+	if code, _, _ = runTool("cat", "-framing", "bogus", "x"); code != 2 {
+		t.Error("Unexpected exit code for unknown framing: ", code)
+	}
+	if code, _, _ = runTool("cat", "-h"); code != 2 {
+		t.Error("Unexpected exit code for help: ", code)
+	}
+}
+
+/*
+Errors reading files must make the tool fail.
+*/
+func TestRunError(t *testing.T) {
+	var code int
+	var stderr string
+
+	code, _, stderr = runTool("cat",
+		filepath.Join(t.TempDir(), "missing"))
+	if code != 1 {
+		t.Error("Unexpected exit code for missing file: ", code)
+	}
+	if !strings.HasPrefix(stderr, "recordio cat: ") {
+		t.Error("Unexpected error for missing file: ", stderr)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"os"
+)
+
+/*
+loadMessageType reads the serialized FileDescriptorSet from the named file,
+as produced by "protoc --include_imports --descriptor_set_out", and looks up
+the message type with the specified full name in it. Messages of the type
+can then be parsed without the generated code using dynamicpb.
+*/
+func loadMessageType(descriptors, typeName string) (
+	protoreflect.MessageDescriptor, error) {
+	var set descriptorpb.FileDescriptorSet
+	var files *protoregistry.Files
+	var desc protoreflect.Descriptor
+	var msg protoreflect.MessageDescriptor
+	var data []byte
+	var ok bool
+	var err error
+
+	if data, err = os.ReadFile(descriptors); err != nil {
+		return nil, err
+	}
+	if err = proto.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("Error parsing descriptor set %s: %w",
+			descriptors, err)
+	}
+	if files, err = protodesc.NewFiles(&set); err != nil {
+		return nil, fmt.Errorf("Error loading descriptor set %s: %w",
+			descriptors, err)
+	}
+
+	desc, err = files.FindDescriptorByName(protoreflect.FullName(typeName))
+	if err != nil {
+		return nil, err
+	}
+	if msg, ok = desc.(protoreflect.MessageDescriptor); !ok {
+		return nil, fmt.Errorf("%s is not a message type", typeName)
+	}
+	return msg, nil
+}
+
+/*
+parseMessage parses the record as a message of the specified type.
+*/
+func parseMessage(desc protoreflect.MessageDescriptor, rec []byte) (
+	*dynamicpb.Message, error) {
+	var msg = dynamicpb.NewMessage(desc)
+
+	if err := proto.Unmarshal(rec, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
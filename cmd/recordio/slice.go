@@ -0,0 +1,218 @@
+package main
+
+import (
+	"flag"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"io"
+	"math"
+	"os"
+)
+
+/*
+sliceFlags holds the flags shared by the commands extracting a range of
+records from a file, which is either printed or written to a new file.
+*/
+type sliceFlags struct {
+	format formatFlags
+	dump   dumpFlags
+	output string
+}
+
+/*
+register adds the flags to the flag set.
+*/
+func (s *sliceFlags) register(flags *flag.FlagSet) {
+	s.format.register(flags, "")
+	s.dump.register(flags)
+	flags.StringVar(&s.output, "o", "",
+		"file to write the records to with the same format flags, instead "+
+			"of printing them")
+}
+
+/*
+extract parses the arguments and calls bounds to determine the range of
+records of the input file to extract, from start up to but not including
+end. The records are then printed as specified by the dump flags, or copied
+verbatim to the output file if one was given.
+
+Seeking to start uses the sparse index in the footer if -footer is given and
+the file has one; otherwise the headers of the records before it are
+scanned.
+*/
+func (s *sliceFlags) extract(ctx context.Context, flags *flag.FlagSet,
+	args []string, stdout io.Writer, bounds func(ctx context.Context,
+		reader *recordio.RecordReader) (int64, int64, error)) error {
+	var opts []recordio.Option
+	var reader *recordio.RecordReader
+	var ranged *recordio.RangeReader
+	var writer *recordio.RecordWriter
+	var file *os.File
+	var start, end int64
+	var n int
+	var err error
+
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return &usageError{"expected exactly one input file"}
+	}
+	if opts, err = s.format.options(); err != nil {
+		return err
+	}
+	if s.output == "" {
+		if err = s.dump.prepare(); err != nil {
+			return err
+		}
+	}
+
+	if file, err = os.Open(flags.Arg(0)); err != nil {
+		return err
+	}
+	reader = recordio.NewIORecordReader(file, opts...)
+	defer reader.Close(ctx)
+
+	if start, end, err = bounds(ctx, reader); err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+
+	if s.output == "" {
+		ranged, err = recordio.NewRangeReader(ctx, reader, start, end)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return s.dump.dump(ctx, stdout, ranged)
+	}
+
+	if file, err = os.Create(s.output); err != nil {
+		return err
+	}
+	writer = recordio.NewIORecordWriter(file, opts...)
+	if n = int(end - start); end == math.MaxInt64 {
+		n = -1
+	}
+	if err = reader.SeekToRecord(ctx, start); err == nil {
+		_, err = recordio.CopyRecords(ctx, writer, reader, n)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	if closeErr := writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(s.output)
+	}
+	return err
+}
+
+/*
+countRecords returns the number of records in the file, taken from its
+footer if there is one, or by scanning the headers of all records. The
+reader is positioned at the end of the file afterwards, or left unchanged if
+the footer was used.
+*/
+func countRecords(ctx context.Context, reader *recordio.RecordReader,
+	footer bool) (int64, error) {
+	var index *recordio.RecordIndex
+	var count int64
+	var err error
+
+	if footer {
+		if index, err = reader.FooterIndex(ctx); err != nil {
+			return 0, err
+		}
+		if index != nil {
+			return index.Len(), nil
+		}
+	}
+
+	count, _, err = reader.Count(ctx)
+	return count, err
+}
+
+/*
+runHead implements the head command, which extracts the first -n records of
+a file.
+*/
+func runHead(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("head", "<file>", stderr)
+	var slice sliceFlags
+	var n int64
+
+	slice.register(flags)
+	flags.Int64Var(&n, "n", 10, "number of records to extract")
+
+	return slice.extract(ctx, flags, args, stdout,
+		func(ctx context.Context, reader *recordio.RecordReader) (int64,
+			int64, error) {
+			return 0, n, nil
+		})
+}
+
+/*
+runTail implements the tail command, which extracts the last -n records of
+a file. The number of records is taken from the footer if -footer is given,
+otherwise the file is scanned to count them first.
+*/
+func runTail(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("tail", "<file>", stderr)
+	var slice sliceFlags
+	var n int64
+
+	slice.register(flags)
+	flags.Int64Var(&n, "n", 10, "number of records to extract")
+
+	return slice.extract(ctx, flags, args, stdout,
+		func(ctx context.Context, reader *recordio.RecordReader) (int64,
+			int64, error) {
+			var count int64
+			var err error
+
+			if count, err = countRecords(ctx, reader,
+				slice.format.footer); err != nil {
+				return 0, 0, err
+			}
+			if n > count {
+				return 0, count, nil
+			}
+			return count - n, count, nil
+		})
+}
+
+/*
+runSlice implements the slice command, which extracts the records with
+indexes from -from up to but not including -to from a file.
+*/
+func runSlice(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("slice", "<file>", stderr)
+	var slice sliceFlags
+	var from, to int64
+
+	slice.register(flags)
+	flags.Int64Var(&from, "from", 0, "index of the first record to extract")
+	flags.Int64Var(&to, "to", -1,
+		"index of the record to stop at, or -1 for the end of the file")
+
+	return slice.extract(ctx, flags, args, stdout,
+		func(ctx context.Context, reader *recordio.RecordReader) (int64,
+			int64, error) {
+			if from < 0 {
+				return 0, 0, &usageError{"-from must not be negative"}
+			}
+			if to < 0 {
+				return from, math.MaxInt64, nil
+			}
+			return from, to, nil
+		})
+}
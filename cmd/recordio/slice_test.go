@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+numbered returns n records numbered from 0.
+*/
+func numbered(n int) []string {
+	var recs = make([]string, n)
+
+	for i := range recs {
+		recs[i] = fmt.Sprint("Rec ", i)
+	}
+	return recs
+}
+
+/*
+head, tail and slice must print the selected records with their indexes in
+the file, with and without a sparse index in the footer.
+*/
+func TestHeadTailSlice(t *testing.T) {
+	var plain = writeFile(t, numbered(20))
+	var indexed = writeFile(t, numbered(20), recordio.WithSparseIndex(4))
+	var stdout string
+
+	for _, test := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"head", "-n", "2"}, "Rec 0\nRec 1\n"},
+		{[]string{"head", "-n", "0"}, ""},
+		{[]string{"tail", "-n", "3"}, "Rec 17\nRec 18\nRec 19\n"},
+		{[]string{"tail", "-n", "100"}, strings.Join(numbered(20), "\n") +
+			"\n"},
+		{[]string{"slice", "-from", "5", "-to", "7"}, "Rec 5\nRec 6\n"},
+		{[]string{"slice", "-from", "18"}, "Rec 18\nRec 19\n"},
+		{[]string{"slice", "-from", "30"}, ""},
+	} {
+		for _, name := range []string{plain, indexed} {
+			var args = append(test.args, "-format", "raw")
+			var code int
+			var stdout, stderr string
+
+			if name == indexed {
+				args = append(args, "-footer")
+			}
+			code, stdout, stderr = runTool(append(args, name)...)
+			if code != 0 {
+				t.Error("Error running ", args, ": ", stderr)
+			}
+			if stdout != test.expected {
+				t.Errorf("Unexpected output of %v: %q", args, stdout)
+			}
+		}
+	}
+
+	// The records before the last one take 10 * 9 + 9 * 10 bytes.
+	_, stdout, _ = runTool("tail", "-n", "1", plain)
+	if !strings.HasPrefix(stdout, "# record 19 at offset 180\n") {
+		t.Errorf("Unexpected marker: %q", stdout)
+	}
+}
+
+/*
+With -o, the selected records must be written to a new file with the same
+format.
+*/
+func TestSliceOutput(t *testing.T) {
+	var input = writeFile(t, numbered(10), recordio.WithChecksum(),
+		recordio.WithFooter())
+	var output = filepath.Join(t.TempDir(), "slice")
+	var code int
+	var stdout, stderr string
+
+	code, _, stderr = runTool("slice", "-checksum", "-footer", "-from", "3",
+		"-to", "5", "-o", output, input)
+	if code != 0 {
+		t.Error("Error running slice: ", stderr)
+	}
+
+	code, stdout, stderr = runTool("inspect", "-checksum", output)
+	if code != 0 {
+		t.Error("Error running inspect: ", stderr)
+	}
+	if !strings.Contains(stdout, "records:     2\n") ||
+		!strings.Contains(stdout, "footer:      2 records") {
+		t.Error("Unexpected output file: ", stdout)
+	}
+
+	code, stdout, _ = runTool("cat", "-checksum", "-footer", "-format",
+		"raw", output)
+	if code != 0 || stdout != "Rec 3\nRec 4\n" {
+		t.Errorf("Unexpected records: %q", stdout)
+	}
+
+	if code, _, _ = runTool("slice", "-from", "-1", input); code != 2 {
+		t.Error("Unexpected exit code for negative index: ", code)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+)
+
+/*
+shardSplitter distributes records across a growing number of shards, which
+are written under temporary names until the number of shards is known.
+*/
+type shardSplitter struct {
+	base    string
+	opts    []recordio.Option
+	records int64
+	bytes   int64
+
+	names   []string
+	writer  *recordio.RecordWriter
+	current int64
+}
+
+/*
+copyAll copies all records of the reader to the shards verbatim, starting a
+new shard whenever the current one is full.
+*/
+func (s *shardSplitter) copyAll(ctx context.Context,
+	reader *recordio.RecordReader) error {
+	var err error
+
+	for {
+		if _, err = reader.Peek(ctx); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if s.writer != nil && ((s.records > 0 && s.current >= s.records) ||
+			(s.bytes > 0 && s.writer.Offset() >= s.bytes)) {
+			if err = s.closeShard(ctx); err != nil {
+				return err
+			}
+		}
+		if s.writer == nil {
+			if err = s.openShard(); err != nil {
+				return err
+			}
+		}
+
+		if _, err = recordio.CopyRecords(ctx, s.writer, reader,
+			1); err != nil {
+			return err
+		}
+		s.current++
+	}
+}
+
+/*
+openShard starts writing a new shard under a temporary name.
+*/
+func (s *shardSplitter) openShard() error {
+	var name = fmt.Sprintf("%s.tmp-%05d", s.base, len(s.names))
+	var file *os.File
+	var err error
+
+	if file, err = os.Create(name); err != nil {
+		return err
+	}
+	s.names = append(s.names, name)
+	s.writer = recordio.NewIORecordWriter(file, s.opts...)
+	s.current = 0
+	return nil
+}
+
+/*
+closeShard finishes the current shard.
+*/
+func (s *shardSplitter) closeShard(ctx context.Context) error {
+	var err = s.writer.Close(ctx)
+
+	s.writer = nil
+	return err
+}
+
+/*
+finish closes the last shard and renames all shards to their final names,
+following the naming convention of recordio.ShardName(). If err is not nil,
+the shards are removed instead, and err is returned.
+*/
+func (s *shardSplitter) finish(ctx context.Context, err error) error {
+	if s.writer != nil {
+		if closeErr := s.closeShard(ctx); err == nil {
+			err = closeErr
+		}
+	}
+
+	for i, name := range s.names {
+		if err == nil {
+			err = os.Rename(name, recordio.ShardName(s.base, i,
+				len(s.names)))
+		}
+		if err != nil {
+			os.Remove(name)
+		}
+	}
+	return err
+}
+
+/*
+runSplit implements the split command, which distributes the records of the
+files given as arguments across new shards named after the base name given
+by -o, with either up to -records records or about -bytes bytes per shard.
+Shards are started at record boundaries, so they exceed -bytes by less than
+a record. Shard sets can be passed by their base name, so datasets can be
+resharded by splitting them again. Records are copied verbatim, with their
+checksums, and the shards use the same format flags as the input.
+*/
+func runSplit(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("split", "<files or shard sets...>", stderr)
+	var format formatFlags
+	var splitter shardSplitter
+	var names []string
+	var err error
+
+	format.register(flags, "")
+	flags.Int64Var(&splitter.records, "records", 0,
+		"maximum number of records per shard")
+	flags.Int64Var(&splitter.bytes, "bytes", 0,
+		"size in bytes after which a new shard is started")
+	flags.StringVar(&splitter.base, "o", "", "base name of the shards")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return &usageError{"no files specified"}
+	}
+	if splitter.base == "" {
+		return &usageError{"no output base name specified with -o"}
+	}
+	if (splitter.records > 0) == (splitter.bytes > 0) {
+		return &usageError{"exactly one of -records and -bytes is required"}
+	}
+	if splitter.opts, err = format.options(); err != nil {
+		return err
+	}
+	if names, err = expandNames(flags.Args()); err != nil {
+		return err
+	}
+
+	err = forEachFile(names, splitter.opts,
+		func(name string, reader *recordio.RecordReader) error {
+			return splitter.copyAll(ctx, reader)
+		})
+	if err = splitter.finish(ctx, err); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Wrote %d shards\n", len(splitter.names))
+	return nil
+}
+
+/*
+runMerge implements the merge command, which concatenates the records of the
+files given as arguments into a single new file given by -o, e.g. to merge
+the shards of a shard set passed by its base name. Records are copied
+verbatim, with their checksums, and the output uses the same format flags as
+the input.
+*/
+func runMerge(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("merge", "<files or shard sets...>", stderr)
+	var format formatFlags
+	var output string
+	var names []string
+	var opts []recordio.Option
+	var writer *recordio.RecordWriter
+	var file *os.File
+	var copied, total int
+	var err error
+
+	format.register(flags, "")
+	flags.StringVar(&output, "o", "", "file to write the records to")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return &usageError{"no files specified"}
+	}
+	if output == "" {
+		return &usageError{"no output file specified with -o"}
+	}
+	if opts, err = format.options(); err != nil {
+		return err
+	}
+	if names, err = expandNames(flags.Args()); err != nil {
+		return err
+	}
+
+	if file, err = os.Create(output); err != nil {
+		return err
+	}
+	writer = recordio.NewIORecordWriter(file, opts...)
+
+	err = forEachFile(names, opts,
+		func(name string, reader *recordio.RecordReader) error {
+			var err error
+
+			copied, err = recordio.CopyRecords(ctx, writer, reader, -1)
+			total += copied
+			return err
+		})
+	if closeErr := writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	fmt.Fprintf(stdout, "Merged %d records from %d files\n", total,
+		len(names))
+	return nil
+}
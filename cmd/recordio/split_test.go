@@ -0,0 +1,110 @@
+package main
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+split must distribute the records across shards of the requested size, and
+merge must put them back together, both keeping the checksums intact.
+*/
+func TestSplitMerge(t *testing.T) {
+	var input = writeFile(t, numbered(10), recordio.WithChecksum())
+	var dir = t.TempDir()
+	var base = filepath.Join(dir, "shards")
+	var merged = filepath.Join(dir, "merged")
+	var code int
+	var stdout, stderr string
+
+	code, stdout, stderr = runTool("split", "-checksum", "-records", "4",
+		"-o", base, input)
+	if code != 0 {
+		t.Error("Error running split: ", stderr)
+	}
+	if stdout != "Wrote 3 shards\n" {
+		t.Errorf("Unexpected output: %q", stdout)
+	}
+
+	for i, expected := range []string{
+		"Rec 0\nRec 1\nRec 2\nRec 3\n",
+		"Rec 4\nRec 5\nRec 6\nRec 7\n",
+		"Rec 8\nRec 9\n",
+	} {
+		code, stdout, stderr = runTool("cat", "-checksum", "-format", "raw",
+			recordio.ShardName(base, i, 3))
+		if code != 0 {
+			t.Error("Error reading shard: ", stderr)
+		}
+		if stdout != expected {
+			t.Errorf("Unexpected records in shard %d: %q", i, stdout)
+		}
+	}
+
+	code, stdout, stderr = runTool("merge", "-checksum", "-o", merged, base)
+	if code != 0 {
+		t.Error("Error running merge: ", stderr)
+	}
+	if stdout != "Merged 10 records from 3 files\n" {
+		t.Errorf("Unexpected output: %q", stdout)
+	}
+
+	code, stdout, stderr = runTool("verify", "-checksum", merged)
+	if code != 0 {
+		t.Error("Error verifying merged file: ", stdout, stderr)
+	}
+	code, stdout, _ = runTool("cat", "-checksum", "-format", "raw", merged)
+	if code != 0 || stdout != strings.Join(numbered(10), "\n")+"\n" {
+		t.Errorf("Unexpected merged records: %q", stdout)
+	}
+}
+
+/*
+Splitting by size must start new shards at record boundaries once a shard
+has reached the size, and not leave temporary files behind.
+*/
+func TestSplitBytes(t *testing.T) {
+	var input = writeFile(t, numbered(10))
+	var dir = t.TempDir()
+	var base = filepath.Join(dir, "shards")
+	var entries []os.DirEntry
+	var code int
+	var stdout, stderr string
+	var err error
+
+	// Every record takes 9 bytes, so shards are full after 3 records.
+	code, stdout, stderr = runTool("split", "-bytes", "20", "-o", base,
+		input)
+	if code != 0 {
+		t.Error("Error running split: ", stderr)
+	}
+	if stdout != "Wrote 4 shards\n" {
+		t.Errorf("Unexpected output: %q", stdout)
+	}
+
+	if entries, err = os.ReadDir(dir); err != nil {
+		t.Fatal("Error listing shards: ", err)
+	}
+	for i, entry := range entries {
+		if entry.Name() != filepath.Base(recordio.ShardName(base, i, 4)) {
+			t.Error("Unexpected file: ", entry.Name())
+		}
+	}
+
+	code, stdout, _ = runTool("cat", "-format", "raw",
+		recordio.ShardName(base, 3, 4))
+	if code != 0 || stdout != "Rec 9\n" {
+		t.Errorf("Unexpected records in last shard: %q", stdout)
+	}
+
+	if code, _, _ = runTool("split", "-records", "1", "-bytes", "1", "-o",
+		base, input); code != 2 {
+		t.Error("Unexpected exit code for -records with -bytes: ", code)
+	}
+	if code, _, _ = runTool("merge", input); code != 2 {
+		t.Error("Unexpected exit code for merge without -o: ", code)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+)
+
+/*
+runVerify implements the verify command, which checks the framing, the
+checksums and the footers of the files given as arguments using
+RecordReader.Verify(), and prints the result for every file. Arguments may
+be glob patterns or the base names of shard sets. All files are checked even
+if some of them are corrupted; the command fails if any of them are, or if
+they cannot be read.
+*/
+func runVerify(ctx context.Context, args []string, stdout,
+	stderr io.Writer) error {
+	var flags = newFlagSet("verify", "<files, globs or shard sets...>",
+		stderr)
+	var format formatFlags
+	var quiet bool
+	var names []string
+	var opts []recordio.Option
+	var failed int
+	var err error
+
+	format.register(flags, "")
+	flags.BoolVar(&quiet, "quiet", false, "only print files which fail")
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return &usageError{"no files specified"}
+	}
+	if opts, err = format.options(); err != nil {
+		return err
+	}
+	if names, err = expandNames(flags.Args()); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		var report recordio.VerifyReport
+
+		if report, err = verifyFile(ctx, name, opts); err != nil {
+			fmt.Fprintf(stdout, "%s: ERROR %v\n", name, err)
+			failed++
+		} else if !report.OK() {
+			fmt.Fprintf(stdout, "%s: CORRUPT at offset %d: %v (%d valid "+
+				"records", name, report.FirstErrorOffset, report.FirstError,
+				report.Records)
+			if report.Unreadable >= 0 {
+				fmt.Fprintf(stdout, ", %d bytes unreadable",
+					report.Unreadable)
+			}
+			fmt.Fprintln(stdout, ")")
+			failed++
+		} else if !quiet {
+			fmt.Fprintf(stdout, "%s: OK (%d records)\n", name,
+				report.Records)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed verification", failed,
+			len(names))
+	}
+	return nil
+}
+
+/*
+verifyFile verifies the named file with the specified options.
+*/
+func verifyFile(ctx context.Context, name string,
+	opts []recordio.Option) (recordio.VerifyReport, error) {
+	var file *os.File
+	var err error
+
+	if file, err = os.Open(name); err != nil {
+		return recordio.VerifyReport{}, err
+	}
+	defer file.Close()
+
+	return recordio.NewIORecordReader(file, opts...).Verify(ctx)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+verify must check all files matching a glob, report the first bad offset of
+corrupted files and fail if any of them are corrupted.
+*/
+func TestVerify(t *testing.T) {
+	var dir = t.TempDir()
+	var good = filepath.Join(dir, "good.rio")
+	var bad = filepath.Join(dir, "bad.rio")
+	var data []byte
+	var code int
+	var stdout, stderr string
+	var err error
+
+	writeNamed(t, good, []string{"one", "two"}, recordio.WithChecksum())
+	writeNamed(t, bad, []string{"one", "two"}, recordio.WithChecksum())
+
+	code, stdout, stderr = runTool("verify", "-checksum",
+		filepath.Join(dir, "*.rio"))
+	if code != 0 {
+		t.Error("Error verifying intact files: ", stderr)
+	}
+	if !strings.Contains(stdout, good+": OK (2 records)\n") ||
+		!strings.Contains(stdout, bad+": OK (2 records)\n") {
+		t.Error("Unexpected output for intact files: ", stdout)
+	}
+
+	// Corrupt the data of the second record, which starts after the 11
+	// bytes of the first one.
+	if data, err = os.ReadFile(bad); err != nil {
+		t.Fatal("Error reading file: ", err)
+	}
+	data[len(data)-1] ^= 0xff
+	if err = os.WriteFile(bad, data, 0644); err != nil {
+		t.Fatal("Error writing file: ", err)
+	}
+
+	code, stdout, stderr = runTool("verify", "-checksum", "-quiet",
+		filepath.Join(dir, "*.rio"))
+	if code != 1 {
+		t.Error("Unexpected exit code for corrupted file: ", code)
+	}
+	if strings.Contains(stdout, good) {
+		t.Error("Intact file reported despite -quiet: ", stdout)
+	}
+	if !strings.Contains(stdout, bad+": CORRUPT at offset 11: ") ||
+		!strings.Contains(stdout, "(1 valid records, 11 bytes unreadable)") {
+		t.Error("Unexpected output for corrupted file: ", stdout)
+	}
+	if !strings.Contains(stderr, "1 of 2 files failed verification") {
+		t.Error("Unexpected error: ", stderr)
+	}
+}
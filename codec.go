@@ -0,0 +1,156 @@
+package recordio
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"sync"
+)
+
+/*
+ErrRecordTooLarge is returned when a record exceeds the maximum record size
+configured using WithMaxRecordSize().
+*/
+var ErrRecordTooLarge = errors.New("Record too large")
+
+/*
+Codec compresses and decompresses the data of individual records.
+*/
+type Codec interface {
+	/*
+		Compress returns the compressed form of the specified data.
+	*/
+	Compress(data []byte) ([]byte, error)
+
+	/*
+		Decompress returns the decompressed form of the specified data. If the
+		decompressed data would be larger than limit bytes,
+		ErrRecordTooLarge must be returned. The data may be returned as is
+		or in part if it is not compressed, but must not be retained after
+		returning, since readers reuse its memory.
+	*/
+	Decompress(data []byte, limit uint32) ([]byte, error)
+}
+
+/*
+DeflateCodec compresses records using the DEFLATE algorithm (RFC 1951) at
+the default compression level.
+*/
+var DeflateCodec Codec = deflateCodec{}
+
+type deflateCodec struct{}
+
+func (deflateCodec) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var fw *flate.Writer
+	var err error
+
+	if fw, err = flate.NewWriter(&buf, flate.DefaultCompression); err != nil {
+		return nil, err
+	}
+	if _, err = fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err = fw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (deflateCodec) Decompress(data []byte, limit uint32) ([]byte, error) {
+	var fr = flate.NewReader(bytes.NewReader(data))
+	var buf bytes.Buffer
+	var n int64
+	var err error
+
+	defer fr.Close()
+
+	n, err = io.CopyN(&buf, fr, int64(limit)+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n > int64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+
+	return buf.Bytes(), nil
+}
+
+/*
+SnappyCodec compresses records using the Snappy block format, which is much
+faster than DEFLATE at the expense of the compression ratio.
+*/
+var SnappyCodec Codec = snappyCodec{}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCodec) Decompress(data []byte, limit uint32) ([]byte, error) {
+	var n int
+	var err error
+
+	// The decompressed size is stored up front, so it can be checked before
+	// allocating memory for it.
+	if n, err = snappy.DecodedLen(data); err != nil {
+		return nil, err
+	}
+	if uint64(n) > uint64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+
+	return snappy.Decode(nil, data)
+}
+
+/*
+ZstdCodec compresses records using Zstandard (RFC 8878) at the default
+compression level.
+*/
+var ZstdCodec Codec = &zstdCodec{}
+
+type zstdCodec struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	err     error
+}
+
+func (z *zstdCodec) Compress(data []byte) ([]byte, error) {
+	// Encoders are expensive to create, but safe for concurrent use by
+	// EncodeAll(), so a single one is shared.
+	z.once.Do(func() {
+		z.encoder, z.err = zstd.NewWriter(nil)
+	})
+	if z.err != nil {
+		return nil, z.err
+	}
+
+	return z.encoder.EncodeAll(data, nil), nil
+}
+
+func (z *zstdCodec) Decompress(data []byte, limit uint32) ([]byte, error) {
+	var zr *zstd.Decoder
+	var buf bytes.Buffer
+	var n int64
+	var err error
+
+	if zr, err = zstd.NewReader(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	n, err = io.CopyN(&buf, zr, int64(limit)+1)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n > int64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+
+	return buf.Bytes(), nil
+}
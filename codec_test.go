@@ -0,0 +1,97 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Records compressed with SnappyCodec and ZstdCodec must be decompressed
+transparently when reading, and the maximum record size must apply to the
+decompressed data.
+*/
+func TestSnappyZstdCodecs(t *testing.T) {
+	var ctx = context.Background()
+	var rec = bytes.Repeat([]byte("recordio "), 100)
+
+	for _, codec := range []Codec{SnappyCodec, ZstdCodec} {
+		var buf = internal.NewAnonymousFile()
+		var writer = NewRecordWriter(buf, WithCodec(codec), WithChecksum())
+		var reader *RecordReader
+		var rbuf []byte
+		var err error
+
+		if _, err = writer.Write(ctx, rec); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+		writer.Close(ctx)
+
+		reader = NewRecordReader(buf, WithCodec(codec), WithChecksum())
+		if rbuf, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if !bytes.Equal(rbuf, rec) {
+			t.Error("Decompressed record does not match the original")
+		}
+
+		buf.Close(ctx)
+		reader = NewRecordReader(buf, WithCodec(codec), WithChecksum(),
+			WithMaxRecordSize(100))
+		if _, err = reader.ReadRecord(ctx); !errors.Is(
+			err, ErrRecordTooLarge) {
+			t.Error("Expected record too large error, got: ", err)
+		}
+	}
+}
+
+/*
+identityCodec stores records uncompressed and returns part of its input
+when decompressing, dropping the first byte.
+*/
+type identityCodec struct{}
+
+func (identityCodec) Compress(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (identityCodec) Decompress(data []byte, limit uint32) ([]byte, error) {
+	if uint64(len(data)) > uint64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+	return data[1:], nil
+}
+
+/*
+Records returned by a codec which aliases its input must not be overwritten
+by reading the following records.
+*/
+func TestAliasingCodec(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithCodec(identityCodec{}))
+	var reader *RecordReader
+	var recs [][]byte
+	var rec []byte
+	var err error
+
+	for _, data := range []string{"_first", "_second"} {
+		if _, err = writer.Write(ctx, []byte(data)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(&buf, WithCodec(identityCodec{}))
+	for i := 0; i < 2; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		recs = append(recs, rec)
+	}
+	if len(recs) != 2 || string(recs[0]) != "first" ||
+		string(recs[1]) != "second" {
+		t.Errorf("Unexpected records: %q", recs)
+	}
+}
@@ -0,0 +1,98 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"hash/crc32"
+	"io"
+)
+
+/*
+CopyRecords copies up to n records from src to dst, or all remaining records
+if n is negative, and returns the number of records copied. This is useful
+for concatenating files or redistributing records among shards.
+
+If both use the same framing, checksum setting and codec, the records are
+copied verbatim: their data is neither decompressed nor verified, and the
+checksums are carried over, so that corruption is still detected when
+reading dst. Copying is then limited by I/O rather than by decoding and
+encoding the records. Otherwise, every record is read from src and written
+to dst as by ReadRecord() and Write().
+
+If src ends before n records have been copied, io.EOF is returned along with
+the number of records copied. Errors are wrapped into RecordErrors for the
+position in src or dst, depending on which failed.
+*/
+func CopyRecords(ctx context.Context, dst *RecordWriter, src *RecordReader,
+	n int) (int, error) {
+	var verbatim = dst.options.framing == src.options.framing &&
+		dst.options.checksum == src.options.checksum &&
+		dst.options.codec == src.options.codec
+	var copied int
+	var rec []byte
+	var err error
+
+	for n < 0 || copied < n {
+		if verbatim {
+			err = copyFrame(ctx, dst, src)
+		} else if rec, err = src.ReadRecord(ctx); err == nil {
+			_, err = dst.Write(ctx, rec)
+		}
+
+		if err == io.EOF && n < 0 {
+			return copied, nil
+		} else if err != nil {
+			return copied, err
+		}
+		copied++
+	}
+
+	return copied, nil
+}
+
+/*
+copyFrame copies the next record from src to dst without decoding it.
+*/
+func copyFrame(ctx context.Context, dst *RecordWriter,
+	src *RecordReader) error {
+	var srcPos = src.position
+	var dstPos = dst.position
+	var body *recordBodyReader
+	var length, crc uint32
+	var err error
+
+	if dst.closed {
+		return recordError(dstPos, ErrClosed)
+	}
+	if dst.incomplete > 0 {
+		return recordError(dstPos, ErrIncompleteRecord)
+	}
+
+	if length, crc, err = src.nextBodyHeader(ctx); err != nil {
+		return recordError(srcPos, err)
+	}
+	if length > dst.options.maxRecordSize {
+		src.unreadHeader(length, crc)
+		return recordError(dstPos, ErrRecordTooLarge)
+	}
+
+	// The data is streamed through the reader for NextRecordReader(), so
+	// that the remainder is skipped if writing fails.
+	body = &recordBodyReader{
+		reader:    src,
+		ctx:       ctx,
+		remaining: int64(length),
+	}
+	if src.options.strictChecksums && src.options.checksum {
+		body.hash = crc32.New(crc32cTable)
+	}
+	src.pending = body
+	src.advance(length)
+
+	if _, err = dst.writeFrame(ctx, body, length, crc); err != nil {
+		return recordError(dstPos, err)
+	}
+	if body.hash != nil && body.hash.Sum32() != crc {
+		return recordError(srcPos, ErrChecksumMismatch)
+	}
+	return nil
+}
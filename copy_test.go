@@ -0,0 +1,79 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Records of streams with matching formats must be copied verbatim, so that
+concatenating streams yields their concatenated bytes.
+*/
+func TestCopyRecordsVerbatim(t *testing.T) {
+	var ctx = context.Background()
+	var first = writeNumbered(t, 10, WithChecksum(), WithCodec(DeflateCodec))
+	var second = writeNumbered(t, 5, WithChecksum(), WithCodec(DeflateCodec))
+	var out bytes.Buffer
+	var writer = NewIORecordWriter(&out, WithChecksum(),
+		WithCodec(DeflateCodec))
+	var copied int
+	var err error
+
+	for i, data := range [][]byte{first, second} {
+		copied, err = CopyRecords(ctx, writer,
+			NewIORecordReader(bytes.NewReader(data), WithChecksum(),
+				WithCodec(DeflateCodec)), -1)
+		if err != nil {
+			t.Error("Error copying records: ", err)
+		}
+		if copied != []int{10, 5}[i] {
+			t.Error("Unexpected number of records copied: ", copied)
+		}
+	}
+
+	if !bytes.Equal(out.Bytes(), append(first, second...)) {
+		t.Error("Copied stream differs from the concatenated input")
+	}
+	if writer.Tell().Index != 15 {
+		t.Error("Unexpected writer position: ", writer.Tell())
+	}
+}
+
+/*
+Records must be re-encoded if the formats differ, and copying must stop at
+the requested number of records or the end of the stream.
+*/
+func TestCopyRecordsReencode(t *testing.T) {
+	var ctx = context.Background()
+	var reader = NewIORecordReader(
+		bytes.NewReader(writeNumbered(t, 10, WithChecksum())),
+		WithChecksum())
+	var out bytes.Buffer
+	var writer = NewIORecordWriter(&out, WithFraming(VarintFraming))
+	var recs [][]byte
+	var copied int
+	var err error
+
+	if copied, err = CopyRecords(ctx, writer, reader, 4); err != nil ||
+		copied != 4 {
+		t.Error("Unexpected result: ", copied, " records, error: ", err)
+	}
+	if copied, err = CopyRecords(ctx, writer, reader, 10); err != io.EOF ||
+		copied != 6 {
+		t.Error("Unexpected result: ", copied, " records, error: ", err)
+	}
+
+	recs, err = NewIORecordReader(&out, WithFraming(VarintFraming)).
+		ReadAll(ctx)
+	if err != nil || len(recs) != 10 {
+		t.Error("Unexpected records: ", len(recs), ", error: ", err)
+	}
+	for i, rec := range recs {
+		if string(rec) != fmt.Sprint("Rec ", i) {
+			t.Errorf("Unexpected data: got %q, expected Rec %d", rec, i)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+Count scans the remainder of the input stream and returns the number of
+records and the total number of bytes of record data in it. Only the headers
+are decoded; record data is skipped as described for Skip(), so checksums are
+not verified. If a codec is used, the compressed size of the records is
+counted.
+
+The reader is positioned at the end of the stream afterwards.
+*/
+func (r *RecordReader) Count(ctx context.Context) (int64, int64, error) {
+	var records, size int64
+	var length, crc uint32
+	var err error
+
+	for {
+		if length, crc, err = r.nextHeader(ctx); err == io.EOF {
+			return records, size, nil
+		} else if err != nil {
+			return records, size, recordError(r.position, err)
+		}
+
+		if err = r.skipRecord(ctx, length, crc); err != nil {
+			return records, size, recordError(r.position, err)
+		}
+
+		r.advance(length)
+		records++
+		size += int64(length)
+	}
+}
+
+/*
+CountRecords counts the records in the specified input stream as described
+for RecordReader.Count(). The options must match those used for writing the
+stream.
+*/
+func CountRecords(ctx context.Context, reader filesystem.ReadCloser,
+	opts ...Option) (int64, int64, error) {
+	return NewRecordReader(reader, opts...).Count(ctx)
+}
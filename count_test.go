@@ -0,0 +1,47 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Count the records and bytes of a stream, after reading the first record.
+*/
+func TestCount(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithFraming(VarintFraming))
+	var reader *RecordReader
+	var records, size int64
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	records, size, err = CountRecords(ctx,
+		NewIOReadCloser(bytes.NewReader(buf.Bytes())), WithFraming(VarintFraming))
+	if err != nil {
+		t.Error("Error counting records: ", err)
+	}
+	if records != 4 || size != 15 {
+		t.Error("Unexpected count: ", records, " records, ", size, " bytes")
+	}
+
+	reader = NewIORecordReader(&buf, WithFraming(VarintFraming))
+	if _, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+
+	records, size, err = reader.Count(ctx)
+	if err != nil {
+		t.Error("Error counting records: ", err)
+	}
+	if records != 3 || size != 12 {
+		t.Error("Unexpected count: ", records, " records, ", size, " bytes")
+	}
+}
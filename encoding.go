@@ -0,0 +1,57 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+)
+
+/*
+Encoding converts values of arbitrary types to and from the data stored in a
+record. Implementations for common serialization formats can be found in the
+subpackages of recordio; callers may also provide their own.
+*/
+type Encoding interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+/*
+WriteEncoded serializes the specified value using the given Encoding and
+writes the result as a new record to the underlying output stream.
+
+The same warnings about locking as for Write() apply to this method.
+*/
+func (w *RecordWriter) WriteEncoded(ctx context.Context, enc Encoding,
+	v interface{}) error {
+	var b []byte
+	var err error
+
+	b, err = enc.Marshal(v)
+	if err != nil {
+		return recordError(w.position, err)
+	}
+
+	_, err = w.Write(ctx, b)
+	return err
+}
+
+/*
+ReadEncoded reads the next record from the input stream and decodes it into
+the value pointed to by v using the given Encoding. If the record cannot be
+decoded, an error will be returned but the reader will be advanced by a
+record.
+
+All warnings from the ReadRecord() method apply here as well.
+*/
+func (r *RecordReader) ReadEncoded(ctx context.Context, enc Encoding,
+	v interface{}) error {
+	var pos = r.position
+	var buf []byte
+	var err error
+
+	buf, err = r.ReadRecord(ctx)
+	if err != nil {
+		return err
+	}
+
+	return recordError(pos, enc.Unmarshal(buf, v))
+}
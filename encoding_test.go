@@ -0,0 +1,85 @@
+package recordio
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+type jsonTestEncoding struct{}
+
+func (jsonTestEncoding) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonTestEncoding) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+/*
+Write values using a custom Encoding and read them back.
+*/
+func TestWriteAndReadEncoded(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf)
+	var reader *RecordReader
+	var rbuf []byte
+	var value []string
+	var err error
+
+	err = writer.WriteEncoded(ctx, jsonTestEncoding{}, []string{"Hello", "World"})
+	if err != nil {
+		t.Error("Error writing value: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+	reader = NewRecordReader(buf)
+
+	if err = reader.ReadEncoded(ctx, jsonTestEncoding{}, &value); err != nil {
+		t.Error("Error reading value: ", err)
+	}
+	if len(value) != 2 || value[0] != "Hello" || value[1] != "World" {
+		t.Error("Unexpected value: ", value)
+	}
+
+	// The record must contain exactly the encoded data.
+	buf.Close(ctx)
+	rbuf, err = NewRecordReader(buf).ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rbuf) != "[\"Hello\",\"World\"]" {
+		t.Errorf("Unexpected record data: %q", rbuf)
+	}
+}
+
+/*
+Errors of the Encoding must be wrapped into RecordErrors for the position of
+the record, like all other errors.
+*/
+func TestEncodedErrors(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf)
+	var recErr *RecordError
+	var value int
+	var err error
+
+	writer.Write(ctx, []byte("Hello"))
+	err = writer.WriteEncoded(ctx, jsonTestEncoding{}, func() {})
+	if !errors.As(err, &recErr) || recErr.Index != 1 {
+		t.Error("Expected RecordError for record 1, got: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	err = NewRecordReader(buf).ReadEncoded(ctx, jsonTestEncoding{}, &value)
+	if !errors.As(err, &recErr) || recErr.Index != 0 {
+		t.Error("Expected RecordError for record 0, got: ", err)
+	}
+}
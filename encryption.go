@@ -0,0 +1,82 @@
+package recordio
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+/*
+ErrDecryptionFailed is returned by readers using WithEncryption() if a
+record cannot be decrypted, because it was corrupted or encrypted with a
+different key.
+*/
+var ErrDecryptionFailed = errors.New("Record decryption failed")
+
+/*
+WithEncryption encrypts the data of every record individually using the
+specified authenticated cipher, such as AES-GCM created using
+cipher.NewGCM(). Every record is sealed with a fresh random nonce, which is
+stored in front of the encrypted data. If a codec is used as well, records
+are compressed before they are encrypted.
+
+Record lengths and checksums refer to the encrypted data, so encrypted
+streams remain seekable. Each record grows by the nonce size and the
+overhead of the cipher, which count towards the maximum record size.
+*/
+func WithEncryption(aead cipher.AEAD) Option {
+	return func(o *options) {
+		o.encryption = aead
+	}
+}
+
+/*
+encryptingCodec encrypts the records after compressing them using codec,
+if any, and decrypts them before decompressing them.
+*/
+type encryptingCodec struct {
+	codec Codec
+	aead  cipher.AEAD
+}
+
+func (e encryptingCodec) Compress(data []byte) ([]byte, error) {
+	var nonceSize = e.aead.NonceSize()
+	var sealed []byte
+	var err error
+
+	if e.codec != nil {
+		if data, err = e.codec.Compress(data); err != nil {
+			return nil, err
+		}
+	}
+
+	sealed = make([]byte, nonceSize, nonceSize+len(data)+e.aead.Overhead())
+	if _, err = io.ReadFull(rand.Reader, sealed); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(sealed, sealed[:nonceSize], data, nil), nil
+}
+
+func (e encryptingCodec) Decompress(data []byte, limit uint32) ([]byte,
+	error) {
+	var nonceSize = e.aead.NonceSize()
+	var opened []byte
+	var err error
+
+	if len(data) < nonceSize+e.aead.Overhead() {
+		return nil, ErrDecryptionFailed
+	}
+	if opened, err = e.aead.Open(nil, data[:nonceSize], data[nonceSize:],
+		nil); err != nil {
+		return nil, ErrDecryptionFailed
+	}
+
+	if e.codec != nil {
+		return e.codec.Decompress(opened, limit)
+	}
+	if uint64(len(opened)) > uint64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+	return opened, nil
+}
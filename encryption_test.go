@@ -0,0 +1,71 @@
+package recordio
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+testCipher creates an AES-GCM cipher with a fixed key for tests.
+*/
+func testCipher(t *testing.T) cipher.AEAD {
+	var block cipher.Block
+	var aead cipher.AEAD
+	var err error
+
+	if block, err = aes.NewCipher(make([]byte, 16)); err != nil {
+		t.Fatal("Error creating cipher: ", err)
+	}
+	if aead, err = cipher.NewGCM(block); err != nil {
+		t.Fatal("Error creating AEAD: ", err)
+	}
+	return aead
+}
+
+/*
+Encrypted records must be read back with the same cipher, and reading them
+with a different key must fail rather than return garbage.
+*/
+func TestEncryptionRoundTrip(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithEncryption(testCipher(t)),
+		WithChecksum())
+	var reader *RecordReader
+	var block cipher.Block
+	var other cipher.AEAD
+	var rec []byte
+	var err error
+
+	for _, data := range []string{"Hello", "", "World"} {
+		if _, err = writer.Write(ctx, []byte(data)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if bytes.Contains(buf.Bytes(), []byte("World")) {
+		t.Error("Record was written unencrypted")
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithEncryption(testCipher(t)), WithChecksum())
+	for _, data := range []string{"Hello", "", "World"} {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != data {
+			t.Errorf("Unexpected data: got %q, expected %q", rec, data)
+		}
+	}
+
+	block, _ = aes.NewCipher(bytes.Repeat([]byte{1}, 16))
+	other, _ = cipher.NewGCM(block)
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithEncryption(other), WithChecksum())
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrDecryptionFailed) {
+		t.Error("Expected ErrDecryptionFailed, got: ", err)
+	}
+}
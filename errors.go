@@ -0,0 +1,161 @@
+package recordio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+ErrClosed is returned when reading from a RecordReader or writing to a
+RecordWriter which has already been closed.
+*/
+var ErrClosed = errors.New("Record stream already closed")
+
+/*
+errShortHeader, errShortBody and errLengthOutOfRange are returned for
+truncated record headers and bodies and for record lengths which cannot be
+represented, which are the typical signs of a torn tail of the stream.
+*/
+var (
+	errShortHeader      = errors.New("Short read for header")
+	errShortBody        = errors.New("Short read for body")
+	errLengthOutOfRange = errors.New("Record length out of range")
+)
+
+/*
+RecordError describes an error which occurred while reading or writing a
+record, along with the position of the record in the stream, to make it
+easier to locate corruption in large files. All errors returned by the
+reading and writing methods of RecordReader and RecordWriter are wrapped
+into RecordErrors, except io.EOF at the end of the stream; use errors.Is()
+and errors.As() to check for specific errors.
+*/
+type RecordError struct {
+	/*
+		Offset is the byte offset of the record, as reported by Tell().
+	*/
+	Offset int64
+
+	/*
+		Index is the index of the record, as reported by Tell(), or -1 if
+		it is unknown.
+	*/
+	Index int64
+
+	/*
+		Err is the underlying error.
+	*/
+	Err error
+}
+
+func (e *RecordError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("Record at offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("Record %d at offset %d: %v", e.Index, e.Offset,
+		e.Err)
+}
+
+/*
+Unwrap returns the underlying error.
+*/
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+/*
+PartialWriteError is returned by RecordWriter if writing a record failed or
+was canceled after part of it had already been written to the output stream.
+The stream then ends in a truncated record, which readers will report as an
+error. Further writes fail with ErrIncompleteRecord until the truncated
+record has been removed using Resume().
+*/
+type PartialWriteError struct {
+	/*
+		Written is the number of bytes of the record which were written.
+	*/
+	Written int
+
+	/*
+		Err is the error which interrupted the write.
+	*/
+	Err error
+
+	/*
+		IndexAhead is set by IndexedRecordWriter if the index entry of the
+		record has been written, in full or in part, but the record itself
+		has not been written completely. The index then ends in an entry for
+		a record which is missing from the record stream.
+	*/
+	IndexAhead bool
+}
+
+func (e *PartialWriteError) Error() string {
+	return fmt.Sprintf("Record partially written (%d bytes): %v", e.Written,
+		e.Err)
+}
+
+/*
+Unwrap returns the error which interrupted the write.
+*/
+func (e *PartialWriteError) Unwrap() error {
+	return e.Err
+}
+
+/*
+partialWrite wraps err into a PartialWriteError if part of a record has been
+written already, and remembers that the output stream ends in an incomplete
+record until Resume() is called.
+*/
+func (w *RecordWriter) partialWrite(written int, err error) error {
+	if written == 0 {
+		return err
+	}
+	w.incomplete = int64(written)
+	return &PartialWriteError{
+		Written: written,
+		Err:     err,
+	}
+}
+
+/*
+recordError wraps err into a RecordError for the record at the specified
+position. io.EOF and errors which are RecordErrors already are returned
+as is.
+*/
+func recordError(pos Position, err error) error {
+	if err == nil || err == io.EOF || isRecordError(err) {
+		return err
+	}
+
+	return &RecordError{
+		Offset: pos.Offset,
+		Index:  pos.Index,
+		Err:    err,
+	}
+}
+
+/*
+unwrapRecordError returns the error wrapped by err if it is a RecordError,
+so that it can be reported for a different position, and err otherwise.
+*/
+func unwrapRecordError(err error) error {
+	var recErr *RecordError
+
+	if errors.As(err, &recErr) {
+		return recErr.Err
+	}
+	return err
+}
+
+/*
+isRecordError returns whether err is or wraps a RecordError. This is kept
+separate from recordError() so that successful reads and writes do not
+allocate.
+*/
+func isRecordError(err error) bool {
+	var recErr *RecordError
+
+	return errors.As(err, &recErr)
+}
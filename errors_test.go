@@ -0,0 +1,144 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"strings"
+	"testing"
+)
+
+/*
+cancelingStream cancels a context after the first read or write.
+*/
+type cancelingStream struct {
+	buf    *bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (c *cancelingStream) Read(p []byte) (int, error) {
+	defer c.cancel()
+	return c.buf.Read(p)
+}
+
+func (c *cancelingStream) Write(p []byte) (int, error) {
+	defer c.cancel()
+	return c.buf.Write(p)
+}
+
+/*
+Canceling the context between writing the header and the data of a streamed
+record must be reported as a partial write.
+*/
+func TestCancelBetweenHeaderAndBody(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&cancelingStream{buf: &buf, cancel: cancel})
+	var reader *RecordReader
+	var partial *PartialWriteError
+	var rec []byte
+	var err error
+
+	_, err = writer.WriteRecordFrom(ctx, strings.NewReader("Hello"), 5)
+	if !errors.As(err, &partial) || partial.Written != 4 {
+		t.Error("Expected partial write of 4 bytes, got: ", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected context to be canceled, got: ", err)
+	}
+
+	buf.Reset()
+	NewIORecordWriter(&buf).Write(context.Background(), []byte("Hello"))
+
+	ctx, cancel = context.WithCancel(context.Background())
+	reader = NewIORecordReader(&cancelingStream{buf: &buf, cancel: cancel})
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, context.Canceled) {
+		t.Error("Expected context to be canceled, got: ", err)
+	}
+
+	if rec, err = reader.ReadRecord(context.Background()); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Hello" {
+		t.Errorf("Unexpected data: got %q, expected Hello", rec)
+	}
+	if _, err = reader.ReadRecord(context.Background()); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+Closing readers and writers twice must be harmless, and using them after
+closing must fail with ErrClosed.
+*/
+func TestClose(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader = NewIORecordReader(&buf)
+	var err error
+
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer again: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("Hello")); !errors.Is(err, ErrClosed) {
+		t.Error("Expected ErrClosed, got: ", err)
+	}
+
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader again: ", err)
+	}
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrClosed) {
+		t.Error("Expected ErrClosed, got: ", err)
+	}
+	if _, err = reader.Peek(ctx); !errors.Is(err, ErrClosed) {
+		t.Error("Expected ErrClosed, got: ", err)
+	}
+}
+
+/*
+Errors must carry the position of the record they occurred at.
+*/
+func TestRecordError(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum())
+	var reader *RecordReader
+	var data []byte
+	var recErr *RecordError
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	// Corrupt the last byte of the second record.
+	data = buf.Bytes()
+	data[21] ^= 0x01
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if _, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+
+	_, err = reader.ReadRecord(ctx)
+	if !errors.As(err, &recErr) {
+		t.Fatal("Expected RecordError, got: ", err)
+	}
+	if recErr.Index != 1 || recErr.Offset != 11 ||
+		!errors.Is(err, ErrChecksumMismatch) {
+		t.Error("Unexpected error: ", err)
+	}
+	if err.Error() != "Record 1 at offset 11: Record checksum mismatch" {
+		t.Error("Unexpected error message: ", err)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Package flatbuffers stores FlatBuffers as records in recordio files.
+
+Every record holds exactly one finished FlatBuffer, as returned by
+Builder.FinishedBytes() after calling Builder.Finish(). Since records are
+already framed by recordio, buffers should not be finished with
+FinishSizePrefixed().
+
+Existing streams of size prefixed FlatBuffers, as produced by writing the
+output of FinishSizePrefixed() back to back, can be read as records by using
+a RecordReader created with recordio.LittleEndianFraming. The reverse is also
+true: records written with recordio.LittleEndianFraming can be consumed by
+other FlatBuffers tools as a stream of size prefixed buffers.
+*/
+package flatbuffers
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/childoftheuniverse/recordio"
+	"github.com/google/flatbuffers/go"
+	"golang.org/x/net/context"
+)
+
+/*
+ErrInvalidBuffer is returned when a record does not contain a structurally
+valid FlatBuffer.
+*/
+var ErrInvalidBuffer = errors.New("Invalid FlatBuffer")
+
+/*
+ErrIdentifierMismatch is returned when the file identifier of a FlatBuffer
+does not match the expected identifier.
+*/
+var ErrIdentifierMismatch = errors.New("FlatBuffer file identifier mismatch")
+
+/*
+WriteBuilder writes the finished buffer of the specified builder as a new
+record to the RecordWriter.
+
+The same warnings about locking as for RecordWriter.Write() apply to this
+function.
+*/
+func WriteBuilder(ctx context.Context, w *recordio.RecordWriter,
+	b *flatbuffers.Builder) error {
+	var err error
+
+	_, err = w.Write(ctx, b.FinishedBytes())
+	return err
+}
+
+/*
+ReadVerified reads the next record from the RecordReader and checks that it
+holds a valid FlatBuffer using Verify(). The buffer is returned if it passes,
+so it can be passed to the GetRootAs function of the generated code.
+
+All warnings from RecordReader.ReadRecord() apply here as well.
+*/
+func ReadVerified(ctx context.Context, r *recordio.RecordReader,
+	identifier string) ([]byte, error) {
+	var buf []byte
+	var err error
+
+	buf, err = r.ReadRecord(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = Verify(buf, identifier); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+/*
+Verify checks that buf holds a FlatBuffer whose root table and its vtable lie
+within the bounds of the buffer, and that none of the fields of the root table
+point outside of the table. If identifier is not empty, the buffer must also
+carry the specified 4 character file identifier.
+
+Only the root table is checked; nested tables, vectors and strings need to be
+verified by the generated code if the buffer comes from an untrusted source.
+*/
+func Verify(buf []byte, identifier string) error {
+	var root, vtable int64
+	var vtableSize, tableSize, fieldOffset int64
+	var i int64
+
+	if len(buf) < flatbuffers.SizeUOffsetT {
+		return ErrInvalidBuffer
+	}
+
+	if identifier != "" {
+		if len(identifier) != 4 || len(buf) < 8 {
+			return ErrIdentifierMismatch
+		}
+		if string(buf[4:8]) != identifier {
+			return ErrIdentifierMismatch
+		}
+	}
+
+	root = int64(binary.LittleEndian.Uint32(buf))
+	if root+flatbuffers.SizeSOffsetT > int64(len(buf)) {
+		return ErrInvalidBuffer
+	}
+
+	vtable = root - int64(int32(binary.LittleEndian.Uint32(buf[root:])))
+	if vtable < 0 || vtable+2*flatbuffers.SizeVOffsetT > int64(len(buf)) {
+		return ErrInvalidBuffer
+	}
+
+	vtableSize = int64(binary.LittleEndian.Uint16(buf[vtable:]))
+	tableSize = int64(binary.LittleEndian.Uint16(buf[vtable+2:]))
+	if vtableSize < 2*flatbuffers.SizeVOffsetT || vtableSize%2 != 0 ||
+		vtable+vtableSize > int64(len(buf)) {
+		return ErrInvalidBuffer
+	}
+	if tableSize < flatbuffers.SizeSOffsetT || root+tableSize > int64(len(buf)) {
+		return ErrInvalidBuffer
+	}
+
+	for i = vtable + 2*flatbuffers.SizeVOffsetT; i < vtable+vtableSize; i += 2 {
+		fieldOffset = int64(binary.LittleEndian.Uint16(buf[i:]))
+		if fieldOffset >= tableSize {
+			return ErrInvalidBuffer
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package flatbuffers
+
+import (
+	"github.com/childoftheuniverse/filesystem-internal"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+A FlatBuffer with the file identifier "TEST" holding a table with a single
+int32 field set to 42.
+*/
+var testBuffer = []byte{
+	16, 0, 0, 0, // Offset of the root table.
+	'T', 'E', 'S', 'T', // File identifier.
+	6, 0, 8, 0, 4, 0, // vtable: vtable size, table size, field offset.
+	0, 0, // Padding.
+	8, 0, 0, 0, // Offset of the vtable from the table.
+	42, 0, 0, 0, // Field value.
+}
+
+/*
+Write a buffer as a record and read it back with verification.
+*/
+func TestReadVerified(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = recordio.NewRecordWriter(buf)
+	var rbuf []byte
+	var err error
+
+	if _, err = writer.Write(ctx, testBuffer); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	rbuf, err = ReadVerified(ctx, recordio.NewRecordReader(buf), "TEST")
+	if err != nil {
+		t.Error("Error reading buffer: ", err)
+	}
+
+	if string(rbuf) != string(testBuffer) {
+		t.Error("Buffer mismatch: got ", rbuf, ", expected ", testBuffer)
+	}
+}
+
+/*
+Check that Verify detects broken buffers and mismatching identifiers.
+*/
+func TestVerify(t *testing.T) {
+	var broken []byte
+	var err error
+
+	if err = Verify(testBuffer, ""); err != nil {
+		t.Error("Unexpected error verifying buffer: ", err)
+	}
+
+	if err = Verify(testBuffer, "NOPE"); err != ErrIdentifierMismatch {
+		t.Error("Expected ErrIdentifierMismatch, got ", err)
+	}
+
+	if err = Verify(testBuffer[:20], ""); err != ErrInvalidBuffer {
+		t.Error("Expected ErrInvalidBuffer for truncated buffer, got ", err)
+	}
+
+	broken = append([]byte{}, testBuffer...)
+	broken[0] = 200
+	if err = Verify(broken, ""); err != ErrInvalidBuffer {
+		t.Error("Expected ErrInvalidBuffer for bad root offset, got ", err)
+	}
+
+	broken = append([]byte{}, testBuffer...)
+	broken[12] = 9
+	if err = Verify(broken, ""); err != ErrInvalidBuffer {
+		t.Error("Expected ErrInvalidBuffer for bad field offset, got ", err)
+	}
+}
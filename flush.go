@@ -0,0 +1,93 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+)
+
+/*
+ErrSyncUnsupported is returned by Sync() if the output stream cannot be
+synced to stable storage.
+*/
+var ErrSyncUnsupported = errors.New("Output stream does not support syncing")
+
+/*
+Flusher is implemented by output streams which buffer data internally, such
+as the adapters returned by NewIOWriteCloser() for writers with a Flush()
+method like bufio.Writer.
+*/
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+/*
+Syncer is implemented by output streams which can commit the data written to
+them to stable storage, such as the adapters returned by NewIOWriteCloser()
+for an os.File.
+*/
+type Syncer interface {
+	Sync(ctx context.Context) error
+}
+
+/*
+Flush pushes all data buffered by the RecordWriter to the underlying output
+stream, and flushes the output stream as well if it implements Flusher. The
+output stream is not closed, so more records can be written afterwards.
+*/
+func (w *RecordWriter) Flush(ctx context.Context) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.flushBuffer(ctx); err != nil {
+		return err
+	}
+	if err := w.pad(ctx, 0); err != nil {
+		return err
+	}
+	if flusher, ok := w.wrappedWriter.(Flusher); ok {
+		if err := flusher.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	w.notifyFlushed(false)
+	return nil
+}
+
+/*
+Sync flushes the RecordWriter as described for Flush() and then commits the
+data written so far to stable storage, so that it survives a crash. This
+requires the output stream to implement Syncer; otherwise,
+ErrSyncUnsupported is returned after flushing.
+*/
+func (w *RecordWriter) Sync(ctx context.Context) error {
+	var err error
+
+	if err = w.Flush(ctx); err != nil {
+		return err
+	}
+
+	if syncer, ok := w.wrappedWriter.(Syncer); ok {
+		if err = syncer.Sync(ctx); err == nil {
+			w.durable = w.position.Offset
+		}
+		return err
+	}
+	return ErrSyncUnsupported
+}
+
+/*
+Barrier guarantees that all records written before it are durable, i.e.
+flushed and synced to stable storage as described for Sync(), before it
+returns, so that checkpointing systems can order their writes without
+syncing every record. If nothing has been written since the last successful
+Barrier() or Sync(), the output stream is not synced again.
+*/
+func (w *RecordWriter) Barrier(ctx context.Context) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.position.Offset == w.durable {
+		return nil
+	}
+	return w.Sync(ctx)
+}
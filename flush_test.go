@@ -0,0 +1,116 @@
+package recordio
+
+import (
+	"bufio"
+	"bytes"
+	"golang.org/x/net/context"
+	"os"
+	"testing"
+)
+
+/*
+Flushing must push data buffered in a bufio.Writer to the underlying buffer.
+*/
+func TestFlush(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(bufio.NewWriter(&buf))
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("Data was written before flushing")
+	}
+
+	if err = writer.Flush(ctx); err != nil {
+		t.Error("Error flushing writer: ", err)
+	}
+	if buf.Len() != 9 {
+		t.Error("Expected 9 bytes after flushing, got ", buf.Len())
+	}
+}
+
+/*
+Syncing must be passed through to files, and fail for streams which cannot
+be synced.
+*/
+func TestSync(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var file *os.File
+	var writer *RecordWriter
+	var err error
+
+	if err = NewIORecordWriter(&buf).Sync(ctx); err != ErrSyncUnsupported {
+		t.Error("Expected sync to be unsupported, got: ", err)
+	}
+
+	if file, err = os.CreateTemp(t.TempDir(), "sync"); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+
+	writer = NewIORecordWriter(file)
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Sync(ctx); err != nil {
+		t.Error("Error syncing file: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing file: ", err)
+	}
+}
+
+/*
+syncingWriter counts the calls to its Sync method.
+*/
+type syncingWriter struct {
+	bytes.Buffer
+	syncs int
+}
+
+func (s *syncingWriter) Sync() error {
+	s.syncs++
+	return nil
+}
+
+/*
+Barriers must sync the output stream only if records have been written
+since the last barrier, and records buffered by the writer must be written
+before syncing.
+*/
+func TestBarrier(t *testing.T) {
+	var ctx = context.Background()
+	var out syncingWriter
+	var writer = NewIORecordWriter(&out, WithWriteBuffer(1024))
+	var err error
+
+	if err = writer.Barrier(ctx); err != nil || out.syncs != 0 {
+		t.Errorf("Unexpected sync without records: %d (%v)", out.syncs, err)
+	}
+
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Barrier(ctx); err != nil {
+		t.Error("Error setting barrier: ", err)
+	}
+	if out.syncs != 1 || out.Len() != 9 {
+		t.Errorf("Record not durable after barrier: %d syncs, %d bytes",
+			out.syncs, out.Len())
+	}
+
+	if err = writer.Barrier(ctx); err != nil || out.syncs != 1 {
+		t.Errorf("Unexpected sync without new records: %d (%v)", out.syncs,
+			err)
+	}
+
+	if _, err = writer.Write(ctx, []byte("World")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Barrier(ctx); err != nil || out.syncs != 2 {
+		t.Errorf("Unexpected number of syncs: %d (%v)", out.syncs, err)
+	}
+}
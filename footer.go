@@ -0,0 +1,305 @@
+package recordio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"math"
+)
+
+/*
+footerMarker is the record length which marks the beginning of the footer
+instead of a record when WithFooter() is used.
+*/
+const footerMarker = math.MaxUint32
+
+/*
+footerMagic identifies the trailer at the very end of a stream with a footer.
+*/
+var footerMagic = []byte("RIOF")
+
+/*
+footerTrailerLength is the length of the trailer at the end of the footer,
+consisting of the total length of the footer and footerMagic.
+*/
+const footerTrailerLength = 8
+
+/*
+ErrCorruptedFooter is returned if the footer of a stream cannot be parsed.
+*/
+var ErrCorruptedFooter = errors.New("Corrupted footer")
+
+/*
+WithFooter makes writers append a footer to the stream when they are closed,
+which records the number of records in the stream. Readers stop at the
+footer as if the stream ended there. Since the footer starts with a header
+claiming a length of 4 GiB - 1 bytes, records of that size cannot be
+written when the footer is enabled.
+
+The footer ends in a trailer holding its length, so readers of seekable
+streams can find it without reading the whole stream.
+*/
+func WithFooter() Option {
+	return func(o *options) {
+		o.footer = true
+	}
+}
+
+/*
+WithSparseIndex enables the footer as described for WithFooter(), and
+additionally records the offset of every interval-th record in it.
+SeekToRecord() loads this sparse index from the footer of seekable streams,
+so that it needs to skip over at most interval - 1 records to reach any
+record, even without a sidecar index. Smaller intervals make seeking faster
+at the expense of a larger footer.
+*/
+func WithSparseIndex(interval int) Option {
+	return func(o *options) {
+		o.footer = true
+		o.footerInterval = interval
+	}
+}
+
+/*
+indexRecord remembers the position of a record which has been written, if
+it is to be included in the sparse index of the footer.
+*/
+func (w *RecordWriter) indexRecord(pos Position) {
+	var interval = int64(w.options.footerInterval)
+
+	if interval > 0 && pos.Index%interval == 0 {
+		w.sparseIndex = append(w.sparseIndex, pos.Offset)
+	}
+}
+
+/*
+writeFooter writes the footer to the output stream. It consists of a header
+with the footerMarker length and the checksum of the footer data, the length
+of the footer data as 4 byte big endian integer, the footer data itself and
+the trailer. The footer data holds the number of records, the interval of
+the sparse index and its offsets, all encoded as varints.
+*/
+func (w *RecordWriter) writeFooter(ctx context.Context) error {
+	var data []byte
+	var header []byte
+	var footer bytes.Buffer
+	var scratch [binary.MaxVarintLen64]byte
+
+	data = append(data, scratch[:binary.PutUvarint(scratch[:],
+		uint64(w.position.Index))]...)
+	data = append(data, scratch[:binary.PutUvarint(scratch[:],
+		uint64(w.options.footerInterval))]...)
+	for _, offset := range w.sparseIndex {
+		data = append(data, scratch[:binary.PutUvarint(scratch[:],
+			uint64(offset))]...)
+	}
+
+	if w.options.checksum {
+		header = w.appendHeader(nil, footerMarker, checksum(data))
+	} else {
+		header = w.appendHeader(nil, footerMarker, 0)
+	}
+
+	footer.Write(header)
+	binary.Write(&footer, binary.BigEndian, uint32(len(data)))
+	footer.Write(data)
+	binary.Write(&footer, binary.BigEndian,
+		uint32(footer.Len()+footerTrailerLength))
+	footer.Write(footerMagic)
+
+	if err := w.pad(ctx, footer.Len()); err != nil {
+		return err
+	}
+	return writeFull(ctx, w.wrappedWriter, footer.Bytes())
+}
+
+/*
+nextRecordHeader reads the header of the next record from the input stream.
+If the footer is enabled and reached, it is read and io.EOF is returned, as
+it is at the zeros following the data of a preallocated stream.
+*/
+func (r *RecordReader) nextRecordHeader(ctx context.Context) (uint32, uint32,
+	error) {
+	var length, crc uint32
+	var consumed int64
+	var index *RecordIndex
+	var err error
+
+	length, crc, err = r.readHeader(ctx)
+	for err == nil && r.options.alignment > 0 && length == paddingMarker {
+		if err = r.skipPadding(ctx); err == nil {
+			length, crc, err = r.readHeader(ctx)
+		}
+	}
+	if err == nil {
+		if zero, zeroErr := r.zeroHeader(ctx, length, crc); zero {
+			if zeroErr != nil {
+				return 0, 0, zeroErr
+			}
+			return 0, 0, io.EOF
+		}
+	}
+	if err != nil || !r.options.footer || length != footerMarker {
+		return length, crc, err
+	}
+
+	if index, consumed, err = r.readFooter(ctx, crc); err != nil {
+		return 0, 0, err
+	}
+	if r.index == nil {
+		r.index = index
+	}
+	r.trailing = int64(r.headerLength(footerMarker)) + consumed
+	return 0, 0, io.EOF
+}
+
+/*
+readFooter reads the remainder of the footer following its header from the
+input stream, and returns the sparse index it contains along with the number
+of bytes read.
+*/
+func (r *RecordReader) readFooter(ctx context.Context, crc uint32) (
+	*RecordIndex, int64, error) {
+	var header [4]byte
+	var trailer [footerTrailerLength]byte
+	var data []byte
+	var length uint32
+	var consumed int64
+	var err error
+
+	if _, err = readFull(ctx, r.wrappedReader, header[:]); err != nil {
+		return nil, 0, noEOF(err)
+	}
+	length = binary.BigEndian.Uint32(header[:])
+	if length > r.options.maxRecordSize {
+		return nil, 0, ErrCorruptedFooter
+	}
+
+	data = make([]byte, length)
+	if _, err = readFull(ctx, r.wrappedReader, data); err != nil {
+		return nil, 0, noEOF(err)
+	}
+	if _, err = readFull(ctx, r.wrappedReader, trailer[:]); err != nil {
+		return nil, 0, noEOF(err)
+	}
+
+	if !bytes.Equal(trailer[4:], footerMagic) {
+		return nil, 0, ErrCorruptedFooter
+	}
+	if r.options.checksum && checksum(data) != crc {
+		return nil, 0, ErrChecksumMismatch
+	}
+
+	consumed = int64(len(header) + len(data) + len(trailer))
+	return parseFooter(data), consumed, nil
+}
+
+/*
+parseFooter decodes the footer data into a RecordIndex. Corrupted data
+results in an incomplete index rather than an error, since the checksum and
+the magic already protect against corruption.
+*/
+func parseFooter(data []byte) *RecordIndex {
+	var index = &RecordIndex{}
+	var values []int64
+	var value uint64
+	var n int
+
+	for len(data) > 0 {
+		if value, n = binary.Uvarint(data); n <= 0 {
+			break
+		}
+		values = append(values, int64(value))
+		data = data[n:]
+	}
+
+	if len(values) >= 2 {
+		index.count = values[0]
+		index.interval = values[1]
+		index.offsets = values[2:]
+	}
+	if index.interval <= 0 {
+		index.offsets = nil
+	}
+	return index
+}
+
+/*
+loadFooter reads the footer from the end of the input stream, which must be
+seekable, without changing the position of the reader. If the stream has no
+footer, e.g. because it is still being written, nil is returned.
+*/
+func (r *RecordReader) loadFooter(ctx context.Context) (*RecordIndex, error) {
+	var seeker = r.wrappedReader.(Seeker)
+	var trailer [footerTrailerLength]byte
+	var index *RecordIndex
+	var current, size int64
+	var length, crc uint32
+	var err error
+
+	if current, err = seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+	if size, err = r.streamSize(ctx); err != nil {
+		return nil, err
+	}
+	if size < footerTrailerLength {
+		return nil, nil
+	}
+
+	if _, err = seeker.Seek(ctx, -footerTrailerLength, io.SeekEnd); err != nil {
+		return nil, err
+	}
+	if _, err = readFull(ctx, r.wrappedReader, trailer[:]); err != nil {
+		return nil, noEOF(err)
+	}
+	length = binary.BigEndian.Uint32(trailer[:])
+	if !bytes.Equal(trailer[4:], footerMagic) || int64(length) > size {
+		_, err = seeker.Seek(ctx, current, io.SeekStart)
+		return nil, err
+	}
+
+	if _, err = seeker.Seek(ctx, -int64(length), io.SeekEnd); err != nil {
+		return nil, err
+	}
+	length, crc, err = r.readHeader(ctx)
+	if err == nil && length != footerMarker {
+		err = ErrCorruptedFooter
+	}
+	if err == nil {
+		index, _, err = r.readFooter(ctx, crc)
+	}
+	if err != nil {
+		return nil, noEOF(err)
+	}
+
+	_, err = seeker.Seek(ctx, current, io.SeekStart)
+	return index, err
+}
+
+/*
+FooterIndex reads the footer from the end of the input stream, which must
+implement Seeker, and returns the sparse index it contains, e.g. for use
+with RandomAccessReader. The number of records in the stream is available
+from its Len() method even if no sparse index was written. The position of
+the reader is not changed. If the stream has no footer, nil is returned.
+*/
+func (r *RecordReader) FooterIndex(ctx context.Context) (*RecordIndex,
+	error) {
+	var index *RecordIndex
+	var err error
+
+	if r.closed {
+		return nil, recordError(r.position, ErrClosed)
+	}
+	if _, ok := r.wrappedReader.(Seeker); !ok {
+		return nil, recordError(r.position, ErrNotSeekable)
+	}
+
+	if index, err = r.loadFooter(ctx); err != nil {
+		return nil, recordError(r.position, err)
+	}
+	return index, nil
+}
@@ -0,0 +1,128 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Records written with a footer must be read back as usual, and the sparse
+index in the footer must be usable for seeking.
+*/
+func TestSparseIndexFooter(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithSparseIndex(3), WithChecksum())
+	var reader *RecordReader
+	var index *RecordIndex
+	var pos Position
+	var rec []byte
+	var ok bool
+	var i int
+	var err error
+
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithSparseIndex(3), WithChecksum())
+	for i = 0; i < 10; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != fmt.Sprint("Record ", i) {
+			t.Errorf("Unexpected data: got %q, expected Record %d", rec, i)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+
+	if err = reader.SeekToRecord(ctx, 7); err != nil {
+		t.Error("Error seeking to record 7: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); string(rec) != "Record 7" {
+		t.Errorf("Unexpected data: got %q, expected Record 7 (%v)", rec, err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithSparseIndex(3), WithChecksum())
+	if index, err = reader.FooterIndex(ctx); err != nil || index == nil {
+		t.Fatal("Error reading footer: ", err)
+	}
+	if index.Len() != 10 {
+		t.Error("Unexpected number of records: ", index.Len())
+	}
+	if pos, ok = index.Record(6); !ok || pos.Offset != 6*16 {
+		t.Error("Unexpected position of record 6: ", pos)
+	}
+	if _, ok = index.Record(7); ok {
+		t.Error("Record 7 should not be in the sparse index")
+	}
+
+	if rec, err = NewRandomAccessReader(bytes.NewReader(buf.Bytes()), index,
+		WithChecksum()).Get(ctx, 8); string(rec) != "Record 8" {
+		t.Errorf("Unexpected data: got %q, expected Record 8 (%v)", rec, err)
+	}
+}
+
+/*
+Compressed streams must be seekable using the sparse index in the footer,
+without decompressing the records skipped.
+*/
+func TestSparseIndexCompressed(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var decompressed int
+	var codec = &countingCodec{Codec: DeflateCodec, count: &decompressed}
+	var writer = NewIORecordWriter(&buf, WithCodec(DeflateCodec),
+		WithSparseIndex(4))
+	var reader *RecordReader
+	var rec []byte
+	var i int
+	var err error
+
+	for i = 0; i < 20; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()), WithCodec(codec),
+		WithSparseIndex(4))
+	if err = reader.SeekToRecord(ctx, 14); err != nil {
+		t.Error("Error seeking to record 14: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); string(rec) != "Record 14" {
+		t.Errorf("Unexpected data: got %q, expected Record 14 (%v)", rec, err)
+	}
+	if decompressed != 1 {
+		t.Error("Unexpected number of records decompressed: ", decompressed)
+	}
+}
+
+/*
+countingCodec counts the number of records decompressed.
+*/
+type countingCodec struct {
+	Codec
+	count *int
+}
+
+func (c *countingCodec) Decompress(data []byte, limit uint32) ([]byte,
+	error) {
+	*c.count++
+	return c.Codec.Decompress(data, limit)
+}
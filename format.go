@@ -0,0 +1,203 @@
+package recordio
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*
+ErrInvalidFormat is returned by FormatBuilder.Build() if the requested
+combination of features is not valid.
+*/
+var ErrInvalidFormat = errors.New("Invalid record format")
+
+/*
+Format describes how records are laid out in a stream: the framing of the
+record lengths, whether checksums are present, the codec and cipher used for
+the record data, the block size records are aligned to and the maximum
+record size. Readers and writers must use the same
+Format for a stream, so it is convenient to define it once using a
+FormatBuilder and pass it to both using WithFormat().
+
+The zero value is the default format used when no options are specified.
+*/
+type Format struct {
+	framing       Framing
+	checksum      bool
+	codec         Codec
+	encryption    cipher.AEAD
+	alignment     int
+	maxRecordSize uint32
+}
+
+/*
+FormatBuilder assembles a Format from individual features, checking that
+they are compatible with each other. Its methods return the builder itself,
+so calls can be chained:
+
+	format, err := recordio.NewFormatBuilder().
+		Framing(recordio.VarintFraming).
+		Checksum().
+		Build()
+
+Any problem is reported by Build(), so the intermediate calls never fail.
+*/
+type FormatBuilder struct {
+	format        Format
+	framingSet    bool
+	codecSet      bool
+	encryptionSet bool
+	alignmentSet  bool
+	err           error
+}
+
+/*
+NewFormatBuilder creates a new FormatBuilder for the default format, i.e.
+FixedLengthFraming without checksums or compression.
+*/
+func NewFormatBuilder() *FormatBuilder {
+	return &FormatBuilder{}
+}
+
+/*
+Framing sets the way record lengths are encoded; see WithFraming().
+*/
+func (b *FormatBuilder) Framing(framing Framing) *FormatBuilder {
+	if framing < FixedLengthFraming || framing > LittleEndianFraming {
+		b.fail("Unknown framing %d", framing)
+	} else if b.framingSet && b.format.framing != framing {
+		b.fail("Conflicting framings %d and %d", b.format.framing, framing)
+	}
+	b.format.framing = framing
+	b.framingSet = true
+	return b
+}
+
+/*
+Checksum adds a checksum to every record; see WithChecksum().
+*/
+func (b *FormatBuilder) Checksum() *FormatBuilder {
+	b.format.checksum = true
+	return b
+}
+
+/*
+Codec compresses every record using the specified codec; see WithCodec().
+*/
+func (b *FormatBuilder) Codec(codec Codec) *FormatBuilder {
+	// Codecs need not be comparable, so setting one twice is an error even
+	// if it is the same codec.
+	if codec == nil {
+		b.fail("Codec must not be nil")
+	} else if b.codecSet {
+		b.fail("Codec set twice")
+	}
+	b.format.codec = codec
+	b.codecSet = true
+	return b
+}
+
+/*
+Encryption encrypts every record using the specified cipher; see
+WithEncryption().
+*/
+func (b *FormatBuilder) Encryption(aead cipher.AEAD) *FormatBuilder {
+	if aead == nil {
+		b.fail("Cipher must not be nil")
+	} else if b.encryptionSet {
+		b.fail("Encryption set twice")
+	}
+	b.format.encryption = aead
+	b.encryptionSet = true
+	return b
+}
+
+/*
+BlockAlignment aligns all reads and writes to blocks of blockSize bytes,
+which must be a power of two; see WithAlignment().
+*/
+func (b *FormatBuilder) BlockAlignment(blockSize int) *FormatBuilder {
+	if blockSize <= 0 || blockSize&(blockSize-1) != 0 {
+		b.fail("Block size %d is not a positive power of two", blockSize)
+	} else if b.alignmentSet && b.format.alignment != blockSize {
+		b.fail("Conflicting block sizes %d and %d", b.format.alignment,
+			blockSize)
+	}
+	b.format.alignment = blockSize
+	b.alignmentSet = true
+	return b
+}
+
+/*
+MaxRecordSize limits the size of records; see WithMaxRecordSize().
+*/
+func (b *FormatBuilder) MaxRecordSize(size uint32) *FormatBuilder {
+	if size == 0 {
+		b.fail("Maximum record size must not be 0")
+	}
+	b.format.maxRecordSize = size
+	return b
+}
+
+/*
+Build returns the assembled Format, or an error wrapping ErrInvalidFormat
+describing the first invalid setting.
+*/
+func (b *FormatBuilder) Build() (Format, error) {
+	var maxRecordSize = b.format.maxRecordSize
+
+	if maxRecordSize == 0 {
+		maxRecordSize = math.MaxUint32
+	}
+
+	// Padding frames of aligned streams use the length below the footer
+	// marker, so records can't be that large.
+	if b.format.alignment > 0 && b.format.maxRecordSize >= paddingMarker {
+		b.fail("Maximum record size %d too large for block alignment",
+			maxRecordSize)
+	}
+
+	// Every encrypted record carries a nonce and the overhead of the
+	// cipher, which must fit within the maximum record size.
+	if b.format.encryption != nil && uint64(maxRecordSize) <= uint64(
+		b.format.encryption.NonceSize()+b.format.encryption.Overhead()) {
+		b.fail("Maximum record size %d too small for encryption",
+			maxRecordSize)
+	}
+
+	if b.err != nil {
+		return Format{}, b.err
+	}
+	return b.format, nil
+}
+
+/*
+fail records the first error encountered while building the format.
+*/
+func (b *FormatBuilder) fail(msg string, args ...interface{}) {
+	if b.err == nil {
+		b.err = fmt.Errorf("%w: %s", ErrInvalidFormat,
+			fmt.Sprintf(msg, args...))
+	}
+}
+
+/*
+WithFormat configures a reader or writer to use the specified Format. This
+replaces the framing, checksum, codec, encryption, alignment and maximum
+record size set by any previous options.
+*/
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.framing = format.framing
+		o.checksum = format.checksum
+		o.codec = format.codec
+		o.encryption = format.encryption
+		o.alignment = format.alignment
+		o.maxRecordSize = format.maxRecordSize
+		if o.maxRecordSize == 0 {
+			o.maxRecordSize = math.MaxUint32
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+A Format assembled by a FormatBuilder must configure readers and writers
+consistently.
+*/
+func TestFormatRoundTrip(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var format Format
+	var writer *RecordWriter
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	format, err = NewFormatBuilder().
+		Framing(VarintFraming).
+		Checksum().
+		Codec(DeflateCodec).
+		MaxRecordSize(1024).
+		Build()
+	if err != nil {
+		t.Fatal("Error building format: ", err)
+	}
+
+	writer = NewIORecordWriter(&buf, WithFormat(format))
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if _, err = writer.Write(ctx, make([]byte, 2048)); !errors.Is(
+		err, ErrRecordTooLarge) {
+		t.Error("Expected ErrRecordTooLarge, got: ", err)
+	}
+
+	reader = NewIORecordReader(&buf, WithFraming(VarintFraming),
+		WithChecksum(), WithCodec(DeflateCodec))
+	if rec, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Hello" {
+		t.Errorf("Unexpected data: got %q, expected Hello", rec)
+	}
+}
+
+/*
+Invalid feature combinations must be rejected by Build().
+*/
+func TestFormatBuilderInvalid(t *testing.T) {
+	var builders = []*FormatBuilder{
+		NewFormatBuilder().Framing(Framing(42)),
+		NewFormatBuilder().Framing(VarintFraming).Framing(FixedLengthFraming),
+		NewFormatBuilder().Codec(nil),
+		NewFormatBuilder().MaxRecordSize(0),
+		NewFormatBuilder().Codec(sliceCodec{}).Codec(sliceCodec{}),
+		NewFormatBuilder().Encryption(nil),
+		NewFormatBuilder().BlockAlignment(0),
+		NewFormatBuilder().BlockAlignment(1000),
+		NewFormatBuilder().BlockAlignment(512).BlockAlignment(4096),
+		NewFormatBuilder().BlockAlignment(512).MaxRecordSize(paddingMarker),
+		NewFormatBuilder().Encryption(testCipher(t)).MaxRecordSize(16),
+	}
+	var err error
+
+	for i, builder := range builders {
+		if _, err = builder.Build(); !errors.Is(err, ErrInvalidFormat) {
+			t.Error("Expected ErrInvalidFormat for builder ", i, ", got: ", err)
+		}
+	}
+
+	if _, err = NewFormatBuilder().Build(); err != nil {
+		t.Error("Error building default format: ", err)
+	}
+}
+
+/*
+sliceCodec is a Codec which is not comparable, which must not make the
+FormatBuilder panic.
+*/
+type sliceCodec []byte
+
+func (sliceCodec) Compress(data []byte) ([]byte, error) {
+	return data, nil
+}
+
+func (sliceCodec) Decompress(data []byte, limit uint32) ([]byte, error) {
+	return data, nil
+}
+
+/*
+Encryption and block alignment set using a FormatBuilder must be applied to
+readers and writers.
+*/
+func TestFormatEncryptionAlignment(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var format Format
+	var writer *RecordWriter
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	format, err = NewFormatBuilder().
+		Codec(DeflateCodec).
+		Encryption(testCipher(t)).
+		BlockAlignment(512).
+		Build()
+	if err != nil {
+		t.Fatal("Error building format: ", err)
+	}
+
+	writer = NewIORecordWriter(&buf, WithFormat(format))
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	if buf.Len() != 512 {
+		t.Error("Expected a single aligned block, got ", buf.Len(), " bytes")
+	}
+	if bytes.Contains(buf.Bytes(), []byte("Hello")) {
+		t.Error("Record was written unencrypted")
+	}
+
+	reader = NewIORecordReader(&buf, WithFormat(format))
+	if rec, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Hello" {
+		t.Errorf("Unexpected data: got %q, expected Hello", rec)
+	}
+}
@@ -0,0 +1,176 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+	"math"
+)
+
+/*
+Framing determines how the length of each record is encoded in front of the
+record data. If checksums are enabled, the checksum follows the length in the
+same byte order; with VarintFraming, it is stored in big endian byte order.
+*/
+type Framing int
+
+const (
+	/*
+		FixedLengthFraming prefixes each record with its length as a 4 byte
+		big endian integer. This is the default framing.
+	*/
+	FixedLengthFraming Framing = iota
+
+	/*
+		VarintFraming prefixes each record with its length as a base 128
+		varint. This is the delimited stream format produced by
+		writeDelimitedTo() in the Java and C++ protocol buffer libraries, so
+		streams written this way can be exchanged with their standard
+		tooling.
+	*/
+	VarintFraming
+
+	/*
+		LittleEndianFraming prefixes each record with its length as a 4 byte
+		little endian integer. This matches the size prefix FlatBuffers
+		writes when finishing a buffer with FinishSizePrefixed(), so a
+		stream of size prefixed FlatBuffers can be read as records.
+	*/
+	LittleEndianFraming
+)
+
+/*
+NewFramedRecordReader creates a new RecordReader wrapped around the specified
+input stream which expects record lengths to be encoded as specified by
+framing. This is equivalent to NewRecordReader(reader, WithFraming(framing)).
+*/
+func NewFramedRecordReader(reader filesystem.ReadCloser,
+	framing Framing) *RecordReader {
+	return NewRecordReader(reader, WithFraming(framing))
+}
+
+/*
+NewFramedRecordWriter creates a new RecordWriter wrapped around the specified
+output stream which encodes record lengths as specified by framing. This is
+equivalent to NewRecordWriter(writer, WithFraming(framing)).
+*/
+func NewFramedRecordWriter(writer filesystem.WriteCloser,
+	framing Framing) *RecordWriter {
+	return NewRecordWriter(writer, WithFraming(framing))
+}
+
+/*
+byteOrder returns the byte order used for fixed size header fields.
+*/
+func (o options) byteOrder() binary.ByteOrder {
+	if o.framing == LittleEndianFraming {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+/*
+readHeader reads the header of the next record and returns the length of the
+record data following it, as well as its checksum if checksums are enabled.
+*/
+func (r *RecordReader) readHeader(ctx context.Context) (uint32, uint32, error) {
+	var buf = headerPool.Get().(*[maxHeaderLength]byte)
+	var header []byte
+	var length uint64
+	var err error
+
+	defer headerPool.Put(buf)
+
+	if r.options.framing == VarintFraming {
+		length, err = binary.ReadUvarint(
+			&byteReader{ctx: ctx, reader: r.wrappedReader})
+		if err != nil {
+			return 0, 0, err
+		}
+		if length > math.MaxUint32 {
+			return 0, 0, errLengthOutOfRange
+		}
+		if !r.options.checksum {
+			return uint32(length), 0, nil
+		}
+
+		header = buf[:4]
+		if _, err = readFull(ctx, r.wrappedReader, header); err != nil {
+			return 0, 0, noEOF(err)
+		}
+		return uint32(length), binary.BigEndian.Uint32(header), nil
+	}
+
+	if r.options.checksum {
+		header = buf[:8]
+	} else {
+		header = buf[:4]
+	}
+
+	_, err = readFull(ctx, r.wrappedReader, header)
+	if err == io.ErrUnexpectedEOF {
+		return 0, 0, errShortHeader
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if r.options.checksum {
+		return r.options.byteOrder().Uint32(header),
+			r.options.byteOrder().Uint32(header[4:]), nil
+	}
+	return r.options.byteOrder().Uint32(header), 0, nil
+}
+
+/*
+appendHeader appends the header for record data of the specified length and
+checksum, encoded according to the options of the writer, to dst.
+*/
+func (w *RecordWriter) appendHeader(dst []byte, length uint32,
+	crc uint32) []byte {
+	var little = w.options.framing == LittleEndianFraming
+
+	if w.options.framing == VarintFraming {
+		for length >= 0x80 {
+			dst = append(dst, byte(length)|0x80)
+			length >>= 7
+		}
+		dst = append(dst, byte(length))
+	} else {
+		dst = appendUint32(dst, length, little)
+	}
+
+	if w.options.checksum {
+		dst = appendUint32(dst, crc, little)
+	}
+	return dst
+}
+
+/*
+appendUint32 appends v to dst as a 4 byte integer in big or little endian
+byte order.
+*/
+func appendUint32(dst []byte, v uint32, littleEndian bool) []byte {
+	if littleEndian {
+		return append(dst, byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+	}
+	return append(dst, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+/*
+headerLength returns the length of the header preceding record data of the
+specified length.
+*/
+func (r *RecordReader) headerLength(length uint32) int {
+	var n = 4
+
+	if r.options.framing == VarintFraming {
+		var lengthAsBytes [binary.MaxVarintLen32]byte
+		n = binary.PutUvarint(lengthAsBytes[:], uint64(length))
+	}
+	if r.options.checksum {
+		n += 4
+	}
+	return n
+}
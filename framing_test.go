@@ -0,0 +1,138 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Write protocol buffers using VarintFraming and check that the output is a
+standard delimited stream which can be read back.
+*/
+func TestVarintFramingMessages(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewFramedRecordWriter(buf, VarintFraming)
+	var reader *RecordReader
+	var header = make([]byte, 1)
+	var err error
+
+	var data MessageForTest
+
+	data.Message = "Test data"
+	err = writer.WriteMessage(ctx, &data)
+	if err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+
+	data.Message = "Toast Data"
+	err = writer.WriteMessage(ctx, &data)
+	if err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+
+	// Reset position
+	writer.Close(ctx)
+
+	// The first byte should be the length of the first message as a varint.
+	buf.Read(ctx, header)
+	if int(header[0]) != len("Test data")+2 {
+		t.Error("Unexpected varint header: ", header[0])
+	}
+	buf.Close(ctx)
+
+	reader = NewFramedRecordReader(buf, VarintFraming)
+	data.Reset()
+
+	err = reader.ReadMessage(ctx, &data)
+	if err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+
+	if data.Message != "Test data" {
+		t.Errorf("Expected: Test data, got: %s", data.Message)
+	}
+	data.Reset()
+
+	err = reader.ReadMessage(ctx, &data)
+	if err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+
+	if data.Message != "Toast Data" {
+		t.Errorf("Expected: Toast Data, got: %s", data.Message)
+	}
+}
+
+/*
+Records longer than 127 bytes require a multi-byte varint header.
+*/
+func TestVarintFramingLongRecord(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewFramedRecordWriter(buf, VarintFraming)
+	var reader *RecordReader
+	var rec = make([]byte, 300)
+	var rbuf []byte
+	var err error
+	var l int
+
+	l, err = writer.Write(ctx, rec)
+	if err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	if l != 302 {
+		t.Error("Write length mismatched (expected 302, got ", l, ")")
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	reader = NewFramedRecordReader(buf, VarintFraming)
+	rbuf, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+
+	if len(rbuf) != 300 {
+		t.Error("Read length mismatched (expected 300, got ", len(rbuf), ")")
+	}
+}
+
+/*
+LittleEndianFraming must write the length as a little endian integer.
+*/
+func TestLittleEndianFraming(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewFramedRecordWriter(buf, LittleEndianFraming)
+	var reader *RecordReader
+	var header = make([]byte, 4)
+	var rbuf []byte
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	buf.Read(ctx, header)
+	if string(header) != "\x05\x00\x00\x00" {
+		t.Errorf("Unexpected header: %v", header)
+	}
+	buf.Close(ctx)
+
+	reader = NewFramedRecordReader(buf, LittleEndianFraming)
+	rbuf, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+
+	if string(rbuf) != "Hello" {
+		t.Errorf("Unexpected data: got %q, expected Hello", rbuf)
+	}
+}
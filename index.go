@@ -0,0 +1,373 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+IndexSuffix is the conventional suffix for the name of the sidecar index of
+a record file, e.g. "data.rio.idx" for "data.rio".
+*/
+const IndexSuffix = ".idx"
+
+/*
+ErrKeyNotFound is returned by SeekToKey() if the key is not in the index.
+*/
+var ErrKeyNotFound = errors.New("Key not found in index")
+
+/*
+IndexedRecordWriter writes records like a RecordWriter, and additionally
+writes the offset of every record, along with an optional key, to a separate
+sidecar index stream. The format of the record stream itself is unchanged,
+so it can still be read without the index.
+
+The index is a record stream of its own using VarintFraming, with one record
+per data record holding the offset of the record as a varint, followed by
+the key if one was specified.
+
+As with RecordWriter, IndexedRecordWriters are not thread safe.
+*/
+type IndexedRecordWriter struct {
+	writer *RecordWriter
+	index  *RecordWriter
+
+	// ahead is set once the index holds an entry for a record which could
+	// not be written.
+	ahead bool
+}
+
+/*
+NewIndexedRecordWriter creates a new IndexedRecordWriter writing records to
+writer and the sidecar index to index. The options apply to the record
+stream only. No actions are performed at the time.
+*/
+func NewIndexedRecordWriter(writer, index filesystem.WriteCloser,
+	opts ...Option) *IndexedRecordWriter {
+	return &IndexedRecordWriter{
+		writer: NewRecordWriter(writer, opts...),
+		index:  NewRecordWriter(index, WithFraming(VarintFraming)),
+	}
+}
+
+/*
+Write writes the record to the record stream and its offset to the index, as
+described for RecordWriter.Write().
+*/
+func (w *IndexedRecordWriter) Write(ctx context.Context, rec []byte) (
+	int, error) {
+	return w.WriteKeyed(ctx, nil, rec)
+}
+
+/*
+WriteKeyed writes the record to the record stream and its offset to the
+index, along with the specified key, so that the record can be found using
+RecordReader.SeekToKey(). An empty key is treated as no key at all.
+
+The index entry is written first, so that the entries of the index always
+line up with the records. If writing the record fails after its index entry
+has been written, a *PartialWriteError with IndexAhead set is returned, and
+further writes fail with ErrIncompleteRecord, since the index would have to
+be truncated to the last complete record first.
+*/
+func (w *IndexedRecordWriter) WriteKeyed(ctx context.Context, key,
+	rec []byte) (int, error) {
+	var pos = w.writer.Tell()
+	var entry []byte
+	var n int
+	var err error
+
+	// Check for errors which do not depend on the output streams before
+	// writing anything, so that they cannot leave the index ahead.
+	if w.writer.closed || w.index.closed {
+		return 0, recordError(pos, ErrClosed)
+	}
+	if w.ahead || w.writer.incomplete > 0 || w.index.incomplete > 0 {
+		return 0, recordError(pos, ErrIncompleteRecord)
+	}
+	if uint64(len(rec)) > uint64(w.writer.options.maxRecordSize) {
+		return 0, recordError(pos, ErrRecordTooLarge)
+	}
+
+	entry = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(key))
+	entry = append(entry[:binary.PutUvarint(entry, uint64(pos.Offset))],
+		key...)
+	if n, err = w.index.Write(ctx, entry); err != nil {
+		if n == 0 {
+			return 0, recordError(pos, unwrapRecordError(err))
+		}
+		return 0, w.indexAhead(pos, 0, err)
+	}
+
+	if n, err = w.writer.Write(ctx, rec); err != nil {
+		return n, w.indexAhead(pos, n, err)
+	}
+
+	return n, nil
+}
+
+/*
+indexAhead records that the index holds an entry for the record at pos,
+which could not be written completely, and returns err as a
+PartialWriteError saying so.
+*/
+func (w *IndexedRecordWriter) indexAhead(pos Position, written int,
+	err error) error {
+	w.ahead = true
+	return recordError(pos, &PartialWriteError{
+		Written:    written,
+		Err:        unwrapRecordError(err),
+		IndexAhead: true,
+	})
+}
+
+/*
+WriteMessage serializes the specified protocol buffer and writes it like
+Write().
+*/
+func (w *IndexedRecordWriter) WriteMessage(ctx context.Context,
+	pb Message) error {
+	var b []byte
+	var err error
+
+	if b, err = w.writer.options.marshalMessage(pb); err != nil {
+		return recordError(w.writer.position, err)
+	}
+
+	_, err = w.Write(ctx, b)
+	return err
+}
+
+/*
+Tell returns the position at which the next record will be written.
+*/
+func (w *IndexedRecordWriter) Tell() Position {
+	return w.writer.Tell()
+}
+
+/*
+Flush flushes both the record stream and the index, as described for
+RecordWriter.Flush().
+*/
+func (w *IndexedRecordWriter) Flush(ctx context.Context) error {
+	if err := w.writer.Flush(ctx); err != nil {
+		return err
+	}
+	return w.index.Flush(ctx)
+}
+
+/*
+Close closes both the record stream and the index. The index is closed even
+if closing the record stream fails, but the first error is returned.
+*/
+func (w *IndexedRecordWriter) Close(ctx context.Context) error {
+	var err = w.writer.Close(ctx)
+
+	if indexErr := w.index.Close(ctx); err == nil {
+		err = indexErr
+	}
+	return err
+}
+
+/*
+RecordIndex holds the offsets and keys of the records of a stream, as read
+from the sidecar index written by IndexedRecordWriter. An index read from the
+footer of a stream (see WithSparseIndex()) is sparse, holding only the
+offsets of every interval-th record and no keys.
+*/
+type RecordIndex struct {
+	offsets  []int64
+	keys     map[string]int64
+	interval int64
+	count    int64
+}
+
+/*
+ReadIndex reads a complete sidecar index written by IndexedRecordWriter from
+the specified input stream. The stream is not closed.
+*/
+func ReadIndex(ctx context.Context, reader filesystem.ReadCloser) (
+	*RecordIndex, error) {
+	var records = NewRecordReader(reader, WithFraming(VarintFraming),
+		WithoutCloseUnderlying())
+	var index = &RecordIndex{keys: make(map[string]int64), interval: 1}
+	var entry []byte
+	var offset uint64
+	var n int
+	var err error
+
+	for {
+		if entry, err = records.ReadRecord(ctx); err == io.EOF {
+			index.count = int64(len(index.offsets))
+			return index, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if offset, n = binary.Uvarint(entry); n <= 0 {
+			return nil, recordError(records.position,
+				errors.New("Corrupted index entry"))
+		}
+		if n < len(entry) {
+			if _, ok := index.keys[string(entry[n:])]; !ok {
+				index.keys[string(entry[n:])] = int64(len(index.offsets))
+			}
+		}
+		index.offsets = append(index.offsets, int64(offset))
+	}
+}
+
+/*
+Len returns the number of records in the stream described by the index.
+*/
+func (i *RecordIndex) Len() int64 {
+	return i.count
+}
+
+/*
+Record returns the position of the record with the specified index, and
+whether it is in the index at all. Sparse indexes only contain every
+interval-th record.
+*/
+func (i *RecordIndex) Record(n int64) (Position, bool) {
+	if n < 0 || n >= i.count || i.interval <= 0 || n%i.interval != 0 ||
+		n/i.interval >= int64(len(i.offsets)) {
+		return Position{}, false
+	}
+	return Position{Offset: i.offsets[n/i.interval], Index: n}, true
+}
+
+/*
+Lookup returns the position of the first record written with the specified
+key, and whether there is such a record.
+*/
+func (i *RecordIndex) Lookup(key []byte) (Position, bool) {
+	var n, ok = i.keys[string(key)]
+
+	if !ok {
+		return Position{}, false
+	}
+	return i.Record(n)
+}
+
+/*
+NewIndexedRecordReader creates a new RecordReader for the specified input
+stream and loads the sidecar index from index, which is closed afterwards.
+SeekToRecord() uses the index to seek directly to the requested record, and
+SeekToKey() can be used to find records by key.
+*/
+func NewIndexedRecordReader(ctx context.Context, reader filesystem.ReadCloser,
+	index filesystem.ReadCloser, opts ...Option) (*RecordReader, error) {
+	var r = NewRecordReader(reader, opts...)
+	var err error
+
+	if r.index, err = ReadIndex(ctx, index); err != nil {
+		index.Close(ctx)
+		return nil, err
+	}
+	if err = index.Close(ctx); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+/*
+SetIndex makes the reader use the specified index for seeking. The index is
+discarded by Reset().
+*/
+func (r *RecordReader) SetIndex(index *RecordIndex) {
+	r.index = index
+}
+
+/*
+SeekToKey positions the reader at the first record written with the
+specified key, using the sidecar index set for the reader. The input stream
+must implement Seeker. If there is no index or the key is not in it,
+ErrKeyNotFound is returned.
+*/
+func (r *RecordReader) SeekToKey(ctx context.Context, key []byte) error {
+	var pos Position
+	var ok bool
+
+	if r.closed {
+		return recordError(r.position, ErrClosed)
+	}
+	if r.index == nil {
+		return recordError(r.position, ErrKeyNotFound)
+	}
+	if pos, ok = r.index.Lookup(key); !ok {
+		return recordError(r.position, ErrKeyNotFound)
+	}
+
+	return recordError(r.position, r.seekTo(ctx, pos))
+}
+
+/*
+nearest returns the position of the record with the specified index, or of
+the last indexed record before it, if there is an index at all.
+*/
+func (i *RecordIndex) nearest(n int64) (Position, bool) {
+	var slot int64
+
+	if i == nil || len(i.offsets) == 0 || i.interval <= 0 || n < 0 {
+		return Position{}, false
+	}
+
+	if slot = n / i.interval; slot >= int64(len(i.offsets)) {
+		slot = int64(len(i.offsets)) - 1
+	}
+	return Position{Offset: i.offsets[slot], Index: slot * i.interval}, true
+}
+
+/*
+prepareIndex makes sure the reader has an index which can be used to seek to
+every record, loading it from the footer or building it if required.
+*/
+func (r *RecordReader) prepareIndex(ctx context.Context) error {
+	var index *RecordIndex
+	var err error
+
+	if r.closed {
+		return ErrClosed
+	}
+	if _, ok := r.wrappedReader.(Seeker); !ok {
+		return ErrNotSeekable
+	}
+	if r.index != nil {
+		return nil
+	}
+
+	if r.options.footer {
+		if index, err = r.loadFooter(ctx); err != nil {
+			return err
+		}
+		if index != nil && (len(index.offsets) > 0 || index.count == 0) {
+			r.index = index
+			return nil
+		}
+	}
+
+	if err = r.seekTo(ctx, Position{}); err != nil {
+		return err
+	}
+
+	index = &RecordIndex{interval: 1}
+	for {
+		var offset = r.position.Offset
+
+		if _, err = r.Skip(ctx, 1); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		index.offsets = append(index.offsets, offset)
+	}
+	index.count = int64(len(index.offsets))
+
+	r.index = index
+	return nil
+}
@@ -0,0 +1,120 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Write records along with a sidecar index and use it to seek by index and by
+key.
+*/
+func TestIndexedRecordWriter(t *testing.T) {
+	var ctx = context.Background()
+	var buf, idx bytes.Buffer
+	var writer = NewIndexedRecordWriter(NewIOWriteCloser(&buf),
+		NewIOWriteCloser(&idx), WithChecksum())
+	var reader *RecordReader
+	var index *RecordIndex
+	var pos Position
+	var rec []byte
+	var ok bool
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		if _, err = writer.WriteKeyed(ctx, []byte("key"+rec),
+			[]byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if _, err = writer.Write(ctx, []byte("Five")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	reader, err = NewIndexedRecordReader(ctx,
+		NewIOReadCloser(bytes.NewReader(buf.Bytes())),
+		NewIOReadCloser(bytes.NewReader(idx.Bytes())), WithChecksum())
+	if err != nil {
+		t.Fatal("Error reading index: ", err)
+	}
+
+	for _, step := range []struct {
+		index    int64
+		expected string
+	}{{4, "Five"}, {1, "Two"}, {3, "Four"}} {
+		if err = reader.SeekToRecord(ctx, step.index); err != nil {
+			t.Error("Error seeking to record ", step.index, ": ", err)
+		}
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != step.expected {
+			t.Errorf("Unexpected data: got %q, expected %s", rec, step.expected)
+		}
+	}
+
+	if err = reader.SeekToKey(ctx, []byte("keyThree")); err != nil {
+		t.Error("Error seeking to key: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); string(rec) != "Three" {
+		t.Errorf("Unexpected data: got %q, expected Three (%v)", rec, err)
+	}
+	if err = reader.SeekToKey(ctx, []byte("keyFive")); !errors.Is(
+		err, ErrKeyNotFound) {
+		t.Error("Expected ErrKeyNotFound, got: ", err)
+	}
+
+	if index, err = ReadIndex(ctx,
+		NewIOReadCloser(bytes.NewReader(idx.Bytes()))); err != nil {
+		t.Fatal("Error reading index: ", err)
+	}
+	if index.Len() != 5 {
+		t.Error("Unexpected number of index entries: ", index.Len())
+	}
+	if pos, ok = index.Lookup([]byte("keyTwo")); !ok || pos.Index != 1 ||
+		pos.Offset != 11 {
+		t.Error("Unexpected position for keyTwo: ", pos)
+	}
+}
+
+/*
+If writing a record fails after its index entry has been written, the error
+must say that the index is ahead of the record stream, and further writes
+must fail rather than let the index and the records drift apart.
+*/
+func TestIndexedRecordWriterFailure(t *testing.T) {
+	var ctx = context.Background()
+	var out = &failingWriter{limit: 10}
+	var idx bytes.Buffer
+	var writer = NewIndexedRecordWriter(NewIOWriteCloser(out),
+		NewIOWriteCloser(&idx))
+	var partial *PartialWriteError
+	var index *RecordIndex
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("One")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	_, err = writer.Write(ctx, []byte("Two"))
+	if !errors.As(err, &partial) || !partial.IndexAhead ||
+		partial.Written != 3 {
+		t.Error("Expected partial write with the index ahead, got: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("Three")); !errors.Is(
+		err, ErrIncompleteRecord) {
+		t.Error("Expected ErrIncompleteRecord, got: ", err)
+	}
+
+	if index, err = ReadIndex(ctx,
+		NewIOReadCloser(bytes.NewReader(idx.Bytes()))); err != nil {
+		t.Fatal("Error reading index: ", err)
+	}
+	if index.Len() != 2 {
+		t.Error("Unexpected number of index entries: ", index.Len())
+	}
+}
@@ -0,0 +1,117 @@
+//go:build go1.23
+
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"io"
+	"iter"
+)
+
+/*
+Records returns an iterator over the remaining records of the input stream,
+for use with range loops:
+
+	for rec, err := range reader.Records(ctx) {
+		if err != nil {
+			return err
+		}
+		...
+	}
+
+Iteration ends at the end of the stream; io.EOF is never yielded. If reading
+a record fails, the error is yielded once and iteration ends.
+*/
+func (r *RecordReader) Records(ctx context.Context) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		var rec []byte
+		var err error
+
+		for {
+			if rec, err = r.ReadRecord(ctx); err == io.EOF {
+				return
+			} else if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+/*
+Messages returns an iterator over the remaining records of the input stream,
+parsed as protocol buffers. factory is called to allocate a new message for
+every record, so messages may be retained after the loop moves on.
+
+Errors are handled as described for Records(). Parse errors are yielded
+without ending the iteration, since the reader has already moved on to the
+next record.
+*/
+func (r *RecordReader) Messages(ctx context.Context,
+	factory func() Message) iter.Seq2[Message, error] {
+	return func(yield func(Message, error) bool) {
+		var pb Message
+		var rec []byte
+		var err error
+
+		for {
+			if rec, err = r.ReadRecord(ctx); err == io.EOF {
+				return
+			} else if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			pb = factory()
+			if err = r.options.unmarshalMessage(rec, pb); err != nil {
+				pb = nil
+			}
+
+			if !yield(pb, err) {
+				return
+			}
+		}
+	}
+}
+
+/*
+ReverseRecords returns an iterator over all records of the input stream from
+the last to the first, e.g. for showing the most recent entries of a log
+first. The input stream must implement Seeker.
+
+The records are located using the index set for the reader, or the sparse
+index from the footer if WithSparseIndex() was used. Otherwise, the stream is
+scanned once to build an index, which is kept for later seeks. After the
+iteration, the reader is positioned after the last record yielded.
+*/
+func (r *RecordReader) ReverseRecords(
+	ctx context.Context) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		var rec []byte
+		var n int64
+		var err error
+
+		if err = r.prepareIndex(ctx); err != nil {
+			yield(nil, recordError(r.position, err))
+			return
+		}
+
+		for n = r.index.Len() - 1; n >= 0; n-- {
+			if err = r.SeekToRecord(ctx, n); err == nil {
+				rec, err = r.ReadRecord(ctx)
+			}
+			if err != nil {
+				yield(nil, noEOF(err))
+				return
+			}
+
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
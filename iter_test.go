@@ -0,0 +1,97 @@
+//go:build go1.23
+
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"strings"
+	"testing"
+)
+
+/*
+Iterate over records and messages using range loops, including breaking out
+of the loop early.
+*/
+func TestIterators(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var messages []string
+	var count int
+	var err error
+
+	for _, msg := range []string{"One", "Two", "Three", "Four"} {
+		if err = writer.WriteMessage(ctx, &MessageForTest{Message: msg}); err != nil {
+			t.Error("Error writing message: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(&buf)
+	for rec, err := range reader.Records(ctx) {
+		if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		if len(rec) == 0 {
+			t.Error("Unexpected empty record")
+		}
+		if count++; count == 2 {
+			break
+		}
+	}
+
+	for pb, err := range reader.Messages(ctx, func() Message {
+		return &MessageForTest{}
+	}) {
+		if err != nil {
+			t.Fatal("Error reading message: ", err)
+		}
+		messages = append(messages, pb.(*MessageForTest).Message)
+	}
+
+	if len(messages) != 2 || messages[0] != "Three" || messages[1] != "Four" {
+		t.Error("Unexpected messages: ", messages)
+	}
+}
+
+/*
+Iterate over records in reverse order, with and without a sparse index in
+the footer.
+*/
+func TestReverseRecords(t *testing.T) {
+	var ctx = context.Background()
+
+	for _, opts := range [][]Option{
+		{WithChecksum()},
+		{WithSparseIndex(2)},
+	} {
+		var buf bytes.Buffer
+		var writer = NewIORecordWriter(&buf, opts...)
+		var reader *RecordReader
+		var records []string
+		var err error
+
+		for _, rec := range []string{"One", "Two", "Three", "Four", "Five"} {
+			if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+				t.Error("Error writing record: ", err)
+			}
+		}
+		if err = writer.Close(ctx); err != nil {
+			t.Error("Error closing writer: ", err)
+		}
+
+		reader = NewIORecordReader(bytes.NewReader(buf.Bytes()), opts...)
+		for rec, err := range reader.ReverseRecords(ctx) {
+			if err != nil {
+				t.Error("Error reading record: ", err)
+				break
+			}
+			records = append(records, string(rec))
+		}
+
+		if strings.Join(records, ",") != "Five,Four,Three,Two,One" {
+			t.Error("Unexpected records: ", records)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package recordio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+JSONLEncoding determines how records are mapped to lines of a JSON Lines
+file.
+*/
+type JSONLEncoding int
+
+const (
+	/*
+		JSONLDocuments stores every record as a JSON document on its own
+		line. Records must contain valid JSON; they are compacted to fit on
+		a single line.
+	*/
+	JSONLDocuments JSONLEncoding = iota
+
+	/*
+		JSONLBase64 stores every record as a JSON string holding the base64
+		encoded record data. This allows arbitrary binary records to be
+		converted to JSON Lines and back without losing data.
+	*/
+	JSONLBase64
+)
+
+/*
+JSONLWriter writes records to a JSON Lines file, one record per line.
+Like RecordWriter, JSONLWriters are not thread safe.
+*/
+type JSONLWriter struct {
+	wrappedWriter filesystem.WriteCloser
+	encoding      JSONLEncoding
+}
+
+/*
+NewJSONLWriter creates a new JSONLWriter wrapped around the specified output
+stream, mapping records to lines as specified by encoding. No actions are
+performed at the time.
+*/
+func NewJSONLWriter(writer filesystem.WriteCloser,
+	encoding JSONLEncoding) *JSONLWriter {
+	return &JSONLWriter{
+		wrappedWriter: writer,
+		encoding:      encoding,
+	}
+}
+
+/*
+Write writes the specified record as a new line to the output stream. With
+JSONLDocuments, an error is returned if the record is not valid JSON.
+*/
+func (w *JSONLWriter) Write(ctx context.Context, rec []byte) (int, error) {
+	var line bytes.Buffer
+	var err error
+
+	if w.encoding == JSONLBase64 {
+		var encoded []byte
+
+		encoded, err = json.Marshal(base64.StdEncoding.EncodeToString(rec))
+		if err != nil {
+			return 0, err
+		}
+		line.Write(encoded)
+	} else if err = json.Compact(&line, rec); err != nil {
+		return 0, err
+	}
+
+	line.WriteByte('\n')
+
+	if err = writeFull(ctx, w.wrappedWriter, line.Bytes()); err != nil {
+		return 0, err
+	}
+	return line.Len(), nil
+}
+
+/*
+WriteValue encodes the specified value as JSON and writes it as a new line to
+the output stream. This is only supported with JSONLDocuments.
+*/
+func (w *JSONLWriter) WriteValue(ctx context.Context, v interface{}) error {
+	var rec []byte
+	var err error
+
+	if w.encoding != JSONLDocuments {
+		return errors.New("WriteValue requires JSONLDocuments encoding")
+	}
+
+	if rec, err = json.Marshal(v); err != nil {
+		return err
+	}
+
+	_, err = w.Write(ctx, rec)
+	return err
+}
+
+/*
+Close just delegates to the close function of the underlying writer.
+*/
+func (w *JSONLWriter) Close(ctx context.Context) error {
+	return w.wrappedWriter.Close(ctx)
+}
+
+/*
+JSONLReader reads records from a JSON Lines file, returning one record per
+line. Empty lines are skipped.
+*/
+type JSONLReader struct {
+	wrappedReader filesystem.ReadCloser
+	encoding      JSONLEncoding
+	buf           []byte
+	eof           bool
+}
+
+/*
+NewJSONLReader creates a new JSONLReader wrapped around the specified input
+stream, mapping lines to records as specified by encoding. No actions are
+performed at the time.
+*/
+func NewJSONLReader(reader filesystem.ReadCloser,
+	encoding JSONLEncoding) *JSONLReader {
+	return &JSONLReader{
+		wrappedReader: reader,
+		encoding:      encoding,
+	}
+}
+
+/*
+ReadRecord reads the next line from the input stream and returns the record
+it encodes. io.EOF is returned at the end of the stream.
+*/
+func (r *JSONLReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var line []byte
+	var err error
+
+	for len(line) == 0 {
+		if line, err = r.readLine(ctx); err != nil {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+	}
+
+	if r.encoding == JSONLBase64 {
+		var encoded string
+
+		if err = json.Unmarshal(line, &encoded); err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	}
+
+	if !json.Valid(line) {
+		return nil, errors.New("Invalid JSON in line")
+	}
+	return line, nil
+}
+
+/*
+ReadValue reads the next line from the input stream and decodes it as JSON
+into v. This is only supported with JSONLDocuments.
+*/
+func (r *JSONLReader) ReadValue(ctx context.Context, v interface{}) error {
+	var rec []byte
+	var err error
+
+	if r.encoding != JSONLDocuments {
+		return errors.New("ReadValue requires JSONLDocuments encoding")
+	}
+
+	if rec, err = r.ReadRecord(ctx); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(rec, v)
+}
+
+/*
+Close just delegates to the close function of the underlying reader.
+*/
+func (r *JSONLReader) Close(ctx context.Context) error {
+	return r.wrappedReader.Close(ctx)
+}
+
+/*
+readLine returns the next line from the input stream without the trailing
+newline. The last line of the stream does not need to be terminated.
+*/
+func (r *JSONLReader) readLine(ctx context.Context) ([]byte, error) {
+	var chunk = make([]byte, 4096)
+	var line []byte
+	var n, i int
+	var err error
+
+	for {
+		if i = bytes.IndexByte(r.buf, '\n'); i >= 0 {
+			line = r.buf[:i]
+			r.buf = r.buf[i+1:]
+			return line, nil
+		}
+
+		if r.eof {
+			if len(r.buf) == 0 {
+				return nil, io.EOF
+			}
+			line = r.buf
+			r.buf = nil
+			return line, nil
+		}
+
+		n, err = r.wrappedReader.Read(ctx, chunk)
+		r.buf = append(r.buf, chunk[:n]...)
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+RecordsToJSONL copies all records from src to dst until the end of src is
+reached, and returns the number of records copied.
+*/
+func RecordsToJSONL(ctx context.Context, dst *JSONLWriter,
+	src *RecordReader) (int64, error) {
+	var count int64
+	var rec []byte
+	var err error
+
+	for {
+		if rec, err = src.ReadRecord(ctx); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+
+		if _, err = dst.Write(ctx, rec); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+/*
+JSONLToRecords copies all lines from src to dst as records until the end of
+src is reached, and returns the number of records copied.
+*/
+func JSONLToRecords(ctx context.Context, dst *RecordWriter,
+	src *JSONLReader) (int64, error) {
+	var count int64
+	var rec []byte
+	var err error
+
+	for {
+		if rec, err = src.ReadRecord(ctx); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+
+		if _, err = dst.Write(ctx, rec); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
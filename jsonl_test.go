@@ -0,0 +1,137 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Convert a record file containing JSON documents to JSON Lines and back.
+*/
+func TestJSONLDocumentsConversion(t *testing.T) {
+	var ctx = context.Background()
+	var recbuf = internal.NewAnonymousFile()
+	var jsonbuf = internal.NewAnonymousFile()
+	var outbuf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(recbuf)
+	var jsonWriter = NewJSONLWriter(jsonbuf, JSONLDocuments)
+	var reader *RecordReader
+	var rec []byte
+	var count int64
+	var err error
+
+	writer.Write(ctx, []byte("{\"greeting\": \"Hello\"}"))
+	writer.Write(ctx, []byte("[1,\n 2]"))
+	writer.Close(ctx)
+
+	count, err = RecordsToJSONL(ctx, jsonWriter, NewRecordReader(recbuf))
+	if err != nil {
+		t.Error("Error converting to JSONL: ", err)
+	}
+	if count != 2 {
+		t.Error("Expected 2 records to be converted, got ", count)
+	}
+
+	jsonWriter.Close(ctx)
+	rec = make([]byte, jsonbuf.Len())
+	jsonbuf.Read(ctx, rec)
+	jsonbuf.Close(ctx)
+	if string(rec) != "{\"greeting\":\"Hello\"}\n[1,2]\n" {
+		t.Errorf("Unexpected JSONL output: %q", rec)
+	}
+
+	writer = NewRecordWriter(outbuf)
+	count, err = JSONLToRecords(ctx, writer, NewJSONLReader(jsonbuf, JSONLDocuments))
+	if err != nil {
+		t.Error("Error converting from JSONL: ", err)
+	}
+	if count != 2 {
+		t.Error("Expected 2 records to be converted, got ", count)
+	}
+
+	writer.Close(ctx)
+	reader = NewRecordReader(outbuf)
+
+	for _, expected := range []string{"{\"greeting\":\"Hello\"}", "[1,2]"} {
+		rec, err = reader.ReadRecord(ctx)
+		if err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != expected {
+			t.Errorf("Unexpected data: got %q, expected %q", rec, expected)
+		}
+	}
+}
+
+/*
+Binary records must survive a round trip through JSONLBase64.
+*/
+func TestJSONLBase64(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewJSONLWriter(buf, JSONLBase64)
+	var reader *JSONLReader
+	var data = []byte{0, 1, '\n', 255}
+	var rec []byte
+	var err error
+
+	if _, err = writer.Write(ctx, data); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	reader = NewJSONLReader(buf, JSONLBase64)
+	rec, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != string(data) {
+		t.Error("Unexpected data: got ", rec, ", expected ", data)
+	}
+
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+}
+
+/*
+Test encoding and decoding values, including an unterminated last line.
+*/
+func TestJSONLValues(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewJSONLWriter(buf, JSONLDocuments)
+	var reader *JSONLReader
+	var value map[string]int
+	var err error
+
+	if err = writer.WriteValue(ctx, map[string]int{"a": 1}); err != nil {
+		t.Error("Error writing value: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("not json")); err == nil {
+		t.Error("Expected error writing invalid JSON")
+	}
+	buf.Write(ctx, []byte("\n{\"a\": 2}"))
+
+	// Reset position.
+	writer.Close(ctx)
+
+	reader = NewJSONLReader(buf, JSONLDocuments)
+	for _, expected := range []int{1, 2} {
+		value = nil
+		if err = reader.ReadValue(ctx, &value); err != nil {
+			t.Error("Error reading value: ", err)
+		}
+		if value["a"] != expected {
+			t.Error("Unexpected value: got ", value, ", expected ", expected)
+		}
+	}
+
+	if err = reader.ReadValue(ctx, &value); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+}
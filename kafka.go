@@ -0,0 +1,363 @@
+package recordio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/net/context"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	kafkaBatchHeaderSize  = 61
+	kafkaMagic            = 2
+	kafkaCompressionMask  = 0x07
+	kafkaCompressionNone  = 0
+	kafkaCompressionGzip  = 1
+	kafkaControlBatchFlag = 0x20
+)
+
+/*
+KafkaHeader is a single header attached to a Kafka message.
+*/
+type KafkaHeader struct {
+	Key   string
+	Value []byte
+}
+
+/*
+KafkaMessage is a single message from a Kafka record batch. A nil Key or
+Value corresponds to a null key or value in Kafka.
+*/
+type KafkaMessage struct {
+	Offset    int64
+	Timestamp time.Time
+	Key       []byte
+	Value     []byte
+	Headers   []KafkaHeader
+}
+
+/*
+ImportKafkaRecordBatch decodes a record batch in the Kafka wire format (magic
+version 2, as returned by fetch requests and stored in log segments) and
+writes every message contained in it to the RecordWriter as a separate
+record. Uncompressed and gzip compressed batches are supported; control
+batches are skipped. The number of records written is returned.
+
+Each record holds a message in the encoding Kafka uses for records within a
+batch, except that the offset and timestamp deltas are relative to zero
+rather than to the batch, so every record is self-contained.
+*/
+func ImportKafkaRecordBatch(ctx context.Context, w *RecordWriter,
+	batch []byte) (int, error) {
+	var msgs []KafkaMessage
+	var written int
+	var err error
+
+	if msgs, err = DecodeKafkaRecordBatch(batch); err != nil {
+		return 0, err
+	}
+
+	for _, msg := range msgs {
+		if _, err = w.Write(ctx, encodeKafkaRecord(msg, 0, 0)); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	return written, nil
+}
+
+/*
+ExportKafkaRecordBatch reads up to max messages written by
+ImportKafkaRecordBatch() from the RecordReader and encodes them as an
+uncompressed Kafka record batch. io.EOF is only returned if no messages
+could be read at all.
+*/
+func ExportKafkaRecordBatch(ctx context.Context, r *RecordReader,
+	max int) ([]byte, error) {
+	var msgs []KafkaMessage
+	var msg KafkaMessage
+	var rec []byte
+	var err error
+
+	for len(msgs) < max {
+		if rec, err = r.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if msg, err = decodeKafkaRecord(rec, 0, 0); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if len(msgs) == 0 {
+		return nil, io.EOF
+	}
+
+	return EncodeKafkaRecordBatch(msgs), nil
+}
+
+/*
+DecodeKafkaRecordBatch decodes all messages from a Kafka record batch. The
+CRC of the batch is verified.
+*/
+func DecodeKafkaRecordBatch(batch []byte) ([]KafkaMessage, error) {
+	var baseOffset, baseTimestamp int64
+	var attributes uint16
+	var count int32
+	var records []byte
+	var msgs []KafkaMessage
+	var msg KafkaMessage
+	var rec []byte
+	var length int64
+	var n int
+	var err error
+
+	if len(batch) < kafkaBatchHeaderSize {
+		return nil, errors.New("Truncated Kafka record batch")
+	}
+	if int(binary.BigEndian.Uint32(batch[8:12])) != len(batch)-12 {
+		return nil, errors.New("Kafka record batch length mismatch")
+	}
+	if batch[16] != kafkaMagic {
+		return nil, errors.New("Unsupported Kafka record batch version")
+	}
+	if crc32.Checksum(batch[21:], crc32cTable) !=
+		binary.BigEndian.Uint32(batch[17:21]) {
+		return nil, errors.New("Kafka record batch CRC mismatch")
+	}
+
+	baseOffset = int64(binary.BigEndian.Uint64(batch[0:8]))
+	attributes = binary.BigEndian.Uint16(batch[21:23])
+	baseTimestamp = int64(binary.BigEndian.Uint64(batch[27:35]))
+	count = int32(binary.BigEndian.Uint32(batch[57:61]))
+	records = batch[kafkaBatchHeaderSize:]
+
+	if attributes&kafkaControlBatchFlag != 0 {
+		return nil, nil
+	}
+
+	switch attributes & kafkaCompressionMask {
+	case kafkaCompressionNone:
+	case kafkaCompressionGzip:
+		var gr *gzip.Reader
+
+		if gr, err = gzip.NewReader(bytes.NewReader(records)); err != nil {
+			return nil, err
+		}
+		records, err = ioutil.ReadAll(gr)
+		gr.Close()
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Unsupported Kafka compression codec")
+	}
+
+	if count < 0 {
+		return nil, errors.New("Invalid Kafka record count")
+	}
+
+	// Every record takes at least one byte, so the untrusted count is only
+	// trusted as far as the records can actually hold that many.
+	if int64(count) > int64(len(records)) {
+		msgs = make([]KafkaMessage, 0, len(records))
+	} else {
+		msgs = make([]KafkaMessage, 0, count)
+	}
+	for ; count > 0; count-- {
+		length, n = binary.Varint(records)
+		if n <= 0 || length < 0 || length > int64(len(records)-n) {
+			return nil, errors.New("Invalid Kafka record length")
+		}
+		rec = records[n : n+int(length)]
+		records = records[n+int(length):]
+
+		msg, err = decodeKafkaRecord(rec, baseOffset, baseTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+/*
+EncodeKafkaRecordBatch encodes the specified messages as an uncompressed
+Kafka record batch. The offset and timestamp of the batch are taken from the
+first message, so messages should be ordered by offset.
+*/
+func EncodeKafkaRecordBatch(msgs []KafkaMessage) []byte {
+	var batch = make([]byte, kafkaBatchHeaderSize)
+	var baseOffset, baseTimestamp, maxTimestamp, ts int64
+	var tmp [binary.MaxVarintLen64]byte
+	var rec []byte
+
+	if len(msgs) > 0 {
+		baseOffset = msgs[0].Offset
+		baseTimestamp = kafkaMillis(msgs[0].Timestamp)
+	}
+	maxTimestamp = baseTimestamp
+
+	for _, msg := range msgs {
+		if ts = kafkaMillis(msg.Timestamp); ts > maxTimestamp {
+			maxTimestamp = ts
+		}
+		rec = encodeKafkaRecord(msg, baseOffset, baseTimestamp)
+		batch = append(batch, tmp[:binary.PutVarint(tmp[:], int64(len(rec)))]...)
+		batch = append(batch, rec...)
+	}
+
+	binary.BigEndian.PutUint64(batch[0:8], uint64(baseOffset))
+	binary.BigEndian.PutUint32(batch[8:12], uint32(len(batch)-12))
+	// Partition leader epoch, producer ID and epoch and the base sequence
+	// are unknown and thus set to -1.
+	binary.BigEndian.PutUint32(batch[12:16], 0xffffffff)
+	batch[16] = kafkaMagic
+	binary.BigEndian.PutUint16(batch[21:23], 0)
+	if len(msgs) > 0 {
+		binary.BigEndian.PutUint32(batch[23:27],
+			uint32(msgs[len(msgs)-1].Offset-baseOffset))
+	}
+	binary.BigEndian.PutUint64(batch[27:35], uint64(baseTimestamp))
+	binary.BigEndian.PutUint64(batch[35:43], uint64(maxTimestamp))
+	binary.BigEndian.PutUint64(batch[43:51], 0xffffffffffffffff)
+	binary.BigEndian.PutUint16(batch[51:53], 0xffff)
+	binary.BigEndian.PutUint32(batch[53:57], 0xffffffff)
+	binary.BigEndian.PutUint32(batch[57:61], uint32(len(msgs)))
+	binary.BigEndian.PutUint32(batch[17:21],
+		crc32.Checksum(batch[21:], crc32cTable))
+
+	return batch
+}
+
+/*
+encodeKafkaRecord encodes a message in the format of a Kafka record within a
+batch, without the leading length.
+*/
+func encodeKafkaRecord(msg KafkaMessage, baseOffset,
+	baseTimestamp int64) []byte {
+	var rec = []byte{0}
+
+	rec = appendKafkaVarint(rec, kafkaMillis(msg.Timestamp)-baseTimestamp)
+	rec = appendKafkaVarint(rec, msg.Offset-baseOffset)
+	rec = appendKafkaBytes(rec, msg.Key)
+	rec = appendKafkaBytes(rec, msg.Value)
+	rec = appendKafkaVarint(rec, int64(len(msg.Headers)))
+	for _, header := range msg.Headers {
+		rec = appendKafkaBytes(rec, []byte(header.Key))
+		rec = appendKafkaBytes(rec, header.Value)
+	}
+
+	return rec
+}
+
+/*
+decodeKafkaRecord decodes a Kafka record within a batch, without the leading
+length.
+*/
+func decodeKafkaRecord(rec []byte, baseOffset, baseTimestamp int64) (
+	KafkaMessage, error) {
+	var msg KafkaMessage
+	var d = kafkaDecoder{data: rec}
+	var numHeaders int64
+	var key []byte
+
+	if len(rec) < 1 {
+		return msg, errors.New("Truncated Kafka record")
+	}
+
+	// Skip the unused attributes.
+	d.data = d.data[1:]
+
+	msg.Timestamp = time.Unix(0,
+		(baseTimestamp+d.varint())*int64(time.Millisecond))
+	msg.Offset = baseOffset + d.varint()
+	msg.Key = d.bytes()
+	msg.Value = d.bytes()
+
+	numHeaders = d.varint()
+	for ; numHeaders > 0 && d.err == nil; numHeaders-- {
+		key = d.bytes()
+		msg.Headers = append(msg.Headers, KafkaHeader{
+			Key:   string(key),
+			Value: d.bytes(),
+		})
+	}
+
+	if d.err == nil && len(d.data) > 0 {
+		d.err = errors.New("Trailing data in Kafka record")
+	}
+
+	return msg, d.err
+}
+
+/*
+kafkaDecoder decodes the variable length fields of a Kafka record, keeping
+track of the first error encountered.
+*/
+type kafkaDecoder struct {
+	data []byte
+	err  error
+}
+
+func (d *kafkaDecoder) varint() int64 {
+	var v int64
+	var n int
+
+	if d.err != nil {
+		return 0
+	}
+
+	if v, n = binary.Varint(d.data); n <= 0 {
+		d.err = errors.New("Invalid varint in Kafka record")
+		return 0
+	}
+
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *kafkaDecoder) bytes() []byte {
+	var length = d.varint()
+	var v []byte
+
+	if d.err != nil || length < 0 {
+		return nil
+	}
+
+	if length > int64(len(d.data)) {
+		d.err = errors.New("Truncated Kafka record")
+		return nil
+	}
+
+	v = d.data[:length:length]
+	d.data = d.data[length:]
+	return v
+}
+
+func appendKafkaVarint(buf []byte, v int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+
+	return append(buf, tmp[:binary.PutVarint(tmp[:], v)]...)
+}
+
+func appendKafkaBytes(buf []byte, v []byte) []byte {
+	if v == nil {
+		return appendKafkaVarint(buf, -1)
+	}
+	return append(appendKafkaVarint(buf, int64(len(v))), v...)
+}
+
+func kafkaMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
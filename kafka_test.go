@@ -0,0 +1,119 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"hash/crc32"
+	"io"
+	"math"
+	"testing"
+	"time"
+)
+
+/*
+Encode messages as a Kafka record batch, import them into a record file and
+export them again, checking that keys, timestamps and headers survive.
+*/
+func TestKafkaImportExport(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf)
+	var reader *RecordReader
+	var now = time.Unix(1500000000, 123000000)
+	var msgs = []KafkaMessage{
+		{
+			Offset:    42,
+			Timestamp: now,
+			Key:       []byte("key"),
+			Value:     []byte("Hello"),
+			Headers:   []KafkaHeader{{Key: "trace", Value: []byte("abc")}},
+		},
+		{
+			Offset:    43,
+			Timestamp: now.Add(time.Second),
+			Value:     []byte("World"),
+		},
+	}
+	var batch []byte
+	var decoded []KafkaMessage
+	var n int
+	var err error
+
+	n, err = ImportKafkaRecordBatch(ctx, writer, EncodeKafkaRecordBatch(msgs))
+	if err != nil {
+		t.Error("Error importing record batch: ", err)
+	}
+	if n != 2 {
+		t.Error("Expected 2 records to be written, got ", n)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+	reader = NewRecordReader(buf)
+
+	batch, err = ExportKafkaRecordBatch(ctx, reader, 10)
+	if err != nil {
+		t.Fatal("Error exporting record batch: ", err)
+	}
+
+	decoded, err = DecodeKafkaRecordBatch(batch)
+	if err != nil {
+		t.Fatal("Error decoding record batch: ", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatal("Expected 2 messages, got ", len(decoded))
+	}
+
+	if decoded[0].Offset != 42 || decoded[1].Offset != 43 {
+		t.Error("Unexpected offsets: ", decoded[0].Offset, decoded[1].Offset)
+	}
+	if !decoded[0].Timestamp.Equal(now) ||
+		!decoded[1].Timestamp.Equal(now.Add(time.Second)) {
+		t.Error("Unexpected timestamps: ", decoded[0].Timestamp,
+			decoded[1].Timestamp)
+	}
+	if string(decoded[0].Key) != "key" || decoded[1].Key != nil {
+		t.Error("Unexpected keys: ", decoded[0].Key, decoded[1].Key)
+	}
+	if string(decoded[0].Value) != "Hello" || string(decoded[1].Value) != "World" {
+		t.Error("Unexpected values: ", decoded[0].Value, decoded[1].Value)
+	}
+	if len(decoded[0].Headers) != 1 || decoded[0].Headers[0].Key != "trace" ||
+		string(decoded[0].Headers[0].Value) != "abc" {
+		t.Error("Unexpected headers: ", decoded[0].Headers)
+	}
+
+	if _, err = ExportKafkaRecordBatch(ctx, reader, 10); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+}
+
+/*
+Corrupted batches must be rejected by the CRC check.
+*/
+func TestKafkaBatchCRC(t *testing.T) {
+	var batch = EncodeKafkaRecordBatch([]KafkaMessage{{Value: []byte("Hello")}})
+	var err error
+
+	batch[len(batch)-1] ^= 1
+	if _, err = DecodeKafkaRecordBatch(batch); err == nil {
+		t.Error("Expected error decoding corrupted batch")
+	}
+}
+
+/*
+A huge record count in an otherwise valid batch must be rejected without
+allocating memory for that many messages.
+*/
+func TestKafkaBatchHugeCount(t *testing.T) {
+	var batch = EncodeKafkaRecordBatch([]KafkaMessage{{Value: []byte("Hello")}})
+	var err error
+
+	binary.BigEndian.PutUint32(batch[57:61], math.MaxInt32)
+	binary.BigEndian.PutUint32(batch[17:21],
+		crc32.Checksum(batch[21:], crc32cTable))
+	if _, err = DecodeKafkaRecordBatch(batch); err == nil {
+		t.Error("Expected error decoding batch with a huge record count")
+	}
+}
@@ -0,0 +1,102 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+/*
+LazyMessage holds the data of a record containing a protocol buffer, which
+is only parsed when the message is accessed for the first time. Filters
+which look at the raw data, or only at some of the records, thereby avoid
+paying for parsing all of them.
+
+A LazyMessage is not safe for concurrent use.
+*/
+type LazyMessage struct {
+	data        []byte
+	position    Position
+	messageType protoreflect.MessageType
+	options     proto.UnmarshalOptions
+
+	message      proto.Message
+	err          error
+	materialized bool
+}
+
+/*
+ReadLazyMessage reads the next record from the input stream and returns it
+as a LazyMessage of the specified type, without parsing it yet. Since the
+record data is retained, the LazyMessage stays valid after the reader has
+moved on.
+
+Errors reading the record are returned right away; errors parsing it are
+returned once the message is accessed. All warnings from the ReadRecord()
+method apply here as well.
+*/
+func (r *RecordReader) ReadLazyMessage(ctx context.Context,
+	messageType protoreflect.MessageType) (*LazyMessage, error) {
+	var pos = r.position
+	var buf []byte
+	var err error
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return nil, err
+	}
+
+	return &LazyMessage{
+		data:        buf,
+		position:    pos,
+		messageType: messageType,
+		options:     r.options.unmarshalOptions(),
+	}, nil
+}
+
+/*
+Bytes returns the serialized message as read from the stream. The returned
+slice must not be modified.
+*/
+func (m *LazyMessage) Bytes() []byte {
+	return m.data
+}
+
+/*
+Materialize parses the message, unless this has been done already. The
+error from parsing is wrapped into a RecordError for the position of the
+record, and returned again by all further calls.
+*/
+func (m *LazyMessage) Materialize() error {
+	if m.materialized {
+		return m.err
+	}
+
+	m.materialized = true
+	m.message = m.messageType.New().Interface()
+	if m.err = m.options.Unmarshal(m.data, m.message); m.err != nil {
+		m.message = nil
+		m.err = recordError(m.position, m.err)
+	}
+	return m.err
+}
+
+/*
+Message returns the parsed message, parsing it first if it has not been
+accessed before. The same message is returned by all calls, so changes made
+to it are retained.
+*/
+func (m *LazyMessage) Message() (proto.Message, error) {
+	var err error
+
+	if err = m.Materialize(); err != nil {
+		return nil, err
+	}
+	return m.message, nil
+}
+
+/*
+Materialized returns whether the message has been parsed already.
+*/
+func (m *LazyMessage) Materialized() bool {
+	return m.materialized
+}
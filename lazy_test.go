@@ -0,0 +1,86 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+/*
+Lazy messages must only be parsed when accessed, and yield the message
+which was written.
+*/
+func TestReadLazyMessage(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var messageType = (&MessageForTest{}).ProtoReflect().Type()
+	var first, second *LazyMessage
+	var pb proto.Message
+	var err error
+
+	for _, msg := range []string{"One", "Two"} {
+		if err = writer.WriteMessage(ctx, &MessageForTest{Message: msg}); err != nil {
+			t.Error("Cannot serialize message: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(&buf)
+	if first, err = reader.ReadLazyMessage(ctx, messageType); err != nil {
+		t.Error("Error reading lazy message: ", err)
+	}
+	if second, err = reader.ReadLazyMessage(ctx, messageType); err != nil {
+		t.Error("Error reading lazy message: ", err)
+	}
+	if first.Materialized() || second.Materialized() {
+		t.Error("Messages were parsed before being accessed")
+	}
+
+	if pb, err = second.Message(); err != nil {
+		t.Error("Error parsing message: ", err)
+	}
+	if pb.(*MessageForTest).Message != "Two" {
+		t.Errorf("Unexpected message: %v", pb)
+	}
+	if first.Materialized() || !second.Materialized() {
+		t.Error("Unexpected messages parsed")
+	}
+}
+
+/*
+Parse errors must be reported when the message is accessed, along with the
+position of the record.
+*/
+func TestLazyMessageError(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var messageType = (&MessageForTest{}).ProtoReflect().Type()
+	var lazy *LazyMessage
+	var recErr *RecordError
+	var err error
+
+	writer.Write(ctx, []byte("OK"))
+	writer.Write(ctx, []byte{0xff})
+
+	reader = NewIORecordReader(&buf)
+	reader.Skip(ctx, 1)
+	if lazy, err = reader.ReadLazyMessage(ctx, messageType); err != nil {
+		t.Error("Error reading lazy message: ", err)
+	}
+	if string(lazy.Bytes()) != "\xff" {
+		t.Errorf("Unexpected data: %q", lazy.Bytes())
+	}
+
+	if err = lazy.Materialize(); !errors.As(err, &recErr) ||
+		recErr.Index != 1 {
+		t.Error("Expected parse error for record 1, got: ", err)
+	}
+	if _, err = lazy.Message(); err == nil {
+		t.Error("Expected error to be returned again")
+	}
+}
@@ -0,0 +1,222 @@
+package recordio
+
+import (
+	"bytes"
+	"container/heap"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+MergeReader performs a k-way merge of a set of streams whose records are
+sorted, yielding all of their records as one globally sorted stream, e.g.
+to combine the sorted runs of an external sort. Only the next record of
+every stream is held in memory. The order of the records is defined by a
+comparator; records which compare as equal are returned in the order of
+the streams they come from, so the merge is stable.
+
+The records of every stream must already be sorted according to the
+comparator; this is not checked.
+
+As with RecordReader, MergeReaders are not thread safe.
+*/
+type MergeReader struct {
+	readers []*RecordReader
+	heads   mergeHeap
+	started bool
+	closed  bool
+
+	// stream is the number of the stream the last record was read from,
+	// and refill that of the stream whose next record has to be read
+	// before the next record can be returned, or -1.
+	stream int
+	refill int
+}
+
+/*
+NewMergeReader opens the specified streams using the open function and
+wraps them into a MergeReader ordering records using compare, which returns
+a negative number, zero or a positive number if a is less than, equal to or
+greater than b, like bytes.Compare(), which is used if compare is nil. The
+options are used for all of the streams. If opening any of the streams
+fails, the streams opened so far are closed again.
+*/
+func NewMergeReader(ctx context.Context, names []string,
+	open func(context.Context, string) (filesystem.ReadCloser, error),
+	compare func(a, b []byte) int, opts ...Option) (*MergeReader, error) {
+	var readers []*RecordReader
+	var reader filesystem.ReadCloser
+	var err error
+
+	for _, name := range names {
+		if reader, err = open(ctx, name); err != nil {
+			NewMergeReaderFromReaders(readers, compare).Close(ctx)
+			return nil, err
+		}
+		readers = append(readers, NewRecordReader(reader, opts...))
+	}
+
+	return NewMergeReaderFromReaders(readers, compare), nil
+}
+
+/*
+NewMergeReaderFromReaders creates a MergeReader merging the records of the
+specified RecordReaders, ordered by compare as described for
+NewMergeReader().
+*/
+func NewMergeReaderFromReaders(readers []*RecordReader,
+	compare func(a, b []byte) int) *MergeReader {
+	if compare == nil {
+		compare = bytes.Compare
+	}
+
+	return &MergeReader{
+		readers: readers,
+		heads:   mergeHeap{compare: compare},
+		stream:  -1,
+		refill:  -1,
+	}
+}
+
+/*
+ReadRecord returns the smallest of the records not read yet from any of the
+streams. io.EOF is returned once all streams have been read completely.
+Errors reading a stream are returned as soon as its next record is needed,
+i.e. after the previous record of the same stream has been returned.
+*/
+func (r *MergeReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var head mergeHead
+	var err error
+
+	if r.closed {
+		return nil, ErrClosed
+	}
+
+	if !r.started {
+		for i := range r.readers {
+			if err = r.fill(ctx, i); err != nil {
+				return nil, err
+			}
+		}
+		r.started = true
+	} else if r.refill >= 0 {
+		if err = r.fill(ctx, r.refill); err != nil {
+			return nil, err
+		}
+		r.refill = -1
+	}
+
+	if r.heads.Len() == 0 {
+		return nil, io.EOF
+	}
+
+	head = heap.Pop(&r.heads).(mergeHead)
+	r.stream = head.stream
+	r.refill = head.stream
+	return head.rec, nil
+}
+
+/*
+ReadMessage reads the next record as described for ReadRecord() and parses
+it as a protocol buffer of the type passed in.
+*/
+func (r *MergeReader) ReadMessage(ctx context.Context, pb Message) error {
+	var buf []byte
+	var err error
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return err
+	}
+
+	return r.readers[r.stream].options.unmarshalMessage(buf, pb)
+}
+
+/*
+Stream returns the number of the stream the last record was read from,
+counting from 0, or -1 if no record has been read yet.
+*/
+func (r *MergeReader) Stream() int {
+	return r.stream
+}
+
+/*
+Close closes all streams. All streams are closed even if closing one of
+them fails; the first error encountered is returned. Closing a MergeReader
+more than once has no effect.
+*/
+func (r *MergeReader) Close(ctx context.Context) error {
+	var firstErr error
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	for _, reader := range r.readers {
+		if err := reader.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+/*
+fill reads the next record of the specified stream and adds it to the
+heads, unless the stream has ended.
+*/
+func (r *MergeReader) fill(ctx context.Context, stream int) error {
+	var rec, err = r.readers[stream].ReadRecord(ctx)
+
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	heap.Push(&r.heads, mergeHead{rec: rec, stream: stream})
+	return nil
+}
+
+/*
+mergeHead is the next record of a stream being merged.
+*/
+type mergeHead struct {
+	rec    []byte
+	stream int
+}
+
+/*
+mergeHeap is a min-heap of the next records of the streams being merged,
+implementing heap.Interface.
+*/
+type mergeHeap struct {
+	heads   []mergeHead
+	compare func(a, b []byte) int
+}
+
+func (h *mergeHeap) Len() int {
+	return len(h.heads)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+	var c = h.compare(h.heads[i].rec, h.heads[j].rec)
+
+	return c < 0 || c == 0 && h.heads[i].stream < h.heads[j].stream
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+	h.heads[i], h.heads[j] = h.heads[j], h.heads[i]
+}
+
+func (h *mergeHeap) Push(x interface{}) {
+	h.heads = append(h.heads, x.(mergeHead))
+}
+
+func (h *mergeHeap) Pop() interface{} {
+	var head = h.heads[len(h.heads)-1]
+
+	h.heads = h.heads[:len(h.heads)-1]
+	return head
+}
@@ -0,0 +1,116 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+/*
+writeSorted writes the specified records to a new stream.
+*/
+func writeSorted(t *testing.T, recs ...string) []byte {
+	var ctx = context.Background()
+	var out bytes.Buffer
+	var writer = NewIORecordWriter(&out, WithChecksum())
+	var err error
+
+	for _, rec := range recs {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	return out.Bytes()
+}
+
+/*
+The records of sorted streams must be merged into one sorted stream, keeping
+records with equal keys in the order of their streams.
+*/
+func TestMergeReader(t *testing.T) {
+	var ctx = context.Background()
+	var streams = map[string][]byte{
+		"a": writeSorted(t, "apple:a", "cherry:a", "fig:a"),
+		"b": writeSorted(t),
+		"c": writeSorted(t, "banana:c", "cherry:c", "grape:c"),
+		"d": writeSorted(t, "date:d"),
+	}
+	var reader *MergeReader
+	var got []string
+	var rec []byte
+	var err error
+
+	// Compare records by the key before the colon only.
+	reader, err = NewMergeReader(ctx, []string{"a", "b", "c", "d"},
+		func(ctx context.Context, name string) (filesystem.ReadCloser,
+			error) {
+			return NewIOReadCloser(bytes.NewReader(streams[name])), nil
+		}, func(a, b []byte) int {
+			return bytes.Compare(bytes.SplitN(a, []byte(":"), 2)[0],
+				bytes.SplitN(b, []byte(":"), 2)[0])
+		}, WithChecksum())
+	if err != nil {
+		t.Fatal("Error creating merge reader: ", err)
+	}
+
+	for {
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		got = append(got, fmt.Sprint(string(rec), "@", reader.Stream()))
+	}
+
+	if strings.Join(got, " ") != "apple:a@0 banana:c@2 cherry:a@0 "+
+		"cherry:c@2 date:d@3 fig:a@0 grape:c@2" {
+		t.Error("Unexpected records: ", got)
+	}
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+}
+
+/*
+Merging many runs with the default comparator must yield all records in
+sorted order.
+*/
+func TestMergeReaderRuns(t *testing.T) {
+	var ctx = context.Background()
+	var readers []*RecordReader
+	var reader *MergeReader
+	var run, want, got []string
+	var rec []byte
+	var err error
+
+	for i := 0; i < 10; i++ {
+		run = nil
+		for j := 0; j < 20; j++ {
+			run = append(run, fmt.Sprintf("%03d", (j*37+i*11)%1000))
+		}
+		sort.Strings(run)
+		want = append(want, run...)
+		readers = append(readers, NewIORecordReader(bytes.NewReader(
+			writeSorted(t, run...)), WithChecksum()))
+	}
+	sort.Strings(want)
+
+	reader = NewMergeReaderFromReaders(readers, nil)
+	for {
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		got = append(got, string(rec))
+	}
+
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Error("Records not merged in order: ", got)
+	}
+}
@@ -0,0 +1,95 @@
+package recordio
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+/*
+Message is the protocol buffer message type accepted by all methods of this
+package. It is implemented both by messages generated for the current
+google.golang.org/protobuf API and by messages generated for the legacy
+github.com/golang/protobuf API, so existing code using either keeps working.
+Messages are converted to the current API using protoadapt before they are
+marshaled or parsed.
+*/
+type Message = protoadapt.MessageV1
+
+/*
+WithDeterministic makes writers marshal protocol buffers deterministically,
+so that equal messages produce identical records, e.g. with respect to the
+order of map entries. See proto.MarshalOptions for the caveats.
+*/
+func WithDeterministic() Option {
+	return func(o *options) {
+		o.deterministic = true
+	}
+}
+
+/*
+WithDiscardUnknown makes readers drop fields unknown to the message type
+when parsing protocol buffers, rather than keeping them around.
+*/
+func WithDiscardUnknown() Option {
+	return func(o *options) {
+		o.discardUnknown = true
+	}
+}
+
+/*
+vtMarshaler is implemented by messages generated with vtprotobuf, which
+provides marshaling code specialized for the message type.
+*/
+type vtMarshaler interface {
+	MarshalVT() ([]byte, error)
+}
+
+/*
+vtUnmarshaler is implemented by messages generated with vtprotobuf, which
+provides parsing code specialized for the message type.
+*/
+type vtUnmarshaler interface {
+	UnmarshalVT(buf []byte) error
+}
+
+/*
+marshalMessage serializes the message according to the options. Messages
+generated with vtprotobuf are serialized using their MarshalVT() method,
+which avoids the overhead of reflection, unless deterministic output was
+requested.
+*/
+func (o options) marshalMessage(pb Message) ([]byte, error) {
+	var opts = proto.MarshalOptions{
+		Deterministic: o.deterministic,
+	}
+
+	if vt, ok := pb.(vtMarshaler); ok && !o.deterministic {
+		return vt.MarshalVT()
+	}
+
+	return opts.Marshal(protoadapt.MessageV2Of(pb))
+}
+
+/*
+unmarshalMessage parses the record data into the message according to the
+options. Any previous contents of the message are cleared. Messages
+generated with vtprotobuf are parsed using their UnmarshalVT() method,
+unless unknown fields are to be discarded.
+*/
+func (o options) unmarshalMessage(buf []byte, pb Message) error {
+	if vt, ok := pb.(vtUnmarshaler); ok && !o.discardUnknown {
+		pb.Reset()
+		return vt.UnmarshalVT(buf)
+	}
+
+	return o.unmarshalOptions().Unmarshal(buf, protoadapt.MessageV2Of(pb))
+}
+
+/*
+unmarshalOptions returns the protocol buffer options for parsing records.
+*/
+func (o options) unmarshalOptions() proto.UnmarshalOptions {
+	return proto.UnmarshalOptions{
+		DiscardUnknown: o.discardUnknown,
+	}
+}
@@ -0,0 +1,190 @@
+package recordio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+/*
+Messages must round trip with deterministic marshaling and discarding of
+unknown fields enabled.
+*/
+func TestMessageOptions(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithDeterministic())
+	var reader *RecordReader
+	var data MessageForTest
+	var err error
+
+	data.Message = "Test data"
+	if err = writer.WriteMessage(ctx, &data); err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+
+	reader = NewIORecordReader(&buf, WithDiscardUnknown())
+	data.Reset()
+	if err = reader.ReadMessage(ctx, &data); err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+
+	if data.Message != "Test data" {
+		t.Errorf("Expected: Test data, got: %s", data.Message)
+	}
+}
+
+/*
+ReadMessageNew must return a fresh message for every record.
+*/
+func TestReadMessageNew(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var messageType = (&MessageForTest{}).ProtoReflect().Type()
+	var first, second proto.Message
+	var err error
+
+	for _, msg := range []string{"One", "Two"} {
+		if err = writer.WriteMessage(ctx, &MessageForTest{Message: msg}); err != nil {
+			t.Error("Cannot serialize message: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(&buf)
+	if first, err = reader.ReadMessageNew(ctx, messageType); err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+	if second, err = reader.ReadMessageNew(ctx, messageType); err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+
+	if first.(*MessageForTest).Message != "One" ||
+		second.(*MessageForTest).Message != "Two" {
+		t.Errorf("Unexpected messages: %v, %v", first, second)
+	}
+}
+
+/*
+vtMessageForTest imitates a message generated with vtprotobuf, counting the
+calls to its specialized methods.
+*/
+type vtMessageForTest struct {
+	MessageForTest
+	marshaled, unmarshaled int
+}
+
+func (m *vtMessageForTest) MarshalVT() ([]byte, error) {
+	var buf = make([]byte, 1+binary.MaxVarintLen64+len(m.Message))
+	var n int
+
+	m.marshaled++
+	buf[0] = 0x0a
+	n = 1 + binary.PutUvarint(buf[1:], uint64(len(m.Message)))
+	return append(buf[:n], m.Message...), nil
+}
+
+func (m *vtMessageForTest) UnmarshalVT(buf []byte) error {
+	var length uint64
+	var n int
+
+	m.unmarshaled++
+	if len(buf) == 0 {
+		return nil
+	}
+	if buf[0] != 0x0a {
+		return errors.New("Unexpected field")
+	}
+	if length, n = binary.Uvarint(buf[1:]); n <= 0 ||
+		uint64(len(buf)-1-n) != length {
+		return errors.New("Invalid length")
+	}
+	m.Message = string(buf[1+n:])
+	return nil
+}
+
+/*
+Messages generated with vtprotobuf must be marshaled and parsed using their
+specialized methods, except when this would ignore the options.
+*/
+func TestMessageVT(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var data = vtMessageForTest{MessageForTest: MessageForTest{Message: "Fast"}}
+	var plain MessageForTest
+	var err error
+
+	if err = writer.WriteMessage(ctx, &data); err != nil {
+		t.Error("Cannot serialize message: ", err)
+	}
+	if data.marshaled != 1 {
+		t.Error("MarshalVT was not used")
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()))
+	if err = reader.ReadMessage(ctx, &plain); err != nil {
+		t.Error("Unable to read message written by MarshalVT: ", err)
+	}
+	if plain.Message != "Fast" {
+		t.Errorf("Expected: Fast, got: %s", plain.Message)
+	}
+
+	data.Message = "Stale"
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()))
+	if err = reader.ReadMessage(ctx, &data); err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+	if data.unmarshaled != 1 || data.Message != "Fast" {
+		t.Error("Unexpected result of UnmarshalVT: ", data.unmarshaled, " ",
+			data.Message)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithDiscardUnknown())
+	if err = reader.ReadMessage(ctx, &data); err != nil {
+		t.Error("Unable to re-read the message: ", err)
+	}
+	if data.unmarshaled != 1 {
+		t.Error("UnmarshalVT was used despite WithDiscardUnknown()")
+	}
+}
+
+/*
+Compare writing and reading messages using reflection against messages
+generated with vtprotobuf.
+*/
+func BenchmarkMessages(b *testing.B) {
+	var ctx = context.Background()
+	var msg = MessageForTest{Message: "A message of moderate length"}
+	var vt = vtMessageForTest{MessageForTest: msg}
+
+	for _, bench := range []struct {
+		name string
+		pb   Message
+	}{
+		{"proto", &msg},
+		{"vtprotobuf", &vt},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			var buf bytes.Buffer
+			var writer = NewIORecordWriter(&buf)
+			var reader = NewIORecordReader(&buf)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := writer.WriteMessage(ctx, bench.pb); err != nil {
+					b.Fatal("Cannot serialize message: ", err)
+				}
+				if err := reader.ReadMessage(ctx, bench.pb); err != nil {
+					b.Fatal("Unable to re-read the message: ", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+//go:build unix
+
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"os"
+	"syscall"
+)
+
+/*
+MmapReader reads records from a memory mapped file. The record data returned
+by ReadRecord() is not copied; it aliases the mapping, which avoids copying
+large read-mostly data sets into memory piece by piece.
+
+The returned slices are only valid until Close() is called, after which
+accessing them crashes the program, and they must never be modified. Callers
+which need to keep records around longer must copy them. If a codec is used,
+records are decompressed into new buffers, which remain valid.
+
+Like RecordReader, MmapReaders are not thread safe.
+*/
+type MmapReader struct {
+	data   []byte
+	reader *RecordReader
+}
+
+/*
+OpenMmap maps the named file into memory and returns an MmapReader for the
+records in it. The options must match those used for writing the file. The
+file must not be truncated while it is mapped.
+*/
+func OpenMmap(name string, opts ...Option) (*MmapReader, error) {
+	var file *os.File
+	var info os.FileInfo
+	var data []byte
+	var err error
+
+	if file, err = os.Open(name); err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if info, err = file.Stat(); err != nil {
+		return nil, err
+	}
+	if info.Size() > 0 {
+		data, err = syscall.Mmap(int(file.Fd()), 0, int(info.Size()),
+			syscall.PROT_READ, syscall.MAP_SHARED)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &MmapReader{
+		data: data,
+		reader: NewRecordReader(NewIOReadCloser(bytes.NewReader(data)),
+			opts...),
+	}, nil
+}
+
+/*
+ReadRecord returns the next record from the mapped file, without copying it.
+See the type documentation for the lifetime of the returned data.
+*/
+func (m *MmapReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var pos = m.reader.position
+	var rec []byte
+	var err error
+
+	rec, err = m.readRecord(ctx)
+	return rec, recordError(pos, err)
+}
+
+/*
+readRecord implements ReadRecord() without adding the position of the record
+to errors.
+*/
+func (m *MmapReader) readRecord(ctx context.Context) ([]byte, error) {
+	var r = m.reader
+	var length, crc uint32
+	var start, end int64
+	var err error
+
+	if length, crc, err = r.nextHeader(ctx); err != nil {
+		return nil, err
+	}
+	if length > r.options.maxRecordSize {
+		return nil, ErrRecordTooLarge
+	}
+
+	start = r.position.Offset + int64(r.headerLength(length))
+	end = start + int64(length)
+	if end > int64(len(m.data)) {
+		return nil, errShortBody
+	}
+	if err = r.skipBody(ctx, int64(length)); err != nil {
+		return nil, err
+	}
+
+	r.advance(length)
+	return r.decodeBody(m.data[start:end:end], crc)
+}
+
+/*
+ReadMessage reads the next record and parses it as the protocol buffer
+passed in. Parsing copies the data, so the message remains valid after
+Close().
+*/
+func (m *MmapReader) ReadMessage(ctx context.Context, pb Message) error {
+	var pos = m.reader.position
+	var buf []byte
+	var err error
+
+	if buf, err = m.ReadRecord(ctx); err != nil {
+		return err
+	}
+
+	return recordError(pos, m.reader.options.unmarshalMessage(buf, pb))
+}
+
+/*
+SeekToRecord positions the reader at the record with the specified index, as
+described for RecordReader.SeekToRecord().
+*/
+func (m *MmapReader) SeekToRecord(ctx context.Context, n int64) error {
+	return m.reader.SeekToRecord(ctx, n)
+}
+
+/*
+Tell returns the position of the next record to be read, as described for
+RecordReader.Tell().
+*/
+func (m *MmapReader) Tell() Position {
+	return m.reader.Tell()
+}
+
+/*
+Close unmaps the file. All record data returned by the reader becomes
+invalid. Closing an MmapReader more than once has no effect.
+*/
+func (m *MmapReader) Close(ctx context.Context) error {
+	var data = m.data
+
+	if m.reader.closed {
+		return nil
+	}
+
+	m.reader.Close(ctx)
+	m.data = nil
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package recordio
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+/*
+Records read from a memory mapped file must match those written.
+*/
+func TestMmapReader(t *testing.T) {
+	var ctx = context.Background()
+	var name = filepath.Join(t.TempDir(), "records")
+	var file *os.File
+	var writer *RecordWriter
+	var reader *MmapReader
+	var rec []byte
+	var i int
+	var err error
+
+	if file, err = os.Create(name); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	writer = NewIORecordWriter(file, WithChecksum())
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	if reader, err = OpenMmap(name, WithChecksum()); err != nil {
+		t.Fatal("Error mapping file: ", err)
+	}
+	for i = 0; i < 10; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != fmt.Sprint("Record ", i) {
+			t.Errorf("Unexpected data: got %q, expected Record %d", rec, i)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+
+	if err = reader.SeekToRecord(ctx, 3); err != nil {
+		t.Error("Error seeking to record 3: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); string(rec) != "Record 3" {
+		t.Errorf("Unexpected data: got %q, expected Record 3 (%v)", rec, err)
+	}
+
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrClosed) {
+		t.Error("Expected ErrClosed, got: ", err)
+	}
+}
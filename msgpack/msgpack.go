@@ -0,0 +1,30 @@
+/*
+Package msgpack provides a recordio.Encoding which stores values as
+MessagePack, so structs can be written to and read from record files without
+marshaling them to bytes by hand:
+
+	err = writer.WriteEncoded(ctx, msgpack.Encoding, &value)
+	err = reader.ReadEncoded(ctx, msgpack.Encoding, &value)
+*/
+package msgpack
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+/*
+Encoding serializes values using github.com/vmihailenco/msgpack, honoring its
+struct tags.
+*/
+var Encoding recordio.Encoding = msgpackEncoding{}
+
+type msgpackEncoding struct{}
+
+func (msgpackEncoding) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackEncoding) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
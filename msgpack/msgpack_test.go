@@ -0,0 +1,45 @@
+package msgpack
+
+import (
+	"github.com/childoftheuniverse/filesystem-internal"
+	"github.com/childoftheuniverse/recordio"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+type testValue struct {
+	Name  string
+	Count int
+}
+
+/*
+Write structs as MessagePack records and read them back.
+*/
+func TestWriteAndReadEncoded(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = recordio.NewRecordWriter(buf)
+	var reader *recordio.RecordReader
+	var value testValue
+	var err error
+
+	for _, v := range []testValue{{"Hello", 1}, {"World", 2}} {
+		if err = writer.WriteEncoded(ctx, Encoding, &v); err != nil {
+			t.Error("Error writing value: ", err)
+		}
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+	reader = recordio.NewRecordReader(buf)
+
+	for _, expected := range []testValue{{"Hello", 1}, {"World", 2}} {
+		value = testValue{}
+		if err = reader.ReadEncoded(ctx, Encoding, &value); err != nil {
+			t.Error("Error reading value: ", err)
+		}
+		if value != expected {
+			t.Error("Unexpected value: got ", value, ", expected ", expected)
+		}
+	}
+}
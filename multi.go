@@ -0,0 +1,196 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+MultiReader reads a sequence of streams back to back as one logical stream
+of records, like io.MultiReader does for bytes. Every stream is read with
+its own RecordReader, so the end of each stream, including its footer if
+WithFooter() is used, is handled as for a single stream, and records never
+span streams. Streams are opened one at a time, when the previous one has
+been read completely, and closed once they have been read, so that large
+numbers of files can be read without holding all of them open.
+
+As with RecordReader, MultiReaders are not thread safe.
+*/
+type MultiReader struct {
+	// Either names are opened using openStream with the options opts, or
+	// readers are used.
+	names      []string
+	openStream func(context.Context, string) (filesystem.ReadCloser, error)
+	opts       []Option
+	readers    []*RecordReader
+
+	current *RecordReader
+	next    int
+	index   int64
+	closed  bool
+}
+
+/*
+NewMultiReader creates a MultiReader reading the streams with the specified
+names in order, opening them using the open function when they are reached.
+The options are used for all of the streams. No actions are performed at
+the time.
+*/
+func NewMultiReader(names []string,
+	open func(context.Context, string) (filesystem.ReadCloser, error),
+	opts ...Option) *MultiReader {
+	return &MultiReader{
+		names:      names,
+		openStream: open,
+		opts:       opts,
+	}
+}
+
+/*
+NewMultiReaderFromReaders creates a MultiReader reading from the specified
+RecordReaders in order. Each reader is closed once all of its records have
+been read.
+*/
+func NewMultiReaderFromReaders(readers []*RecordReader) *MultiReader {
+	return &MultiReader{
+		readers: readers,
+	}
+}
+
+/*
+streams returns the number of streams to be read.
+*/
+func (r *MultiReader) streams() int {
+	if r.readers != nil {
+		return len(r.readers)
+	}
+	return len(r.names)
+}
+
+/*
+open returns a RecordReader for the stream with the specified number.
+*/
+func (r *MultiReader) open(ctx context.Context, n int) (*RecordReader,
+	error) {
+	var reader filesystem.ReadCloser
+	var err error
+
+	if r.readers != nil {
+		return r.readers[n], nil
+	}
+	if reader, err = r.openStream(ctx, r.names[n]); err != nil {
+		return nil, err
+	}
+	return NewRecordReader(reader, r.opts...), nil
+}
+
+/*
+ReadRecord returns the next record of the current stream, moving on to the
+next stream once the end of the current one has been reached. io.EOF is
+returned after the last record of the last stream.
+*/
+func (r *MultiReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var rec []byte
+	var err error
+
+	if r.closed {
+		return nil, ErrClosed
+	}
+
+	for {
+		if r.current == nil {
+			if r.next >= r.streams() {
+				return nil, io.EOF
+			}
+			if r.current, err = r.open(ctx, r.next); err != nil {
+				return nil, err
+			}
+			r.next++
+		}
+
+		if rec, err = r.current.ReadRecord(ctx); err != io.EOF {
+			if err == nil {
+				r.index++
+			}
+			return rec, err
+		}
+
+		err = r.current.Close(ctx)
+		r.current = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+ReadMessage reads the next record as described for ReadRecord() and parses
+it as a protocol buffer of the type passed in.
+*/
+func (r *MultiReader) ReadMessage(ctx context.Context, pb Message) error {
+	var buf []byte
+	var err error
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return err
+	}
+
+	return r.current.options.unmarshalMessage(buf, pb)
+}
+
+/*
+Stream returns the number of the stream the last record was read from,
+counting from 0, or -1 if no stream has been opened yet.
+*/
+func (r *MultiReader) Stream() int {
+	return r.next - 1
+}
+
+/*
+Tell returns the position of the reader within the current stream, as
+described for RecordReader.Tell(), or the zero Position if no stream is
+open.
+*/
+func (r *MultiReader) Tell() Position {
+	if r.current == nil {
+		return Position{}
+	}
+	return r.current.Tell()
+}
+
+/*
+Index returns the number of records read from all streams so far, which is
+the index of the next record in the logical stream.
+*/
+func (r *MultiReader) Index() int64 {
+	return r.index
+}
+
+/*
+Close closes the current stream, if one is open. Streams which have not
+been reached yet are not opened; readers passed to
+NewMultiReaderFromReaders() which have not been reached yet are closed as
+well. Closing a MultiReader more than once has no effect.
+*/
+func (r *MultiReader) Close(ctx context.Context) error {
+	var firstErr error
+	var err error
+
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if r.current != nil {
+		firstErr = r.current.Close(ctx)
+		r.current = nil
+	}
+	for ; r.next < len(r.readers); r.next++ {
+		if err = r.readers[r.next].Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,83 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Records of several streams with footers, including an empty one, must be
+read back to back, opening only one stream at a time.
+*/
+func TestMultiReader(t *testing.T) {
+	var ctx = context.Background()
+	var streams = map[string][]byte{
+		"a": writeNumbered(t, 3, WithSparseIndex(2)),
+		"b": writeNumbered(t, 0, WithSparseIndex(2)),
+		"c": writeNumbered(t, 2, WithSparseIndex(2)),
+	}
+	var opened []string
+	var reader *MultiReader
+	var got []string
+	var rec []byte
+	var err error
+
+	reader = NewMultiReader([]string{"a", "b", "c"},
+		func(ctx context.Context, name string) (filesystem.ReadCloser,
+			error) {
+			opened = append(opened, name)
+			return NewIOReadCloser(bytes.NewReader(streams[name])), nil
+		}, WithSparseIndex(2))
+
+	if reader.Stream() != -1 || len(opened) != 0 {
+		t.Error("Streams opened before reading: ", opened)
+	}
+
+	for {
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		got = append(got, fmt.Sprint(reader.Stream(), ":", string(rec)))
+		if len(got) == 1 && len(opened) != 1 {
+			t.Error("Unexpected streams opened: ", opened)
+		}
+	}
+
+	if fmt.Sprint(got) != "[0:Rec 0 0:Rec 1 0:Rec 2 2:Rec 0 2:Rec 1]" {
+		t.Error("Unexpected records: ", got)
+	}
+	if reader.Index() != 5 {
+		t.Error("Unexpected number of records read: ", reader.Index())
+	}
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrClosed) {
+		t.Error("Expected ErrClosed after closing, got: ", err)
+	}
+}
+
+/*
+Errors opening a stream must be returned from ReadRecord.
+*/
+func TestMultiReaderOpenError(t *testing.T) {
+	var ctx = context.Background()
+	var failure = errors.New("No such file")
+	var reader = NewMultiReader([]string{"missing"},
+		func(ctx context.Context, name string) (filesystem.ReadCloser,
+			error) {
+			return nil, failure
+		})
+	var err error
+
+	if _, err = reader.ReadRecord(ctx); err != failure {
+		t.Error("Expected open error, got: ", err)
+	}
+}
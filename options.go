@@ -0,0 +1,169 @@
+package recordio
+
+import (
+	"crypto/cipher"
+	"math"
+)
+
+/*
+Option configures optional features of RecordReaders and RecordWriters. The
+same options must be used for reading a stream as were used for writing it,
+since the stream itself does not record which features are enabled.
+
+Options which only affect reading are ignored by RecordWriters, and vice
+versa.
+*/
+type Option func(*options)
+
+/*
+options holds the configuration assembled from all Options passed to a
+reader or writer constructor.
+*/
+type options struct {
+	framing         Framing
+	checksum        bool
+	strictChecksums bool
+	codec           Codec
+	encryption      cipher.AEAD
+	maxRecordSize   uint32
+
+	deterministic  bool
+	discardUnknown bool
+
+	keepUnderlyingOpen bool
+
+	footer         bool
+	footerInterval int
+
+	writeCallback func(RecordInfo)
+	flushNotifier *FlushNotifier
+
+	writeBufferSize int
+	readahead       int
+	alignment       int
+	segmentSize     int64
+
+	bytesPerSecond   float64
+	recordsPerSecond float64
+
+	corruptionPolicy  CorruptionPolicy
+	corruptionHandler func(ByteRange, error)
+	quarantine        *RecordWriter
+	retry             RetryPolicy
+
+	readAllMaxRecords int
+	readAllMaxBytes   int64
+}
+
+/*
+applyOptions returns the configuration resulting from applying all of the
+specified options to the defaults.
+*/
+func applyOptions(opts []Option) options {
+	var o = options{
+		framing:       FixedLengthFraming,
+		maxRecordSize: math.MaxUint32,
+
+		readAllMaxRecords: DefaultReadAllMaxRecords,
+		readAllMaxBytes:   DefaultReadAllMaxBytes,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// Encryption is applied to the output of the codec, so readers and
+	// writers see both as a single codec.
+	if o.encryption != nil {
+		o.codec = encryptingCodec{codec: o.codec, aead: o.encryption}
+	}
+
+	// The largest possible length marks the footer.
+	if o.footer && o.maxRecordSize == footerMarker {
+		o.maxRecordSize--
+	}
+
+	// The next largest length marks padding.
+	if o.alignment > 0 && o.maxRecordSize >= paddingMarker {
+		o.maxRecordSize = paddingMarker - 1
+	}
+
+	return o
+}
+
+/*
+WithFraming sets the way record lengths are encoded in the stream. The
+default is FixedLengthFraming.
+*/
+func WithFraming(framing Framing) Option {
+	return func(o *options) {
+		o.framing = framing
+	}
+}
+
+/*
+WithChecksum adds a CRC-32C checksum of the record data to the header of
+every record. Readers verify the checksum and return ErrChecksumMismatch if
+the data was corrupted. The checksum is computed using the CRC32
+instructions of SSE 4.2 on amd64 and of ARMv8 on arm64 where available, so
+that it adds little overhead to reading and writing records.
+*/
+func WithChecksum() Option {
+	return func(o *options) {
+		o.checksum = true
+	}
+}
+
+/*
+WithCodec compresses the data of every record individually using the
+specified Codec. Record lengths and checksums refer to the compressed data.
+
+Since there are no compressed blocks spanning several records, compressed
+streams remain seekable: Skip(), SeekToRecord() and the indexes never need
+to decompress any records. Combine with WithSparseIndex() to record a seek
+table in the footer.
+*/
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+/*
+WithMaxRecordSize limits the size of the records which can be written or
+read. Readers return ErrRecordTooLarge instead of allocating a buffer for a
+record exceeding the limit, which protects against excessive memory use when
+reading corrupted data. If a codec is used, the limit applies to both the
+compressed and the uncompressed size of the record.
+
+By default, records can be up to 4 GiB in size.
+*/
+func WithMaxRecordSize(size uint32) Option {
+	return func(o *options) {
+		o.maxRecordSize = size
+	}
+}
+
+/*
+WithoutCloseUnderlying makes closing a RecordReader or RecordWriter leave the
+wrapped stream open, so that it can still be used for other purposes
+afterwards, such as appending a custom trailer. Writers flush the wrapped
+stream instead of closing it if it implements Flusher.
+*/
+func WithoutCloseUnderlying() Option {
+	return func(o *options) {
+		o.keepUnderlyingOpen = true
+	}
+}
+
+/*
+WithReadAllLimits sets the maximum number of records and the maximum total
+size of the record data ReadAll() reads before giving up. The defaults are
+DefaultReadAllMaxRecords and DefaultReadAllMaxBytes.
+*/
+func WithReadAllLimits(maxRecords int, maxBytes int64) Option {
+	return func(o *options) {
+		o.readAllMaxRecords = maxRecords
+		o.readAllMaxBytes = maxBytes
+	}
+}
@@ -0,0 +1,210 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Records written with checksums enabled must be read back unmodified, with
+every framing.
+*/
+func TestChecksumRoundTrip(t *testing.T) {
+	var ctx = context.Background()
+	var framing Framing
+	var expected = map[Framing]int{
+		FixedLengthFraming:  13,
+		VarintFraming:       10,
+		LittleEndianFraming: 13,
+	}
+
+	for _, framing = range []Framing{
+		FixedLengthFraming, VarintFraming, LittleEndianFraming} {
+		var buf = internal.NewAnonymousFile()
+		var writer = NewRecordWriter(buf, WithFraming(framing), WithChecksum())
+		var reader *RecordReader
+		var rbuf []byte
+		var l int
+		var err error
+
+		l, err = writer.Write(ctx, []byte("Hello"))
+		if err != nil {
+			t.Error("Error writing record: ", err)
+		}
+		if l != expected[framing] {
+			t.Error("Unexpected write length for framing ", framing, ": ", l)
+		}
+
+		// Reset position.
+		writer.Close(ctx)
+
+		reader = NewRecordReader(buf, WithFraming(framing), WithChecksum())
+		rbuf, err = reader.ReadRecord(ctx)
+		if err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rbuf) != "Hello" {
+			t.Errorf("Unexpected data: got %q, expected Hello", rbuf)
+		}
+	}
+}
+
+/*
+Corrupted record data must be detected when checksums are enabled.
+*/
+func TestChecksumMismatch(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf, WithChecksum())
+	var reader *RecordReader
+	var contents = make([]byte, 13)
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	buf.Read(ctx, contents)
+	buf.Close(ctx)
+
+	contents[10] ^= 0x01
+	buf = internal.NewAnonymousFile()
+	buf.Write(ctx, contents)
+	buf.Close(ctx)
+
+	reader = NewRecordReader(buf, WithChecksum())
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Error("Expected checksum mismatch, got: ", err)
+	}
+}
+
+/*
+Records exceeding the maximum record size must be rejected by both the
+writer and the reader.
+*/
+func TestMaxRecordSize(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf, WithMaxRecordSize(4))
+	var reader *RecordReader
+	var err error
+
+	_, err = writer.Write(ctx, []byte("Hello"))
+	if !errors.Is(err, ErrRecordTooLarge) {
+		t.Error("Expected record too large error, got: ", err)
+	}
+
+	writer = NewRecordWriter(buf)
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	reader = NewRecordReader(buf, WithMaxRecordSize(4))
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrRecordTooLarge) {
+		t.Error("Expected record too large error, got: ", err)
+	}
+}
+
+/*
+Records compressed with DeflateCodec must be stored compressed and
+decompressed transparently when reading.
+*/
+func TestDeflateCodec(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf, WithCodec(DeflateCodec), WithChecksum())
+	var reader *RecordReader
+	var rec = bytes.Repeat([]byte("recordio "), 100)
+	var rbuf []byte
+	var l int
+	var err error
+
+	l, err = writer.Write(ctx, rec)
+	if err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if l >= len(rec) {
+		t.Error("Record was not compressed, wrote ", l, " bytes")
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	reader = NewRecordReader(buf, WithCodec(DeflateCodec), WithChecksum())
+	rbuf, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if !bytes.Equal(rbuf, rec) {
+		t.Error("Decompressed record does not match the original")
+	}
+
+	buf.Close(ctx)
+	reader = NewRecordReader(buf, WithCodec(DeflateCodec), WithChecksum(),
+		WithMaxRecordSize(100))
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrRecordTooLarge) {
+		t.Error("Expected record too large error, got: ", err)
+	}
+}
+
+/*
+closeRecorder records whether it has been closed.
+*/
+type closeRecorder struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+/*
+WithoutCloseUnderlying must leave the wrapped stream open for further use.
+*/
+func TestWithoutCloseUnderlying(t *testing.T) {
+	var ctx = context.Background()
+	var stream closeRecorder
+	var writer = NewIORecordWriter(&stream, WithoutCloseUnderlying())
+	var reader *RecordReader
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if stream.closed {
+		t.Error("Underlying stream was closed")
+	}
+	stream.WriteString("trailer")
+
+	reader = NewIORecordReader(&stream, WithoutCloseUnderlying())
+	if _, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+	if stream.closed || stream.String() != "trailer" {
+		t.Error("Underlying stream was closed or consumed")
+	}
+
+	if err = NewIORecordWriter(&stream).Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if !stream.closed {
+		t.Error("Underlying stream was not closed")
+	}
+}
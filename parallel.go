@@ -0,0 +1,175 @@
+//go:build go1.20
+
+package recordio
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"sync"
+)
+
+/*
+CompletionOrder determines the order in which ForEachRecordParallel()
+completes the records processed in parallel.
+*/
+type CompletionOrder int
+
+const (
+	/*
+		Unordered completes records as soon as they have been processed,
+		which keeps all workers busy even if some records take longer
+		than others.
+	*/
+	Unordered CompletionOrder = iota
+
+	/*
+		Ordered completes records in the order of the stream, holding
+		back the results of records processed early until all records
+		before them have been completed.
+	*/
+	Ordered
+)
+
+/*
+parallelResult is the result of processing a record.
+*/
+type parallelResult[T any] struct {
+	index  int64
+	result T
+	err    error
+}
+
+/*
+ForEachRecordParallel reads the records of the source one after the other,
+and processes them on up to workers goroutines at the same time by calling
+process with the index of the record in the source and its data. complete,
+if it is not nil, is then called with the result, from the goroutine which
+called ForEachRecordParallel, one record at a time, so it can e.g. write
+results without locking. The order of the calls to complete is determined
+by order.
+
+If reading the source, process or complete fails, or the context is
+canceled, no more records are read, the context passed to process is
+canceled, and ForEachRecordParallel returns once the records being
+processed have been finished, without completing them. All errors which
+occurred are returned, joined using errors.Join(); errors of process and
+complete are wrapped into errors naming the index of the record, and errors
+of the canceled context caused by an earlier failure are left out. The
+source is not closed.
+*/
+func ForEachRecordParallel[T any](ctx context.Context, source RecordSource,
+	workers int, order CompletionOrder,
+	process func(ctx context.Context, index int64, rec []byte) (T, error),
+	complete func(index int64, result T) error) error {
+	var wg sync.WaitGroup
+	var cancel context.CancelFunc
+	var futures chan chan parallelResult[T]
+	var done chan parallelResult[T]
+	var slots chan struct{}
+	var results <-chan parallelResult[T]
+	var readErr error
+	var errs []error
+
+	if workers < 1 {
+		workers = 1
+	}
+	futures = make(chan chan parallelResult[T], workers)
+	done = make(chan parallelResult[T], workers)
+	slots = make(chan struct{}, workers)
+
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	// Read records and start a goroutine processing each of them. In
+	// ordered mode, every record gets a channel of its own for its result,
+	// which are queued in the order of the records.
+	go func() {
+		var future chan parallelResult[T]
+		var rec []byte
+		var err error
+
+		defer func() {
+			close(futures)
+			wg.Wait()
+			close(done)
+		}()
+
+		for index := int64(0); ; index++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			if rec, err = source.ReadRecord(ctx); err != nil {
+				if err != io.EOF {
+					readErr = err
+				}
+				return
+			}
+
+			future = done
+			if order == Ordered {
+				future = make(chan parallelResult[T], 1)
+				futures <- future
+			}
+
+			wg.Add(1)
+			go func(index int64, rec []byte,
+				future chan<- parallelResult[T]) {
+				var result = parallelResult[T]{index: index}
+
+				defer wg.Done()
+
+				result.result, result.err = process(ctx, index, rec)
+				future <- result
+				<-slots
+			}(index, rec, future)
+		}
+	}()
+
+	// Complete the results as they arrive, or in the order of the records.
+	// After a failure, the remaining results are only collected, so that
+	// all goroutines end.
+	if order == Ordered {
+		var ordered = make(chan parallelResult[T])
+
+		go func() {
+			defer close(ordered)
+			for future := range futures {
+				ordered <- <-future
+			}
+		}()
+		results = ordered
+	} else {
+		results = done
+	}
+
+	for result := range results {
+		if result.err == nil && len(errs) == 0 && complete != nil {
+			result.err = complete(result.index, result.result)
+		}
+		if result.err != nil && (len(errs) == 0 ||
+			!errors.Is(result.err, context.Canceled)) {
+			errs = append(errs, fmt.Errorf("Record %d: %w", result.index,
+				result.err))
+			cancel()
+		}
+	}
+
+	// Wait for all records to have been processed, which done is closed
+	// after also in ordered mode, where it is not used for the results.
+	for range done {
+	}
+
+	if readErr != nil && (len(errs) == 0 ||
+		!errors.Is(readErr, context.Canceled)) {
+		errs = append(errs, readErr)
+	}
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,123 @@
+//go:build go1.20
+
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+/*
+Records must be completed in the order of the stream in ordered mode, and
+all of them exactly once in unordered mode.
+*/
+func TestForEachRecordParallel(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 200)
+	var process = func(ctx context.Context, index int64, rec []byte) (
+		string, error) {
+		time.Sleep(time.Duration(rand.Intn(500)) * time.Microsecond)
+		return string(bytes.ToUpper(rec)), nil
+	}
+	var completed []int64
+	var seen map[int64]bool
+	var err error
+
+	err = ForEachRecordParallel(ctx,
+		NewIORecordReader(bytes.NewReader(data)), 8, Ordered, process,
+		func(index int64, result string) error {
+			if result != fmt.Sprint("REC ", index) {
+				t.Error("Unexpected result: ", result)
+			}
+			completed = append(completed, index)
+			return nil
+		})
+	if err != nil {
+		t.Error("Error processing records: ", err)
+	}
+	for i, index := range completed {
+		if index != int64(i) {
+			t.Fatal("Records completed out of order: ", completed)
+		}
+	}
+	if len(completed) != 200 {
+		t.Error("Unexpected number of records completed: ", len(completed))
+	}
+
+	seen = make(map[int64]bool)
+	err = ForEachRecordParallel(ctx,
+		NewIORecordReader(bytes.NewReader(data)), 8, Unordered, process,
+		func(index int64, result string) error {
+			if seen[index] {
+				t.Error("Record completed twice: ", index)
+			}
+			seen[index] = true
+			return nil
+		})
+	if err != nil {
+		t.Error("Error processing records: ", err)
+	}
+	if len(seen) != 200 {
+		t.Error("Unexpected number of records completed: ", len(seen))
+	}
+}
+
+/*
+Errors of several records must all be returned, while errors caused by
+canceling the remaining work must be left out.
+*/
+func TestForEachRecordParallelErrors(t *testing.T) {
+	var ctx = context.Background()
+	var failure = errors.New("Bad record")
+	var started = make(chan struct{}, 2)
+	var completed int
+	var err error
+
+	for _, order := range []CompletionOrder{Ordered, Unordered} {
+		completed = 0
+		err = ForEachRecordParallel(ctx,
+			NewIORecordReader(bytes.NewReader(writeNumbered(t, 100))), 4,
+			order, func(ctx context.Context, index int64, rec []byte) (
+				struct{}, error) {
+				switch index {
+				case 10, 11:
+					// Fail both records once both have started.
+					started <- struct{}{}
+					for len(started) < 2 {
+						time.Sleep(time.Millisecond)
+					}
+					time.Sleep(10 * time.Millisecond)
+					return struct{}{}, failure
+				case 12, 13:
+					<-ctx.Done()
+					return struct{}{}, ctx.Err()
+				}
+				return struct{}{}, nil
+			}, func(index int64, result struct{}) error {
+				completed++
+				return nil
+			})
+		<-started
+		<-started
+
+		if !errors.Is(err, failure) {
+			t.Error("Expected record error, got: ", err)
+		}
+		if errors.Is(err, context.Canceled) {
+			t.Error("Cancellation reported as error: ", err)
+		}
+		if err != nil && err.Error() !=
+			"Record 10: Bad record\nRecord 11: Bad record" &&
+			err.Error() != "Record 11: Bad record\nRecord 10: Bad record" {
+			t.Error("Unexpected errors: ", err)
+		}
+		if completed > 10 {
+			t.Error("Records completed after the failure: ", completed)
+		}
+	}
+}
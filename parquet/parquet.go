@@ -0,0 +1,237 @@
+/*
+Package parquet exports record files containing protocol buffers to Parquet,
+so they can be queried using SQL engines and other analytics tools.
+
+The Parquet schema is derived from the protocol buffer message descriptor.
+Every field of the message becomes a column named after the field:
+
+  - Scalar fields map to the corresponding Parquet types. Enums are stored
+    as the name of the enum value.
+  - Fields with explicit presence (proto2 optional fields, proto3 optional
+    fields) become optional columns.
+  - Repeated scalar fields become repeated columns.
+  - Message fields are stored as the serialized protocol buffer in a byte
+    array column, since Parquet requires nested structures to be known in
+    full up front.
+  - Map fields are not exported.
+*/
+package parquet
+
+import (
+	"github.com/childoftheuniverse/recordio"
+	"github.com/parquet-go/parquet-go"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+)
+
+/*
+DefaultRowGroupSize is the number of rows written to every row group if no
+other size is specified.
+*/
+const DefaultRowGroupSize = 100000
+
+/*
+Schema derives a Parquet schema from the specified message descriptor as
+described in the package documentation.
+*/
+func Schema(md protoreflect.MessageDescriptor) *parquet.Schema {
+	var group = make(parquet.Group)
+	var fields = md.Fields()
+	var fd protoreflect.FieldDescriptor
+	var node parquet.Node
+	var i int
+
+	for i = 0; i < fields.Len(); i++ {
+		fd = fields.Get(i)
+		if fd.IsMap() {
+			continue
+		}
+
+		node = leafNode(fd)
+		if fd.IsList() {
+			node = parquet.Repeated(node)
+		} else if fd.HasPresence() {
+			node = parquet.Optional(node)
+		}
+		group[string(fd.Name())] = node
+	}
+
+	return parquet.NewSchema(string(md.Name()), group)
+}
+
+/*
+Export reads all records from src, parses them as protocol buffers of the
+same type as prototype and writes them as rows of a Parquet file to dst. A
+new row group is started every rowGroupSize rows; if rowGroupSize is not
+positive, DefaultRowGroupSize is used. The contents of prototype are
+overwritten. The number of rows written is returned.
+*/
+func Export(ctx context.Context, dst io.Writer, src *recordio.RecordReader,
+	prototype recordio.Message, rowGroupSize int) (int64, error) {
+	var msg = protoadapt.MessageV2Of(prototype).ProtoReflect()
+	var schema = Schema(msg.Descriptor())
+	var writer = parquet.NewWriter(dst, schema)
+	var columns = schema.Columns()
+	var rows = make([]parquet.Row, 0, 1)
+	var count int64
+	var row parquet.Row
+	var err error
+
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	for {
+		if err = src.ReadMessage(ctx, prototype); err == io.EOF {
+			break
+		} else if err != nil {
+			writer.Close()
+			return count, err
+		}
+
+		if row, err = messageRow(msg, columns); err != nil {
+			writer.Close()
+			return count, err
+		}
+
+		if _, err = writer.WriteRows(append(rows[:0], row)); err != nil {
+			writer.Close()
+			return count, err
+		}
+
+		count++
+		if count%int64(rowGroupSize) == 0 {
+			if err = writer.Flush(); err != nil {
+				writer.Close()
+				return count, err
+			}
+		}
+	}
+
+	return count, writer.Close()
+}
+
+/*
+leafNode returns the Parquet node for a single value of the specified field.
+*/
+func leafNode(fd protoreflect.FieldDescriptor) parquet.Node {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return parquet.Leaf(parquet.BooleanType)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind,
+		protoreflect.Sfixed32Kind:
+		return parquet.Int(32)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed64Kind:
+		return parquet.Int(64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return parquet.Uint(32)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return parquet.Uint(64)
+	case protoreflect.FloatKind:
+		return parquet.Leaf(parquet.FloatType)
+	case protoreflect.DoubleKind:
+		return parquet.Leaf(parquet.DoubleType)
+	case protoreflect.StringKind, protoreflect.EnumKind:
+		return parquet.String()
+	default:
+		return parquet.Leaf(parquet.ByteArrayType)
+	}
+}
+
+/*
+messageRow converts the message into a Parquet row with the values in the
+order of the specified columns.
+*/
+func messageRow(msg protoreflect.Message, columns [][]string) (
+	parquet.Row, error) {
+	var fields = msg.Descriptor().Fields()
+	var row parquet.Row
+	var fd protoreflect.FieldDescriptor
+	var list protoreflect.List
+	var value parquet.Value
+	var col, i, rep int
+	var err error
+
+	for col = range columns {
+		fd = fields.ByName(protoreflect.Name(columns[col][0]))
+
+		if fd.IsList() {
+			list = msg.Get(fd).List()
+			if list.Len() == 0 {
+				row = append(row, parquet.NullValue().Level(0, 0, col))
+				continue
+			}
+			for i = 0; i < list.Len(); i++ {
+				if value, err = leafValue(fd, list.Get(i)); err != nil {
+					return nil, err
+				}
+				rep = 1
+				if i == 0 {
+					rep = 0
+				}
+				row = append(row, value.Level(rep, 1, col))
+			}
+			continue
+		}
+
+		if fd.HasPresence() && !msg.Has(fd) {
+			row = append(row, parquet.NullValue().Level(0, 0, col))
+			continue
+		}
+
+		if value, err = leafValue(fd, msg.Get(fd)); err != nil {
+			return nil, err
+		}
+		if fd.HasPresence() {
+			row = append(row, value.Level(0, 1, col))
+		} else {
+			row = append(row, value.Level(0, 0, col))
+		}
+	}
+
+	return row, nil
+}
+
+/*
+leafValue converts a single value of the specified field to a Parquet value.
+*/
+func leafValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (
+	parquet.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return parquet.BooleanValue(v.Bool()), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind,
+		protoreflect.Sfixed32Kind:
+		return parquet.Int32Value(int32(v.Int())), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed64Kind:
+		return parquet.Int64Value(v.Int()), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return parquet.Int32Value(int32(uint32(v.Uint()))), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return parquet.Int64Value(int64(v.Uint())), nil
+	case protoreflect.FloatKind:
+		return parquet.FloatValue(float32(v.Float())), nil
+	case protoreflect.DoubleKind:
+		return parquet.DoubleValue(v.Float()), nil
+	case protoreflect.StringKind:
+		return parquet.ByteArrayValue([]byte(v.String())), nil
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return parquet.ByteArrayValue([]byte(ev.Name())), nil
+		}
+		return parquet.ByteArrayValue(nil), nil
+	case protoreflect.BytesKind:
+		return parquet.ByteArrayValue(v.Bytes()), nil
+	default:
+		var b []byte
+		var err error
+
+		b, err = proto.Marshal(v.Message().Interface())
+		return parquet.ByteArrayValue(b), err
+	}
+}
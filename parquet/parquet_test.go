@@ -0,0 +1,58 @@
+package parquet
+
+import (
+	"bytes"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"github.com/childoftheuniverse/recordio"
+	"github.com/parquet-go/parquet-go"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Export a record file containing protocol buffers to Parquet and check the
+number of rows and the schema of the result.
+*/
+func TestExport(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var writer = recordio.NewRecordWriter(buf)
+	var out bytes.Buffer
+	var data recordio.MessageForTest
+	var file *parquet.File
+	var columns [][]string
+	var count int64
+	var err error
+
+	for _, s := range []string{"Hello", "World", "!"} {
+		data.Message = s
+		if err = writer.WriteMessage(ctx, &data); err != nil {
+			t.Error("Cannot serialize message: ", err)
+		}
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	count, err = Export(ctx, &out, recordio.NewRecordReader(buf), &data, 2)
+	if err != nil {
+		t.Error("Error exporting records: ", err)
+	}
+	if count != 3 {
+		t.Error("Expected 3 rows to be exported, got ", count)
+	}
+
+	file, err = parquet.OpenFile(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal("Error opening Parquet file: ", err)
+	}
+
+	if file.NumRows() != 3 {
+		t.Error("Expected 3 rows in Parquet file, got ", file.NumRows())
+	}
+
+	columns = file.Schema().Columns()
+	if len(columns) != 1 || columns[0][0] != "message" {
+		t.Error("Unexpected columns: ", columns)
+	}
+}
@@ -0,0 +1,237 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"io"
+	"sync"
+)
+
+/*
+RecordSource is a stream of records which a Pipeline reads from, such as a
+RecordReader, MultiReader, MergeReader or ShardedReader. ReadRecord returns
+io.EOF at the end of the stream.
+*/
+type RecordSource interface {
+	ReadRecord(ctx context.Context) ([]byte, error)
+}
+
+/*
+RecordSink receives the records produced by a Pipeline, such as a
+RecordWriter, ShardedWriter, ReplicatedWriter or AsyncRecordWriter.
+*/
+type RecordSink interface {
+	Write(ctx context.Context, rec []byte) (int, error)
+}
+
+/*
+Transform is a stage of a Pipeline. It is called for every record reaching
+the stage, and returns the records to pass on to the next stage in its
+place: none to filter the record out, one to modify it, or several to split
+it up. Transforms may be called concurrently.
+*/
+type Transform func(ctx context.Context, rec []byte) ([][]byte, error)
+
+/*
+Pipeline reads records from a RecordSource, passes them through a sequence
+of Transform stages and writes the results to a RecordSink, so that jobs
+reading, filtering and rewriting records do not have to implement the
+plumbing between goroutines themselves. Every stage processes up to a fixed
+number of records concurrently, but records leave every stage in the order
+in which they entered it, so the output is in the order of the input.
+
+Stages are added using Then(), whose calls can be chained:
+
+	written, err := recordio.NewPipeline(reader, writer).
+		Then(parse, 4).
+		Then(filter, 1).
+		Run(ctx)
+*/
+type Pipeline struct {
+	source RecordSource
+	sink   RecordSink
+	stages []pipelineStage
+}
+
+/*
+pipelineStage is a Transform along with the number of records it may
+process concurrently.
+*/
+type pipelineStage struct {
+	transform   Transform
+	parallelism int
+}
+
+/*
+pipelineResult is the outcome of a stage for one record, delivered through
+a channel so that stages can wait for the results in order while they are
+computed concurrently.
+*/
+type pipelineResult struct {
+	recs [][]byte
+	err  error
+}
+
+/*
+NewPipeline creates a new Pipeline copying records from source to sink,
+without any stages. No actions are performed until Run() is called.
+*/
+func NewPipeline(source RecordSource, sink RecordSink) *Pipeline {
+	return &Pipeline{
+		source: source,
+		sink:   sink,
+	}
+}
+
+/*
+Then appends a stage to the pipeline which calls transform for up to
+parallelism records at the same time; values below 1 are treated as 1. The
+pipeline itself is returned, so calls can be chained.
+*/
+func (p *Pipeline) Then(transform Transform, parallelism int) *Pipeline {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	p.stages = append(p.stages, pipelineStage{
+		transform:   transform,
+		parallelism: parallelism,
+	})
+	return p
+}
+
+/*
+Run reads all records from the source, passes them through the stages and
+writes the results to the sink, returning the number of records written.
+The first error reading from the source, in any of the stages or writing to
+the sink stops the pipeline and is returned, as is the error of the context
+if it is canceled. Run returns only once all goroutines it started have
+ended. Neither the source nor the sink are closed.
+*/
+func (p *Pipeline) Run(ctx context.Context) (int64, error) {
+	var wg sync.WaitGroup
+	var cancel context.CancelFunc
+	var results <-chan chan pipelineResult
+	var result pipelineResult
+	var written int64
+	var err error
+
+	ctx, cancel = context.WithCancel(ctx)
+	defer wg.Wait()
+	defer cancel()
+
+	results = p.read(ctx, &wg)
+	for _, stage := range p.stages {
+		results = stage.run(ctx, &wg, results)
+	}
+
+	for future := range results {
+		if result = <-future; result.err != nil {
+			return written, result.err
+		}
+		for _, rec := range result.recs {
+			if _, err = p.sink.Write(ctx, rec); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+
+	return written, ctx.Err()
+}
+
+/*
+read starts a goroutine reading the records from the source. The error
+ending the stream is passed on as a result unless it is io.EOF.
+*/
+func (p *Pipeline) read(ctx context.Context,
+	wg *sync.WaitGroup) <-chan chan pipelineResult {
+	var out = make(chan chan pipelineResult, 1)
+
+	wg.Add(1)
+	go func() {
+		var future chan pipelineResult
+		var rec []byte
+		var err error
+
+		defer wg.Done()
+		defer close(out)
+
+		for {
+			future = make(chan pipelineResult, 1)
+			if rec, err = p.source.ReadRecord(ctx); err == io.EOF {
+				return
+			} else if err != nil {
+				future <- pipelineResult{err: err}
+			} else {
+				future <- pipelineResult{recs: [][]byte{rec}}
+			}
+
+			select {
+			case out <- future:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+/*
+run starts a goroutine applying the transform of the stage to the results
+of the previous stage in, in order. Every record is transformed on a
+goroutine of its own, with up to parallelism of them running at the same
+time; their results are passed on in order.
+*/
+func (s pipelineStage) run(ctx context.Context, wg *sync.WaitGroup,
+	in <-chan chan pipelineResult) <-chan chan pipelineResult {
+	var out = make(chan chan pipelineResult, s.parallelism)
+	var slots = make(chan struct{}, s.parallelism)
+
+	wg.Add(1)
+	go func() {
+		var future chan pipelineResult
+		var result pipelineResult
+
+		defer wg.Done()
+		defer close(out)
+
+		for previous := range in {
+			if result = <-previous; result.err != nil {
+				future = make(chan pipelineResult, 1)
+				future <- result
+				select {
+				case out <- future:
+				case <-ctx.Done():
+				}
+				return
+			}
+
+			for _, rec := range result.recs {
+				future = make(chan pipelineResult, 1)
+				select {
+				case out <- future:
+				case <-ctx.Done():
+					return
+				}
+				slots <- struct{}{}
+
+				wg.Add(1)
+				go func(rec []byte, future chan<- pipelineResult) {
+					var result pipelineResult
+
+					defer wg.Done()
+
+					result.recs, result.err = s.transform(ctx, rec)
+					future <- result
+					<-slots
+				}(rec, future)
+			}
+		}
+	}()
+
+	return out
+}
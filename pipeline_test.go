@@ -0,0 +1,94 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+Records must pass through parallel stages which filter, modify and split
+them, and reach the sink in the order of the source.
+*/
+func TestPipeline(t *testing.T) {
+	var ctx = context.Background()
+	var out bytes.Buffer
+	var reader = NewIORecordReader(bytes.NewReader(writeNumbered(t, 100)))
+	var writer = NewIORecordWriter(&out)
+	var written int64
+	var rec []byte
+	var got []string
+	var want []string
+	var err error
+
+	written, err = NewPipeline(reader, writer).
+		Then(func(ctx context.Context, rec []byte) ([][]byte, error) {
+			// Delay records randomly to shuffle their completion.
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+			if rec[len(rec)-1]%2 == 1 {
+				return nil, nil
+			}
+			return [][]byte{bytes.ToUpper(rec)}, nil
+		}, 8).
+		Then(func(ctx context.Context, rec []byte) ([][]byte, error) {
+			return [][]byte{append([]byte("a-"), rec...),
+				append([]byte("b-"), rec...)}, nil
+		}, 3).
+		Run(ctx)
+	if err != nil {
+		t.Error("Error running pipeline: ", err)
+	}
+	if written != 100 {
+		t.Error("Unexpected number of records written: ", written)
+	}
+
+	for i := 0; i < 100; i += 2 {
+		want = append(want, fmt.Sprint("a-REC ", i), fmt.Sprint("b-REC ", i))
+	}
+	reader = NewIORecordReader(bytes.NewReader(out.Bytes()))
+	for {
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		got = append(got, string(rec))
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Error("Unexpected records: ", got)
+	}
+}
+
+/*
+An error in a stage must stop the pipeline and be returned, after the
+records before the failing one have been written.
+*/
+func TestPipelineError(t *testing.T) {
+	var ctx = context.Background()
+	var failure = errors.New("Bad record")
+	var out bytes.Buffer
+	var reader = NewIORecordReader(bytes.NewReader(writeNumbered(t, 100)))
+	var written int64
+	var err error
+
+	written, err = NewPipeline(reader, NewIORecordWriter(&out)).
+		Then(func(ctx context.Context, rec []byte) ([][]byte, error) {
+			if string(rec) == "Rec 42" {
+				return nil, failure
+			}
+			return [][]byte{rec}, nil
+		}, 4).
+		Run(ctx)
+	if err != failure {
+		t.Error("Expected stage error, got: ", err)
+	}
+	if written != 42 {
+		t.Error("Unexpected number of records written: ", written)
+	}
+}
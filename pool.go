@@ -0,0 +1,87 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"sync"
+	"unsafe"
+)
+
+/*
+maxHeaderLength is the maximum length of a record header: a varint encoded
+length followed by a checksum.
+*/
+const maxHeaderLength = binary.MaxVarintLen32 + 4
+
+/*
+headerPool holds buffers for decoding record headers, so that reading
+records does not allocate a new header every time. Writers keep their own
+header buffer instead.
+*/
+var headerPool = sync.Pool{
+	New: func() interface{} {
+		return new([maxHeaderLength]byte)
+	},
+}
+
+/*
+scratchPool holds buffers for copying or discarding record data in chunks.
+*/
+var scratchPool = sync.Pool{
+	New: func() interface{} {
+		return new([streamBufferSize]byte)
+	},
+}
+
+/*
+maxPooledBuffer is the capacity up to which buffers are returned to
+bufferPool, so that a single huge record does not pin its buffer in memory.
+*/
+const maxPooledBuffer = 1 << 20
+
+/*
+bufferPool holds growable buffers for temporary data of varying size, such
+as batches of framed records and compressed record data.
+*/
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+/*
+getBuffer returns a buffer of the specified length from bufferPool.
+*/
+func getBuffer(length int) *[]byte {
+	var buf = bufferPool.Get().(*[]byte)
+
+	if cap(*buf) < length {
+		*buf = make([]byte, length)
+	}
+	*buf = (*buf)[:length]
+	return buf
+}
+
+/*
+putBuffer returns a buffer obtained from getBuffer() to bufferPool, unless
+it has grown too large. The buffer must not be used afterwards.
+*/
+func putBuffer(buf *[]byte) {
+	if cap(*buf) <= maxPooledBuffer {
+		bufferPool.Put(buf)
+	}
+}
+
+/*
+overlaps determines whether the memory of the two slices overlaps, such as
+when a codec returns part of its input rather than a copy.
+*/
+func overlaps(a, b []byte) bool {
+	var aStart, bStart uintptr
+
+	if cap(a) == 0 || cap(b) == 0 {
+		return false
+	}
+	aStart = uintptr(unsafe.Pointer(&a[:cap(a)][0]))
+	bStart = uintptr(unsafe.Pointer(&b[:cap(b)][0]))
+	return aStart < bStart+uintptr(cap(b)) && bStart < aStart+uintptr(cap(a))
+}
@@ -0,0 +1,55 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Writing records must not allocate in the steady state, and reading them must
+only allocate the returned record data.
+*/
+func TestAllocations(t *testing.T) {
+	var ctx = context.Background()
+	var writer = NewIORecordWriter(io.Discard, WithChecksum())
+	var data bytes.Buffer
+	var stream = bytes.NewReader(nil)
+	var reader = NewIORecordReader(stream, WithChecksum())
+	var rec = []byte("Hello")
+	var buf = make([]byte, 0, 16)
+	var allocs float64
+
+	allocs = testing.AllocsPerRun(100, func() {
+		writer.Write(ctx, rec)
+	})
+	if allocs != 0 {
+		t.Error("Unexpected allocations per write: ", allocs)
+	}
+
+	NewIORecordWriter(&data, WithChecksum()).Write(ctx, rec)
+	allocs = testing.AllocsPerRun(100, func() {
+		stream.Reset(data.Bytes())
+		reader.ReadRecord(ctx)
+	})
+	if allocs != 1 {
+		t.Error("Unexpected allocations per read: ", allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() {
+		stream.Reset(data.Bytes())
+		buf, _ = reader.ReadRecordAppend(ctx, buf[:0])
+	})
+	if allocs != 0 {
+		t.Error("Unexpected allocations per appending read: ", allocs)
+	}
+
+	allocs = testing.AllocsPerRun(100, func() {
+		stream.Reset(data.Bytes())
+		reader.Read(ctx, buf[:cap(buf)])
+	})
+	if allocs != 0 {
+		t.Error("Unexpected allocations per Read: ", allocs)
+	}
+}
@@ -0,0 +1,87 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+)
+
+/*
+Position describes a position within a record stream, as reported by the
+Tell() methods of RecordReader and RecordWriter.
+*/
+type Position struct {
+	/*
+		Offset is the number of bytes between the point where the reader or
+		writer started and the beginning of the next record.
+	*/
+	Offset int64
+
+	/*
+		Index is the number of records read or written so far, or -1 if
+		it is unknown because the reader was positioned by byte offset.
+	*/
+	Index int64
+}
+
+/*
+Tell returns the position of the next record to be read, relative to the
+position of the input stream when the reader was created. Records passed
+over using Skip() or Count() are included, as are records which failed to
+decode, e.g. due to a checksum mismatch. Peeking does not change the
+position. After SeekToOffset(), the Index is -1 since it is not known.
+
+Tell can be used to build external indexes or to record resume points.
+*/
+func (r *RecordReader) Tell() Position {
+	return r.position
+}
+
+/*
+Offset returns the byte offset of the next record to be read, as described
+for Tell().
+*/
+func (r *RecordReader) Offset() int64 {
+	return r.position.Offset
+}
+
+/*
+Tell returns the position at which the next record will be written, relative
+to the position of the output stream when the writer was created.
+*/
+func (w *RecordWriter) Tell() Position {
+	return w.position
+}
+
+/*
+Offset returns the byte offset at which the next record will be written, as
+described for Tell().
+*/
+func (w *RecordWriter) Offset() int64 {
+	return w.position.Offset
+}
+
+/*
+AppendRecord writes the record like Write() and returns the position at which
+it was written, so that sidecar indexes or references to the record can be
+built while writing.
+*/
+func (w *RecordWriter) AppendRecord(ctx context.Context, rec []byte) (
+	Position, error) {
+	var pos = w.position
+	var err error
+
+	_, err = w.Write(ctx, rec)
+	return pos, err
+}
+
+/*
+advance moves the position of the reader past a record whose data has the
+specified length. An unknown index remains unknown.
+*/
+func (r *RecordReader) advance(length uint32) {
+	r.sizes.add(int64(length))
+	r.resumeConsumed = 0
+	r.position.Offset += int64(r.headerLength(length)) + int64(length)
+	if r.position.Index >= 0 {
+		r.position.Index++
+	}
+}
@@ -0,0 +1,101 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+The positions reported by the writer must match those reported by the
+reader for the same records.
+*/
+func TestTell(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum(),
+		WithCodec(DeflateCodec))
+	var reader *RecordReader
+	var positions []Position
+	var pos Position
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		positions = append(positions, writer.Tell())
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	positions = append(positions, writer.Tell())
+
+	if writer.Offset() != int64(buf.Len()) {
+		t.Error("Writer offset ", writer.Offset(), " does not match length ",
+			buf.Len())
+	}
+
+	reader = NewIORecordReader(&buf, WithChecksum(), WithCodec(DeflateCodec))
+	if pos = reader.Tell(); pos != positions[0] {
+		t.Error("Unexpected initial position: ", pos)
+	}
+
+	if _, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if pos = reader.Tell(); pos != positions[1] {
+		t.Error("Expected position ", positions[1], ", got ", pos)
+	}
+
+	if _, err = reader.Peek(ctx); err != nil {
+		t.Error("Error peeking at record: ", err)
+	}
+	if pos = reader.Tell(); pos != positions[1] {
+		t.Error("Peek changed the position to ", pos)
+	}
+
+	if _, err = reader.Skip(ctx, 2); err != nil {
+		t.Error("Error skipping records: ", err)
+	}
+	if pos = reader.Tell(); pos != positions[3] {
+		t.Error("Expected position ", positions[3], ", got ", pos)
+	}
+
+	if _, _, err = reader.Count(ctx); err != nil {
+		t.Error("Error counting records: ", err)
+	}
+	if pos = reader.Tell(); pos != positions[4] {
+		t.Error("Expected position ", positions[4], ", got ", pos)
+	}
+}
+
+/*
+AppendRecord must return positions at which the records can be read back.
+*/
+func TestAppendRecord(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReaderAt
+	var positions []Position
+	var pos Position
+	var rec []byte
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three"} {
+		if pos, err = writer.AppendRecord(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+		positions = append(positions, pos)
+	}
+
+	if positions[2].Index != 2 || positions[2].Offset != 14 {
+		t.Error("Unexpected position: ", positions[2])
+	}
+
+	reader = NewRecordReaderAt(bytes.NewReader(buf.Bytes()))
+	if rec, _, err = reader.ReadRecordAt(ctx, positions[1].Offset); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Two" {
+		t.Errorf("Unexpected data: got %q, expected Two", rec)
+	}
+}
@@ -0,0 +1,195 @@
+package recordio
+
+import (
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+ErrEmptyRecord is returned when writing an empty record with
+WithPreallocation(), since its header could not be told apart from the
+zeros following the last record.
+*/
+var ErrEmptyRecord = errors.New(
+	"Empty records cannot be written to preallocated streams")
+
+/*
+WithPreallocation makes writers extend the output stream in zero-filled
+segments of segmentSize bytes ahead of the data written, so that appending
+records does not require updating the size of the file on every write, and
+readers stop at the first header consisting of zeros as if the stream ended
+there. Since the zeros following the last record are never mistaken for
+records, a file left behind by a crash can be read up to the last record
+written, and appends have lower latency, e.g. when syncing every record.
+
+Preallocation requires the output stream to implement Seeker and
+Truncater, like the adapters returned by NewIOWriteCloser() for an os.File;
+otherwise, the option has no effect on writers. When the writer is closed,
+the unused part of the last segment is cut off again. Empty records cannot
+be written; ErrEmptyRecord is returned instead.
+*/
+func WithPreallocation(segmentSize int64) Option {
+	return func(o *options) {
+		o.segmentSize = segmentSize
+	}
+}
+
+/*
+preallocatingWriter grows its output stream in segments ahead of the data
+written to it.
+*/
+type preallocatingWriter struct {
+	writer    filesystem.WriteCloser
+	seeker    Seeker
+	truncater Truncater
+	segment   int64
+
+	// offset and allocated are the absolute offset of the stream and its
+	// size, once known is set.
+	offset    int64
+	allocated int64
+	known     bool
+}
+
+/*
+preallocating wraps writer into a preallocatingWriter, or returns it
+unchanged if preallocation is disabled or the stream does not support it.
+*/
+func preallocating(writer filesystem.WriteCloser,
+	segment int64) filesystem.WriteCloser {
+	var seeker, seekable = writer.(Seeker)
+	var truncater, truncatable = writer.(Truncater)
+
+	if segment <= 0 || !seekable || !truncatable {
+		return writer
+	}
+
+	return &preallocatingWriter{
+		writer:    writer,
+		seeker:    seeker,
+		truncater: truncater,
+		segment:   segment,
+	}
+}
+
+func (p *preallocatingWriter) Write(ctx context.Context, b []byte) (int,
+	error) {
+	var n int
+	var err error
+
+	if err = p.reserve(ctx, int64(len(b))); err != nil {
+		return 0, err
+	}
+
+	n, err = p.writer.Write(ctx, b)
+	p.offset += int64(n)
+	return n, err
+}
+
+/*
+reserve makes sure that the next length bytes written fall into the
+allocated part of the stream, allocating more segments if needed.
+*/
+func (p *preallocatingWriter) reserve(ctx context.Context,
+	length int64) error {
+	var size int64
+	var err error
+
+	if !p.known {
+		if p.offset, err = p.seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+			return err
+		}
+		if p.allocated, err = p.seeker.Seek(ctx, 0, io.SeekEnd); err != nil {
+			return err
+		}
+		if _, err = p.seeker.Seek(ctx, p.offset, io.SeekStart); err != nil {
+			return err
+		}
+		p.known = true
+	}
+
+	if p.offset+length <= p.allocated {
+		return nil
+	}
+
+	size = (p.offset + length + p.segment - 1) / p.segment * p.segment
+	if err = p.truncater.Truncate(ctx, size); err != nil {
+		return err
+	}
+	p.allocated = size
+	return nil
+}
+
+func (p *preallocatingWriter) Flush(ctx context.Context) error {
+	if flusher, ok := p.writer.(Flusher); ok {
+		return flusher.Flush(ctx)
+	}
+	return nil
+}
+
+func (p *preallocatingWriter) Sync(ctx context.Context) error {
+	if syncer, ok := p.writer.(Syncer); ok {
+		return syncer.Sync(ctx)
+	}
+	return ErrSyncUnsupported
+}
+
+func (p *preallocatingWriter) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	var abs, err = p.seeker.Seek(ctx, offset, whence)
+
+	if err == nil {
+		p.offset = abs
+	} else {
+		p.known = false
+	}
+	return abs, err
+}
+
+func (p *preallocatingWriter) Truncate(ctx context.Context,
+	size int64) error {
+	var err = p.truncater.Truncate(ctx, size)
+
+	if err == nil && p.known {
+		p.allocated = size
+	}
+	return err
+}
+
+/*
+Close cuts off the unused part of the last segment and closes the output
+stream.
+*/
+func (p *preallocatingWriter) Close(ctx context.Context) error {
+	var err error
+
+	if p.known && p.allocated > p.offset {
+		err = p.truncater.Truncate(ctx, p.offset)
+	}
+	if closeErr := p.writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+/*
+zeroHeader checks whether a header read from the input stream consists of
+zeros marking the end of the data in a preallocated stream. If so, the
+input stream is moved back to the beginning of the header, if possible, so
+that records written there later can still be read.
+*/
+func (r *RecordReader) zeroHeader(ctx context.Context, length,
+	crc uint32) (bool, error) {
+	var err error
+
+	if r.options.segmentSize <= 0 || length != 0 || crc != 0 {
+		return false, nil
+	}
+
+	if seeker, ok := r.wrappedReader.(Seeker); ok {
+		_, err = seeker.Seek(ctx, -int64(r.headerLength(0)), io.SeekCurrent)
+	}
+	return true, err
+}
@@ -0,0 +1,69 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"testing"
+)
+
+/*
+Preallocated files must grow in whole segments, be readable up to the last
+record while being written, including records appended after reaching the
+end, and be cut to their actual length when closed.
+*/
+func TestPreallocation(t *testing.T) {
+	var ctx = context.Background()
+	var opts = []Option{WithChecksum(), WithPreallocation(4096)}
+	var file, in *os.File
+	var writer *RecordWriter
+	var reader *RecordReader
+	var info os.FileInfo
+	var rec []byte
+	var err error
+
+	if file, err = os.CreateTemp(t.TempDir(), "prealloc"); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	writer = NewIORecordWriter(file, opts...)
+	for _, s := range []string{"Hello", "World"} {
+		if _, err = writer.Write(ctx, []byte(s)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if _, err = writer.Write(ctx, []byte{}); !errors.Is(err, ErrEmptyRecord) {
+		t.Error("Expected empty record to be rejected, got: ", err)
+	}
+	if info, err = file.Stat(); err != nil || info.Size() != 4096 {
+		t.Error("Unexpected size of preallocated file: ", info.Size(), err)
+	}
+
+	if in, err = os.Open(file.Name()); err != nil {
+		t.Fatal("Error opening file: ", err)
+	}
+	defer in.Close()
+	reader = NewIORecordReader(in, opts...)
+	for _, s := range []string{"Hello", "World"} {
+		if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != s {
+			t.Errorf("Unexpected record: %q (%v)", rec, err)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF at the preallocated zeros, got: ", err)
+	}
+
+	if _, err = writer.Write(ctx, []byte("Again")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Again" {
+		t.Errorf("Unexpected appended record: %q (%v)", rec, err)
+	}
+
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if info, err = os.Stat(file.Name()); err != nil || info.Size() != 39 {
+		t.Error("Unexpected size of closed file: ", info.Size(), err)
+	}
+}
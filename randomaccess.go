@@ -0,0 +1,142 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"math"
+)
+
+/*
+ErrRecordOutOfRange is returned by RandomAccessReader if a record index is
+not covered by the index.
+*/
+var ErrRecordOutOfRange = errors.New("Record index out of range")
+
+/*
+RandomAccessReader reads records by their index, looking up their offsets in
+a RecordIndex such as the sidecar index written by IndexedRecordWriter or
+the sparse index from the footer of a stream. With a sparse index, the
+records between the closest indexed record and the requested one are
+skipped over. Like RecordReaderAt, it does not maintain a position of its
+own, so it can be used by multiple goroutines concurrently as long as the
+underlying io.ReaderAt supports concurrent calls.
+*/
+type RandomAccessReader struct {
+	reader  io.ReaderAt
+	index   *RecordIndex
+	options options
+}
+
+/*
+NewRandomAccessReader creates a new RandomAccessReader reading records from
+the specified io.ReaderAt at the offsets recorded in index. The options must
+match those used for writing the records. No actions are performed at the
+time.
+*/
+func NewRandomAccessReader(reader io.ReaderAt, index *RecordIndex,
+	opts ...Option) *RandomAccessReader {
+	return &RandomAccessReader{
+		reader:  reader,
+		index:   index,
+		options: applyOptions(opts),
+	}
+}
+
+/*
+Len returns the number of records which can be read.
+*/
+func (r *RandomAccessReader) Len() int64 {
+	return r.index.Len()
+}
+
+/*
+Get returns the record with the specified index.
+*/
+func (r *RandomAccessReader) Get(ctx context.Context, n int64) ([]byte,
+	error) {
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	if reader, err = r.open(ctx, n); err != nil {
+		return nil, err
+	}
+
+	rec, err = reader.ReadRecord(ctx)
+	return rec, noEOF(err)
+}
+
+/*
+GetRange returns the records with indexes from i up to, but not including,
+j. The records are read sequentially starting at record i, so only a single
+index lookup is required.
+*/
+func (r *RandomAccessReader) GetRange(ctx context.Context, i, j int64) (
+	[][]byte, error) {
+	var reader *RecordReader
+	var recs [][]byte
+	var rec []byte
+	var n int64
+	var err error
+
+	if i > j || j > r.index.Len() {
+		return nil, ErrRecordOutOfRange
+	}
+	if reader, err = r.open(ctx, i); err != nil {
+		return nil, err
+	}
+
+	recs = make([][]byte, 0, j-i)
+	for n = i; n < j; n++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			return recs, noEOF(err)
+		}
+		recs = append(recs, rec)
+	}
+
+	return recs, nil
+}
+
+/*
+GetMessage reads the record with the specified index and parses it as the
+protocol buffer passed in.
+*/
+func (r *RandomAccessReader) GetMessage(ctx context.Context, n int64,
+	pb Message) error {
+	var reader *RecordReader
+	var err error
+
+	if reader, err = r.open(ctx, n); err != nil {
+		return err
+	}
+
+	return noEOF(reader.ReadMessage(ctx, pb))
+}
+
+/*
+open returns a RecordReader positioned at the record with the specified
+index.
+*/
+func (r *RandomAccessReader) open(ctx context.Context, n int64) (
+	*RecordReader, error) {
+	var pos, ok = r.index.nearest(n)
+	var reader *RecordReader
+	var err error
+
+	if !ok || n >= r.index.Len() {
+		return nil, ErrRecordOutOfRange
+	}
+
+	reader = &RecordReader{
+		wrappedReader: NewIOReadCloser(io.NewSectionReader(r.reader,
+			pos.Offset, math.MaxInt64-pos.Offset)),
+		options:  r.options,
+		position: pos,
+	}
+	if _, err = reader.Skip(ctx, int(n-pos.Index)); err != nil {
+		return nil, noEOF(err)
+	}
+
+	return reader, nil
+}
@@ -0,0 +1,71 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Read individual records and ranges of records by index.
+*/
+func TestRandomAccessReader(t *testing.T) {
+	var ctx = context.Background()
+	var buf, idx bytes.Buffer
+	var writer = NewIndexedRecordWriter(NewIOWriteCloser(&buf),
+		NewIOWriteCloser(&idx), WithFraming(VarintFraming))
+	var reader *RandomAccessReader
+	var index *RecordIndex
+	var recs [][]byte
+	var rec []byte
+	var i int
+	var err error
+
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	if index, err = ReadIndex(ctx,
+		NewIOReadCloser(bytes.NewReader(idx.Bytes()))); err != nil {
+		t.Fatal("Error reading index: ", err)
+	}
+	reader = NewRandomAccessReader(bytes.NewReader(buf.Bytes()), index,
+		WithFraming(VarintFraming))
+
+	if reader.Len() != 10 {
+		t.Error("Unexpected number of records: ", reader.Len())
+	}
+	if rec, err = reader.Get(ctx, 7); err != nil {
+		t.Error("Error reading record 7: ", err)
+	}
+	if string(rec) != "Record 7" {
+		t.Errorf("Unexpected data: got %q, expected Record 7", rec)
+	}
+
+	if recs, err = reader.GetRange(ctx, 3, 6); err != nil {
+		t.Error("Error reading records 3 to 6: ", err)
+	}
+	if len(recs) != 3 {
+		t.Error("Unexpected number of records: ", len(recs))
+	}
+	for i, rec = range recs {
+		if string(rec) != fmt.Sprint("Record ", i+3) {
+			t.Errorf("Unexpected data: got %q, expected Record %d", rec, i+3)
+		}
+	}
+
+	if _, err = reader.Get(ctx, 10); !errors.Is(err, ErrRecordOutOfRange) {
+		t.Error("Expected ErrRecordOutOfRange, got: ", err)
+	}
+	if _, err = reader.GetRange(ctx, 8, 11); !errors.Is(
+		err, ErrRecordOutOfRange) {
+		t.Error("Expected ErrRecordOutOfRange, got: ", err)
+	}
+}
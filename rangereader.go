@@ -0,0 +1,114 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"io"
+	"math"
+)
+
+/*
+RangeReader reads a contiguous range of records from a RecordReader,
+reporting io.EOF once the end of the range has been reached even if the
+stream contains more records. The range is either given by record indexes,
+which is useful for serving pages of a record file, or by byte offsets, as
+used for splitting files between parallel workers.
+*/
+type RangeReader struct {
+	reader    *RecordReader
+	endIndex  int64
+	endOffset int64
+}
+
+/*
+NewRangeReader positions the reader at the record with index startRecord
+using SeekToRecord(), which uses the index of the reader if available, and
+returns a RangeReader yielding the records from startRecord up to but not
+including endRecord. If the stream ends before startRecord, io.EOF is
+returned.
+*/
+func NewRangeReader(ctx context.Context, reader *RecordReader, startRecord,
+	endRecord int64) (*RangeReader, error) {
+	var err error
+
+	if err = reader.SeekToRecord(ctx, startRecord); err != nil {
+		return nil, err
+	}
+
+	return &RangeReader{
+		reader:    reader,
+		endIndex:  endRecord,
+		endOffset: math.MaxInt64,
+	}, nil
+}
+
+/*
+NewByteRangeReader positions the reader at the first record beginning at or
+after startOffset using SeekToOffset(), and returns a RangeReader yielding
+all records which begin before endOffset. The last record may thus extend
+beyond endOffset. Splitting a stream into adjacent byte ranges, e.g. using
+SplitPoints() or just by size, and reading each of them this way processes
+every record exactly once. Since SeekToOffset() is used, the input stream
+must implement Seeker, and checksums should be enabled unless the offsets
+are known to be record boundaries.
+*/
+func NewByteRangeReader(ctx context.Context, reader *RecordReader,
+	startOffset, endOffset int64) (*RangeReader, error) {
+	var err error
+
+	err = reader.SeekToOffset(ctx, startOffset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return &RangeReader{
+		reader:    reader,
+		endIndex:  math.MaxInt64,
+		endOffset: endOffset,
+	}, nil
+}
+
+/*
+ReadRecord reads the next record of the range as described for
+RecordReader.ReadRecord(). io.EOF is returned at the end of the range.
+*/
+func (r *RangeReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	if r.done() {
+		return nil, io.EOF
+	}
+	return r.reader.ReadRecord(ctx)
+}
+
+/*
+ReadMessage reads the next record of the range and parses it as the
+protocol buffer passed in, as described for RecordReader.ReadMessage().
+io.EOF is returned at the end of the range.
+*/
+func (r *RangeReader) ReadMessage(ctx context.Context, pb Message) error {
+	if r.done() {
+		return io.EOF
+	}
+	return r.reader.ReadMessage(ctx, pb)
+}
+
+/*
+Tell returns the position of the next record to be read, as described for
+RecordReader.Tell().
+*/
+func (r *RangeReader) Tell() Position {
+	return r.reader.Tell()
+}
+
+/*
+Close closes the underlying RecordReader.
+*/
+func (r *RangeReader) Close(ctx context.Context) error {
+	return r.reader.Close(ctx)
+}
+
+/*
+done returns whether the end of the range has been reached.
+*/
+func (r *RangeReader) done() bool {
+	return r.reader.position.Index >= r.endIndex ||
+		r.reader.position.Offset >= r.endOffset
+}
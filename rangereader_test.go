@@ -0,0 +1,99 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+A RangeReader must only return the records in its range.
+*/
+func TestRangeReader(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RangeReader
+	var rec []byte
+	var i int
+	var err error
+
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader, err = NewRangeReader(ctx,
+		NewIORecordReader(bytes.NewReader(buf.Bytes())), 4, 7)
+	if err != nil {
+		t.Fatal("Error creating range reader: ", err)
+	}
+	for i = 4; i < 7; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != fmt.Sprint("Record ", i) {
+			t.Errorf("Unexpected data: got %q, expected Record %d", rec, i)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+
+	_, err = NewRangeReader(ctx,
+		NewIORecordReader(bytes.NewReader(buf.Bytes())), 12, 15)
+	if err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+Reading adjacent byte ranges at arbitrary offsets must return every record
+exactly once.
+*/
+func TestByteRangeReader(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum())
+	var records []string
+	var i int
+	var err error
+
+	for i = 0; i < 20; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	for start := int64(0); start < int64(buf.Len()); start += 50 {
+		var reader *RangeReader
+		var rec []byte
+
+		reader, err = NewByteRangeReader(ctx, NewIORecordReader(
+			bytes.NewReader(buf.Bytes()), WithChecksum()), start, start+50)
+		if err != nil {
+			t.Fatal("Error creating range reader: ", err)
+		}
+		for {
+			if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal("Error reading record: ", err)
+			}
+			records = append(records, string(rec))
+		}
+	}
+
+	if len(records) != 20 {
+		t.Fatal("Unexpected number of records: ", len(records))
+	}
+	for i = range records {
+		if records[i] != fmt.Sprint("Record ", i) {
+			t.Errorf("Unexpected data: got %q, expected Record %d",
+				records[i], i)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+WithReadahead makes readers prefetch up to size bytes of the input stream on
+a background goroutine, so that the latency of the storage is hidden while
+records are being processed during sequential scans. The data is read in
+chunks of up to 32 KiB into a bounded queue, which is refilled as it is
+consumed.
+
+Seeking within the data prefetched for the current chunk is free; seeking
+anywhere else discards the queue and restarts prefetching at the new
+position. Readers using readahead must be closed to stop the goroutine,
+even if WithoutCloseUnderlying() was specified.
+*/
+func WithReadahead(size int) Option {
+	return func(o *options) {
+		o.readahead = size
+	}
+}
+
+/*
+prefetchChunk is a piece of the input stream read by the background
+goroutine, along with the error returned by the read.
+*/
+type prefetchChunk struct {
+	data []byte
+	err  error
+}
+
+/*
+prefetchReader wraps an input stream, reading ahead of the consumer on a
+background goroutine which is started by the first read.
+*/
+type prefetchReader struct {
+	reader    filesystem.ReadCloser
+	chunkSize int
+	depth     int
+
+	chunks   chan prefetchChunk
+	cancel   context.CancelFunc
+	done     chan struct{}
+	leftover *prefetchChunk
+
+	current []byte
+	err     error
+
+	offset     int64
+	positioned bool
+}
+
+/*
+prefetchSeekReader is a prefetchReader for input streams implementing
+Seeker, which implements Seeker as well.
+*/
+type prefetchSeekReader struct {
+	*prefetchReader
+	seeker Seeker
+}
+
+/*
+readahead wraps reader into a prefetchReader reading up to size bytes ahead,
+or returns it unchanged if size is not positive.
+*/
+func readahead(reader filesystem.ReadCloser,
+	size int) filesystem.ReadCloser {
+	var p *prefetchReader
+
+	if size <= 0 {
+		return reader
+	}
+
+	p = &prefetchReader{
+		reader:    reader,
+		chunkSize: size,
+		depth:     1,
+	}
+	if size > streamBufferSize {
+		p.chunkSize = streamBufferSize
+		p.depth = (size + streamBufferSize - 1) / streamBufferSize
+	}
+
+	if seeker, ok := reader.(Seeker); ok {
+		return &prefetchSeekReader{
+			prefetchReader: p,
+			seeker:         seeker,
+		}
+	}
+	return p
+}
+
+/*
+start launches the background goroutine filling the queue.
+*/
+func (p *prefetchReader) start() {
+	var ctx context.Context
+
+	ctx, p.cancel = context.WithCancel(context.Background())
+	p.chunks = make(chan prefetchChunk, p.depth)
+	p.done = make(chan struct{})
+	p.leftover = nil
+
+	go p.fill(ctx, p.chunks, p.done)
+}
+
+/*
+fill reads chunks from the input stream into the queue until reading fails
+or the context is canceled. A chunk which has been read but could not be
+queued anymore is kept as leftover, so that the amount of data taken from
+the input stream is always known.
+*/
+func (p *prefetchReader) fill(ctx context.Context,
+	chunks chan<- prefetchChunk, done chan<- struct{}) {
+	var chunk prefetchChunk
+	var buf []byte
+	var n int
+	var err error
+
+	defer close(done)
+
+	for {
+		buf = make([]byte, p.chunkSize)
+		n, err = p.reader.Read(ctx, buf)
+		chunk = prefetchChunk{data: buf[:n], err: err}
+
+		if ctx.Err() != nil {
+			if err == ctx.Err() {
+				chunk.err = nil
+			}
+			p.leftover = &chunk
+			return
+		}
+
+		select {
+		case chunks <- chunk:
+		case <-ctx.Done():
+			p.leftover = &chunk
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+/*
+stop terminates the background goroutine, if it is running, and discards
+all prefetched data. The number of bytes which had been read from the input
+stream but not consumed yet is returned.
+*/
+func (p *prefetchReader) stop() int64 {
+	var buffered = int64(len(p.current))
+
+	if p.cancel == nil {
+		return 0
+	}
+
+	p.cancel()
+	<-p.done
+
+	for {
+		select {
+		case chunk := <-p.chunks:
+			buffered += int64(len(chunk.data))
+			continue
+		default:
+		}
+		break
+	}
+	if p.leftover != nil {
+		buffered += int64(len(p.leftover.data))
+	}
+
+	p.cancel = nil
+	p.chunks = nil
+	p.done = nil
+	p.leftover = nil
+	p.current = nil
+	p.err = nil
+	return buffered
+}
+
+func (p *prefetchReader) Read(ctx context.Context, b []byte) (int, error) {
+	var chunk prefetchChunk
+	var n int
+
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if p.cancel == nil {
+		p.start()
+	}
+
+	if len(p.current) == 0 {
+		if p.err != nil {
+			return 0, p.err
+		}
+
+		select {
+		case chunk = <-p.chunks:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+		p.current, p.err = chunk.data, chunk.err
+		if len(p.current) == 0 {
+			return 0, p.err
+		}
+	}
+
+	n = copy(b, p.current)
+	p.current = p.current[n:]
+	p.offset += int64(n)
+	return n, nil
+}
+
+func (p *prefetchReader) Close(ctx context.Context) error {
+	p.stop()
+	return p.reader.Close(ctx)
+}
+
+func (p *prefetchSeekReader) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	var pos int64
+	var err error
+
+	if whence == io.SeekCurrent && p.positioned && offset >= 0 &&
+		offset <= int64(len(p.current)) {
+		p.current = p.current[offset:]
+		p.offset += offset
+		return p.offset, nil
+	}
+
+	if whence == io.SeekCurrent {
+		offset -= p.stop()
+	} else {
+		p.stop()
+	}
+
+	if pos, err = p.seeker.Seek(ctx, offset, whence); err != nil {
+		p.positioned = false
+		return pos, err
+	}
+	p.offset = pos
+	p.positioned = true
+	return pos, nil
+}
+
+/*
+stopReadahead terminates the prefetching goroutine of the input stream, if
+any, without closing it.
+*/
+func stopReadahead(reader filesystem.ReadCloser) {
+	switch p := reader.(type) {
+	case *prefetchReader:
+		p.stop()
+	case *prefetchSeekReader:
+		p.stop()
+	case *bufferedReader:
+		stopReadahead(p.reader)
+	case *bufferedSeekReader:
+		stopReadahead(p.reader)
+	}
+}
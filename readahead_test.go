@@ -0,0 +1,99 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+writeNumbered writes n records numbered from 0 and returns the stream.
+*/
+func writeNumbered(t *testing.T, n int, opts ...Option) []byte {
+	var ctx = context.Background()
+	var out bytes.Buffer
+	var writer = NewIORecordWriter(&out, opts...)
+	var err error
+
+	for i := 0; i < n; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Rec ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	return out.Bytes()
+}
+
+/*
+Records must be read in order with readahead, regardless of the size of the
+queue, also from streams which cannot seek.
+*/
+func TestReadahead(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 1000)
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	for _, size := range []int{1, 7, 4096, 100000} {
+		reader = NewRecordReader(
+			NewIOReadCloser(io.MultiReader(bytes.NewReader(data))),
+			WithReadahead(size))
+		for i := 0; i < 1000; i++ {
+			if rec, err = reader.ReadRecord(ctx); err != nil {
+				t.Error("Error reading record: ", err)
+				break
+			}
+			if string(rec) != fmt.Sprint("Rec ", i) {
+				t.Errorf("Unexpected data: got %q, expected Rec %d", rec, i)
+			}
+		}
+		if _, err = reader.ReadRecord(ctx); err != io.EOF {
+			t.Error("Expected EOF, got: ", err)
+		}
+		if err = reader.Close(ctx); err != nil {
+			t.Error("Error closing reader: ", err)
+		}
+	}
+}
+
+/*
+Skipping and seeking must discard the prefetched data correctly.
+*/
+func TestReadaheadSeek(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 1000)
+	var reader = NewIORecordReader(bytes.NewReader(data), WithReadahead(64))
+	var rec []byte
+	var err error
+
+	if _, err = reader.Skip(ctx, 3); err != nil {
+		t.Error("Error skipping records: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Rec 3" {
+		t.Errorf("Unexpected record after skipping: %q (%v)", rec, err)
+	}
+
+	if _, err = reader.Skip(ctx, 500); err != nil {
+		t.Error("Error skipping records: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil ||
+		string(rec) != "Rec 504" {
+		t.Errorf("Unexpected record after skipping: %q (%v)", rec, err)
+	}
+
+	if err = reader.SeekToRecord(ctx, 10); err != nil {
+		t.Error("Error seeking: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Rec 10" {
+		t.Errorf("Unexpected record after seeking: %q (%v)", rec, err)
+	}
+
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+}
@@ -0,0 +1,195 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+const (
+	/*
+		recordsPerRead is the number of records of average size requested
+		from the input stream at a time once a reader has switched to
+		buffered reads.
+	*/
+	recordsPerRead = 64
+
+	/*
+		minReadBufferSize and maxReadBufferSize limit the amount of data
+		requested at a time, regardless of the size of the records.
+	*/
+	minReadBufferSize = 4 * 1024
+	maxReadBufferSize = 4 * 1024 * 1024
+)
+
+/*
+sizeEstimate tracks a moving average of the sizes of the records passed by
+a reader, so that its buffers can be sized to hold a useful number of
+records without wasting memory.
+*/
+type sizeEstimate struct {
+	average int64
+	samples int64
+}
+
+/*
+add updates the average with the size of another record. Recent records
+are weighted more heavily, so the estimate follows changes in the stream.
+*/
+func (s *sizeEstimate) add(size int64) {
+	if s.samples < 8 {
+		s.samples++
+	}
+	s.average += (size - s.average) / s.samples
+}
+
+/*
+readSize returns the amount of data to request from the input stream at a
+time for records of the estimated size.
+*/
+func (s *sizeEstimate) readSize() int {
+	var size = s.average * recordsPerRead
+
+	if size < minReadBufferSize {
+		return minReadBufferSize
+	}
+	if size > maxReadBufferSize {
+		return maxReadBufferSize
+	}
+	return int(size)
+}
+
+/*
+bufferedReader wraps an input stream, requesting large chunks of data from
+it and serving smaller reads from memory, so that reading many small
+records does not require a call to the input stream for each of them. The
+buffer is sized according to the sizes of the records read so far.
+*/
+type bufferedReader struct {
+	reader filesystem.ReadCloser
+	sizes  *sizeEstimate
+	align  int
+	buf    []byte
+	data   []byte
+	skip   int64
+	err    error
+}
+
+/*
+bufferedSeekReader is a bufferedReader for input streams implementing
+Seeker, which implements Seeker as well.
+*/
+type bufferedSeekReader struct {
+	*bufferedReader
+	seeker Seeker
+}
+
+/*
+buffered wraps reader into a bufferedReader sizing its buffer according to
+sizes, unless it is one already. If align is positive, the input stream is
+only read in whole blocks of align bytes.
+*/
+func buffered(reader filesystem.ReadCloser, sizes *sizeEstimate,
+	align int) filesystem.ReadCloser {
+	var b *bufferedReader
+
+	switch reader.(type) {
+	case *bufferedReader, *bufferedSeekReader:
+		return reader
+	}
+
+	b = &bufferedReader{
+		reader: reader,
+		sizes:  sizes,
+		align:  align,
+	}
+	if seeker, ok := reader.(Seeker); ok {
+		return &bufferedSeekReader{
+			bufferedReader: b,
+			seeker:         seeker,
+		}
+	}
+	return b
+}
+
+func (b *bufferedReader) Read(ctx context.Context, p []byte) (int, error) {
+	var size = b.sizes.readSize()
+	var n int
+	var err error
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if b.align > 0 {
+		size = (size + b.align - 1) / b.align * b.align
+	}
+
+	for len(b.data) == 0 {
+		if b.err != nil {
+			err, b.err = b.err, nil
+			return 0, err
+		}
+
+		// Large reads go to the input stream directly rather than being
+		// copied through the buffer.
+		if len(p) >= size && b.align == 0 {
+			return b.reader.Read(ctx, p)
+		}
+
+		// Replace the buffer if it is too small for the records, or
+		// much larger than needed.
+		if cap(b.buf) < size || cap(b.buf) > 4*size {
+			if b.align > 0 {
+				b.buf = alignedBuffer(size, b.align)
+			} else {
+				b.buf = make([]byte, size)
+			}
+		}
+		b.buf = b.buf[:size]
+
+		// Errors accompanying data are returned once the data has been
+		// consumed.
+		if n, err = b.reader.Read(ctx, b.buf); n == 0 {
+			return 0, err
+		}
+		b.data, b.err = b.buf[:n], err
+		b.skipAlignment()
+	}
+
+	n = copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func (b *bufferedReader) Close(ctx context.Context) error {
+	b.data = nil
+	return b.reader.Close(ctx)
+}
+
+func (b *bufferedSeekReader) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	var pos int64
+	var err error
+
+	if whence == io.SeekCurrent {
+		if offset >= 0 && offset <= int64(len(b.data)) {
+			if pos, err = b.seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+				return pos, err
+			}
+			b.data = b.data[offset:]
+			return pos - int64(len(b.data)) + b.skip, nil
+		}
+		offset += b.skip - int64(len(b.data))
+	}
+
+	b.data = nil
+	b.err = nil
+	b.skip = 0
+	if pos, err = b.seeker.Seek(ctx, offset, whence); err != nil {
+		return pos, err
+	}
+	if b.align > 0 {
+		err = b.seekAligned(ctx, pos)
+	}
+	return pos, err
+}
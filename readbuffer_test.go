@@ -0,0 +1,75 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+The size estimate must follow the sizes of recent records, within the
+limits for the buffer size.
+*/
+func TestSizeEstimate(t *testing.T) {
+	var sizes sizeEstimate
+	var i int
+
+	if sizes.readSize() != minReadBufferSize {
+		t.Error("Unexpected initial read size: ", sizes.readSize())
+	}
+
+	for i = 0; i < 100; i++ {
+		sizes.add(1024)
+	}
+	if sizes.readSize() != 1024*recordsPerRead {
+		t.Error("Unexpected read size for 1 KiB records: ", sizes.readSize())
+	}
+
+	for i = 0; i < 100; i++ {
+		sizes.add(10)
+	}
+	if sizes.readSize() != minReadBufferSize {
+		t.Error("Unexpected read size for tiny records: ", sizes.readSize())
+	}
+
+	for i = 0; i < 100; i++ {
+		sizes.add(1 << 20)
+	}
+	if sizes.readSize() != maxReadBufferSize {
+		t.Error("Unexpected read size for large records: ", sizes.readSize())
+	}
+}
+
+/*
+The read buffer must be resized as the sizes of the records change.
+*/
+func TestReadBufferAdapts(t *testing.T) {
+	var ctx = context.Background()
+	var data bytes.Buffer
+	var writer = NewIORecordWriter(&data)
+	var reader = NewIORecordReader(&data)
+	var buffer *bufferedReader
+	var err error
+
+	for i := 0; i < 100; i++ {
+		writer.Write(ctx, bytes.Repeat([]byte{'x'}, 10))
+	}
+	for i := 0; i < 100; i++ {
+		writer.Write(ctx, bytes.Repeat([]byte{'y'}, 4096))
+	}
+
+	if _, err = reader.ReadRecords(ctx, 100); err != nil {
+		t.Error("Error reading records: ", err)
+	}
+	buffer = reader.wrappedReader.(*bufferedReader)
+	if cap(buffer.buf) != minReadBufferSize {
+		t.Error("Unexpected buffer size for small records: ", cap(buffer.buf))
+	}
+
+	if _, err = reader.ReadRecords(ctx, 100); err != nil {
+		t.Error("Error reading records: ", err)
+	}
+	if cap(buffer.buf) <= minReadBufferSize {
+		t.Error("Buffer did not grow for large records: ", cap(buffer.buf))
+	}
+}
@@ -1,11 +1,12 @@
 package recordio
 
 import (
-	"encoding/binary"
 	"errors"
 	"github.com/childoftheuniverse/filesystem"
-	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
 )
 
 /*
@@ -19,54 +20,361 @@ data!
 type RecordReader struct {
 	filesystem.ReadCloser
 	wrappedReader filesystem.ReadCloser
+	options       options
+
+	peeked         bool
+	peekedLength   uint32
+	peekedChecksum uint32
+
+	position Position
+	pending  *recordBodyReader
+	closed   bool
+	index    *RecordIndex
+	trailing int64
+	sizes    sizeEstimate
+	limiter  *rateLimiter
+
+	// messageBuf holds the data of the last record read by
+	// ReadMessageReuse().
+	messageBuf []byte
+
+	// source is the io.ReaderAt of sourceSize bytes the reader was
+	// created from by NewCloneableRecordReader(), if any.
+	source     io.ReaderAt
+	sourceSize int64
+
+	// resumeConsumed is the number of bytes of the next record to skip
+	// in NextRecordReader(), as set by ResumeFrom().
+	resumeConsumed int64
 }
 
+/*
+errInsufficientBuffer is returned by Read() if the buffer passed in cannot
+hold the next record.
+*/
+var errInsufficientBuffer = errors.New("Insufficiently large buffer")
+
 /*
 NewRecordReader creates a new RecordReader wrapped around the specified
-input stream. No actions are performed at the time.
+input stream. The options must match those used for writing the stream. No
+actions are performed at the time.
+*/
+func NewRecordReader(reader filesystem.ReadCloser,
+	opts ...Option) *RecordReader {
+	var r = &RecordReader{
+		options: applyOptions(opts),
+	}
+
+	r.limiter = newRateLimiter(r.options)
+	r.wrappedReader = r.wrap(reader)
+	return r
+}
+
+/*
+wrap adds the layers required by the options of the reader, such as
+readahead or aligned reads, to the input stream.
 */
-func NewRecordReader(reader filesystem.ReadCloser) *RecordReader {
-	return &RecordReader{
-		wrappedReader: reader,
+func (r *RecordReader) wrap(reader filesystem.ReadCloser) filesystem.ReadCloser {
+	reader = retryingReadCloser(reader, r.options.retry)
+	reader = readahead(reader, r.options.readahead)
+	if r.options.alignment > 0 {
+		reader = buffered(reader, &r.sizes, r.options.alignment)
 	}
+	return reader
+}
+
+/*
+Reset makes the RecordReader read from the specified input stream instead,
+discarding any peeked header and index, resetting the position reported by
+Tell() and reopening the reader if it has been closed.
+The options of the reader are preserved, so pooled readers can be reused for
+many streams written the same way. The previous input stream is not closed.
+*/
+func (r *RecordReader) Reset(reader filesystem.ReadCloser) {
+	stopReadahead(r.wrappedReader)
+	r.wrappedReader = r.wrap(reader)
+	r.peeked = false
+	r.position = Position{}
+	r.pending = nil
+	r.closed = false
+	r.index = nil
+	r.trailing = 0
+	r.resumeConsumed = 0
+	r.source = nil
+}
+
+/*
+Close closes the underlying reader, unless WithoutCloseUnderlying() was
+specified. Closing a RecordReader more than once has no effect; afterwards,
+all reads fail with ErrClosed.
+*/
+func (r *RecordReader) Close(ctx context.Context) error {
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+	r.pending = nil
+	r.peeked = false
+	if r.options.keepUnderlyingOpen {
+		stopReadahead(r.wrappedReader)
+		return nil
+	}
+	return r.wrappedReader.Close(ctx)
 }
 
 /*
 ReadRecord() reads the next record from the input stream and returns it to the
 caller.
 
-This will read the length of the upcoming record first (4 bytes), which will
-be used to size the buffer. Therefor, this function must only be called on
-trusted data which is known to be a RecordWriter compatible stream. Also, the
-stream should be pointed at the beginning of a record. Otherwise, large
-amounts of memory may be allocated for no good reason, and the result is
-probably going to be garbage.
+This will read the length of the upcoming record first (4 bytes, or a varint
+when using VarintFraming), which will be used to size the buffer. Therefor,
+this function must only be called on trusted data which is known to be a
+RecordWriter compatible stream, or with a maximum record size configured
+using WithMaxRecordSize(). Also, the stream should be pointed at the
+beginning of a record. Otherwise, large amounts of memory may be allocated
+for no good reason, and the result is probably going to be garbage.
+
+If checksums are enabled and the record data doesn't match its checksum,
+ErrChecksumMismatch is returned along with the corrupted data.
+
+The context is checked for cancellation before reading the header and again
+before reading the record data. In the latter case, the header is retained,
+so the record is returned by the next call as if nothing had been read.
 */
 func (r *RecordReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var pos Position
 	var rec []byte
-	var lengthAsBytes []byte = make([]byte, 4)
-	var headerLength int
-	var bodyLength uint32
-	var lengthRead int
 	var err error
 
-	headerLength, err = r.wrappedReader.Read(ctx, lengthAsBytes)
-	if err != nil {
+	rec, pos, err = r.readSalvaging(ctx)
+	return rec, recordError(pos, err)
+}
+
+/*
+ReadRecordAppend reads the next record from the input stream like
+ReadRecord(), but appends its data to dst and returns the extended slice.
+Passing the result of the previous call, truncated to zero length, reuses
+its buffer, so that reading records in a tight loop does not allocate once
+the buffer is large enough for all of them:
+
+	for {
+		if buf, err = reader.ReadRecordAppend(ctx, buf[:0]); err != nil {
+			...
+		}
+		...
+	}
+
+If a codec is used, the decompressed data is allocated by the codec and
+copied to dst. On errors, dst is returned, possibly extended by the data
+read so far, as ReadRecord() would return it.
+*/
+func (r *RecordReader) ReadRecordAppend(ctx context.Context, dst []byte) (
+	[]byte, error) {
+	var pos = r.position
+	var length, crc uint32
+	var err error
+
+	if length, crc, err = r.nextBodyHeader(ctx); err != nil {
+		return dst, recordError(pos, err)
+	}
+
+	dst, err = r.readBody(ctx, dst, length, crc)
+	return dst, recordError(pos, err)
+}
+
+/*
+readRecord implements ReadRecord() without adding the position of the record
+to errors.
+*/
+func (r *RecordReader) readRecord(ctx context.Context) ([]byte, error) {
+	var length, crc uint32
+	var err error
+
+	if length, crc, err = r.nextBodyHeader(ctx); err != nil {
 		return []byte{}, err
 	}
 
-	if headerLength != 4 {
-		return []byte{}, errors.New("Short read for header")
+	return r.readBody(ctx, nil, length, crc)
+}
+
+/*
+nextBodyHeader returns the header of the next record to be read in full,
+checking its length against the maximum record size.
+*/
+func (r *RecordReader) nextBodyHeader(ctx context.Context) (uint32, uint32,
+	error) {
+	var length, crc uint32
+	var err error
+
+	if length, crc, err = r.nextHeader(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	if length > r.options.maxRecordSize {
+		return 0, 0, ErrRecordTooLarge
+	}
+
+	// Keep the header around if the context was canceled in the meantime,
+	// so the record can still be read later.
+	if err = ctx.Err(); err == nil {
+		err = r.limiter.wait(ctx, int64(length), 1)
+	}
+	if err != nil {
+		r.unreadHeader(length, crc)
+		return 0, 0, err
+	}
+
+	return length, crc, nil
+}
+
+/*
+readBody reads the record data following a header, verifies and decodes
+it and appends the result to dst.
+*/
+func (r *RecordReader) readBody(ctx context.Context, dst []byte,
+	length, crc uint32) ([]byte, error) {
+	var pooled *[]byte
+	var rec, body []byte
+	var err error
+
+	// Compressed data is only needed until it has been decompressed.
+	if r.options.codec != nil {
+		pooled = getBuffer(int(length))
+		body = *pooled
+	} else {
+		rec = grow(dst, int(length))
+		body = rec[len(dst):]
+	}
+
+	_, err = readFull(ctx, r.wrappedReader, body)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = errShortBody
+	}
+	if err != nil {
+		if pooled != nil {
+			return dst, err
+		}
+		return rec, err
 	}
 
-	bodyLength = binary.BigEndian.Uint32(lengthAsBytes)
-	rec = make([]byte, bodyLength)
-	lengthRead, err = r.wrappedReader.Read(ctx, rec)
-	if err == nil && uint32(lengthRead) < bodyLength {
-		err = errors.New("Short read for body")
+	r.advance(length)
+	if pooled == nil {
+		_, err = r.decodeBody(body, crc)
+		return rec, err
 	}
 
-	return rec, err
+	// The decoded data has to be copied if the codec returned its input, so
+	// that the buffer can be reused.
+	body, err = r.decodeBody(body, crc)
+	if dst == nil && overlaps(body, *pooled) {
+		dst = make([]byte, 0, len(body))
+	}
+	if dst != nil {
+		body = append(dst, body...)
+	}
+	if err == nil {
+		putBuffer(pooled)
+	}
+	return body, err
+}
+
+/*
+grow extends dst by n bytes, reallocating it only if its capacity does not
+suffice.
+*/
+func grow(dst []byte, n int) []byte {
+	var grown []byte
+
+	if cap(dst)-len(dst) >= n {
+		return dst[:len(dst)+n]
+	}
+
+	grown = make([]byte, len(dst)+n)
+	copy(grown, dst)
+	return grown
+}
+
+/*
+Peek reads the header of the next record and returns the length of the record
+data without consuming the record, so the following read returns it as usual.
+Calling Peek() again before reading the record returns the same length.
+
+If a codec is used, the length of the compressed data is returned, since the
+uncompressed length is not known before decompressing the record.
+*/
+func (r *RecordReader) Peek(ctx context.Context) (uint32, error) {
+	var err error
+
+	if r.closed {
+		return 0, recordError(r.position, ErrClosed)
+	}
+
+	if err = r.discardPending(ctx); err != nil {
+		return 0, recordError(r.position, err)
+	}
+
+	if !r.peeked {
+		r.peekedLength, r.peekedChecksum, err = r.nextRecordHeader(ctx)
+		if err != nil {
+			return 0, recordError(r.position, err)
+		}
+		r.peeked = true
+	}
+
+	return r.peekedLength, nil
+}
+
+/*
+nextHeader returns the header of the next record, either from a previous
+call to Peek() or by reading it from the input stream. Unread data of a
+record being streamed is skipped first.
+*/
+func (r *RecordReader) nextHeader(ctx context.Context) (uint32, uint32, error) {
+	if r.closed {
+		return 0, 0, ErrClosed
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	if err := r.discardPending(ctx); err != nil {
+		return 0, 0, err
+	}
+
+	if r.peeked {
+		r.peeked = false
+		return r.peekedLength, r.peekedChecksum, nil
+	}
+
+	return r.nextRecordHeader(ctx)
+}
+
+/*
+unreadHeader stores a header which has been read from the input stream so
+that it is returned again by the next call to nextHeader().
+*/
+func (r *RecordReader) unreadHeader(length, crc uint32) {
+	r.peeked = true
+	r.peekedLength = length
+	r.peekedChecksum = crc
+}
+
+/*
+decodeBody verifies the checksum of the record data read from the stream
+and decompresses it, as required by the options of the reader.
+*/
+func (r *RecordReader) decodeBody(body []byte, crc uint32) ([]byte, error) {
+	if r.options.checksum && checksum(body) != crc {
+		return body, ErrChecksumMismatch
+	}
+
+	if r.options.codec != nil {
+		return r.options.codec.Decompress(body, r.options.maxRecordSize)
+	}
+
+	return body, nil
 }
 
 /*
@@ -74,6 +382,9 @@ For filesystem.ReadCloser compatibility. This will read the next record and
 place it into the specified buffer. This will only ever read data the size of
 the following record.
 
+Unless a codec is used, the length of the record is checked before reading
+it, and the data is read directly into the buffer without being copied.
+
 If the buffer is too small to hold the data, an error will be returned and no
 data will be placed into the buffer. The reader will still be advanced by one
 record.
@@ -81,21 +392,39 @@ record.
 All warnings from the ReadRecord() method apply here as well.
 */
 func (r *RecordReader) Read(ctx context.Context, buffer []byte) (int, error) {
-	var internalBuffer []byte
+	var pos = r.position
+	var rec []byte
+	var length, crc uint32
 	var err error
 
-	internalBuffer, err = r.ReadRecord(ctx)
-	if err != nil {
-		return 0, err
+	if length, crc, err = r.nextBodyHeader(ctx); err != nil {
+		return 0, recordError(pos, err)
+	}
+
+	if r.options.codec == nil && int(length) > len(buffer) {
+		if err = r.skipRecord(ctx, length, crc); err != nil {
+			return 0, recordError(pos, err)
+		}
+		r.advance(length)
+		return 0, recordError(pos, errInsufficientBuffer)
 	}
 
-	if len(internalBuffer) > cap(buffer) {
-		return 0, errors.New("Insufficiently large buffer")
+	// Compressed records are decompressed into a separate buffer, so the
+	// buffer passed in is never overwritten by a record which is too large.
+	if r.options.codec != nil {
+		rec, err = r.readBody(ctx, nil, length, crc)
+	} else {
+		rec, err = r.readBody(ctx, buffer[:0], length, crc)
+	}
+	if err != nil {
+		return 0, recordError(pos, err)
 	}
 
-	copy(buffer, internalBuffer)
+	if len(rec) > len(buffer) {
+		return 0, recordError(pos, errInsufficientBuffer)
+	}
 
-	return len(internalBuffer), nil
+	return copy(buffer, rec), nil
 }
 
 /*
@@ -110,7 +439,8 @@ be advanced by a record.
 
 All warnings from the ReadRecord() method apply here as well.
 */
-func (r *RecordReader) ReadMessage(ctx context.Context, pb proto.Message) error {
+func (r *RecordReader) ReadMessage(ctx context.Context, pb Message) error {
+	var pos = r.position
 	var buf []byte
 	var err error
 
@@ -119,5 +449,107 @@ func (r *RecordReader) ReadMessage(ctx context.Context, pb proto.Message) error
 		return err
 	}
 
-	return proto.Unmarshal(buf, pb)
+	return recordError(pos, r.options.unmarshalMessage(buf, pb))
+}
+
+/*
+ReadMessageReuse reads the next record from the input stream and parses it
+into the specified message like ReadMessage(), clearing its previous
+contents. The record data is read into a buffer kept by the reader, so that
+scanning a stream with a single message, or messages taken from a
+MessagePool, does not allocate anything but the contents of the messages.
+
+The parsed message never refers to the buffer, since parsing copies all
+data out of it, so the message may be retained after the next read. The
+message must not be used concurrently with the call, though, and must not
+be returned to a MessagePool while it is still in use.
+*/
+func (r *RecordReader) ReadMessageReuse(ctx context.Context,
+	pb Message) error {
+	var pos = r.position
+	var err error
+
+	r.messageBuf, err = r.ReadRecordAppend(ctx, r.messageBuf[:0])
+	if err != nil {
+		return err
+	}
+
+	err = r.options.unmarshalMessage(r.messageBuf, pb)
+	if cap(r.messageBuf) > maxPooledBuffer {
+		r.messageBuf = nil
+	}
+	return recordError(pos, err)
+}
+
+/*
+ReadMessageNew reads the next record from the input stream and parses it as
+a newly allocated protocol buffer of the specified type, which is returned.
+The message type can be obtained from any message using
+pb.ProtoReflect().Type(), or looked up by name in protoregistry.GlobalTypes.
+
+Unlike ReadMessage(), there is no need to pass in a message to fill, so the
+returned messages can be retained by the caller. All warnings from the
+ReadRecord() method apply here as well.
+*/
+func (r *RecordReader) ReadMessageNew(ctx context.Context,
+	messageType protoreflect.MessageType) (proto.Message, error) {
+	var pos = r.position
+	var pb proto.Message
+	var buf []byte
+	var err error
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return nil, err
+	}
+
+	pb = messageType.New().Interface()
+	if err = r.options.unmarshalOptions().Unmarshal(buf, pb); err != nil {
+		return nil, recordError(pos, err)
+	}
+
+	return pb, nil
+}
+
+/*
+readFull reads exactly len(buf) bytes from the specified reader, issuing
+multiple reads if the reader returns less data than requested. io.EOF is only
+returned if no data could be read at all; if the stream ends in the middle of
+the buffer, io.ErrUnexpectedEOF is returned instead.
+*/
+func readFull(ctx context.Context, reader filesystem.ReadCloser,
+	buf []byte) (int, error) {
+	var total, n int
+	var err error
+
+	for total < len(buf) && err == nil {
+		n, err = reader.Read(ctx, buf[total:])
+		total += n
+	}
+
+	if total == len(buf) {
+		return total, nil
+	}
+	if err == io.EOF && total > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return total, err
+}
+
+/*
+byteReader reads single bytes from a filesystem.ReadCloser so that variable
+length integers can be decoded without reading ahead of them.
+*/
+type byteReader struct {
+	ctx    context.Context
+	reader filesystem.ReadCloser
+	buf    [1]byte
+}
+
+func (b *byteReader) ReadByte() (byte, error) {
+	var err error
+
+	if _, err = readFull(b.ctx, b.reader, b.buf[:]); err != nil {
+		return 0, err
+	}
+	return b.buf[0], nil
 }
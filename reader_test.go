@@ -0,0 +1,150 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Peeking must return the length of the next record without consuming it.
+*/
+func TestPeek(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithFraming(VarintFraming),
+		WithChecksum())
+	var reader *RecordReader
+	var rec []byte
+	var length uint32
+	var err error
+
+	for _, rec := range []string{"Hello", "Hi"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(&buf, WithFraming(VarintFraming), WithChecksum())
+	for i := 0; i < 2; i++ {
+		if length, err = reader.Peek(ctx); err != nil {
+			t.Error("Error peeking at record: ", err)
+		}
+		if length != 5 {
+			t.Error("Unexpected peeked length: ", length)
+		}
+	}
+
+	if rec, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Hello" {
+		t.Errorf("Unexpected data: got %q, expected Hello", rec)
+	}
+
+	if length, err = reader.Peek(ctx); err != nil || length != 2 {
+		t.Error("Unexpected peeked length: ", length, ", error: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Hi" {
+		t.Errorf("Unexpected record %q, error: %v", rec, err)
+	}
+
+	if _, err = reader.Peek(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+A reset reader must read the new stream from the start with the same options.
+*/
+func TestReaderReset(t *testing.T) {
+	var ctx = context.Background()
+	var first, second bytes.Buffer
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	NewIORecordWriter(&first, WithChecksum()).Write(ctx, []byte("First"))
+	NewIORecordWriter(&second, WithChecksum()).Write(ctx, []byte("Second"))
+
+	reader = NewIORecordReader(&first, WithChecksum())
+	if _, err = reader.Peek(ctx); err != nil {
+		t.Error("Error peeking at record: ", err)
+	}
+
+	reader.Reset(NewIOReadCloser(&second))
+	if reader.Offset() != 0 {
+		t.Error("Offset was not reset: ", reader.Offset())
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Second" {
+		t.Errorf("Unexpected data: got %q, expected Second", rec)
+	}
+}
+
+/*
+ReadRecordAppend must append records to the buffer passed in, and leave it
+unchanged at the end of the stream.
+*/
+func TestReadRecordAppend(t *testing.T) {
+	var ctx = context.Background()
+	var data bytes.Buffer
+	var writer = NewIORecordWriter(&data, WithChecksum())
+	var reader = NewIORecordReader(&data, WithChecksum())
+	var buf []byte
+	var err error
+
+	writer.Write(ctx, []byte("Hello"))
+	writer.Write(ctx, []byte("World"))
+
+	if buf, err = reader.ReadRecordAppend(ctx, buf); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if buf, err = reader.ReadRecordAppend(ctx, append(buf, ' ')); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(buf) != "Hello World" {
+		t.Errorf("Unexpected data: got %q, expected Hello World", buf)
+	}
+
+	if buf, err = reader.ReadRecordAppend(ctx, buf[:5]); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+	if string(buf) != "Hello" {
+		t.Errorf("Unexpected data at EOF: %q", buf)
+	}
+}
+
+/*
+Read must reject buffers too small for the record without touching them,
+and still move on to the next record.
+*/
+func TestReadShortBuffer(t *testing.T) {
+	var ctx = context.Background()
+	var data bytes.Buffer
+	var writer = NewIORecordWriter(&data, WithChecksum())
+	var reader = NewIORecordReader(&data, WithChecksum())
+	var buf = []byte("xxxx")
+	var n int
+	var err error
+
+	writer.Write(ctx, []byte("Hello"))
+	writer.Write(ctx, []byte("Hi"))
+
+	if n, err = reader.Read(ctx, buf); err == nil || n != 0 {
+		t.Error("Expected error for short buffer, got ", n, " bytes")
+	}
+	if string(buf) != "xxxx" {
+		t.Errorf("Buffer was modified: %q", buf)
+	}
+
+	if n, err = reader.Read(ctx, buf); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(buf[:n]) != "Hi" {
+		t.Errorf("Unexpected data: got %q, expected Hi", buf[:n])
+	}
+}
@@ -0,0 +1,77 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"io"
+	"math"
+)
+
+/*
+RecordReaderAt reads records at arbitrary offsets from an io.ReaderAt, such
+as an os.File. Unlike RecordReader, it does not maintain a position of its
+own, so it can be used by multiple goroutines concurrently as long as the
+underlying io.ReaderAt supports concurrent calls, which os.File does.
+
+All warnings from RecordReader.ReadRecord() apply here as well; in
+particular, the offsets passed in must point at the beginning of a record.
+*/
+type RecordReaderAt struct {
+	reader  io.ReaderAt
+	options options
+}
+
+/*
+NewRecordReaderAt creates a new RecordReaderAt reading from the specified
+io.ReaderAt. The options must match those used for writing the records. No
+actions are performed at the time.
+*/
+func NewRecordReaderAt(reader io.ReaderAt, opts ...Option) *RecordReaderAt {
+	return &RecordReaderAt{
+		reader:  reader,
+		options: applyOptions(opts),
+	}
+}
+
+/*
+ReadRecordAt reads the record starting at the specified offset. Besides the
+record data, the offset of the following record is returned, so that
+consecutive records can be read by passing it to the next call. io.EOF is
+returned if offset points at the end of the data.
+*/
+func (r *RecordReaderAt) ReadRecordAt(ctx context.Context, offset int64) (
+	[]byte, int64, error) {
+	var section = io.NewSectionReader(r.reader, offset, math.MaxInt64-offset)
+	var reader = &RecordReader{
+		wrappedReader: NewIOReadCloser(section),
+		options:       r.options,
+		position:      Position{Offset: offset, Index: -1},
+	}
+	var rec []byte
+	var err error
+
+	rec, err = reader.ReadRecord(ctx)
+	if err != nil {
+		return rec, offset, err
+	}
+
+	return rec, reader.position.Offset, nil
+}
+
+/*
+ReadMessageAt reads the record starting at the specified offset and parses
+it as the protocol buffer passed in. The offset of the following record is
+returned.
+*/
+func (r *RecordReaderAt) ReadMessageAt(ctx context.Context, offset int64,
+	pb Message) (int64, error) {
+	var buf []byte
+	var next int64
+	var err error
+
+	if buf, next, err = r.ReadRecordAt(ctx, offset); err != nil {
+		return next, err
+	}
+
+	return next, recordError(Position{Offset: offset, Index: -1},
+		r.options.unmarshalMessage(buf, pb))
+}
@@ -0,0 +1,73 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"sync"
+	"testing"
+)
+
+/*
+Read all records of a stream sequentially by following the returned offsets,
+and then concurrently from multiple goroutines.
+*/
+func TestRecordReaderAt(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithFraming(VarintFraming),
+		WithChecksum())
+	var reader *RecordReaderAt
+	var offsets []int64
+	var offset int64
+	var rec []byte
+	var wg sync.WaitGroup
+	var i int
+	var err error
+
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewRecordReaderAt(bytes.NewReader(buf.Bytes()),
+		WithFraming(VarintFraming), WithChecksum())
+
+	for i = 0; ; i++ {
+		offsets = append(offsets, offset)
+		rec, offset, err = reader.ReadRecordAt(ctx, offset)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		if string(rec) != fmt.Sprint("Record ", i) {
+			t.Errorf("Unexpected record %d: %q", i, rec)
+		}
+	}
+
+	if i != 10 {
+		t.Error("Expected 10 records, got ", i)
+	}
+
+	for i = 9; i >= 0; i-- {
+		wg.Add(1)
+		go func(i int) {
+			var rec []byte
+			var err error
+
+			defer wg.Done()
+
+			if rec, _, err = reader.ReadRecordAt(ctx, offsets[i]); err != nil {
+				t.Error("Error reading record: ", err)
+			}
+			if string(rec) != fmt.Sprint("Record ", i) {
+				t.Errorf("Unexpected record %d: %q", i, rec)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
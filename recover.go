@@ -0,0 +1,106 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"hash/crc32"
+	"io"
+)
+
+/*
+RecoverTail reads the remaining records of the input stream to find the end
+of the last complete, valid record, and returns its offset relative to the
+position of the input stream when the reader was created. If the stream was
+opened at its beginning, this is the length to which a file should be
+truncated to remove a record torn by a crash while appending to it, so that
+writing can continue with a valid file. If the stream ends cleanly, its
+length is returned, including the footer if there is one.
+
+Records are only checked for completeness and, if checksums are enabled,
+for checksum mismatches; they are not decompressed. Everything following
+the first invalid record is considered part of the torn tail, since valid
+records cannot be found reliably after it; see WithSkipCorrupted() for
+salvaging them. Only truncated records, record lengths out of range and
+checksum mismatches are taken to mark a torn tail; other errors, such as
+I/O errors of the input stream, ErrClosed and errors of the context, are
+returned wrapped into a RecordError, since the stream may well continue with
+valid records.
+*/
+func (r *RecordReader) RecoverTail(ctx context.Context) (int64, error) {
+	var valid = r.position.Offset
+	var length, crc uint32
+	var err error
+
+	for {
+		if length, crc, err = r.nextBodyHeader(ctx); err == io.EOF {
+			return r.streamOffset(), nil
+		} else if err != nil {
+			return valid, recordError(r.position, noTornTail(ctx, err))
+		}
+
+		if err = r.verifyBody(ctx, int64(length), crc); err != nil {
+			return valid, recordError(r.position, noTornTail(ctx, err))
+		}
+
+		r.advance(length)
+		valid = r.position.Offset
+	}
+}
+
+/*
+noTornTail returns nil for errors indicating a torn tail of the stream, the
+error of the context if it has been canceled, and err otherwise.
+*/
+func noTornTail(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if tornTail(err) {
+		return nil
+	}
+	return err
+}
+
+/*
+tornTail determines whether err indicates a record which was only partially
+written, or whose header or data were damaged, at the end of the stream.
+*/
+func tornTail(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, errShortHeader) || errors.Is(err, errShortBody) ||
+		errors.Is(err, errLengthOutOfRange) ||
+		errors.Is(err, ErrRecordTooLarge) ||
+		errors.Is(err, ErrChecksumMismatch)
+}
+
+/*
+verifyBody reads the next length bytes of record data from the input
+stream, and checks that they are all present and match the checksum, if
+checksums are enabled, without keeping them in memory.
+*/
+func (r *RecordReader) verifyBody(ctx context.Context, length int64,
+	crc uint32) error {
+	var hash = crc32.New(crc32cTable)
+	var buf = scratchPool.Get().(*[streamBufferSize]byte)
+	var scratch = buf[:]
+	var n int
+	var err error
+
+	defer scratchPool.Put(buf)
+
+	for length > 0 {
+		if length < int64(len(scratch)) {
+			scratch = scratch[:length]
+		}
+		if n, err = readFull(ctx, r.wrappedReader, scratch); err != nil {
+			return noEOF(err)
+		}
+		hash.Write(scratch[:n])
+		length -= int64(n)
+	}
+
+	if r.options.checksum && hash.Sum32() != crc {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+/*
+RecoverTail must find the end of the last valid record for intact, torn and
+corrupted streams.
+*/
+func TestRecoverTail(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var corrupted = append([]byte{}, data...)
+	var footer = writeNumbered(t, 10, WithChecksum(), WithFooter())
+	var length int64
+	var err error
+
+	// Every record takes 8 bytes of header and 5 bytes of data.
+	corrupted[5*13+10] ^= 0xff
+
+	for _, test := range []struct {
+		name     string
+		data     []byte
+		opts     []Option
+		expected int64
+	}{
+		{"Intact", data, []Option{WithChecksum()}, 130},
+		{"Footer", footer, []Option{WithChecksum(), WithFooter()},
+			int64(len(footer))},
+		{"TornBody", data[:125], []Option{WithChecksum()}, 117},
+		{"TornHeader", data[:120], []Option{WithChecksum()}, 117},
+		{"Corrupted", corrupted, []Option{WithChecksum()}, 65},
+	} {
+		length, err = NewIORecordReader(bytes.NewReader(test.data),
+			test.opts...).RecoverTail(ctx)
+		if err != nil {
+			t.Error(test.name, ": error recovering tail: ", err)
+		}
+		if length != test.expected {
+			t.Error(test.name, ": unexpected length ", length, ", expected ",
+				test.expected)
+		}
+	}
+}
+
+/*
+RecoverTail must return I/O errors of the input stream and ErrClosed rather
+than mistake them for a torn tail.
+*/
+func TestRecoverTailErrors(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var failure = errors.New("Disk failure")
+	var reader *RecordReader
+	var recordErr *RecordError
+	var err error
+
+	reader = NewIORecordReader(io.MultiReader(bytes.NewReader(data[:60]),
+		iotest.ErrReader(failure)), WithChecksum())
+	if _, err = reader.RecoverTail(ctx); !errors.Is(err, failure) {
+		t.Error("Unexpected error for failing stream: ", err)
+	}
+	if !errors.As(err, &recordErr) {
+		t.Error("Error not wrapped into a RecordError: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum())
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+	if _, err = reader.RecoverTail(ctx); !errors.Is(err, ErrClosed) {
+		t.Error("Unexpected error for closed reader: ", err)
+	}
+}
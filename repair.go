@@ -0,0 +1,71 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+Repair copies every readable record from the damaged input stream src into
+the fresh output stream dst, producing a valid stream holding all records
+which could be salvaged, and returns the number of records copied. The
+options are used for both streams; records are verified and re-encoded
+rather than copied verbatim.
+
+Corrupted parts of src are skipped as described for WithSkipCorrupted(),
+and reported to manifest, if it is not nil, so that a manifest of the data
+lost can be kept. If src does not implement Seeker, records cannot be found
+after the first corrupted one, so the rest of the stream is skipped and
+reported with an End of -1.
+
+dst is closed afterwards, writing the footer if WithFooter() is used, even
+if repairing fails due to a write error or the context being canceled. src
+is not closed.
+*/
+func Repair(ctx context.Context, src filesystem.ReadCloser,
+	dst filesystem.WriteCloser, manifest func(skipped ByteRange, err error),
+	opts ...Option) (int64, error) {
+	var report = func(skipped ByteRange, err error) {
+		if manifest != nil {
+			manifest(skipped, err)
+		}
+	}
+	var reader = NewRecordReader(src, append(opts[:len(opts):len(opts)],
+		WithSkipCorrupted(func(skipped ByteRange, err error) {
+			// Failures of streams which cannot be scanned are reported
+			// below, along with the rest of the stream.
+			if skipped.End > skipped.Start {
+				report(skipped, err)
+			}
+		}))...)
+	var writer = NewRecordWriter(dst, opts...)
+	var copied int64
+	var pos Position
+	var rec []byte
+	var err error
+
+	for {
+		pos = reader.Tell()
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			err = nil
+			break
+		} else if reader.corrupted(ctx, err) {
+			report(ByteRange{Start: pos.Offset, End: -1}, err)
+			err = nil
+			break
+		} else if err != nil {
+			break
+		}
+
+		if _, err = writer.Write(ctx, rec); err != nil {
+			break
+		}
+		copied++
+	}
+
+	if closeErr := writer.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return copied, err
+}
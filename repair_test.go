@@ -0,0 +1,56 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Repairing a damaged stream must produce a valid stream with all readable
+records, and report the regions skipped, also for streams which cannot
+seek.
+*/
+func TestRepair(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var opts = []Option{WithChecksum(), WithFooter()}
+	var out bytes.Buffer
+	var skipped []ByteRange
+	var manifest = func(r ByteRange, err error) {
+		skipped = append(skipped, r)
+	}
+	var report VerifyReport
+	var copied int64
+	var err error
+
+	// Every record takes 8 bytes of header and 5 bytes of data.
+	data[3*13+9] ^= 0xff
+
+	copied, err = Repair(ctx, NewIOReadCloser(bytes.NewReader(data)),
+		NewIOWriteCloser(&out), manifest, opts...)
+	if err != nil || copied != 9 {
+		t.Errorf("Unexpected repair result: %d (%v)", copied, err)
+	}
+	if len(skipped) != 1 || skipped[0] != (ByteRange{Start: 39, End: 52}) {
+		t.Error("Unexpected ranges skipped: ", skipped)
+	}
+
+	report, err = NewIORecordReader(bytes.NewReader(out.Bytes()),
+		opts...).Verify(ctx)
+	if err != nil || !report.OK() || report.Records != 9 {
+		t.Errorf("Repaired stream is invalid: %+v (%v)", report, err)
+	}
+
+	out.Reset()
+	skipped = nil
+	copied, err = Repair(ctx, NewIOReadCloser(bytes.NewBuffer(data)),
+		NewIOWriteCloser(&out), manifest, opts...)
+	if err != nil || copied != 3 {
+		t.Errorf("Unexpected repair result: %d (%v)", copied, err)
+	}
+	if fmt.Sprint(skipped) != "[{39 -1}]" {
+		t.Error("Unexpected ranges skipped: ", skipped)
+	}
+}
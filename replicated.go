@@ -0,0 +1,182 @@
+package recordio
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"sync"
+)
+
+/*
+ErrQuorumLost is returned by ReplicatedWriter when fewer replicas than the
+quorum have succeeded.
+*/
+var ErrQuorumLost = errors.New("Too few replicas succeeded")
+
+/*
+ErrNoReplicas is returned when creating a ReplicatedWriter without any
+replicas.
+*/
+var ErrNoReplicas = errors.New("At least one replica is required")
+
+/*
+ReplicatedWriter mirrors every record to a set of RecordWriters, e.g. on
+different volumes, so that the output survives the loss of some of them.
+Writes go to all replicas at the same time, and succeed if at least a quorum
+of replicas succeeded. A replica which fails once is not written to anymore,
+since the state of its output stream is unknown.
+
+As with RecordWriter, ReplicatedWriters are not thread safe.
+*/
+type ReplicatedWriter struct {
+	writers  []*RecordWriter
+	failures []error
+	quorum   int
+}
+
+/*
+NewReplicatedWriter creates a ReplicatedWriter mirroring records to the
+specified RecordWriters, which should be configured with the same options.
+Operations succeed if at least quorum replicas succeed; a quorum below 1 or
+above the number of replicas requires all replicas to succeed. ErrNoReplicas
+is returned if there are no replicas, since nothing could be written then.
+*/
+func NewReplicatedWriter(writers []*RecordWriter,
+	quorum int) (*ReplicatedWriter, error) {
+	if len(writers) == 0 {
+		return nil, ErrNoReplicas
+	}
+	if quorum < 1 || quorum > len(writers) {
+		quorum = len(writers)
+	}
+
+	return &ReplicatedWriter{
+		writers:  writers,
+		failures: make([]error, len(writers)),
+		quorum:   quorum,
+	}, nil
+}
+
+/*
+Write writes the record to all healthy replicas. If fewer than the quorum of
+replicas succeed, an error wrapping ErrQuorumLost is returned, and the record
+may still have been written to some of them.
+*/
+func (w *ReplicatedWriter) Write(ctx context.Context, rec []byte) (int,
+	error) {
+	var err = w.each(func(writer *RecordWriter) error {
+		var _, err = writer.Write(ctx, rec)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rec), nil
+}
+
+/*
+WriteMessage serializes the protocol buffer once and writes it to all
+healthy replicas, as described for Write().
+*/
+func (w *ReplicatedWriter) WriteMessage(ctx context.Context,
+	pb Message) error {
+	var b []byte
+	var err error
+
+	if b, err = w.writers[0].options.marshalMessage(pb); err != nil {
+		return err
+	}
+
+	_, err = w.Write(ctx, b)
+	return err
+}
+
+/*
+Flush flushes all healthy replicas, requiring the quorum to succeed.
+*/
+func (w *ReplicatedWriter) Flush(ctx context.Context) error {
+	return w.each(func(writer *RecordWriter) error {
+		return writer.Flush(ctx)
+	})
+}
+
+/*
+Sync syncs all healthy replicas to stable storage, requiring the quorum to
+succeed.
+*/
+func (w *ReplicatedWriter) Sync(ctx context.Context) error {
+	return w.each(func(writer *RecordWriter) error {
+		return writer.Sync(ctx)
+	})
+}
+
+/*
+Barrier makes all records written so far durable on all healthy replicas
+as described for RecordWriter.Barrier(), requiring the quorum to succeed.
+*/
+func (w *ReplicatedWriter) Barrier(ctx context.Context) error {
+	return w.each(func(writer *RecordWriter) error {
+		return writer.Barrier(ctx)
+	})
+}
+
+/*
+Close closes all replicas, including those which have failed before. An
+error is returned if fewer than the quorum of replicas were healthy and
+could be closed successfully.
+*/
+func (w *ReplicatedWriter) Close(ctx context.Context) error {
+	for i, writer := range w.writers {
+		if w.failures[i] != nil {
+			writer.Close(ctx)
+		}
+	}
+
+	return w.each(func(writer *RecordWriter) error {
+		return writer.Close(ctx)
+	})
+}
+
+/*
+Failures returns the error which made each replica fail, or nil for the
+replicas which are still healthy.
+*/
+func (w *ReplicatedWriter) Failures() []error {
+	return append([]error(nil), w.failures...)
+}
+
+/*
+each calls fn for all healthy replicas concurrently, marks the replicas for
+which it fails as failed, and checks that the quorum is still healthy.
+*/
+func (w *ReplicatedWriter) each(fn func(writer *RecordWriter) error) error {
+	var wg sync.WaitGroup
+	var healthy int
+	var firstErr error
+
+	for i, writer := range w.writers {
+		if w.failures[i] != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, writer *RecordWriter) {
+			defer wg.Done()
+			w.failures[i] = fn(writer)
+		}(i, writer)
+	}
+	wg.Wait()
+
+	for _, failure := range w.failures {
+		if failure == nil {
+			healthy++
+		} else if firstErr == nil {
+			firstErr = failure
+		}
+	}
+
+	if healthy < w.quorum {
+		return fmt.Errorf("%w: %d healthy, %d required, first error: %v",
+			ErrQuorumLost, healthy, w.quorum, firstErr)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Writes must succeed as long as the quorum of replicas succeeds, stop going
+to failed replicas, and fail once the quorum has been lost.
+*/
+func TestReplicatedWriter(t *testing.T) {
+	var ctx = context.Background()
+	var good1, good2 bytes.Buffer
+	var bad = &failingWriter{limit: 20}
+	var writer *ReplicatedWriter
+	var rec []byte
+	var err error
+
+	if writer, err = NewReplicatedWriter([]*RecordWriter{
+		NewIORecordWriter(&good1),
+		NewIORecordWriter(bad),
+		NewIORecordWriter(&good2),
+	}, 2); err != nil {
+		t.Fatal("Error creating writer: ", err)
+	}
+
+	for _, s := range []string{"Hello", "World", "Foo", "Bar"} {
+		if _, err = writer.Write(ctx, []byte(s)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if writer.Failures()[1] == nil || writer.Failures()[0] != nil {
+		t.Error("Unexpected failures: ", writer.Failures())
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	for _, buf := range []*bytes.Buffer{&good1, &good2} {
+		var reader = NewIORecordReader(bytes.NewReader(buf.Bytes()))
+
+		for _, s := range []string{"Hello", "World", "Foo", "Bar"} {
+			if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != s {
+				t.Errorf("Unexpected record: %q (%v)", rec, err)
+			}
+		}
+	}
+
+	if writer, err = NewReplicatedWriter([]*RecordWriter{
+		NewIORecordWriter(&good1),
+		NewIORecordWriter(&failingWriter{}),
+	}, 2); err != nil {
+		t.Fatal("Error creating writer: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("Hello")); !errors.Is(err,
+		ErrQuorumLost) {
+		t.Error("Expected quorum to be lost, got: ", err)
+	}
+}
+
+/*
+A ReplicatedWriter without replicas can't write anything and must be
+rejected rather than report success.
+*/
+func TestReplicatedWriterNoReplicas(t *testing.T) {
+	var err error
+
+	if _, err = NewReplicatedWriter(nil, 1); err != ErrNoReplicas {
+		t.Error("Expected ErrNoReplicas, got: ", err)
+	}
+}
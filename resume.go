@@ -0,0 +1,71 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+ErrIncompleteRecord is returned when writing to a RecordWriter whose output
+stream ends in a partially written record, until Resume() has been called.
+*/
+var ErrIncompleteRecord = errors.New(
+	"Output stream ends in an incomplete record")
+
+/*
+ErrNotResumable is returned by Resume() if the output stream does not
+implement Seeker, so the incomplete record cannot be removed.
+*/
+var ErrNotResumable = errors.New(
+	"Output stream does not support removing incomplete records")
+
+/*
+Truncater is implemented by output streams which can be cut off at a given
+size, such as the adapters returned by NewIOWriteCloser() for an os.File.
+*/
+type Truncater interface {
+	Truncate(ctx context.Context, size int64) error
+}
+
+/*
+Resume removes the partially written record left behind by a failed or
+canceled write (see PartialWriteError), so that writing can continue as if
+the failed write had never happened. If no record is incomplete, Resume does
+nothing.
+
+The output stream must implement Seeker; the writer seeks back to the offset
+the incomplete record started at, and truncates the stream there if it also
+implements Truncater, so the next record overwrites the incomplete one. If
+the stream is not seekable, ErrNotResumable is returned and the output
+should be discarded.
+*/
+func (w *RecordWriter) Resume(ctx context.Context) error {
+	var seeker Seeker
+	var offset int64
+	var ok bool
+	var err error
+
+	if w.closed {
+		return ErrClosed
+	}
+	if w.incomplete == 0 {
+		return nil
+	}
+
+	if seeker, ok = w.wrappedWriter.(Seeker); !ok {
+		return ErrNotResumable
+	}
+	if offset, err = seeker.Seek(ctx, -w.incomplete, io.SeekCurrent); err != nil {
+		return err
+	}
+	if truncater, ok := w.wrappedWriter.(Truncater); ok {
+		if err = truncater.Truncate(ctx, offset); err != nil {
+			return err
+		}
+	}
+
+	w.position.Offset -= w.incomplete
+	w.incomplete = 0
+	return nil
+}
@@ -0,0 +1,115 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+/*
+cancelingFile cancels a context after every write to the file, once armed.
+*/
+type cancelingFile struct {
+	*os.File
+	cancel context.CancelFunc
+}
+
+func (c *cancelingFile) Write(p []byte) (int, error) {
+	if c.cancel != nil {
+		defer c.cancel()
+	}
+	return c.File.Write(p)
+}
+
+/*
+A record left incomplete by a canceled write must block further writes until
+it has been removed using Resume(), after which writing continues normally.
+*/
+func TestResume(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var file *os.File
+	var stream *cancelingFile
+	var writer *RecordWriter
+	var reader *RecordReader
+	var partial *PartialWriteError
+	var rec []byte
+	var err error
+
+	if file, err = os.CreateTemp(t.TempDir(), "resume"); err != nil {
+		t.Fatal("Error creating temporary file: ", err)
+	}
+	stream = &cancelingFile{File: file}
+	writer = NewIORecordWriter(stream, WithChecksum())
+
+	if _, err = writer.Write(ctx, []byte("Hello")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	stream.cancel = cancel
+	_, err = writer.WriteRecordFrom(ctx, strings.NewReader("World"), 5)
+	if !errors.As(err, &partial) {
+		t.Error("Expected partial write, got: ", err)
+	}
+	stream.cancel = nil
+
+	ctx = context.Background()
+	if _, err = writer.Write(ctx, []byte("Again")); !errors.Is(
+		err, ErrIncompleteRecord) {
+		t.Error("Expected ErrIncompleteRecord, got: ", err)
+	}
+
+	if err = writer.Resume(ctx); err != nil {
+		t.Error("Error resuming writer: ", err)
+	}
+	if writer.Offset() != 13 {
+		t.Error("Unexpected offset after resuming: ", writer.Offset())
+	}
+	if _, err = writer.Write(ctx, []byte("Again")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	if file, err = os.Open(file.Name()); err != nil {
+		t.Fatal("Error reopening file: ", err)
+	}
+	reader = NewIORecordReader(file, WithChecksum())
+	defer reader.Close(ctx)
+
+	for _, expected := range []string{"Hello", "Again"} {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != expected {
+			t.Errorf("Unexpected data: got %q, expected %s", rec, expected)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+Resume() must report that incomplete records cannot be removed from streams
+which are not seekable.
+*/
+func TestResumeNotSeekable(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&cancelingStream{buf: &buf, cancel: cancel})
+	var err error
+
+	if err = writer.Resume(ctx); err != nil {
+		t.Error("Unexpected error resuming complete stream: ", err)
+	}
+
+	writer.WriteRecordFrom(ctx, strings.NewReader("Hello"), 5)
+	if err = writer.Resume(context.Background()); err != ErrNotResumable {
+		t.Error("Expected ErrNotResumable, got: ", err)
+	}
+}
@@ -0,0 +1,187 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"golang.org/x/net/context"
+	"hash/crc32"
+	"io"
+)
+
+/*
+SeekToOffset positions the reader at the first valid record starting at or
+after the specified byte offset, relative to the position of the input
+stream when the reader was created. This allows splitting a large stream
+into byte ranges which are read in parallel, with every reader starting at
+the first record in its range. The input stream must implement Seeker.
+
+Since the stream contains no sync markers, a candidate record is considered
+valid if its length does not exceed the maximum record size and its data
+fits into the stream. If checksums are enabled, the checksum of the data
+must match as well. Resynchronizing is therefore only reliable with
+WithChecksum(); without checksums, the offset should already point at a
+record boundary. With WithFooter() and WithAlignment(), the footer and
+padding frames are recognized as well, so that the reader can be positioned
+at them rather than inside them; reading then skips the padding or ends at
+the footer as usual. Every candidate offset is tried in turn, so
+resynchronizing after corruption is slow.
+
+The index of the records is unknown after seeking, so Tell() reports an
+Index of -1 until SeekToRecord() is used. If no valid record is found before
+the end of the stream, io.EOF is returned.
+*/
+func (r *RecordReader) SeekToOffset(ctx context.Context, off int64) error {
+	var valid bool
+	var size int64
+	var err error
+
+	if r.closed {
+		return recordError(r.position, ErrClosed)
+	}
+
+	if err = r.seekTo(ctx, Position{Offset: off, Index: -1}); err != nil {
+		return recordError(r.position, err)
+	}
+	if size, err = r.streamSize(ctx); err != nil {
+		return recordError(r.position, err)
+	}
+
+	for {
+		if valid, err = r.probeRecord(ctx, size); err != nil {
+			return recordError(r.position, err)
+		}
+		if valid {
+			return nil
+		}
+
+		err = r.seekTo(ctx, Position{Offset: r.position.Offset + 1, Index: -1})
+		if err != nil {
+			return recordError(r.position, err)
+		}
+	}
+}
+
+/*
+streamSize returns the absolute offset of the end of the input stream,
+leaving the stream at its current position.
+*/
+func (r *RecordReader) streamSize(ctx context.Context) (int64, error) {
+	var seeker = r.wrappedReader.(Seeker)
+	var current, size int64
+	var err error
+
+	if current, err = seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	if size, err = seeker.Seek(ctx, 0, io.SeekEnd); err != nil {
+		return 0, err
+	}
+	if _, err = seeker.Seek(ctx, current, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+/*
+probeRecord checks whether a valid record starts at the current position of
+the input stream, which must be seekable and end at the specified absolute
+offset. The stream is returned to where it was afterwards. io.EOF is
+returned if the stream ends at the current position.
+*/
+func (r *RecordReader) probeRecord(ctx context.Context, size int64) (
+	bool, error) {
+	var seeker = r.wrappedReader.(Seeker)
+	var start, end int64
+	var length, crc uint32
+	var valid bool
+	var err error
+
+	if err = ctx.Err(); err != nil {
+		return false, err
+	}
+	if start, err = seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+		return false, err
+	}
+	if start >= size {
+		return false, io.EOF
+	}
+
+	length, crc, err = r.readHeader(ctx)
+	switch {
+	case err != nil:
+	case r.options.footer && length == footerMarker:
+		// The footer has to end exactly at the end of the stream.
+		if _, _, err = r.readFooter(ctx, crc); err == nil {
+			end, err = seeker.Seek(ctx, 0, io.SeekCurrent)
+			valid = err == nil && end == size
+		}
+	case r.options.alignment > 0 && length == paddingMarker:
+		valid = r.probePadding(ctx, size, crc)
+	case length <= r.options.maxRecordSize:
+		end, err = seeker.Seek(ctx, 0, io.SeekCurrent)
+		valid = err == nil && end+int64(length) <= size
+		if valid && r.options.checksum {
+			valid = r.bodyChecksum(ctx, int64(length)) == crc
+		}
+	}
+
+	if err = ctx.Err(); err != nil {
+		return false, err
+	}
+	if _, err = seeker.Seek(ctx, start, io.SeekStart); err != nil {
+		return false, err
+	}
+	return valid, nil
+}
+
+/*
+probePadding checks whether the remainder of a padding frame following its
+header is plausible, i.e. whether it fits into the stream ending at the
+specified absolute offset and, if checksums are enabled, its header carries
+the zero checksum written for padding.
+*/
+func (r *RecordReader) probePadding(ctx context.Context, size int64,
+	crc uint32) bool {
+	var seeker = r.wrappedReader.(Seeker)
+	var header [4]byte
+	var end int64
+	var err error
+
+	if r.options.checksum && crc != 0 {
+		return false
+	}
+	if _, err = readFull(ctx, r.wrappedReader, header[:]); err != nil {
+		return false
+	}
+	if end, err = seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+		return false
+	}
+	return end+int64(binary.BigEndian.Uint32(header[:])) <= size
+}
+
+/*
+bodyChecksum reads the next length bytes from the input stream and returns
+their checksum, without holding all of them in memory. Read errors are
+reported as a checksum which almost certainly doesn't match.
+*/
+func (r *RecordReader) bodyChecksum(ctx context.Context, length int64) uint32 {
+	var hash = crc32.New(crc32cTable)
+	var buf = scratchPool.Get().(*[streamBufferSize]byte)
+	var scratch = buf[:]
+	var n int
+	var err error
+
+	defer scratchPool.Put(buf)
+
+	for length > 0 {
+		if length < int64(len(scratch)) {
+			scratch = scratch[:length]
+		}
+		if n, err = readFull(ctx, r.wrappedReader, scratch); err != nil {
+			return ^hash.Sum32()
+		}
+		hash.Write(scratch[:n])
+		length -= int64(n)
+	}
+
+	return hash.Sum32()
+}
@@ -0,0 +1,127 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Seeking to arbitrary offsets must find the next record boundary when
+checksums are enabled.
+*/
+func TestSeekToOffset(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum())
+	var reader *RecordReader
+	var offsets []int64
+	var rec []byte
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		offsets = append(offsets, writer.Offset())
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()), WithChecksum())
+	for _, step := range []struct {
+		offset   int64
+		expected string
+	}{
+		{0, "One"},
+		{offsets[1], "Two"},
+		{offsets[1] + 1, "Three"},
+		{offsets[3] - 1, "Four"},
+	} {
+		if err = reader.SeekToOffset(ctx, step.offset); err != nil {
+			t.Error("Error seeking to offset ", step.offset, ": ", err)
+		}
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != step.expected {
+			t.Errorf("Unexpected data at offset %d: got %q, expected %s",
+				step.offset, rec, step.expected)
+		}
+		if reader.Tell().Index != -1 {
+			t.Error("Unexpected index: ", reader.Tell().Index)
+		}
+	}
+
+	if err = reader.SeekToOffset(ctx, offsets[3]+1); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+
+	if err = reader.SeekToRecord(ctx, 1); err != nil {
+		t.Error("Error seeking to record 1: ", err)
+	}
+	if reader.Tell() != (Position{Offset: offsets[1], Index: 1}) {
+		t.Error("Unexpected position: ", reader.Tell())
+	}
+}
+
+/*
+Seeking to the footer or to a padding frame must position the reader there
+rather than inside them.
+*/
+func TestSeekToOffsetFrames(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var opts = []Option{WithChecksum(), WithFooter()}
+	var writer = NewIORecordWriter(&buf, opts...)
+	var reader *RecordReader
+	var offset int64
+	var rec []byte
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("One")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	offset = writer.Offset()
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()), opts...)
+	if err = reader.SeekToOffset(ctx, offset); err != nil {
+		t.Error("Error seeking to the footer: ", err)
+	}
+	if reader.Tell().Offset != offset {
+		t.Error("Unexpected position: ", reader.Tell())
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF at the footer, got: ", err)
+	}
+
+	buf.Reset()
+	opts = []Option{WithChecksum(), WithAlignment(64)}
+	writer = NewIORecordWriter(&buf, opts...)
+	if _, err = writer.Write(ctx, []byte("One")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	offset = writer.Offset()
+	if err = writer.Flush(ctx); err != nil {
+		t.Error("Error flushing writer: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("Two")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()), opts...)
+	if err = reader.SeekToOffset(ctx, offset); err != nil {
+		t.Error("Error seeking to the padding: ", err)
+	}
+	if reader.Tell().Offset != offset {
+		t.Error("Unexpected position: ", reader.Tell())
+	}
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Two" {
+		t.Errorf("Unexpected record after padding: %q, %v", rec, err)
+	}
+}
@@ -0,0 +1,356 @@
+package recordio
+
+import (
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+	"time"
+)
+
+/*
+RetryPolicy configures how WithRetry() retries failed reads and writes.
+*/
+type RetryPolicy struct {
+	/*
+		Attempts is the maximum number of attempts of every read or write,
+		including the first one. Values below 2 disable retries.
+	*/
+	Attempts int
+
+	/*
+		Backoff is the delay before the first retry, which doubles with
+		every further retry up to MaxBackoff, if it is set.
+	*/
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+
+	/*
+		Retryable decides which errors are transient and worth retrying.
+		If it is nil, errors with a Temporary() or Timeout() method
+		returning true are retried, such as the errors of the net
+		package.
+	*/
+	Retryable func(err error) bool
+}
+
+/*
+WithRetry makes readers and writers retry reads and writes of the underlying
+stream which fail with transient errors, as classified by the policy, so
+that record loops do not have to handle flaky storage themselves. Retries
+resume at the exact byte where the failed call left off, so the framing of
+the stream stays intact: if the stream implements Seeker, it is seeked back
+to the end of the data read or written successfully before retrying, and
+output streams implementing Truncater are also cut off there, removing
+anything a failed write may have left behind.
+
+Flushing and syncing are retried as well. io.EOF, errors of the context and
+errors which are not retryable are returned immediately, as is the last
+error once all attempts have failed.
+*/
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = policy
+	}
+}
+
+/*
+retry decides whether the operation which failed with err in the specified
+attempt should be retried, and waits for the backoff if so. It returns nil
+to retry, or the error to return otherwise.
+*/
+func (p RetryPolicy) retry(ctx context.Context, attempt int, err error) error {
+	var delay = p.Backoff
+	var timer *time.Timer
+
+	if attempt >= p.Attempts || err == io.EOF || ctx.Err() != nil ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if p.Retryable != nil && !p.Retryable(err) ||
+		p.Retryable == nil && !temporary(err) {
+		return err
+	}
+
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && (delay > p.MaxBackoff || delay < p.Backoff) {
+		delay = p.MaxBackoff
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer = time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*
+temporary returns whether err reports itself as temporary or as a timeout.
+*/
+func temporary(err error) bool {
+	var temp interface{ Temporary() bool }
+	var timeout interface{ Timeout() bool }
+
+	return errors.As(err, &temp) && temp.Temporary() ||
+		errors.As(err, &timeout) && timeout.Timeout()
+}
+
+/*
+retryingReader retries failed reads of an input stream.
+*/
+type retryingReader struct {
+	reader filesystem.ReadCloser
+	seeker Seeker
+	policy RetryPolicy
+
+	// offset is the absolute offset of the end of the data transferred
+	// successfully, once known is set. positioned is set while the
+	// stream is at offset.
+	offset     int64
+	known      bool
+	positioned bool
+}
+
+/*
+retryingSeekReader is a retryingReader for input streams implementing
+Seeker, which implements Seeker as well.
+*/
+type retryingSeekReader struct {
+	*retryingReader
+}
+
+/*
+retryingReadCloser wraps reader into a retryingReader, or returns it
+unchanged if the policy does not allow retries.
+*/
+func retryingReadCloser(reader filesystem.ReadCloser,
+	policy RetryPolicy) filesystem.ReadCloser {
+	var r *retryingReader
+
+	if policy.Attempts < 2 {
+		return reader
+	}
+
+	r = &retryingReader{reader: reader, policy: policy}
+	if seeker, ok := reader.(Seeker); ok {
+		r.seeker = seeker
+		return &retryingSeekReader{r}
+	}
+	return r
+}
+
+func (r *retryingReader) Read(ctx context.Context, p []byte) (int, error) {
+	var n int
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = r.position(ctx); err == nil {
+			n, err = r.reader.Read(ctx, p)
+			r.offset += int64(n)
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if err = r.policy.retry(ctx, attempt, err); err != nil {
+			return n, err
+		}
+		// Return the data read so far; the next read retries.
+		r.positioned = false
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+/*
+position learns the offset of the input stream before the first read, and
+seeks back to the end of the data read so far after a failed read. Streams
+which are not seekable are left alone.
+*/
+func (r *retryingReader) position(ctx context.Context) error {
+	var err error
+
+	if r.seeker == nil || r.positioned {
+		return nil
+	}
+	if !r.known {
+		r.offset, err = r.seeker.Seek(ctx, 0, io.SeekCurrent)
+		r.known = err == nil
+	} else {
+		_, err = r.seeker.Seek(ctx, r.offset, io.SeekStart)
+	}
+	r.positioned = err == nil
+	return err
+}
+
+func (r *retryingReader) Close(ctx context.Context) error {
+	return r.reader.Close(ctx)
+}
+
+func (r *retryingSeekReader) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	var abs, err = r.seeker.Seek(ctx, offset, whence)
+
+	if err == nil {
+		r.offset, r.known, r.positioned = abs, true, true
+	} else {
+		r.positioned = false
+	}
+	return abs, err
+}
+
+/*
+retryingWriter retries failed writes, flushes and syncs of an output
+stream.
+*/
+type retryingWriter struct {
+	writer filesystem.WriteCloser
+	seeker Seeker
+	policy RetryPolicy
+
+	// offset is the absolute offset of the end of the data transferred
+	// successfully, once known is set. positioned is set while the
+	// stream is at offset.
+	offset     int64
+	known      bool
+	positioned bool
+}
+
+/*
+retryingSeekWriter is a retryingWriter for output streams implementing
+Seeker, which implements Seeker and Truncater as well.
+*/
+type retryingSeekWriter struct {
+	*retryingWriter
+}
+
+/*
+retryingWriteCloser wraps writer into a retryingWriter, or returns it
+unchanged if the policy does not allow retries.
+*/
+func retryingWriteCloser(writer filesystem.WriteCloser,
+	policy RetryPolicy) filesystem.WriteCloser {
+	var w *retryingWriter
+
+	if policy.Attempts < 2 {
+		return writer
+	}
+
+	w = &retryingWriter{writer: writer, policy: policy}
+	if seeker, ok := writer.(Seeker); ok {
+		w.seeker = seeker
+		return &retryingSeekWriter{w}
+	}
+	return w
+}
+
+func (w *retryingWriter) Write(ctx context.Context, p []byte) (int, error) {
+	var written, n int
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = w.position(ctx); err == nil {
+			n, err = w.writer.Write(ctx, p[written:])
+			written += n
+			w.offset += int64(n)
+		}
+		if err == nil {
+			return written, nil
+		}
+		if err = w.policy.retry(ctx, attempt, err); err != nil {
+			return written, err
+		}
+		w.positioned = false
+	}
+}
+
+/*
+position learns the offset of the output stream before the first write, and
+seeks back to the end of the data written successfully after a failed
+write, cutting off anything written beyond it. Streams which are not
+seekable are left alone.
+*/
+func (w *retryingWriter) position(ctx context.Context) error {
+	var err error
+
+	if w.seeker == nil || w.positioned {
+		return nil
+	}
+	if !w.known {
+		w.offset, err = w.seeker.Seek(ctx, 0, io.SeekCurrent)
+		w.known = err == nil
+	} else if _, err = w.seeker.Seek(ctx, w.offset, io.SeekStart); err == nil {
+		if truncater, ok := w.writer.(Truncater); ok {
+			err = truncater.Truncate(ctx, w.offset)
+		}
+	}
+	w.positioned = err == nil
+	return err
+}
+
+func (w *retryingWriter) Flush(ctx context.Context) error {
+	return w.repeat(ctx, func() error {
+		if flusher, ok := w.writer.(Flusher); ok {
+			return flusher.Flush(ctx)
+		}
+		return nil
+	})
+}
+
+func (w *retryingWriter) Sync(ctx context.Context) error {
+	return w.repeat(ctx, func() error {
+		if syncer, ok := w.writer.(Syncer); ok {
+			return syncer.Sync(ctx)
+		}
+		return ErrSyncUnsupported
+	})
+}
+
+/*
+repeat calls fn until it succeeds or the policy gives up.
+*/
+func (w *retryingWriter) repeat(ctx context.Context, fn func() error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if err = w.policy.retry(ctx, attempt, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *retryingWriter) Close(ctx context.Context) error {
+	return w.writer.Close(ctx)
+}
+
+func (w *retryingSeekWriter) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	var abs, err = w.seeker.Seek(ctx, offset, whence)
+
+	if err == nil {
+		w.offset, w.known, w.positioned = abs, true, true
+	} else {
+		w.positioned = false
+	}
+	return abs, err
+}
+
+func (w *retryingSeekWriter) Truncate(ctx context.Context, size int64) error {
+	if truncater, ok := w.writer.(Truncater); ok {
+		return truncater.Truncate(ctx, size)
+	}
+	return nil
+}
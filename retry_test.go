@@ -0,0 +1,104 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"os"
+	"testing"
+	"time"
+)
+
+/*
+temporaryError is a transient error as returned by network file systems.
+*/
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "Temporary failure" }
+func (temporaryError) Temporary() bool { return true }
+
+/*
+flakyReader fails every other read after consuming a byte of the stream.
+*/
+type flakyReader struct {
+	*bytes.Reader
+	reads int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.reads++; f.reads%2 == 0 {
+		f.Reader.ReadByte()
+		return 0, temporaryError{}
+	}
+	return f.Reader.Read(p[:len(p)/2+1])
+}
+
+/*
+flakyFile fails every other write after writing half of the data.
+*/
+type flakyFile struct {
+	*os.File
+	writes int
+}
+
+func (f *flakyFile) Write(p []byte) (int, error) {
+	if f.writes++; f.writes%2 == 0 {
+		f.File.Write(p[:len(p)/2])
+		return 0, temporaryError{}
+	}
+	return f.File.Write(p)
+}
+
+/*
+Failed reads and writes must be retried at the right offset, so that all
+records survive intact, and errors which are not retryable must be returned
+right away.
+*/
+func TestRetry(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var policy = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	var reader *RecordReader
+	var writer *RecordWriter
+	var file *os.File
+	var report VerifyReport
+	var recs [][]byte
+	var err error
+
+	reader = NewIORecordReader(&flakyReader{Reader: bytes.NewReader(data)},
+		WithChecksum(), WithRetry(policy))
+	if recs, err = reader.ReadAll(ctx); err != nil || len(recs) != 10 {
+		t.Errorf("Unexpected result reading: %d records (%v)", len(recs), err)
+	}
+
+	if file, err = os.CreateTemp(t.TempDir(), "retry"); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	writer = NewIORecordWriter(&flakyFile{File: file}, WithChecksum(),
+		WithFooter(), WithRetry(policy))
+	for _, rec := range recs {
+		if _, err = writer.Write(ctx, rec); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	if file, err = os.Open(file.Name()); err != nil {
+		t.Fatal("Error opening file: ", err)
+	}
+	defer file.Close()
+	report, err = NewIORecordReader(file, WithChecksum(),
+		WithFooter()).Verify(ctx)
+	if err != nil || !report.OK() || report.Records != 10 {
+		t.Errorf("Unexpected verification result: %+v (%v)", report, err)
+	}
+
+	policy.Retryable = func(error) bool { return false }
+	reader = NewIORecordReader(&flakyReader{Reader: bytes.NewReader(data)},
+		WithChecksum(), WithRetry(policy))
+	if _, err = reader.ReadAll(ctx); !errors.As(err, &temporaryError{}) {
+		t.Error("Expected temporary error, got: ", err)
+	}
+}
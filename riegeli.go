@@ -0,0 +1,399 @@
+package recordio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/andybalholm/brotli"
+	"github.com/childoftheuniverse/filesystem"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/net/context"
+	"io"
+)
+
+const (
+	riegeliBlockSize       = 1 << 16
+	riegeliBlockHeaderSize = 24
+	riegeliChunkHeaderSize = 40
+
+	riegeliChunkSignature  = 's'
+	riegeliChunkMetadata   = 'm'
+	riegeliChunkPadding    = 'p'
+	riegeliChunkSimple     = 'r'
+	riegeliChunkTransposed = 't'
+
+	riegeliCompressionNone   = 0
+	riegeliCompressionBrotli = 'b'
+	riegeliCompressionZstd   = 'z'
+	riegeliCompressionSnappy = 's'
+
+	// riegeliReadIncrement is the largest amount of chunk data allocated
+	// ahead of reading it, so that corrupted chunk headers claiming huge
+	// sizes cannot exhaust memory before the end of the file is reached.
+	riegeliReadIncrement = 1 << 20
+)
+
+/*
+ErrUnsupportedRiegeliChunk is returned by RiegeliReader when it encounters a
+chunk it cannot decode, i.e. one of an unknown chunk type or using an
+unknown compression type.
+*/
+var ErrUnsupportedRiegeliChunk = errors.New("Unsupported Riegeli chunk")
+
+/*
+RiegeliReader reads records from a Riegeli record file. Records are returned
+through the same ReadRecord()/ReadMessage() API as RecordReader provides.
+
+Both simple and transposed chunks are decoded, uncompressed or compressed
+with brotli, zstd or snappy, following the Riegeli format specification.
+The decoder has only been tested against chunks built by hand rather than
+files written by the reference implementation, so compatibility with all
+files it writes, particularly transposed chunks of nested messages, is not
+guaranteed. The highway hashes protecting block and chunk
+headers are not verified, so the same warnings about only reading trusted
+data as for RecordReader apply. Sizes read from the file are checked before
+memory is allocated for them, though: chunk data is only allocated as it is
+read, and WithMaxRecordSize() limits both the size of records and the
+decompressed size of every compressed part of a chunk, which defaults to
+4 GiB - 1 bytes.
+*/
+type RiegeliReader struct {
+	wrappedReader filesystem.ReadCloser
+	options       options
+	pos           int64
+	records       [][]byte
+	started       bool
+}
+
+/*
+NewRiegeliReader creates a new RiegeliReader wrapped around the specified
+input stream, which must be positioned at the beginning of the file. Of the
+options, only WithMaxRecordSize(), WithDiscardUnknown() and the options
+controlling how messages are parsed apply. No actions are performed at the
+time.
+*/
+func NewRiegeliReader(reader filesystem.ReadCloser,
+	opts ...Option) *RiegeliReader {
+	return &RiegeliReader{
+		wrappedReader: reader,
+		options:       applyOptions(opts),
+	}
+}
+
+/*
+ReadRecord returns the next record from the Riegeli file, decoding further
+chunks as required. io.EOF is returned at the end of the file.
+*/
+func (r *RiegeliReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var rec []byte
+	var err error
+
+	for len(r.records) == 0 {
+		if err = r.readChunk(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	rec = r.records[0]
+	r.records = r.records[1:]
+	return rec, nil
+}
+
+/*
+ReadMessage reads the next record from the Riegeli file and parses it as the
+protocol buffer passed in.
+*/
+func (r *RiegeliReader) ReadMessage(ctx context.Context, pb Message) error {
+	var buf []byte
+	var err error
+
+	buf, err = r.ReadRecord(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.options.unmarshalMessage(buf, pb)
+}
+
+/*
+Close just delegates to the close function of the underlying reader.
+*/
+func (r *RiegeliReader) Close(ctx context.Context) error {
+	return r.wrappedReader.Close(ctx)
+}
+
+/*
+readChunk reads the next chunk from the file and queues up the records
+contained in it.
+*/
+func (r *RiegeliReader) readChunk(ctx context.Context) error {
+	var header = make([]byte, riegeliChunkHeaderSize)
+	var chunkBegin = r.pos
+	var dataSize, numRecords, chunkEnd int64
+	var decodedSize uint64
+	var chunkType byte
+	var data []byte
+	var err error
+
+	if _, err = r.readLogical(ctx, header); err != nil {
+		return err
+	}
+
+	dataSize = int64(binary.LittleEndian.Uint64(header[8:16]))
+	chunkType = header[24]
+	numRecords = int64(binary.LittleEndian.Uint64(header[24:32]) >> 8)
+	decodedSize = binary.LittleEndian.Uint64(header[32:40])
+
+	if !r.started {
+		if chunkType != riegeliChunkSignature {
+			return errors.New("Not a Riegeli record file")
+		}
+		r.started = true
+	}
+
+	if dataSize < 0 || numRecords < 0 {
+		return errors.New("Invalid Riegeli chunk header")
+	}
+
+	if data, err = r.readData(ctx, dataSize); err != nil {
+		return noEOF(err)
+	}
+
+	// Chunks are padded so that they span at least as many bytes as they
+	// contain records.
+	chunkEnd = chunkBegin + numRecords
+	if chunkEnd > r.pos {
+		if err = r.skipPhysical(ctx, chunkEnd-r.pos); err != nil {
+			return noEOF(err)
+		}
+	}
+	if off := r.pos % riegeliBlockSize; off > 0 && off < riegeliBlockHeaderSize {
+		if err = r.skipPhysical(ctx, riegeliBlockHeaderSize-off); err != nil {
+			return noEOF(err)
+		}
+	}
+
+	switch chunkType {
+	case riegeliChunkSignature, riegeliChunkMetadata, riegeliChunkPadding:
+		return nil
+	case riegeliChunkSimple:
+		r.records, err = decodeRiegeliSimpleChunk(data, numRecords,
+			r.options.maxRecordSize)
+		return err
+	case riegeliChunkTransposed:
+		r.records, err = decodeRiegeliTransposedChunk(data, numRecords,
+			decodedSize, r.options.maxRecordSize)
+		return err
+	default:
+		return ErrUnsupportedRiegeliChunk
+	}
+}
+
+/*
+readData reads size bytes of chunk data. The buffer is grown as the data
+is read rather than allocated upfront, so a corrupted size fails with an
+unexpected end of file rather than exhausting memory.
+*/
+func (r *RiegeliReader) readData(ctx context.Context, size int64) ([]byte,
+	error) {
+	var data []byte
+	var n int64
+	var err error
+
+	for int64(len(data)) < size {
+		if n = size - int64(len(data)); n > riegeliReadIncrement {
+			n = riegeliReadIncrement
+		}
+		data = append(data, make([]byte, n)...)
+		if _, err = r.readLogical(ctx, data[int64(len(data))-n:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+/*
+readLogical fills buf with chunk data, skipping over the block headers which
+interrupt the data at every block boundary.
+*/
+func (r *RiegeliReader) readLogical(ctx context.Context, buf []byte) (
+	int, error) {
+	var total, n, remaining int
+	var err error
+
+	for total < len(buf) {
+		if r.pos%riegeliBlockSize == 0 {
+			if err = r.skipPhysical(ctx, riegeliBlockHeaderSize); err != nil {
+				if total > 0 {
+					err = noEOF(err)
+				}
+				return total, err
+			}
+		}
+
+		n = len(buf) - total
+		remaining = riegeliBlockSize - int(r.pos%riegeliBlockSize)
+		if n > remaining {
+			n = remaining
+		}
+
+		n, err = readFull(ctx, r.wrappedReader, buf[total:total+n])
+		r.pos += int64(n)
+		total += n
+		if err != nil {
+			if total > 0 {
+				err = noEOF(err)
+			}
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+/*
+skipPhysical discards the specified number of bytes from the underlying
+stream without regard for block boundaries.
+*/
+func (r *RiegeliReader) skipPhysical(ctx context.Context, length int64) error {
+	var scratch = make([]byte, riegeliBlockHeaderSize)
+	var n int
+	var err error
+
+	for length > 0 {
+		if length < int64(len(scratch)) {
+			scratch = scratch[:length]
+		}
+		n, err = readFull(ctx, r.wrappedReader, scratch)
+		r.pos += int64(n)
+		length -= int64(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/*
+decodeRiegeliSimpleChunk splits the data of a simple chunk into its records.
+The record sizes and the record data are compressed separately; neither may
+decompress to more than limit bytes.
+*/
+func decodeRiegeliSimpleChunk(data []byte, numRecords int64,
+	limit uint32) ([][]byte, error) {
+	var records [][]byte
+	var sizesSize, size uint64
+	var compression byte
+	var sizes, values []byte
+	var n int
+	var i int64
+	var err error
+
+	if len(data) < 1 {
+		return nil, errors.New("Truncated Riegeli simple chunk")
+	}
+	compression = data[0]
+	data = data[1:]
+
+	sizesSize, n = binary.Uvarint(data)
+	if n <= 0 || sizesSize > uint64(len(data)-n) {
+		return nil, errors.New("Invalid Riegeli record sizes")
+	}
+	if sizes, err = riegeliDecompress(compression,
+		data[n:n+int(sizesSize)], limit); err != nil {
+		return nil, err
+	}
+	if values, err = riegeliDecompress(compression,
+		data[n+int(sizesSize):], limit); err != nil {
+		return nil, err
+	}
+
+	// Every size takes at least one byte, which bounds the number of
+	// records before allocating memory for them.
+	if numRecords > int64(len(sizes)) {
+		return nil, errors.New("Invalid Riegeli record count")
+	}
+
+	records = make([][]byte, 0, numRecords)
+	for i = 0; i < numRecords; i++ {
+		size, n = binary.Uvarint(sizes)
+		if n <= 0 || size > uint64(len(values)) {
+			return nil, errors.New("Invalid Riegeli record sizes")
+		}
+		if size > uint64(limit) {
+			return nil, ErrRecordTooLarge
+		}
+		sizes = sizes[n:]
+		records = append(records, values[:size:size])
+		values = values[size:]
+	}
+
+	if len(values) > 0 {
+		return nil, errors.New("Trailing data in Riegeli simple chunk")
+	}
+
+	return records, nil
+}
+
+/*
+riegeliDecompress decompresses a part of a chunk compressed with the
+specified compression type. Compressed data is prefixed with its
+decompressed size, which must not exceed limit.
+*/
+func riegeliDecompress(compression byte, data []byte, limit uint32) (
+	[]byte, error) {
+	var decompressor io.Reader
+	var decoder *zstd.Decoder
+	var size uint64
+	var decompressed []byte
+	var n int
+	var err error
+
+	if compression == riegeliCompressionNone {
+		return data, nil
+	}
+
+	if size, n = binary.Uvarint(data); n <= 0 {
+		return nil, errors.New("Invalid Riegeli decompressed size")
+	}
+	if size > uint64(limit) {
+		return nil, ErrRecordTooLarge
+	}
+	data = data[n:]
+
+	switch compression {
+	case riegeliCompressionBrotli:
+		decompressor = brotli.NewReader(bytes.NewReader(data))
+	case riegeliCompressionZstd:
+		if decoder, err = zstd.NewReader(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+		decompressor = decoder
+	case riegeliCompressionSnappy:
+		if n, err = snappy.DecodedLen(data); err != nil {
+			return nil, err
+		}
+		if uint64(n) != size {
+			return nil, errors.New("Riegeli decompressed size mismatch")
+		}
+		return snappy.Decode(nil, data)
+	default:
+		return nil, ErrUnsupportedRiegeliChunk
+	}
+
+	// Read one byte more than expected to detect data decompressing to
+	// more than the announced size.
+	decompressed, err = io.ReadAll(io.LimitReader(decompressor,
+		int64(size)+1))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(decompressed)) != size {
+		return nil, errors.New("Riegeli decompressed size mismatch")
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,301 @@
+package recordio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/andybalholm/brotli"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/net/context"
+	"io"
+	"math"
+	"testing"
+)
+
+/*
+Build a Riegeli chunk of the specified type. Hashes are left empty since
+RiegeliReader doesn't verify them.
+*/
+func riegeliTestChunk(chunkType byte, numRecords uint64, data []byte) []byte {
+	var chunk = make([]byte, riegeliChunkHeaderSize,
+		riegeliChunkHeaderSize+len(data))
+
+	binary.LittleEndian.PutUint64(chunk[8:16], uint64(len(data)))
+	binary.LittleEndian.PutUint64(chunk[24:32],
+		numRecords<<8|uint64(chunkType))
+	return append(chunk, data...)
+}
+
+/*
+Build a simple chunk containing the specified records without compression.
+*/
+func riegeliTestSimpleChunk(records ...string) []byte {
+	var sizes, data []byte
+	var tmp [binary.MaxVarintLen64]byte
+
+	for _, rec := range records {
+		sizes = append(sizes,
+			tmp[:binary.PutUvarint(tmp[:], uint64(len(rec)))]...)
+	}
+
+	data = append(data, 0)
+	data = append(data, tmp[:binary.PutUvarint(tmp[:], uint64(len(sizes)))]...)
+	data = append(data, sizes...)
+	for _, rec := range records {
+		data = append(data, rec...)
+	}
+
+	return riegeliTestChunk(riegeliChunkSimple, uint64(len(records)), data)
+}
+
+/*
+Read records from a hand-built Riegeli file with a signature chunk and a
+simple chunk.
+*/
+func TestRiegeliSimpleChunk(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var reader *RiegeliReader
+	var rec []byte
+	var err error
+
+	buf.Write(ctx, make([]byte, riegeliBlockHeaderSize))
+	buf.Write(ctx, riegeliTestChunk(riegeliChunkSignature, 0, nil))
+	buf.Write(ctx, riegeliTestSimpleChunk("Hello", "", "World"))
+	buf.Close(ctx)
+
+	reader = NewRiegeliReader(buf)
+
+	for _, expected := range []string{"Hello", "", "World"} {
+		rec, err = reader.ReadRecord(ctx)
+		if err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != expected {
+			t.Errorf("Unexpected data: got %q, expected %q", rec, expected)
+		}
+	}
+
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+}
+
+/*
+Check that chunks spanning a block boundary are read correctly, skipping the
+block header in the middle of the chunk.
+*/
+func TestRiegeliBlockBoundary(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var reader *RiegeliReader
+	var large = make([]byte, riegeliBlockSize)
+	var chunk []byte
+	var rec []byte
+	var err error
+
+	for i := range large {
+		large[i] = byte(i)
+	}
+
+	chunk = append(make([]byte, riegeliBlockHeaderSize),
+		riegeliTestChunk(riegeliChunkSignature, 0, nil)...)
+	chunk = append(chunk, riegeliTestSimpleChunk(string(large), "End")...)
+
+	// Insert a block header at the block boundary.
+	buf.Write(ctx, chunk[:riegeliBlockSize])
+	buf.Write(ctx, make([]byte, riegeliBlockHeaderSize))
+	buf.Write(ctx, chunk[riegeliBlockSize:])
+	buf.Close(ctx)
+
+	reader = NewRiegeliReader(buf)
+
+	rec, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Fatal("Error reading record: ", err)
+	}
+	if string(rec) != string(large) {
+		t.Error("Large record was not read back correctly")
+	}
+
+	rec, err = reader.ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "End" {
+		t.Errorf("Unexpected data: got %q, expected \"End\"", rec)
+	}
+}
+
+/*
+Decode a transposed chunk containing two protocol buffers, one of them with
+a varint stored in a buffer and a string field, followed by a record which
+is not a protocol buffer.
+*/
+func TestRiegeliTransposedChunk(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var reader *RiegeliReader
+	var header = []byte{
+		1, 4, // Buckets, buffers
+		9,          // Bucket lengths
+		3, 2, 3, 1, // Buffer lengths
+		5,                      // State machine size
+		0x08, 2, 0x12, 0x08, 1, // Tags
+		0, 0, 8, 0, 0, // Next nodes
+		13, 0, 1, // Subtypes
+		0, 1, 2, // Buffer indexes
+		4, // First node
+	}
+	var bucket = []byte{2, 'h', 'i', 0x16, 0x01, 'x', 'y', 'z', 3}
+	var transitions = []byte{0, 4, 8, 4}
+	var expected = []string{
+		"\x08\x96\x01\x12\x02hi",
+		"\x08\x03",
+		"xyz",
+	}
+	var data, chunk, rec []byte
+	var err error
+
+	data = append(data, 0, byte(len(header)))
+	data = append(data, header...)
+	data = append(data, bucket...)
+	data = append(data, transitions...)
+	chunk = riegeliTestChunk(riegeliChunkTransposed, 3, data)
+	binary.LittleEndian.PutUint64(chunk[32:40], 12)
+
+	buf.Write(ctx, make([]byte, riegeliBlockHeaderSize))
+	buf.Write(ctx, riegeliTestChunk(riegeliChunkSignature, 0, nil))
+	buf.Write(ctx, chunk)
+	buf.Close(ctx)
+
+	reader = NewRiegeliReader(buf)
+
+	for _, exp := range expected {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		if string(rec) != exp {
+			t.Errorf("Unexpected record: got %q, expected %q", rec, exp)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+}
+
+/*
+Transposed chunks decoding to a different size than announced in the chunk
+header must be rejected.
+*/
+func TestRiegeliTransposedChunkSizeMismatch(t *testing.T) {
+	var header = []byte{0, 0, 1, 2, 0, 0}
+	var data []byte
+	var err error
+
+	data = append(data, 0, byte(len(header)))
+	data = append(data, header...)
+
+	if _, err = decodeRiegeliTransposedChunk(data, 1, 5,
+		math.MaxUint32); err != ErrInvalidRiegeliTransposedChunk {
+		t.Error("Expected ErrInvalidRiegeliTransposedChunk, got ", err)
+	}
+	if _, err = decodeRiegeliTransposedChunk(data, 1, 0,
+		math.MaxUint32); err != nil {
+		t.Error("Error decoding empty message: ", err)
+	}
+}
+
+/*
+Read simple chunks whose sizes and values are compressed with each of the
+supported compression types.
+*/
+func TestRiegeliCompressedSimpleChunk(t *testing.T) {
+	var ctx = context.Background()
+	var compressors = map[byte]func(data []byte) []byte{
+		riegeliCompressionBrotli: func(data []byte) []byte {
+			var out bytes.Buffer
+			var w = brotli.NewWriter(&out)
+
+			w.Write(data)
+			w.Close()
+			return out.Bytes()
+		},
+		riegeliCompressionZstd: func(data []byte) []byte {
+			var enc, _ = zstd.NewWriter(nil)
+
+			return enc.EncodeAll(data, nil)
+		},
+		riegeliCompressionSnappy: func(data []byte) []byte {
+			return snappy.Encode(nil, data)
+		},
+	}
+
+	for compression, compress := range compressors {
+		var buf = internal.NewAnonymousFile()
+		var reader *RiegeliReader
+		var sizes, values, data []byte
+		var tmp [binary.MaxVarintLen64]byte
+		var rec []byte
+		var err error
+
+		sizes = append([]byte{2}, compress([]byte{5, 5})...)
+		values = append([]byte{10}, compress([]byte("HelloWorld"))...)
+
+		data = append(data, compression)
+		data = append(data, tmp[:binary.PutUvarint(tmp[:],
+			uint64(len(sizes)))]...)
+		data = append(data, sizes...)
+		data = append(data, values...)
+
+		buf.Write(ctx, make([]byte, riegeliBlockHeaderSize))
+		buf.Write(ctx, riegeliTestChunk(riegeliChunkSignature, 0, nil))
+		buf.Write(ctx, riegeliTestChunk(riegeliChunkSimple, 2, data))
+		buf.Close(ctx)
+
+		reader = NewRiegeliReader(buf)
+
+		for _, exp := range []string{"Hello", "World"} {
+			if rec, err = reader.ReadRecord(ctx); err != nil {
+				t.Fatalf("Error reading record compressed with %q: %v",
+					compression, err)
+			}
+			if string(rec) != exp {
+				t.Errorf("Unexpected record compressed with %q: got %q, "+
+					"expected %q", compression, rec, exp)
+			}
+		}
+	}
+}
+
+/*
+Sizes in corrupted chunks must be checked against the data actually
+available before allocating memory for them.
+*/
+func TestRiegeliCorruptSizes(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var reader *RiegeliReader
+	var chunk []byte
+	var err error
+
+	chunk = riegeliTestSimpleChunk("Hello")
+	binary.LittleEndian.PutUint64(chunk[8:16], 1<<60)
+
+	buf.Write(ctx, make([]byte, riegeliBlockHeaderSize))
+	buf.Write(ctx, riegeliTestChunk(riegeliChunkSignature, 0, nil))
+	buf.Write(ctx, chunk)
+	buf.Close(ctx)
+
+	reader = NewRiegeliReader(buf)
+
+	if _, err = reader.ReadRecord(ctx); err != io.ErrUnexpectedEOF {
+		t.Error("Expected io.ErrUnexpectedEOF, got ", err)
+	}
+
+	if _, err = decodeRiegeliSimpleChunk(chunk[riegeliChunkHeaderSize:],
+		1<<60, math.MaxUint32); err == nil {
+		t.Error("Expected error for too many records in chunk")
+	}
+}
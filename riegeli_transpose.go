@@ -0,0 +1,612 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+/*
+Reserved tags of the state machine of transposed chunks, which denote
+operations other than writing a field. Since field number 0 is invalid,
+they cannot collide with the tags of actual fields.
+*/
+const (
+	riegeliTagNoOp              = 0
+	riegeliTagNonProto          = 1
+	riegeliTagStartOfMessage    = 2
+	riegeliTagStartOfSubmessage = 3
+)
+
+/*
+Subtypes refining the state machine nodes of varint and length delimited
+fields. Varints of up to riegeliMaxVarintInline are stored in the subtype
+itself; longer ones are stored in a buffer, with the subtype giving their
+length.
+*/
+const (
+	riegeliSubtypeVarint1        = 0
+	riegeliSubtypeVarintInline0  = binary.MaxVarintLen64
+	riegeliMaxVarintInline       = 3
+	riegeliSubtypeString         = 0
+	riegeliSubtypeStartOfMessage = 1
+	riegeliSubtypeEndOfMessage   = 2
+)
+
+/*
+Protocol buffer wire types.
+*/
+const (
+	wireVarint     = 0
+	wireFixed64    = 1
+	wireBytes      = 2
+	wireStartGroup = 3
+	wireEndGroup   = 4
+	wireFixed32    = 5
+)
+
+/*
+ErrInvalidRiegeliTransposedChunk is returned if the data of a transposed
+chunk is inconsistent.
+*/
+var ErrInvalidRiegeliTransposedChunk = errors.New(
+	"Invalid Riegeli transposed chunk")
+
+/*
+riegeliNode is a node of the state machine of a transposed chunk. Executing
+it writes a field, or parts of one, using the tag and data from its buffer.
+implicit nodes are followed by their next node without consuming a
+transition.
+*/
+type riegeliNode struct {
+	tag      uint32
+	subtype  byte
+	buffer   int
+	next     int
+	implicit bool
+}
+
+/*
+riegeliSource is a cursor over a decompressed part of a transposed chunk.
+*/
+type riegeliSource struct {
+	data []byte
+}
+
+/*
+varint reads a varint from the source.
+*/
+func (s *riegeliSource) varint() (uint64, error) {
+	var v, n = binary.Uvarint(s.data)
+
+	if n <= 0 {
+		return 0, ErrInvalidRiegeliTransposedChunk
+	}
+	s.data = s.data[n:]
+	return v, nil
+}
+
+/*
+varint32 reads a varint from the source which must fit into 32 bits.
+*/
+func (s *riegeliSource) varint32() (uint32, error) {
+	var v, err = s.varint()
+
+	if err == nil && v > math.MaxUint32 {
+		err = ErrInvalidRiegeliTransposedChunk
+	}
+	return uint32(v), err
+}
+
+/*
+bytes reads n bytes from the source.
+*/
+func (s *riegeliSource) bytes(n uint64) ([]byte, error) {
+	var b []byte
+
+	if n > uint64(len(s.data)) {
+		return nil, ErrInvalidRiegeliTransposedChunk
+	}
+	b, s.data = s.data[:n:n], s.data[n:]
+	return b, nil
+}
+
+/*
+riegeliBackwardWriter assembles the records of a transposed chunk, which are
+decoded from their end towards their beginning. The buffer grows as data is
+prepended, up to the decoded size announced in the chunk header.
+*/
+type riegeliBackwardWriter struct {
+	buf   []byte
+	start int
+	limit uint64
+}
+
+/*
+written returns the number of bytes written so far.
+*/
+func (w *riegeliBackwardWriter) written() int {
+	return len(w.buf) - w.start
+}
+
+/*
+prepend writes data in front of the data written so far.
+*/
+func (w *riegeliBackwardWriter) prepend(data ...byte) error {
+	var grown []byte
+	var size int
+
+	if uint64(w.written()+len(data)) > w.limit {
+		return ErrInvalidRiegeliTransposedChunk
+	}
+	if len(data) > w.start {
+		if size = 2 * len(w.buf); size < w.written()+len(data) {
+			size = w.written() + len(data)
+		}
+		if uint64(size) > w.limit {
+			size = int(w.limit)
+		}
+		grown = make([]byte, size)
+		copy(grown[size-w.written():], w.buf[w.start:])
+		w.start = size - w.written()
+		w.buf = grown
+	}
+
+	w.start -= len(data)
+	copy(w.buf[w.start:], data)
+	return nil
+}
+
+/*
+prependVarint writes v as a varint in front of the data written so far.
+*/
+func (w *riegeliBackwardWriter) prependVarint(v uint64) error {
+	var tmp [binary.MaxVarintLen64]byte
+
+	return w.prepend(tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+/*
+validRiegeliTag returns whether tag is the tag of a protocol buffer field,
+as opposed to one of the reserved tags.
+*/
+func validRiegeliTag(tag uint32) bool {
+	return tag>>3 > 0 && tag&7 <= wireFixed32
+}
+
+/*
+riegeliHasSubtype returns whether nodes writing fields with tag have a
+subtype.
+*/
+func riegeliHasSubtype(tag uint32) bool {
+	return validRiegeliTag(tag) &&
+		(tag&7 == wireVarint || tag&7 == wireBytes)
+}
+
+/*
+riegeliHasBuffer returns whether nodes writing fields with tag and subtype
+read their data from a buffer.
+*/
+func riegeliHasBuffer(tag uint32, subtype byte) bool {
+	switch tag & 7 {
+	case wireVarint:
+		return subtype < riegeliSubtypeVarintInline0
+	case wireFixed32, wireFixed64:
+		return true
+	case wireBytes:
+		return subtype == riegeliSubtypeString
+	}
+	return false
+}
+
+/*
+decodeRiegeliTransposedChunk decodes the records of a transposed chunk.
+Transposed chunks store the fields of protocol buffers column by column in
+buffers grouped into separately compressed buckets, along with a state
+machine whose transitions reconstruct the records from the buffers. Records
+which are not protocol buffers are stored as they are. No decompressed part
+of the chunk may exceed limit bytes.
+*/
+func decodeRiegeliTransposedChunk(data []byte, numRecords int64,
+	decodedSize uint64, limit uint32) ([][]byte, error) {
+	var src = riegeliSource{data: data}
+	var header, transitions riegeliSource
+	var compression byte
+	var headerSize uint64
+	var buffers []riegeliSource
+	var nodes []riegeliNode
+	var first uint32
+	var raw []byte
+	var err error
+
+	if raw, err = src.bytes(1); err != nil {
+		return nil, err
+	}
+	compression = raw[0]
+	if headerSize, err = src.varint(); err != nil {
+		return nil, err
+	}
+	if raw, err = src.bytes(headerSize); err != nil {
+		return nil, err
+	}
+	if header.data, err = riegeliDecompress(compression, raw,
+		limit); err != nil {
+		return nil, err
+	}
+
+	if buffers, err = readRiegeliBuffers(&header, &src, compression,
+		limit); err != nil {
+		return nil, err
+	}
+	if nodes, err = readRiegeliStateMachine(&header,
+		len(buffers)); err != nil {
+		return nil, err
+	}
+	if first, err = header.varint32(); err != nil {
+		return nil, err
+	}
+	if int64(first) >= int64(len(nodes)) || len(header.data) > 0 {
+		return nil, ErrInvalidRiegeliTransposedChunk
+	}
+
+	if transitions.data, err = riegeliDecompress(compression, src.data,
+		limit); err != nil {
+		return nil, err
+	}
+
+	return runRiegeliStateMachine(nodes, int(first), buffers, &transitions,
+		numRecords, decodedSize)
+}
+
+/*
+readRiegeliBuffers reads the bucket and buffer lengths from the header, and
+decompresses the buckets from src, splitting them up into buffers.
+*/
+func readRiegeliBuffers(header, src *riegeliSource, compression byte,
+	limit uint32) ([]riegeliSource, error) {
+	var numBuckets, numBuffers uint32
+	var bucketLengths, bufferLengths []uint64
+	var buffers []riegeliSource
+	var bucket riegeliSource
+	var raw []byte
+	var length uint64
+	var err error
+
+	if numBuckets, err = header.varint32(); err != nil {
+		return nil, err
+	}
+	if numBuffers, err = header.varint32(); err != nil {
+		return nil, err
+	}
+
+	// Every length takes at least one byte of the header, which bounds the
+	// counts before allocating memory for them.
+	if uint64(numBuckets)+uint64(numBuffers) > uint64(len(header.data)) ||
+		(numBuckets == 0 && numBuffers > 0) {
+		return nil, ErrInvalidRiegeliTransposedChunk
+	}
+
+	for i := uint32(0); i < numBuckets; i++ {
+		if length, err = header.varint(); err != nil {
+			return nil, err
+		}
+		bucketLengths = append(bucketLengths, length)
+	}
+	for i := uint32(0); i < numBuffers; i++ {
+		if length, err = header.varint(); err != nil {
+			return nil, err
+		}
+		bufferLengths = append(bufferLengths, length)
+	}
+
+	// Buffers are stored one after the other in the decompressed buckets,
+	// never crossing the end of a bucket.
+	for _, length = range bucketLengths {
+		if raw, err = src.bytes(length); err != nil {
+			return nil, err
+		}
+		if bucket.data, err = riegeliDecompress(compression, raw,
+			limit); err != nil {
+			return nil, err
+		}
+
+		for len(bucket.data) > 0 {
+			if len(buffers) == len(bufferLengths) {
+				return nil, ErrInvalidRiegeliTransposedChunk
+			}
+			if raw, err = bucket.bytes(
+				bufferLengths[len(buffers)]); err != nil {
+				return nil, err
+			}
+			buffers = append(buffers, riegeliSource{data: raw})
+		}
+	}
+	if len(buffers) != len(bufferLengths) {
+		return nil, ErrInvalidRiegeliTransposedChunk
+	}
+
+	return buffers, nil
+}
+
+/*
+readRiegeliStateMachine reads the nodes of the state machine from the
+header: first the tags of all nodes, then the indexes of their next nodes,
+then the subtypes of the nodes which have one, and finally, for every node
+reading from a buffer, the index of its buffer.
+*/
+func readRiegeliStateMachine(header *riegeliSource, numBuffers int) (
+	[]riegeliNode, error) {
+	var numNodes uint32
+	var nodes []riegeliNode
+	var subtypes []byte
+	var numSubtypes uint64
+	var next, buffer uint32
+	var err error
+
+	if numNodes, err = header.varint32(); err != nil {
+		return nil, err
+	}
+	if numNodes == 0 || uint64(numNodes) > uint64(len(header.data)) {
+		return nil, ErrInvalidRiegeliTransposedChunk
+	}
+
+	nodes = make([]riegeliNode, numNodes)
+	for i := range nodes {
+		if nodes[i].tag, err = header.varint32(); err != nil {
+			return nil, err
+		}
+		if riegeliHasSubtype(nodes[i].tag) {
+			numSubtypes++
+		}
+		nodes[i].buffer = -1
+	}
+	for i := range nodes {
+		if next, err = header.varint32(); err != nil {
+			return nil, err
+		}
+		if next >= numNodes {
+			next -= numNodes
+			nodes[i].implicit = true
+		}
+		if next >= numNodes {
+			return nil, ErrInvalidRiegeliTransposedChunk
+		}
+		nodes[i].next = int(next)
+	}
+	if subtypes, err = header.bytes(numSubtypes); err != nil {
+		return nil, err
+	}
+
+	for i := range nodes {
+		var tag = nodes[i].tag
+		var hasBuffer bool
+
+		switch {
+		case tag == riegeliTagNonProto:
+			hasBuffer = true
+		case validRiegeliTag(tag):
+			if riegeliHasSubtype(tag) {
+				nodes[i].subtype, subtypes = subtypes[0], subtypes[1:]
+			}
+			hasBuffer = riegeliHasBuffer(tag, nodes[i].subtype)
+		case tag > riegeliTagStartOfSubmessage:
+			return nil, ErrInvalidRiegeliTransposedChunk
+		}
+
+		if hasBuffer {
+			if buffer, err = header.varint32(); err != nil {
+				return nil, err
+			}
+			if int64(buffer) >= int64(numBuffers) {
+				return nil, ErrInvalidRiegeliTransposedChunk
+			}
+			nodes[i].buffer = int(buffer)
+		}
+	}
+
+	return nodes, nil
+}
+
+/*
+runRiegeliStateMachine executes the state machine starting at first, as
+directed by the transitions, and splits the resulting data into records.
+
+Every transition byte moves from the next node of the current node by its
+upper six bits, and its lower two bits give the number of further
+transitions which simply follow the next node. Implicit nodes move on to
+their next node without a transition. Fields are written from the end of
+the chunk towards its beginning; the start of every record is marked by a
+node, or, for records which are not protocol buffers, by the node copying
+them, whose lengths are stored in the last buffer.
+*/
+func runRiegeliStateMachine(nodes []riegeliNode, first int,
+	buffers []riegeliSource, transitions *riegeliSource, numRecords int64,
+	decodedSize uint64) ([][]byte, error) {
+	var dest = riegeliBackwardWriter{limit: decodedSize}
+	var nonProtoLengths *riegeliSource
+	var submessages []int
+	var submessageTags []uint32
+	var starts []int
+	var records [][]byte
+	var node *riegeliNode
+	var index = first
+	var iterations int
+	var err error
+
+	for i := range nodes {
+		if nodes[i].tag == riegeliTagNonProto {
+			nonProtoLengths = &buffers[len(buffers)-1]
+		}
+	}
+
+	if nodes[index].implicit {
+		iterations++
+	}
+
+	for {
+		node = &nodes[index]
+
+		switch {
+		case node.tag == riegeliTagNoOp:
+		case node.tag == riegeliTagNonProto:
+			err = copyRiegeliNonProto(&dest, &buffers[node.buffer],
+				nonProtoLengths)
+			starts = append(starts, dest.written())
+		case node.tag == riegeliTagStartOfMessage:
+			if len(submessages) > 0 {
+				return nil, ErrInvalidRiegeliTransposedChunk
+			}
+			starts = append(starts, dest.written())
+		case node.tag == riegeliTagStartOfSubmessage:
+			var end = len(submessages) - 1
+
+			if end < 0 {
+				return nil, ErrInvalidRiegeliTransposedChunk
+			}
+			if err = dest.prependVarint(uint64(dest.written() -
+				submessages[end])); err == nil {
+				err = dest.prependVarint(uint64(submessageTags[end]))
+			}
+			submessages = submessages[:end]
+			submessageTags = submessageTags[:end]
+		case node.tag&7 == wireBytes &&
+			node.subtype == riegeliSubtypeEndOfMessage:
+			submessages = append(submessages, dest.written())
+			submessageTags = append(submessageTags, node.tag)
+		default:
+			err = writeRiegeliField(&dest, node, buffers)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(starts)) > numRecords {
+			return nil, ErrInvalidRiegeliTransposedChunk
+		}
+
+		index = node.next
+		if iterations == 0 {
+			if len(transitions.data) == 0 {
+				break
+			}
+			index += int(transitions.data[0] >> 2)
+			iterations = int(transitions.data[0] & 3)
+			transitions.data = transitions.data[1:]
+			if index >= len(nodes) {
+				return nil, ErrInvalidRiegeliTransposedChunk
+			}
+			if nodes[index].implicit {
+				iterations++
+			}
+		} else if !nodes[index].implicit {
+			iterations--
+		}
+	}
+
+	if int64(len(starts)) != numRecords || len(submessages) > 0 ||
+		uint64(dest.written()) != decodedSize ||
+		(len(starts) > 0 && starts[len(starts)-1] != dest.written()) {
+		return nil, ErrInvalidRiegeliTransposedChunk
+	}
+
+	// The records were decoded from the last to the first, so the distances
+	// from the end at which they start are turned into offsets in order.
+	for i := range starts {
+		starts[i] = dest.written() - starts[i]
+	}
+	sort.Ints(starts)
+	for i, start := range starts {
+		var end = dest.written()
+
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		start, end = dest.start+start, dest.start+end
+		records = append(records, dest.buf[start:end:end])
+	}
+	return records, nil
+}
+
+/*
+copyRiegeliNonProto copies a record which is not a protocol buffer from its
+buffer, taking its length from the buffer of non-proto lengths.
+*/
+func copyRiegeliNonProto(dest *riegeliBackwardWriter, buffer,
+	lengths *riegeliSource) error {
+	var length uint64
+	var data []byte
+	var err error
+
+	if length, err = lengths.varint(); err != nil {
+		return err
+	}
+	if data, err = buffer.bytes(length); err != nil {
+		return err
+	}
+	return dest.prepend(data...)
+}
+
+/*
+writeRiegeliField writes the field of a node along with its tag.
+*/
+func writeRiegeliField(dest *riegeliBackwardWriter, node *riegeliNode,
+	buffers []riegeliSource) error {
+	var buffer *riegeliSource
+	var data []byte
+	var length uint64
+	var err error
+
+	if node.buffer >= 0 {
+		buffer = &buffers[node.buffer]
+	}
+
+	switch node.tag & 7 {
+	case wireVarint:
+		if node.subtype >= riegeliSubtypeVarintInline0 {
+			if node.subtype-riegeliSubtypeVarintInline0 >
+				riegeliMaxVarintInline {
+				return ErrInvalidRiegeliTransposedChunk
+			}
+			err = dest.prepend(node.subtype - riegeliSubtypeVarintInline0)
+			break
+		}
+
+		// Varints are stored without their continuation bits, which are
+		// implied by their length.
+		if data, err = buffer.bytes(uint64(node.subtype -
+			riegeliSubtypeVarint1 + 1)); err != nil {
+			return err
+		}
+		data = append([]byte(nil), data...)
+		for i := 0; i < len(data)-1; i++ {
+			data[i] |= 0x80
+		}
+		err = dest.prepend(data...)
+	case wireFixed32:
+		if data, err = buffer.bytes(4); err == nil {
+			err = dest.prepend(data...)
+		}
+	case wireFixed64:
+		if data, err = buffer.bytes(8); err == nil {
+			err = dest.prepend(data...)
+		}
+	case wireBytes:
+		if node.subtype != riegeliSubtypeString {
+			return ErrInvalidRiegeliTransposedChunk
+		}
+		if length, err = buffer.varint(); err != nil {
+			return err
+		}
+		if data, err = buffer.bytes(length); err != nil {
+			return err
+		}
+		if err = dest.prepend(data...); err == nil {
+			err = dest.prependVarint(length)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return dest.prependVarint(uint64(node.tag))
+}
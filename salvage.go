@@ -0,0 +1,225 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"log"
+)
+
+/*
+CorruptionPolicy governs what readers do when they encounter corrupted
+records, such as checksum mismatches or invalid framing.
+*/
+type CorruptionPolicy int
+
+const (
+	/*
+		FailFast returns errors for corrupted records to the caller,
+		which is the default.
+	*/
+	FailFast CorruptionPolicy = iota
+
+	/*
+		SkipAndLog skips over corrupted parts of the stream as described
+		for WithSkipCorrupted(), and logs every range skipped using the
+		standard log package.
+	*/
+	SkipAndLog
+
+	/*
+		SkipSilently skips over corrupted parts of the stream as described
+		for WithSkipCorrupted(), without logging.
+	*/
+	SkipSilently
+)
+
+/*
+WithCorruptionPolicy selects how readers handle corrupted records, so that
+the same code can serve both strict ingestion, failing on the first
+corrupted record, and best-effort analytics, skipping whatever cannot be
+read. If handler is not nil, it receives the details of every corruption
+encountered: the range of the stream skipped, which is empty if nothing was
+skipped because the error was returned to the caller, and the error.
+*/
+func WithCorruptionPolicy(policy CorruptionPolicy,
+	handler func(skipped ByteRange, err error)) Option {
+	return func(o *options) {
+		o.corruptionPolicy = policy
+		o.corruptionHandler = handler
+	}
+}
+
+/*
+WithSkipCorrupted makes ReadRecord(), and all methods reading records
+through it, skip over corrupted parts of the input stream instead of
+failing, which is useful for salvaging data from damaged files. When
+reading a record fails, e.g. due to a checksum mismatch, an implausible
+length or a truncated record, the reader scans forward for the next valid
+record as described for SeekToOffset() and continues there. The skipped
+range of the stream is reported to handler along with the error which
+caused it to be skipped.
+
+Scanning requires the input stream to implement Seeker; otherwise, errors
+are returned as usual. Since records are only recognized reliably with
+checksums, WithChecksum() should be used as well. After skipping, the index
+of the records is unknown, so Tell() reports an Index of -1.
+*/
+func WithSkipCorrupted(handler func(skipped ByteRange, err error)) Option {
+	return WithCorruptionPolicy(SkipSilently, handler)
+}
+
+/*
+WithQuarantine makes readers skipping corrupted data copy the raw data of
+every range of the stream they skip to quarantine, one record per range, so
+that the damaged data can be inspected offline rather than being discarded.
+Each range is held in memory while it is copied. If writing to quarantine
+fails, reading fails with the error.
+*/
+func WithQuarantine(quarantine *RecordWriter) Option {
+	return func(o *options) {
+		o.quarantine = quarantine
+	}
+}
+
+/*
+readSalvaging reads the next record like readRecord(), handling corrupted
+parts of the stream according to the corruption policy. The position of the
+record returned, or of the failed read, is returned as well.
+*/
+func (r *RecordReader) readSalvaging(ctx context.Context) ([]byte, Position,
+	error) {
+	var pos = r.position
+	var base int64
+	var rec []byte
+	var err error
+
+	// Remember where the stream starts, since the position of the stream
+	// is not known exactly after a failed read.
+	if r.options.corruptionPolicy == FailFast {
+		err = ErrNotSeekable
+	} else {
+		base, err = r.streamBase(ctx)
+	}
+	if err != nil {
+		if rec, err = r.readRecord(ctx); r.corrupted(ctx, err) {
+			r.reportCorrupted(ByteRange{Start: pos.Offset, End: pos.Offset},
+				err)
+		}
+		return rec, pos, err
+	}
+
+	for {
+		pos = r.position
+		if rec, err = r.readRecord(ctx); !r.corrupted(ctx, err) {
+			return rec, pos, err
+		}
+		if err = r.skipCorrupted(ctx, base, pos, err); err != nil {
+			return []byte{}, r.position, err
+		}
+	}
+}
+
+/*
+corrupted returns whether err indicates that the stream is corrupted,
+rather than it having ended or the reader having been closed or canceled.
+*/
+func (r *RecordReader) corrupted(ctx context.Context, err error) bool {
+	return err != nil && err != io.EOF && ctx.Err() == nil &&
+		!errors.Is(err, ErrClosed)
+}
+
+/*
+streamBase returns the absolute offset of the input stream at which the
+reader started.
+*/
+func (r *RecordReader) streamBase(ctx context.Context) (int64, error) {
+	var seeker, ok = r.wrappedReader.(Seeker)
+	var current int64
+	var err error
+
+	if !ok {
+		return 0, ErrNotSeekable
+	}
+	if current, err = seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	return current - r.streamOffset(), nil
+}
+
+/*
+skipCorrupted positions the reader at the first valid record after the
+corrupted record at pos, copies the range skipped over to the quarantine
+writer if there is one, and reports it.
+*/
+func (r *RecordReader) skipCorrupted(ctx context.Context, base int64,
+	pos Position, cause error) error {
+	var seeker = r.wrappedReader.(Seeker)
+	var skipped ByteRange
+	var err error
+
+	if _, err = seeker.Seek(ctx, base+pos.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.position = pos
+	r.pending = nil
+	r.peeked = false
+	r.trailing = 0
+
+	err = r.SeekToOffset(ctx, pos.Offset+1)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	skipped = ByteRange{Start: pos.Offset, End: r.position.Offset}
+	if r.options.quarantine != nil {
+		if qErr := r.quarantine(ctx, base, skipped); qErr != nil {
+			return qErr
+		}
+	}
+	r.reportCorrupted(skipped, cause)
+	return err
+}
+
+/*
+reportCorrupted passes the details of a corruption to the handler, and logs
+skipped ranges if the corruption policy asks for it.
+*/
+func (r *RecordReader) reportCorrupted(skipped ByteRange, err error) {
+	if r.options.corruptionPolicy == SkipAndLog && skipped.End > skipped.Start {
+		log.Printf("Skipped corrupted records at offsets %d to %d: %v",
+			skipped.Start, skipped.End, err)
+	}
+	if r.options.corruptionHandler != nil {
+		r.options.corruptionHandler(skipped, err)
+	}
+}
+
+/*
+quarantine copies the raw data of the skipped range of the stream starting
+at the absolute offset base to the quarantine writer, leaving the stream at
+its current position.
+*/
+func (r *RecordReader) quarantine(ctx context.Context, base int64,
+	skipped ByteRange) error {
+	var seeker = r.wrappedReader.(Seeker)
+	var data = make([]byte, skipped.End-skipped.Start)
+	var current int64
+	var err error
+
+	if current, err = seeker.Seek(ctx, 0, io.SeekCurrent); err != nil {
+		return err
+	}
+	if _, err = seeker.Seek(ctx, base+skipped.Start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err = readFull(ctx, r.wrappedReader, data); err != nil {
+		return noEOF(err)
+	}
+	if _, err = seeker.Seek(ctx, current, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err = r.options.quarantine.Write(ctx, data)
+	return err
+}
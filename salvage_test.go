@@ -0,0 +1,137 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+/*
+Corrupted and truncated records must be skipped and reported, and all other
+records must be read.
+*/
+func TestSkipCorrupted(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var skipped []ByteRange
+	var causes []error
+	var reader *RecordReader
+	var recs [][]byte
+	var err error
+
+	// Every record takes 8 bytes of header and 5 bytes of data.
+	data[3*13+9] ^= 0xff
+	data = data[:len(data)-2]
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum(),
+		WithSkipCorrupted(func(r ByteRange, err error) {
+			skipped = append(skipped, r)
+			causes = append(causes, err)
+		}))
+	if recs, err = reader.ReadAll(ctx); err != nil {
+		t.Error("Error reading records: ", err)
+	}
+
+	if len(recs) != 8 {
+		t.Error("Unexpected number of records: ", len(recs))
+	}
+	for i, rec := range recs {
+		var expected = i
+
+		if i >= 3 {
+			expected++
+		}
+		if string(rec) != fmt.Sprint("Rec ", expected) {
+			t.Errorf("Unexpected data: got %q, expected Rec %d", rec, expected)
+		}
+	}
+
+	if len(skipped) != 2 || skipped[0] != (ByteRange{Start: 39, End: 52}) ||
+		skipped[1] != (ByteRange{Start: 117, End: 128}) {
+		t.Error("Unexpected ranges skipped: ", skipped)
+	}
+	if len(causes) != 2 || !errors.Is(causes[0], ErrChecksumMismatch) {
+		t.Error("Unexpected causes: ", causes)
+	}
+}
+
+/*
+The raw data of the ranges skipped must be copied to the quarantine writer,
+one record per range.
+*/
+func TestQuarantine(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var damaged = append([]byte{}, data...)
+	var out bytes.Buffer
+	var quarantine = NewIORecordWriter(&out)
+	var reader *RecordReader
+	var recs [][]byte
+	var err error
+
+	// Every record takes 8 bytes of header and 5 bytes of data.
+	damaged[3*13+9] ^= 0xff
+	damaged = damaged[:len(damaged)-2]
+
+	reader = NewIORecordReader(bytes.NewReader(damaged), WithChecksum(),
+		WithSkipCorrupted(func(ByteRange, error) {}),
+		WithQuarantine(quarantine))
+	if recs, err = reader.ReadAll(ctx); err != nil || len(recs) != 8 {
+		t.Errorf("Unexpected result: %d records (%v)", len(recs), err)
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(out.Bytes()))
+	if recs, err = reader.ReadAll(ctx); err != nil || len(recs) != 2 {
+		t.Fatalf("Unexpected quarantine: %d records (%v)", len(recs), err)
+	}
+	if !bytes.Equal(recs[0], damaged[39:52]) ||
+		!bytes.Equal(recs[1], damaged[117:128]) {
+		t.Errorf("Unexpected data in quarantine: %q", recs)
+	}
+}
+
+/*
+FailFast must return corruption errors after reporting them to the handler,
+and SkipAndLog must log the ranges it skips.
+*/
+func TestCorruptionPolicy(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var logged bytes.Buffer
+	var reported []ByteRange
+	var handler = func(r ByteRange, err error) {
+		reported = append(reported, r)
+	}
+	var reader *RecordReader
+	var recs [][]byte
+	var err error
+
+	// Every record takes 8 bytes of header and 5 bytes of data.
+	data[3*13+9] ^= 0xff
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum(),
+		WithCorruptionPolicy(FailFast, handler))
+	if _, err = reader.ReadAll(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Error("Expected checksum mismatch, got: ", err)
+	}
+	if fmt.Sprint(reported) != "[{39 39}]" {
+		t.Error("Unexpected corruption reported: ", reported)
+	}
+
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	reader = NewIORecordReader(bytes.NewReader(data), WithChecksum(),
+		WithCorruptionPolicy(SkipAndLog, nil))
+	if recs, err = reader.ReadAll(ctx); err != nil || len(recs) != 9 {
+		t.Errorf("Unexpected result: %d records (%v)", len(recs), err)
+	}
+	if !strings.Contains(logged.String(), "offsets 39 to 52") {
+		t.Error("Skipped range not logged: ", logged.String())
+	}
+}
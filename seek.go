@@ -0,0 +1,177 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+ErrNotSeekable is returned when an operation requires seeking backwards in
+an input stream which does not implement Seeker.
+*/
+var ErrNotSeekable = errors.New("Input stream does not support seeking")
+
+/*
+Seeker is implemented by input streams which support changing the position
+of the next read, such as the adapters returned by NewIOReadCloser() for
+seekable readers. RecordReader uses it to skip over record data without
+reading it, if the underlying stream implements it.
+
+whence has the same meaning as for io.Seeker.
+*/
+type Seeker interface {
+	Seek(ctx context.Context, offset int64, whence int) (int64, error)
+}
+
+/*
+Skip advances the reader past the next n records without returning them. On
+streams implementing Seeker, the record data is skipped by seeking over it;
+otherwise it is read and discarded, without allocating a buffer for every
+record. Checksums are not verified for skipped records.
+
+The number of records skipped is returned. If the stream ends before n
+records have been skipped, io.EOF is returned as well.
+*/
+func (r *RecordReader) Skip(ctx context.Context, n int) (int, error) {
+	var skipped int
+	var length, crc uint32
+	var err error
+
+	for skipped < n {
+		if length, crc, err = r.nextHeader(ctx); err != nil {
+			return skipped, recordError(r.position, err)
+		}
+		if err = r.skipRecord(ctx, length, crc); err != nil {
+			return skipped, recordError(r.position, err)
+		}
+		r.advance(length)
+		skipped++
+	}
+
+	return skipped, nil
+}
+
+/*
+SeekToRecord positions the reader at the record with the specified index,
+counting from the position of the input stream when the reader was created,
+so that the next read returns it. Records ahead of the current position are
+skipped as described for Skip(), which works on any stream; seeking
+backwards requires the stream to implement Seeker, otherwise ErrNotSeekable
+is returned. If an index has been set using SetIndex() or
+NewIndexedRecordReader(), the reader seeks directly to the record. If the
+stream has a footer with a sparse index (see WithSparseIndex()), it is loaded
+and used to seek close to the record. Otherwise, since records have varying
+lengths, the record headers between the start of the stream and the
+requested record have to be scanned.
+
+If the stream ends before the requested record, io.EOF is returned and the
+reader is positioned at the end of the stream.
+*/
+func (r *RecordReader) SeekToRecord(ctx context.Context, n int64) error {
+	var pos, indexed = r.index.nearest(n)
+	var _, seekable = r.wrappedReader.(Seeker)
+	var err error
+
+	if r.closed {
+		return recordError(r.position, ErrClosed)
+	}
+
+	if r.options.footer && r.index == nil && seekable {
+		if r.index, err = r.loadFooter(ctx); err != nil {
+			return recordError(r.position, err)
+		}
+		pos, indexed = r.index.nearest(n)
+	}
+
+	if indexed && seekable && (pos.Index > r.position.Index ||
+		n < r.position.Index || r.position.Index < 0) {
+		if err = r.seekTo(ctx, pos); err != nil {
+			return recordError(r.position, err)
+		}
+	} else if n < r.position.Index || r.position.Index < 0 {
+		if err = r.seekTo(ctx, Position{}); err != nil {
+			return recordError(r.position, err)
+		}
+	}
+
+	_, err = r.Skip(ctx, int(n-r.position.Index))
+	return err
+}
+
+/*
+seekTo moves the input stream to the beginning of the record at the specified
+position, discarding any peeked header or partially streamed record. The
+stream must implement Seeker.
+*/
+func (r *RecordReader) seekTo(ctx context.Context, pos Position) error {
+	var seeker Seeker
+	var ok bool
+	var err error
+
+	if seeker, ok = r.wrappedReader.(Seeker); !ok {
+		return ErrNotSeekable
+	}
+
+	_, err = seeker.Seek(ctx, pos.Offset-r.streamOffset(), io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	r.position = pos
+	r.pending = nil
+	r.peeked = false
+	r.trailing = 0
+	r.resumeConsumed = 0
+	return nil
+}
+
+/*
+streamOffset returns the offset of the input stream relative to where the
+reader started, which differs from the position of the reader while a
+header has been peeked at or a record is being streamed.
+*/
+func (r *RecordReader) streamOffset() int64 {
+	var current = r.position.Offset
+
+	// The position has already been advanced past a record being
+	// streamed, but not past a peeked header.
+	if r.pending != nil {
+		current -= r.pending.remaining
+	} else if r.peeked {
+		current += int64(r.headerLength(r.peekedLength))
+	}
+	return current + r.trailing
+}
+
+/*
+skipBody skips over the specified number of bytes of record data.
+*/
+func (r *RecordReader) skipBody(ctx context.Context, length int64) error {
+	var buf *[streamBufferSize]byte
+	var scratch []byte
+	var n int
+	var err error
+
+	if seeker, ok := r.wrappedReader.(Seeker); ok {
+		_, err = seeker.Seek(ctx, length, io.SeekCurrent)
+		return err
+	}
+
+	buf = scratchPool.Get().(*[streamBufferSize]byte)
+	defer scratchPool.Put(buf)
+	scratch = buf[:]
+
+	for length > 0 {
+		if length < int64(len(scratch)) {
+			scratch = scratch[:length]
+		}
+		n, err = readFull(ctx, r.wrappedReader, scratch)
+		length -= int64(n)
+		if err != nil {
+			return noEOF(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Skip records on a seekable and a non-seekable stream and check that reading
+continues at the right record.
+*/
+func TestSkip(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum())
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	for _, reader := range []*RecordReader{
+		NewIORecordReader(bytes.NewReader(buf.Bytes()), WithChecksum()),
+		NewIORecordReader(bytes.NewBuffer(buf.Bytes()), WithChecksum()),
+	} {
+		var rec []byte
+		var skipped int
+
+		if skipped, err = reader.Skip(ctx, 2); err != nil || skipped != 2 {
+			t.Error("Unexpected skip result: ", skipped, ", error: ", err)
+		}
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != "Three" {
+			t.Errorf("Unexpected data: got %q, expected Three", rec)
+		}
+		if skipped, err = reader.Skip(ctx, 5); err != io.EOF || skipped != 1 {
+			t.Error("Unexpected skip result: ", skipped, ", error: ", err)
+		}
+	}
+}
+
+/*
+Seek back and forth between records by index, including from a peeked or
+partially streamed record.
+*/
+func TestSeekToRecord(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithFraming(VarintFraming))
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()),
+		WithFraming(VarintFraming))
+	for _, step := range []struct {
+		index    int64
+		expected string
+	}{{2, "Three"}, {0, "One"}, {3, "Four"}, {1, "Two"}} {
+		if err = reader.SeekToRecord(ctx, step.index); err != nil {
+			t.Error("Error seeking to record ", step.index, ": ", err)
+		}
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != step.expected {
+			t.Errorf("Unexpected data: got %q, expected %s", rec, step.expected)
+		}
+
+		// Leave the reader in the middle of the next record, if any.
+		reader.Peek(ctx)
+	}
+
+	if _, _, err = reader.NextRecordReader(ctx); err != nil {
+		t.Error("Error streaming record: ", err)
+	}
+	if err = reader.SeekToRecord(ctx, 1); err != nil {
+		t.Error("Error seeking to record 1: ", err)
+	}
+	if rec, err = reader.ReadRecord(ctx); string(rec) != "Two" {
+		t.Errorf("Unexpected data: got %q, expected Two (%v)", rec, err)
+	}
+	if reader.Tell() != (Position{Offset: 8, Index: 2}) {
+		t.Error("Unexpected position: ", reader.Tell())
+	}
+
+	if err = reader.SeekToRecord(ctx, 10); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+
+	reader = NewIORecordReader(bytes.NewBuffer(buf.Bytes()),
+		WithFraming(VarintFraming))
+	reader.Skip(ctx, 2)
+	if err = reader.SeekToRecord(ctx, 0); !errors.Is(err, ErrNotSeekable) {
+		t.Error("Expected ErrNotSeekable, got: ", err)
+	}
+}
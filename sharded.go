@@ -0,0 +1,378 @@
+package recordio
+
+import (
+	"errors"
+	"fmt"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+var shardNameRegexp = regexp.MustCompile(`^(.*)-(\d+)-of-(\d+)$`)
+
+/*
+ErrNoShards is returned when creating a ShardedWriter without any shards.
+*/
+var ErrNoShards = errors.New("At least one shard is required")
+
+/*
+ShardName returns the name of the specified shard of a sharded file, using
+the common base-00001-of-00010 naming convention. Shards are numbered from 0
+to numShards-1.
+*/
+func ShardName(base string, shard, numShards int) string {
+	return fmt.Sprintf("%s-%05d-of-%05d", base, shard, numShards)
+}
+
+/*
+ShardNames returns the names of all shards of a sharded file, in order.
+*/
+func ShardNames(base string, numShards int) []string {
+	var names = make([]string, numShards)
+	var i int
+
+	for i = 0; i < numShards; i++ {
+		names[i] = ShardName(base, i, numShards)
+	}
+
+	return names
+}
+
+/*
+ParseShardName splits the name of a shard following the base-00001-of-00010
+naming convention into its base name, shard number and number of shards.
+*/
+func ParseShardName(name string) (string, int, int, error) {
+	var match = shardNameRegexp.FindStringSubmatch(name)
+	var shard, numShards int
+	var err error
+
+	if match == nil {
+		return "", 0, 0, errors.New("Not a shard name: " + name)
+	}
+
+	if shard, err = strconv.Atoi(match[2]); err != nil {
+		return "", 0, 0, err
+	}
+	if numShards, err = strconv.Atoi(match[3]); err != nil {
+		return "", 0, 0, err
+	}
+	if shard >= numShards {
+		return "", 0, 0, errors.New("Shard number out of range: " + name)
+	}
+
+	return match[1], shard, numShards, nil
+}
+
+/*
+ShardedWriter distributes records across a set of shards, writing every
+record to the next shard in turn, or to the shard selected by hashing its
+key if a key function has been set using SetKeyFunc().
+
+As with RecordWriter, ShardedWriters are not thread safe.
+*/
+type ShardedWriter struct {
+	writers []*RecordWriter
+	next    int
+	key     func(rec []byte) []byte
+}
+
+/*
+NewShardedWriter opens numShards output streams named according to
+ShardNames() using the specified open function, and wraps them into a
+ShardedWriter writing every shard with the specified options. If opening
+any of the shards fails, the shards opened so far are closed again.
+*/
+func NewShardedWriter(ctx context.Context, base string, numShards int,
+	open func(context.Context, string) (filesystem.WriteCloser, error),
+	opts ...Option) (*ShardedWriter, error) {
+	var w = &ShardedWriter{}
+	var writer filesystem.WriteCloser
+	var err error
+
+	if numShards < 1 {
+		return nil, ErrNoShards
+	}
+
+	for _, name := range ShardNames(base, numShards) {
+		if writer, err = open(ctx, name); err != nil {
+			w.Close(ctx)
+			return nil, err
+		}
+		w.writers = append(w.writers, NewRecordWriter(writer, opts...))
+	}
+
+	return w, nil
+}
+
+/*
+NewShardedWriterFromWriters creates a ShardedWriter distributing records
+across the specified RecordWriters. ErrNoShards is returned if there are
+none.
+*/
+func NewShardedWriterFromWriters(writers []*RecordWriter) (*ShardedWriter,
+	error) {
+	if len(writers) == 0 {
+		return nil, ErrNoShards
+	}
+
+	return &ShardedWriter{
+		writers: writers,
+	}, nil
+}
+
+/*
+SetKeyFunc makes the ShardedWriter route every record to the shard selected
+by a hash of the key which key returns for it, so that all records with the
+same key end up in the same shard, e.g. for producing a dataset partitioned
+by key in one pass. For protocol buffers, key is called with the serialized
+message. Passing nil restores round-robin distribution.
+*/
+func (w *ShardedWriter) SetKeyFunc(key func(rec []byte) []byte) {
+	w.key = key
+}
+
+/*
+shard returns the number of the shard the record is to be written to,
+advancing to the next shard in turn if no key function has been set.
+*/
+func (w *ShardedWriter) shard(rec []byte) int {
+	var hash = fnv.New32a()
+	var shard = w.next
+
+	if w.key == nil {
+		w.next = (w.next + 1) % len(w.writers)
+		return shard
+	}
+
+	hash.Write(w.key(rec))
+	return int(hash.Sum32() % uint32(len(w.writers)))
+}
+
+/*
+Write writes the record to the next shard in turn, or to the shard selected
+by its key.
+*/
+func (w *ShardedWriter) Write(ctx context.Context, rec []byte) (int, error) {
+	return w.writers[w.shard(rec)].Write(ctx, rec)
+}
+
+/*
+WriteMessage serializes the protocol buffer and writes it as described
+for Write().
+*/
+func (w *ShardedWriter) WriteMessage(ctx context.Context,
+	pb Message) error {
+	var b []byte
+	var err error
+
+	if b, err = w.writers[0].options.marshalMessage(pb); err != nil {
+		return err
+	}
+
+	_, err = w.Write(ctx, b)
+	return err
+}
+
+/*
+Flush flushes all shards. All shards are flushed even if flushing one of
+them fails; the first error encountered is returned.
+*/
+func (w *ShardedWriter) Flush(ctx context.Context) error {
+	var firstErr error
+
+	for _, writer := range w.writers {
+		if err := writer.Flush(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+/*
+Barrier makes all records written so far durable on all shards as described
+for RecordWriter.Barrier(). Barriers are set on all shards even if one of
+them fails; the first error encountered is returned.
+*/
+func (w *ShardedWriter) Barrier(ctx context.Context) error {
+	var firstErr error
+
+	for _, writer := range w.writers {
+		if err := writer.Barrier(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+/*
+Close closes all shards. All shards are closed even if closing one of them
+fails; the first error encountered is returned.
+*/
+func (w *ShardedWriter) Close(ctx context.Context) error {
+	var firstErr error
+
+	for _, writer := range w.writers {
+		if err := writer.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+/*
+ShardedReader reads the records from a set of shards, either one shard after
+the other using ReadRecord() and ReadMessage(), or all shards at the same
+time using ReadParallel().
+*/
+type ShardedReader struct {
+	readers []*RecordReader
+	current int
+}
+
+/*
+NewShardedReader opens the specified shards using the open function and
+wraps them into a ShardedReader reading every shard with the specified
+options. If opening any of the shards fails, the shards opened so far are
+closed again.
+*/
+func NewShardedReader(ctx context.Context, names []string,
+	open func(context.Context, string) (filesystem.ReadCloser, error),
+	opts ...Option) (*ShardedReader, error) {
+	var r = &ShardedReader{}
+	var reader filesystem.ReadCloser
+	var err error
+
+	for _, name := range names {
+		if reader, err = open(ctx, name); err != nil {
+			r.Close(ctx)
+			return nil, err
+		}
+		r.readers = append(r.readers, NewRecordReader(reader, opts...))
+	}
+
+	return r, nil
+}
+
+/*
+NewShardedReaderFromReaders creates a ShardedReader reading from the
+specified RecordReaders.
+*/
+func NewShardedReaderFromReaders(readers []*RecordReader) *ShardedReader {
+	return &ShardedReader{
+		readers: readers,
+	}
+}
+
+/*
+ReadRecord returns the next record from the current shard, moving on to the
+next shard once the end of the current one has been reached. io.EOF is
+returned after the last record of the last shard.
+*/
+func (r *ShardedReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var rec []byte
+	var err error
+
+	for r.current < len(r.readers) {
+		rec, err = r.readers[r.current].ReadRecord(ctx)
+		if err != io.EOF {
+			return rec, err
+		}
+		r.current++
+	}
+
+	return nil, io.EOF
+}
+
+/*
+ReadMessage reads the next record as described for ReadRecord() and parses
+it as a protocol buffer of the type passed in.
+*/
+func (r *ShardedReader) ReadMessage(ctx context.Context,
+	pb Message) error {
+	var buf []byte
+	var err error
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return err
+	}
+
+	return r.readers[r.current].options.unmarshalMessage(buf, pb)
+}
+
+/*
+ReadParallel reads all shards at the same time, one goroutine per shard, and
+calls fn for every record with the number of the shard it was read from. fn
+must be safe for concurrent use. Records of the same shard are passed to fn
+in order.
+
+If reading a shard or fn fails, the remaining shards are canceled and the
+first error is returned. ReadParallel should not be mixed with ReadRecord()
+on the same ShardedReader.
+*/
+func (r *ShardedReader) ReadParallel(ctx context.Context,
+	fn func(shard int, rec []byte) error) error {
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	var firstErr error
+	var cancel context.CancelFunc
+
+	ctx, cancel = context.WithCancel(ctx)
+	defer cancel()
+
+	for i, reader := range r.readers {
+		wg.Add(1)
+		go func(shard int, reader *RecordReader) {
+			var rec []byte
+			var err error
+
+			defer wg.Done()
+
+			for {
+				if err = ctx.Err(); err == nil {
+					if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+						return
+					}
+				}
+				if err == nil {
+					err = fn(shard, rec)
+				}
+				if err != nil {
+					mtx.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mtx.Unlock()
+					return
+				}
+			}
+		}(i, reader)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+/*
+Close closes all shards. All shards are closed even if closing one of them
+fails; the first error encountered is returned.
+*/
+func (r *ShardedReader) Close(ctx context.Context) error {
+	var firstErr error
+
+	for _, reader := range r.readers {
+		if err := reader.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
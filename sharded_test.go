@@ -0,0 +1,243 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/childoftheuniverse/filesystem"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+)
+
+/*
+Check generating and parsing shard names.
+*/
+func TestShardNames(t *testing.T) {
+	var names = ShardNames("data", 3)
+	var base string
+	var shard, numShards int
+	var err error
+
+	if len(names) != 3 || names[0] != "data-00000-of-00003" ||
+		names[2] != "data-00002-of-00003" {
+		t.Error("Unexpected shard names: ", names)
+	}
+
+	base, shard, numShards, err = ParseShardName("/tmp/data-00001-of-00010")
+	if err != nil {
+		t.Error("Error parsing shard name: ", err)
+	}
+	if base != "/tmp/data" || shard != 1 || numShards != 10 {
+		t.Error("Unexpected result: ", base, shard, numShards)
+	}
+
+	if _, _, _, err = ParseShardName("data-00010-of-00010"); err == nil {
+		t.Error("Expected error for out of range shard number")
+	}
+	if _, _, _, err = ParseShardName("data"); err == nil {
+		t.Error("Expected error for non-shard name")
+	}
+}
+
+/*
+Write records round-robin to three shards and read them back, both in order
+and in parallel.
+*/
+func TestShardedWriterAndReader(t *testing.T) {
+	var ctx = context.Background()
+	var files = make(map[string]*internal.AnonymousFile)
+	var writer *ShardedWriter
+	var reader *ShardedReader
+	var rec []byte
+	var got []string
+	var mtx sync.Mutex
+	var err error
+
+	var openWriter = func(ctx context.Context, name string) (
+		filesystem.WriteCloser, error) {
+		files[name] = internal.NewAnonymousFile()
+		return files[name], nil
+	}
+	var openReader = func(ctx context.Context, name string) (
+		filesystem.ReadCloser, error) {
+		if f, ok := files[name]; ok {
+			return f, nil
+		}
+		return nil, errors.New("No such file: " + name)
+	}
+
+	writer, err = NewShardedWriter(ctx, "data", 3, openWriter)
+	if err != nil {
+		t.Fatal("Error creating sharded writer: ", err)
+	}
+
+	for _, s := range []string{"a", "b", "c", "d"} {
+		if _, err = writer.Write(ctx, []byte(s)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	// Reset position.
+	writer.Close(ctx)
+
+	if files["data-00000-of-00003"].Len() != 10 ||
+		files["data-00002-of-00003"].Len() != 5 {
+		t.Error("Records were not distributed round-robin")
+	}
+
+	reader, err = NewShardedReader(ctx, ShardNames("data", 3), openReader)
+	if err != nil {
+		t.Fatal("Error creating sharded reader: ", err)
+	}
+
+	for {
+		if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal("Error reading record: ", err)
+		}
+		got = append(got, string(rec))
+	}
+
+	if len(got) != 4 || got[0] != "a" || got[1] != "d" || got[2] != "b" ||
+		got[3] != "c" {
+		t.Error("Unexpected records: ", got)
+	}
+
+	// Reset position.
+	reader.Close(ctx)
+	got = nil
+
+	reader, err = NewShardedReader(ctx, ShardNames("data", 3), openReader)
+	if err != nil {
+		t.Fatal("Error creating sharded reader: ", err)
+	}
+
+	err = reader.ReadParallel(ctx, func(shard int, rec []byte) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		got = append(got, string(rec))
+		return nil
+	})
+	if err != nil {
+		t.Error("Error reading in parallel: ", err)
+	}
+
+	sort.Strings(got)
+	if len(got) != 4 || got[0] != "a" || got[3] != "d" {
+		t.Error("Unexpected records: ", got)
+	}
+}
+
+/*
+With a key function, records with the same key must be written to the same
+shard, and all shards must be flushed and closed.
+*/
+func TestShardedWriterKeyFunc(t *testing.T) {
+	var ctx = context.Background()
+	var outs [4]countingWriter
+	var writers []*RecordWriter
+	var writer *ShardedWriter
+	var reader *RecordReader
+	var shards = make(map[string]int)
+	var key string
+	var rec []byte
+	var err error
+
+	for i := range outs {
+		writers = append(writers, NewIORecordWriter(&outs[i],
+			WithWriteBuffer(1024)))
+	}
+	if writer, err = NewShardedWriterFromWriters(writers); err != nil {
+		t.Fatal("Error creating sharded writer: ", err)
+	}
+	writer.SetKeyFunc(func(rec []byte) []byte {
+		return bytes.SplitN(rec, []byte(":"), 2)[0]
+	})
+
+	for i := 0; i < 40; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("key", i%5, ":",
+			i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Flush(ctx); err != nil {
+		t.Error("Error flushing shards: ", err)
+	}
+
+	for i := range outs {
+		reader = NewIORecordReader(bytes.NewReader(outs[i].Bytes()))
+		for {
+			if rec, err = reader.ReadRecord(ctx); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal("Error reading record: ", err)
+			}
+			key = string(bytes.SplitN(rec, []byte(":"), 2)[0])
+			if shard, ok := shards[key]; ok && shard != i {
+				t.Errorf("Key %s found in shards %d and %d", key, shard, i)
+			}
+			shards[key] = i
+		}
+	}
+	if len(shards) != 5 {
+		t.Error("Unexpected keys read: ", shards)
+	}
+
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing shards: ", err)
+	}
+	if _, err = writer.Write(ctx, []byte("key0:40")); !errors.Is(err,
+		ErrClosed) {
+		t.Error("Expected ErrClosed after closing, got: ", err)
+	}
+}
+
+/*
+Sharded writers without any shards must be rejected rather than failing on
+the first write, and sharded readers must apply their options to all shards.
+*/
+func TestShardedEdgeCases(t *testing.T) {
+	var ctx = context.Background()
+	var files = make(map[string]*internal.AnonymousFile)
+	var reader *ShardedReader
+	var rec []byte
+	var err error
+
+	if _, err = NewShardedWriterFromWriters(nil); err != ErrNoShards {
+		t.Error("Unexpected error for no writers: ", err)
+	}
+	if _, err = NewShardedWriter(ctx, "data", 0, nil); err != ErrNoShards {
+		t.Error("Unexpected error for no shards: ", err)
+	}
+
+	for i, name := range ShardNames("data", 2) {
+		var w *RecordWriter
+
+		files[name] = internal.NewAnonymousFile()
+		w = NewRecordWriter(files[name], WithFraming(VarintFraming))
+		if _, err = w.Write(ctx, []byte(fmt.Sprint("shard ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader, err = NewShardedReader(ctx, ShardNames("data", 2),
+		func(ctx context.Context, name string) (filesystem.ReadCloser,
+			error) {
+			return files[name], nil
+		}, WithFraming(VarintFraming))
+	if err != nil {
+		t.Fatal("Error creating sharded reader: ", err)
+	}
+	for i := 0; i < 2; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		} else if string(rec) != fmt.Sprint("shard ", i) {
+			t.Errorf("Unexpected record: %q", rec)
+		}
+	}
+}
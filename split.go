@@ -0,0 +1,83 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"sort"
+)
+
+/*
+ByteRange describes a range of a record stream, from the offset Start up to
+but not including End. A record belongs to the range if it begins within it.
+*/
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+/*
+SplitPoints divides the records of the input stream into at most
+desiredShards ranges of roughly equal size in bytes, which start and end at
+record boundaries, so that a large stream can be processed by several
+workers in parallel without any record being processed twice. Offsets are
+relative to the position of the input stream when the reader was created,
+and each range contains at least one record. Streams without any records
+produce no ranges.
+
+The input stream must implement Seeker. Record boundaries are taken from the
+index of the reader, or the sparse index from the footer; otherwise, the
+stream is scanned once to build an index, as described for ReverseRecords().
+The position of the reader is restored afterwards.
+*/
+func (r *RecordReader) SplitPoints(ctx context.Context, desiredShards int) (
+	[]ByteRange, error) {
+	var pos = r.position
+	var ranges []ByteRange
+	var offsets []int64
+	var end int64
+	var shard int
+	var err error
+
+	if err = r.prepareIndex(ctx); err != nil {
+		return nil, recordError(r.position, err)
+	}
+	if r.index.Len() == 0 {
+		return nil, nil
+	}
+	if desiredShards < 1 {
+		desiredShards = 1
+	}
+
+	// Find the end of the last record.
+	if err = r.SeekToRecord(ctx, r.index.Len()-1); err != nil {
+		return nil, err
+	}
+	if _, err = r.Skip(ctx, 1); err != nil {
+		return nil, noEOF(err)
+	}
+	end = r.position.Offset
+	if err = r.seekTo(ctx, pos); err != nil {
+		return nil, recordError(r.position, err)
+	}
+
+	offsets = r.index.offsets
+	for shard = 0; shard < desiredShards; shard++ {
+		var target = offsets[0] + (end-offsets[0])*int64(shard)/
+			int64(desiredShards)
+		var next = sort.Search(len(offsets), func(i int) bool {
+			return offsets[i] >= target
+		})
+
+		if next == len(offsets) {
+			break
+		}
+		if len(ranges) > 0 {
+			if offsets[next] <= ranges[len(ranges)-1].Start {
+				continue
+			}
+			ranges[len(ranges)-1].End = offsets[next]
+		}
+		ranges = append(ranges, ByteRange{Start: offsets[next], End: end})
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,57 @@
+package recordio
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Split points must cover all records exactly once and start at record
+boundaries.
+*/
+func TestSplitPoints(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var ranges []ByteRange
+	var i int
+	var err error
+
+	for i = 0; i < 100; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Record ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(bytes.NewReader(buf.Bytes()))
+	if ranges, err = reader.SplitPoints(ctx, 4); err != nil {
+		t.Fatal("Error computing split points: ", err)
+	}
+	if len(ranges) != 4 {
+		t.Fatal("Unexpected number of ranges: ", ranges)
+	}
+	if ranges[0].Start != 0 || ranges[3].End != int64(buf.Len()) {
+		t.Error("Ranges do not cover the stream: ", ranges)
+	}
+	for i = 1; i < len(ranges); i++ {
+		if ranges[i].Start != ranges[i-1].End {
+			t.Error("Ranges are not contiguous: ", ranges)
+		}
+		if err = reader.SeekToOffset(ctx, ranges[i].Start); err != nil {
+			t.Error("Error seeking to range start: ", err)
+		}
+		if reader.Offset() != ranges[i].Start {
+			t.Error("Range does not start at a record: ", ranges[i])
+		}
+	}
+
+	if ranges, err = reader.SplitPoints(ctx, 1000); err != nil {
+		t.Error("Error computing split points: ", err)
+	}
+	if len(ranges) != 100 {
+		t.Error("Unexpected number of ranges: ", len(ranges))
+	}
+}
@@ -0,0 +1,200 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+ioReadCloser adapts a standard library io.Reader to filesystem.ReadCloser.
+*/
+type ioReadCloser struct {
+	reader io.Reader
+}
+
+/*
+ioReadSeekCloser adapts a standard library io.ReadSeeker to
+filesystem.ReadCloser, additionally implementing Seeker.
+*/
+type ioReadSeekCloser struct {
+	ioReadCloser
+	seeker io.Seeker
+}
+
+/*
+ioWriteCloser adapts a standard library io.Writer to filesystem.WriteCloser.
+*/
+type ioWriteCloser struct {
+	writer io.Writer
+}
+
+/*
+ioWriteSeekCloser adapts a standard library io.WriteSeeker to
+filesystem.WriteCloser, additionally implementing Seeker.
+*/
+type ioWriteSeekCloser struct {
+	ioWriteCloser
+	seeker io.Seeker
+}
+
+/*
+ioWriteSeekTruncateCloser adapts a standard library io.WriteSeeker which can
+also be truncated, such as an os.File, to filesystem.WriteCloser,
+additionally implementing Seeker and Truncater.
+*/
+type ioWriteSeekTruncateCloser struct {
+	ioWriteSeekCloser
+	truncater interface{ Truncate(int64) error }
+}
+
+/*
+NewIOReadCloser wraps a standard library io.Reader, such as an os.File, a
+bytes.Buffer or a net.Conn, into a filesystem.ReadCloser. If the reader also
+implements io.Closer, closing the returned ReadCloser closes it; otherwise,
+Close does nothing. If the reader implements io.Seeker, the returned
+ReadCloser implements Seeker.
+
+Since io.Reader does not support contexts, the context is only checked for
+cancellation before every read.
+*/
+func NewIOReadCloser(reader io.Reader) filesystem.ReadCloser {
+	if seeker, ok := reader.(io.Seeker); ok {
+		return &ioReadSeekCloser{
+			ioReadCloser: ioReadCloser{reader: reader},
+			seeker:       seeker,
+		}
+	}
+
+	return &ioReadCloser{
+		reader: reader,
+	}
+}
+
+/*
+NewIOWriteCloser wraps a standard library io.Writer, such as an os.File, a
+bytes.Buffer or a net.Conn, into a filesystem.WriteCloser. If the writer
+also implements io.Closer, closing the returned WriteCloser closes it;
+otherwise, Close does nothing. The returned WriteCloser implements Flusher
+and Syncer, calling the Flush() and Sync() methods of the writer if it has
+them, e.g. for a bufio.Writer or an os.File. If the writer cannot be synced,
+ErrSyncUnsupported is returned. If the writer implements io.Seeker, the
+returned WriteCloser implements Seeker, so incomplete records can be removed
+using RecordWriter.Resume(); if it has a Truncate(int64) error method as
+well, like an os.File, the returned WriteCloser also implements Truncater.
+
+Since io.Writer does not support contexts, the context is only checked for
+cancellation before every write.
+*/
+func NewIOWriteCloser(writer io.Writer) filesystem.WriteCloser {
+	var seeker, seekable = writer.(io.Seeker)
+
+	if truncater, ok := writer.(interface {
+		Truncate(int64) error
+	}); ok && seekable {
+		return &ioWriteSeekTruncateCloser{
+			ioWriteSeekCloser: ioWriteSeekCloser{
+				ioWriteCloser: ioWriteCloser{writer: writer},
+				seeker:        seeker,
+			},
+			truncater: truncater,
+		}
+	}
+	if seekable {
+		return &ioWriteSeekCloser{
+			ioWriteCloser: ioWriteCloser{writer: writer},
+			seeker:        seeker,
+		}
+	}
+
+	return &ioWriteCloser{
+		writer: writer,
+	}
+}
+
+/*
+NewIORecordReader creates a new RecordReader reading from the specified
+standard library io.Reader. This is equivalent to
+NewRecordReader(NewIOReadCloser(reader), opts...).
+*/
+func NewIORecordReader(reader io.Reader, opts ...Option) *RecordReader {
+	return NewRecordReader(NewIOReadCloser(reader), opts...)
+}
+
+/*
+NewIORecordWriter creates a new RecordWriter writing to the specified
+standard library io.Writer. This is equivalent to
+NewRecordWriter(NewIOWriteCloser(writer), opts...).
+*/
+func NewIORecordWriter(writer io.Writer, opts ...Option) *RecordWriter {
+	return NewRecordWriter(NewIOWriteCloser(writer), opts...)
+}
+
+func (r *ioReadCloser) Read(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}
+
+func (r *ioReadCloser) Close(ctx context.Context) error {
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *ioReadSeekCloser) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.seeker.Seek(offset, whence)
+}
+
+func (w *ioWriteCloser) Write(ctx context.Context, p []byte) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.writer.Write(p)
+}
+
+func (w *ioWriteCloser) Close(ctx context.Context) error {
+	if closer, ok := w.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (w *ioWriteCloser) Flush(ctx context.Context) error {
+	if flusher, ok := w.writer.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+func (w *ioWriteCloser) Sync(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if syncer, ok := w.writer.(interface{ Sync() error }); ok {
+		return syncer.Sync()
+	}
+	return ErrSyncUnsupported
+}
+
+func (w *ioWriteSeekCloser) Seek(ctx context.Context, offset int64,
+	whence int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.seeker.Seek(offset, whence)
+}
+
+func (w *ioWriteSeekTruncateCloser) Truncate(ctx context.Context,
+	size int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return w.truncater.Truncate(size)
+}
@@ -0,0 +1,117 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"testing"
+)
+
+/*
+oneByteReader returns at most one byte per read, like a slow network
+connection might.
+*/
+type oneByteReader struct {
+	reader io.Reader
+}
+
+func (o *oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return o.reader.Read(p)
+}
+
+/*
+Write records to a bytes.Buffer and read them back from it.
+*/
+func TestIORoundTrip(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum())
+	var reader *RecordReader
+	var rbuf []byte
+	var err error
+
+	for _, rec := range []string{"Hello", "World"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	reader = NewIORecordReader(&oneByteReader{reader: &buf}, WithChecksum())
+	for _, rec := range []string{"Hello", "World"} {
+		if rbuf, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rbuf) != rec {
+			t.Errorf("Unexpected data: got %q, expected %s", rbuf, rec)
+		}
+	}
+
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+The adapters must not touch the underlying stream once the context has been
+canceled.
+*/
+func TestIOCanceledContext(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var err error
+
+	cancel()
+
+	_, err = writer.Write(ctx, []byte("Hello"))
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected context to be canceled, got: ", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("Data was written despite the canceled context")
+	}
+}
+
+/*
+seekOnlyWriter is a seekable writer which cannot be truncated.
+*/
+type seekOnlyWriter struct {
+	bytes.Buffer
+}
+
+func (s *seekOnlyWriter) Seek(offset int64, whence int) (int64, error) {
+	return 0, nil
+}
+
+/*
+Only writers which can actually be truncated must be wrapped into a
+Truncater, so that callers don't mistake a no-op for a truncation.
+*/
+func TestIOTruncater(t *testing.T) {
+	var file *os.File
+	var err error
+
+	if _, ok := NewIOWriteCloser(&seekOnlyWriter{}).(Truncater); ok {
+		t.Error("Writer without Truncate() was wrapped into a Truncater")
+	}
+	if _, ok := NewIOWriteCloser(&seekOnlyWriter{}).(Seeker); !ok {
+		t.Error("Seekable writer was not wrapped into a Seeker")
+	}
+
+	if file, err = os.CreateTemp(t.TempDir(), "stdio"); err != nil {
+		t.Fatal("Error creating temporary file: ", err)
+	}
+	defer file.Close()
+
+	if _, ok := NewIOWriteCloser(file).(Truncater); !ok {
+		t.Error("os.File was not wrapped into a Truncater")
+	}
+}
@@ -0,0 +1,52 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+Stream reads the remaining records of the input stream in a separate
+goroutine and delivers them through the returned records channel. Up to
+readahead records are read before they are consumed.
+
+The records channel is closed once the end of the stream has been reached or
+reading failed. Exactly one value can then be received from the error
+channel: nil at the end of the stream, the error if reading failed, or the
+error of the context if it was canceled. Canceling the context stops the
+goroutine even if nobody is consuming records anymore.
+
+The RecordReader must not be used otherwise while the stream is running.
+*/
+func (r *RecordReader) Stream(ctx context.Context, readahead int) (
+	<-chan []byte, <-chan error) {
+	var records = make(chan []byte, readahead)
+	var errors = make(chan error, 1)
+
+	go func() {
+		var rec []byte
+		var err error
+
+		defer close(errors)
+		defer close(records)
+
+		for {
+			if rec, err = r.ReadRecord(ctx); err == io.EOF {
+				errors <- nil
+				return
+			} else if err != nil {
+				errors <- err
+				return
+			}
+
+			select {
+			case records <- rec:
+			case <-ctx.Done():
+				errors <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return records, errors
+}
@@ -0,0 +1,66 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+All records must be delivered through the stream, followed by a nil error.
+*/
+func TestStream(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var records <-chan []byte
+	var errors <-chan error
+	var got []string
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	records, errors = NewIORecordReader(&buf).Stream(ctx, 2)
+	for rec := range records {
+		got = append(got, string(rec))
+	}
+
+	if err = <-errors; err != nil {
+		t.Error("Unexpected stream error: ", err)
+	}
+	if len(got) != 3 || got[0] != "One" || got[2] != "Three" {
+		t.Error("Unexpected records: ", got)
+	}
+}
+
+/*
+Canceling the context must stop a stream nobody consumes records from.
+*/
+func TestStreamCancel(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var records <-chan []byte
+	var errors <-chan error
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	records, errors = NewIORecordReader(&buf).Stream(ctx, 0)
+	<-records
+	cancel()
+
+	for range records {
+	}
+	if err = <-errors; err != context.Canceled {
+		t.Error("Expected context to be canceled, got: ", err)
+	}
+}
@@ -0,0 +1,313 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+/*
+ErrStreamingUnsupported is returned by WriteRecordFrom() if the options of
+the writer require the record data to be known in full before writing it.
+*/
+var ErrStreamingUnsupported = errors.New(
+	"Streaming writes are not supported with these options")
+
+/*
+streamBufferSize is the size of the buffer used for copying record data from
+and to streams, or discarding it.
+*/
+const streamBufferSize = 32 * 1024
+
+/*
+WriteRecordFrom writes a record of the specified length whose data is read
+from src, without holding the entire record in memory. This is useful for
+storing large payloads, such as files being archived, as records.
+
+Since the checksum precedes the record data, src must implement io.Seeker if
+checksums are enabled; the data is then read twice, first to compute the
+checksum and then to write it. Records cannot be streamed when a codec is
+used; ErrStreamingUnsupported is returned in both cases.
+
+If src ends before length bytes could be read, or the context is canceled
+while the record is being written, the error is returned as a
+*PartialWriteError. The output stream then ends in a truncated record, which
+can be removed using Resume(). The number of bytes added to the stream is
+returned.
+*/
+func (w *RecordWriter) WriteRecordFrom(ctx context.Context, src io.Reader,
+	length int64) (int64, error) {
+	var pos = w.position
+	var written int64
+	var err error
+
+	written, err = w.writeRecordFrom(ctx, src, length)
+	return written, recordError(pos, err)
+}
+
+/*
+writeRecordFrom implements WriteRecordFrom() without adding the position of
+the record to errors.
+*/
+func (w *RecordWriter) writeRecordFrom(ctx context.Context, src io.Reader,
+	length int64) (int64, error) {
+	var crc uint32
+	var err error
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.incomplete > 0 {
+		return 0, ErrIncompleteRecord
+	}
+	if w.options.codec != nil {
+		return 0, ErrStreamingUnsupported
+	}
+	if length < 0 || length > int64(w.options.maxRecordSize) {
+		return 0, ErrRecordTooLarge
+	}
+
+	if w.options.checksum {
+		if crc, err = streamChecksum(src, length); err != nil {
+			return 0, err
+		}
+	}
+
+	return w.writeFrame(ctx, src, uint32(length), crc)
+}
+
+/*
+writeFrame writes a record header with the specified length and checksum,
+followed by exactly length bytes of record data read from src.
+*/
+func (w *RecordWriter) writeFrame(ctx context.Context, src io.Reader,
+	length, crc uint32) (int64, error) {
+	var written, copied int64
+	var n int
+	var err error
+
+	if length == 0 && w.options.segmentSize > 0 {
+		return 0, ErrEmptyRecord
+	}
+	if err = w.limiter.wait(ctx, int64(length), 1); err != nil {
+		return 0, err
+	}
+	if err = w.flushBuffer(ctx); err != nil {
+		return 0, err
+	}
+
+	n, err = w.wrappedWriter.Write(ctx, w.appendHeader(w.header[:0], length,
+		crc))
+	w.position.Offset += int64(n)
+	written += int64(n)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return written, w.partialWrite(int(written), err)
+	}
+
+	copied, err = w.copyFrom(ctx, src, int64(length))
+	written += copied
+	if err != nil {
+		return written, w.partialWrite(int(written), err)
+	}
+
+	w.recordWritten(Position{Offset: w.position.Offset - written,
+		Index: w.position.Index}, length, crc)
+	w.position.Index++
+	return written, nil
+}
+
+/*
+copyFrom copies exactly length bytes from src to the output stream, checking
+the context for cancellation before every chunk.
+*/
+func (w *RecordWriter) copyFrom(ctx context.Context, src io.Reader,
+	length int64) (int64, error) {
+	var scratch = scratchPool.Get().(*[streamBufferSize]byte)
+	var buf = scratch[:]
+	var copied int64
+	var n int
+	var err error
+
+	defer scratchPool.Put(scratch)
+
+	for copied < length {
+		if err = ctx.Err(); err != nil {
+			return copied, err
+		}
+		if length-copied < int64(len(buf)) {
+			buf = buf[:length-copied]
+		}
+		if n, err = io.ReadFull(src, buf); err != nil {
+			return copied, noEOF(err)
+		}
+		if err = writeFull(ctx, w.wrappedWriter, buf[:n]); err != nil {
+			return copied, err
+		}
+		w.position.Offset += int64(n)
+		copied += int64(n)
+	}
+
+	return copied, nil
+}
+
+/*
+streamChecksum computes the checksum of the next length bytes of src and
+seeks back to where it started.
+*/
+func streamChecksum(src io.Reader, length int64) (uint32, error) {
+	var seeker io.Seeker
+	var start int64
+	var hash = crc32.New(crc32cTable)
+	var ok bool
+	var err error
+
+	if seeker, ok = src.(io.Seeker); !ok {
+		return 0, ErrStreamingUnsupported
+	}
+
+	if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	if _, err = io.CopyN(hash, src, length); err != nil {
+		return 0, noEOF(err)
+	}
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return hash.Sum32(), nil
+}
+
+/*
+recordBodyReader reads the data of a single record from the input stream of
+a RecordReader, verifying its checksum once all data has been read.
+*/
+type recordBodyReader struct {
+	reader    *RecordReader
+	ctx       context.Context
+	start     Position
+	length    int64
+	remaining int64
+	hash      hash.Hash32
+	crc       uint32
+}
+
+/*
+NextRecordReader returns an io.Reader over the data of the next record, along
+with the length of the record, so that large records can be processed
+incrementally rather than being read into memory as a whole. The returned
+reader returns io.EOF at the end of the record; if checksums are enabled,
+ErrChecksumMismatch is returned instead if the data is corrupted.
+
+The returned reader is only valid until the RecordReader is used again; any
+data of the record which has not been read by then is skipped. Records
+cannot be streamed when a codec is used, in which case
+ErrStreamingUnsupported is returned. After ResumeFrom() with a checkpoint
+taken in the middle of a record, the reader starts where the checkpoint was
+taken, and the length of the remaining data is returned.
+*/
+func (r *RecordReader) NextRecordReader(ctx context.Context) (io.Reader,
+	int64, error) {
+	var body *recordBodyReader
+	var length, crc uint32
+	var consumed = r.resumeConsumed
+	var err error
+
+	if r.options.codec != nil {
+		return nil, 0, recordError(r.position, ErrStreamingUnsupported)
+	}
+
+	if length, crc, err = r.nextHeader(ctx); err != nil {
+		return nil, 0, recordError(r.position, err)
+	}
+	if length > r.options.maxRecordSize {
+		return nil, 0, recordError(r.position, ErrRecordTooLarge)
+	}
+	if err = r.limiter.wait(ctx, int64(length), 1); err != nil {
+		r.unreadHeader(length, crc)
+		return nil, 0, recordError(r.position, err)
+	}
+
+	body = &recordBodyReader{
+		reader:    r,
+		ctx:       ctx,
+		start:     r.position,
+		length:    int64(length),
+		remaining: int64(length),
+		crc:       crc,
+	}
+	if r.options.checksum {
+		body.hash = crc32.New(crc32cTable)
+	}
+
+	r.pending = body
+	r.advance(length)
+
+	// Skip the part of the record consumed before the checkpoint passed
+	// to ResumeFrom(), still feeding it into the checksum.
+	if consumed > body.length {
+		consumed = body.length
+	}
+	if consumed > 0 {
+		if _, err = io.CopyN(io.Discard, body, consumed); err != nil {
+			return nil, 0, recordError(body.start, err)
+		}
+	}
+	return body, body.remaining, nil
+}
+
+func (b *recordBodyReader) Read(p []byte) (int, error) {
+	var n int
+	var err error
+
+	if b.remaining == 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+
+	n, err = b.reader.wrappedReader.Read(b.ctx, p)
+	b.remaining -= int64(n)
+	if b.hash != nil {
+		b.hash.Write(p[:n])
+	}
+
+	if b.remaining == 0 {
+		if b.hash != nil && b.hash.Sum32() != b.crc {
+			return n, ErrChecksumMismatch
+		}
+		return n, nil
+	}
+	if err == io.EOF {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+/*
+discardPending skips the unread data of the record returned by the last call
+to NextRecordReader(), if any.
+*/
+func (r *RecordReader) discardPending(ctx context.Context) error {
+	var body = r.pending
+	var err error
+
+	if body == nil {
+		return nil
+	}
+
+	r.pending = nil
+	if r.options.strictChecksums && body.hash != nil {
+		_, err = io.Copy(io.Discard, body)
+	} else {
+		err = r.skipBody(ctx, body.remaining)
+	}
+	body.remaining = 0
+	return err
+}
@@ -0,0 +1,138 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"strings"
+	"testing"
+)
+
+/*
+Records streamed from a reader must be readable as regular records, with
+and without checksums.
+*/
+func TestWriteRecordFrom(t *testing.T) {
+	var ctx = context.Background()
+	var data = strings.Repeat("0123456789", 10000)
+
+	for _, opts := range [][]Option{nil, {WithChecksum()}} {
+		var buf bytes.Buffer
+		var writer = NewIORecordWriter(&buf, opts...)
+		var rec []byte
+		var n int64
+		var err error
+
+		n, err = writer.WriteRecordFrom(ctx, strings.NewReader(data),
+			int64(len(data)))
+		if err != nil {
+			t.Error("Error writing record: ", err)
+		}
+		if n != int64(buf.Len()) || writer.Offset() != n {
+			t.Error("Unexpected write length ", n, " for ", buf.Len(), " bytes")
+		}
+
+		if rec, err = NewIORecordReader(&buf, opts...).ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != data {
+			t.Error("Record data does not match")
+		}
+	}
+}
+
+/*
+Streaming must fail if the data cannot be checksummed up front or if the
+source is too short.
+*/
+func TestWriteRecordFromErrors(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var err error
+
+	_, err = NewIORecordWriter(&buf, WithChecksum()).WriteRecordFrom(ctx,
+		bytes.NewBufferString("Hello"), 5)
+	if !errors.Is(err, ErrStreamingUnsupported) {
+		t.Error("Expected streaming to be unsupported, got: ", err)
+	}
+
+	_, err = NewIORecordWriter(&buf).WriteRecordFrom(ctx,
+		strings.NewReader("Hello"), 10)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("Expected unexpected EOF, got: ", err)
+	}
+}
+
+/*
+Canceling the context after the header of a streamed record has been written
+must stop writing and be reported as a partial write.
+*/
+func TestWriteRecordFromCancel(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&cancelingStream{buf: &buf, cancel: cancel})
+	var partial *PartialWriteError
+	var err error
+
+	_, err = writer.WriteRecordFrom(ctx, strings.NewReader("Hello"), 5)
+	if !errors.As(err, &partial) || partial.Written != 4 {
+		t.Error("Expected partial write of 4 bytes, got: ", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Error("Expected context to be canceled, got: ", err)
+	}
+	if buf.Len() != 4 {
+		t.Error("Unexpected data written after canceling: ", buf.Len())
+	}
+}
+
+/*
+Records must be readable incrementally, and partially read records must be
+skipped when moving on to the next record.
+*/
+func TestNextRecordReader(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithChecksum())
+	var reader *RecordReader
+	var body io.Reader
+	var data []byte
+	var length int64
+	var err error
+
+	for _, rec := range []string{"First record", "Second record", "Third"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+
+	reader = NewIORecordReader(&buf, WithChecksum())
+	if body, length, err = reader.NextRecordReader(ctx); err != nil {
+		t.Fatal("Error opening record: ", err)
+	}
+	if length != 12 {
+		t.Error("Unexpected record length: ", length)
+	}
+	if data, err = io.ReadAll(body); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(data) != "First record" {
+		t.Errorf("Unexpected data: got %q, expected First record", data)
+	}
+
+	if body, _, err = reader.NextRecordReader(ctx); err != nil {
+		t.Fatal("Error opening record: ", err)
+	}
+	data = make([]byte, 6)
+	if _, err = io.ReadFull(body, data); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+
+	if data, err = reader.ReadRecord(ctx); err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(data) != "Third" {
+		t.Errorf("Unexpected data: got %q, expected Third", data)
+	}
+}
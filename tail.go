@@ -0,0 +1,202 @@
+package recordio
+
+import (
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+	"sync"
+)
+
+/*
+FlushNotifier connects a RecordWriter appending to a file with readers
+tailing the same file in the same process. The writer publishes how much of
+the stream is visible every time it is flushed, and TailingReaders wait for
+these notifications instead of polling the file for new records.
+
+A FlushNotifier is safe for concurrent use, and can be shared by any number
+of TailingReaders, but only by a single writer.
+*/
+type FlushNotifier struct {
+	mtx     sync.Mutex
+	offset  int64
+	closed  bool
+	changed chan struct{}
+}
+
+/*
+NewFlushNotifier creates a new FlushNotifier with nothing visible yet.
+*/
+func NewFlushNotifier() *FlushNotifier {
+	return &FlushNotifier{
+		changed: make(chan struct{}),
+	}
+}
+
+/*
+WithFlushNotifier makes writers publish the offset up to which the stream
+has been flushed to the notifier after every successful Flush() or Sync(),
+and once they are closed, so that TailingReaders of the same file can read
+the records written up to there. Records become visible to TailingReaders
+only once they have been flushed, never while they are being written, so
+readers do not encounter partial records.
+*/
+func WithFlushNotifier(notifier *FlushNotifier) Option {
+	return func(o *options) {
+		o.flushNotifier = notifier
+	}
+}
+
+/*
+Flushed returns the offset up to which the stream is visible, and whether
+the writer has been closed, so that nothing will be appended anymore.
+*/
+func (n *FlushNotifier) Flushed() (int64, bool) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.offset, n.closed
+}
+
+/*
+Wait blocks until the stream is visible beyond offset, the writer has been
+closed or the context is canceled, and returns the results of Flushed()
+afterwards.
+*/
+func (n *FlushNotifier) Wait(ctx context.Context, offset int64) (int64,
+	bool, error) {
+	var changed chan struct{}
+
+	for {
+		n.mtx.Lock()
+		if n.offset > offset || n.closed {
+			defer n.mtx.Unlock()
+			return n.offset, n.closed, nil
+		}
+		changed = n.changed
+		n.mtx.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		}
+	}
+}
+
+/*
+publish records the new visible offset and wakes up all waiting readers.
+*/
+func (n *FlushNotifier) publish(offset int64, closed bool) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if offset > n.offset {
+		n.offset = offset
+	}
+	n.closed = n.closed || closed
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+/*
+notifyFlushed publishes the position of the writer to its FlushNotifier, if
+it has one.
+*/
+func (w *RecordWriter) notifyFlushed(closed bool) {
+	if w.options.flushNotifier != nil {
+		w.options.flushNotifier.publish(w.position.Offset, closed)
+	}
+}
+
+/*
+TailingReader reads the records of a file which is still being appended to
+by a RecordWriter in the same process, following it as it grows. Only
+records which the writer has flushed are read; once all of them have been
+read, ReadRecord() waits for the writer to flush more, as announced through
+a FlushNotifier shared with the writer using WithFlushNotifier(). io.EOF is
+returned once the writer has been closed and all of its records have been
+read.
+
+The reader and the writer must start at the same offset of the file, e.g.
+at the beginning of a new file, and the reader must use its own file
+descriptor. WithReadahead() must not be used for tailing readers, since it
+reads beyond the records which have been flushed.
+
+As with RecordReader, TailingReaders are not thread safe; use one per
+goroutine.
+*/
+type TailingReader struct {
+	reader   *RecordReader
+	notifier *FlushNotifier
+}
+
+/*
+NewTailingReader creates a new TailingReader reading the records of the
+specified input stream with the specified options as they are flushed by
+the writer publishing to notifier. No actions are performed at the time.
+*/
+func NewTailingReader(reader filesystem.ReadCloser, notifier *FlushNotifier,
+	opts ...Option) *TailingReader {
+	return &TailingReader{
+		reader:   NewRecordReader(reader, opts...),
+		notifier: notifier,
+	}
+}
+
+/*
+ReadRecord returns the next record which has been flushed by the writer,
+waiting for it to be flushed if necessary. io.EOF is returned once the
+writer has been closed and all of its records have been read. If the
+context is canceled while waiting, its error is returned, and reading can
+be continued with a new context later.
+*/
+func (r *TailingReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var offset int64
+	var closed bool
+	var err error
+
+	for {
+		offset, closed = r.notifier.Flushed()
+		if r.reader.position.Offset < offset {
+			return r.reader.ReadRecord(ctx)
+		}
+		if closed {
+			return nil, io.EOF
+		}
+
+		_, _, err = r.notifier.Wait(ctx, r.reader.position.Offset)
+		if err != nil {
+			return nil, recordError(r.reader.position, err)
+		}
+	}
+}
+
+/*
+ReadMessage reads the next record as described for ReadRecord() and parses
+it as a protocol buffer of the type passed in.
+*/
+func (r *TailingReader) ReadMessage(ctx context.Context, pb Message) error {
+	var pos = r.reader.position
+	var buf []byte
+	var err error
+
+	if buf, err = r.ReadRecord(ctx); err != nil {
+		return err
+	}
+
+	return recordError(pos, r.reader.options.unmarshalMessage(buf, pb))
+}
+
+/*
+Tell returns the position of the reader, as described for
+RecordReader.Tell().
+*/
+func (r *TailingReader) Tell() Position {
+	return r.reader.Tell()
+}
+
+/*
+Close closes the underlying RecordReader.
+*/
+func (r *TailingReader) Close(ctx context.Context) error {
+	return r.reader.Close(ctx)
+}
@@ -0,0 +1,133 @@
+package recordio
+
+import (
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+/*
+A tailing reader must only see records once they have been flushed, wait for
+more records without polling, and end once the writer has been closed.
+*/
+func TestTailingReader(t *testing.T) {
+	var ctx = context.Background()
+	var name = t.TempDir() + "/tail"
+	var notifier = NewFlushNotifier()
+	var out, in *os.File
+	var writer *RecordWriter
+	var reader *TailingReader
+	var timeout context.Context
+	var cancel context.CancelFunc
+	var got = make(chan string)
+	var next string
+	var ok bool
+	var err error
+
+	if out, err = os.Create(name); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	if in, err = os.Open(name); err != nil {
+		t.Fatal("Error opening file: ", err)
+	}
+	writer = NewRecordWriter(NewIOWriteCloser(out), WithChecksum(),
+		WithFooter(), WithFlushNotifier(notifier))
+	reader = NewTailingReader(NewIOReadCloser(in), notifier, WithChecksum(),
+		WithFooter())
+	defer reader.Close(ctx)
+
+	// Records which have been written but not flushed are not visible.
+	if _, err = writer.Write(ctx, []byte("Rec 0")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	timeout, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	if _, err = reader.ReadRecord(timeout); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Error("Expected unflushed record to be invisible, got: ", err)
+	}
+	cancel()
+
+	go func() {
+		var rec []byte
+		var err error
+
+		defer close(got)
+		for {
+			if rec, err = reader.ReadRecord(ctx); err != nil {
+				if err != io.EOF {
+					got <- err.Error()
+				}
+				return
+			}
+			got <- string(rec)
+		}
+	}()
+
+	for i := 1; i <= 6; i++ {
+		if err = writer.Flush(ctx); err != nil {
+			t.Error("Error flushing writer: ", err)
+		}
+		if next = <-got; next != fmt.Sprint("Rec ", i-1) {
+			t.Error("Unexpected record: ", next)
+		}
+		if i < 6 {
+			if _, err = writer.Write(ctx,
+				[]byte(fmt.Sprint("Rec ", i))); err != nil {
+				t.Error("Error writing record: ", err)
+			}
+		}
+	}
+
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+	if next, ok = <-got; ok {
+		t.Error("Unexpected record after closing: ", next)
+	}
+}
+
+/*
+Errors parsing messages must carry the position of the record they were
+read from rather than that of the following record.
+*/
+func TestTailingReaderMessageError(t *testing.T) {
+	var ctx = context.Background()
+	var name = t.TempDir() + "/tail"
+	var notifier = NewFlushNotifier()
+	var out, in *os.File
+	var writer *RecordWriter
+	var reader *TailingReader
+	var data MessageForTest
+	var recErr *RecordError
+	var err error
+
+	if out, err = os.Create(name); err != nil {
+		t.Fatal("Error creating file: ", err)
+	}
+	if in, err = os.Open(name); err != nil {
+		t.Fatal("Error opening file: ", err)
+	}
+	writer = NewRecordWriter(NewIOWriteCloser(out),
+		WithFlushNotifier(notifier))
+	reader = NewTailingReader(NewIOReadCloser(in), notifier)
+	defer reader.Close(ctx)
+
+	if _, err = writer.Write(ctx, []byte("Not a message")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing writer: ", err)
+	}
+
+	err = reader.ReadMessage(ctx, &data)
+	if !errors.As(err, &recErr) {
+		t.Fatal("Expected RecordError, got: ", err)
+	}
+	if recErr.Index != 0 || recErr.Offset != 0 {
+		t.Error("Unexpected position of error: ", err)
+	}
+}
@@ -0,0 +1,134 @@
+package recordio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"io"
+	"strings"
+)
+
+const textDumpRecordMarker = "# record "
+
+/*
+ErrTextOutsideRecord is returned by ImportText() if the text dump contains
+text before the first record marker, which would otherwise be lost.
+*/
+var ErrTextOutsideRecord = errors.New(
+	"Text dump contains text before the first record")
+
+/*
+textMarshalOptions produces the text of records in dumps. prototext.Format()
+is not used since its output is explicitly unstable.
+*/
+var textMarshalOptions = prototext.MarshalOptions{
+	Multiline: true,
+	Indent:    "  ",
+}
+
+/*
+DumpText reads all records from src, parses them as protocol buffers of the
+same type as prototype and writes them to dst in the protocol buffer text
+format. Every record is preceded by a comment line containing its index and
+the byte offset of its header in the input stream:
+
+	# record 0 at offset 0
+	message: "Hello"
+
+The contents of prototype are overwritten. The number of records dumped is
+returned. This is intended for debugging; the output can be turned back into
+a record file using ImportText().
+*/
+func DumpText(ctx context.Context, dst io.Writer, src *RecordReader,
+	prototype Message) (int64, error) {
+	var count, offset int64
+	var rec, text []byte
+	var err error
+
+	for {
+		offset = src.Offset()
+		if rec, err = src.ReadRecord(ctx); err == io.EOF {
+			return count, nil
+		} else if err != nil {
+			return count, err
+		}
+
+		if err = src.options.unmarshalMessage(rec, prototype); err != nil {
+			return count, fmt.Errorf("Error parsing record %d: %v", count, err)
+		}
+
+		if text, err = textMarshalOptions.Marshal(
+			protoadapt.MessageV2Of(prototype)); err != nil {
+			return count, fmt.Errorf("Error formatting record %d: %v", count,
+				err)
+		}
+		if _, err = fmt.Fprintf(dst, "%s%d at offset %d\n%s",
+			textDumpRecordMarker, count, offset, text); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+}
+
+/*
+ImportText parses a text dump as written by DumpText() from src, with the
+message type described by desc, and writes every message as a new record to
+dst. The messages are built using dynamicpb, so no generated code is needed
+for the message type; the descriptor can be taken from generated code,
+protoregistry or a descriptor set. The number of records written is
+returned. ErrTextOutsideRecord is returned if the dump contains anything but
+empty lines before the first record marker.
+*/
+func ImportText(ctx context.Context, dst *RecordWriter, src io.Reader,
+	desc protoreflect.MessageDescriptor) (int64, error) {
+	var scanner = bufio.NewScanner(src)
+	var msg = dynamicpb.NewMessage(desc)
+	var text strings.Builder
+	var count int64
+	var started bool
+	var err error
+
+	var flush = func() error {
+		if !started {
+			return nil
+		}
+		if err := prototext.Unmarshal([]byte(text.String()),
+			msg); err != nil {
+			return fmt.Errorf("Error parsing record %d: %v", count, err)
+		}
+		if err := dst.WriteMessage(ctx,
+			protoadapt.MessageV1Of(msg)); err != nil {
+			return err
+		}
+		text.Reset()
+		count++
+		return nil
+	}
+
+	scanner.Buffer(nil, 1<<30)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), textDumpRecordMarker) {
+			if err = flush(); err != nil {
+				return count, err
+			}
+			started = true
+			continue
+		}
+		if !started && strings.TrimSpace(scanner.Text()) != "" {
+			return count, ErrTextOutsideRecord
+		}
+		text.WriteString(scanner.Text())
+		text.WriteByte('\n')
+	}
+	if err = scanner.Err(); err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
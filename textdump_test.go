@@ -0,0 +1,90 @@
+package recordio
+
+import (
+	"bytes"
+	"github.com/childoftheuniverse/filesystem-internal"
+	"golang.org/x/net/context"
+	"strings"
+	"testing"
+)
+
+/*
+Dump protocol buffer records as text and import the dump into a new file.
+*/
+func TestDumpAndImportText(t *testing.T) {
+	var ctx = context.Background()
+	var buf = internal.NewAnonymousFile()
+	var outbuf = internal.NewAnonymousFile()
+	var writer = NewRecordWriter(buf)
+	var reader *RecordReader
+	var dump bytes.Buffer
+	var data MessageForTest
+	var count int64
+	var err error
+
+	data.Message = "Test data"
+	writer.WriteMessage(ctx, &data)
+	data.Message = "Toast\nData"
+	writer.WriteMessage(ctx, &data)
+
+	// Reset position
+	writer.Close(ctx)
+
+	count, err = DumpText(ctx, &dump, NewRecordReader(buf), &data)
+	if err != nil {
+		t.Error("Error dumping records: ", err)
+	}
+	if count != 2 {
+		t.Error("Expected 2 records to be dumped, got ", count)
+	}
+	if !strings.Contains(dump.String(), "# record 1 at offset 15\n") {
+		t.Error("Record marker missing from dump: ", dump.String())
+	}
+
+	writer = NewRecordWriter(outbuf)
+	count, err = ImportText(ctx, writer, &dump,
+		data.ProtoReflect().Descriptor())
+	if err != nil {
+		t.Error("Error importing dump: ", err)
+	}
+	if count != 2 {
+		t.Error("Expected 2 records to be imported, got ", count)
+	}
+
+	// Reset position
+	writer.Close(ctx)
+	reader = NewRecordReader(outbuf)
+
+	for _, expected := range []string{"Test data", "Toast\nData"} {
+		data.Reset()
+		if err = reader.ReadMessage(ctx, &data); err != nil {
+			t.Error("Unable to re-read the message: ", err)
+		}
+		if data.Message != expected {
+			t.Errorf("Expected: %q, got: %q", expected, data.Message)
+		}
+	}
+}
+
+/*
+Text before the first record marker must not be dropped silently.
+*/
+func TestImportTextOutsideRecord(t *testing.T) {
+	var ctx = context.Background()
+	var writer = NewRecordWriter(internal.NewAnonymousFile())
+	var desc = (&MessageForTest{}).ProtoReflect().Descriptor()
+	var err error
+
+	_, err = ImportText(ctx, writer, strings.NewReader(
+		"message: \"Lost\"\n# record 0 at offset 0\nmessage: \"Kept\"\n"),
+		desc)
+	if err != ErrTextOutsideRecord {
+		t.Error("Expected ErrTextOutsideRecord, got ", err)
+	}
+
+	if _, err = ImportText(ctx, writer, strings.NewReader(
+		"\n# record 0 at offset 0\nmessage: \"Kept\"\n"),
+		desc); err != nil {
+		t.Error("Error importing dump starting with an empty line: ", err)
+	}
+}
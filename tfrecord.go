@@ -0,0 +1,141 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+)
+
+/*
+tfRecordHeaderSize is the size of the header of a TFRecord: the length of
+the record as 8 byte little endian integer, followed by its masked CRC-32C.
+*/
+const tfRecordHeaderSize = 12
+
+/*
+maskedChecksum computes the masked CRC-32C used by TFRecord files, which
+keeps checksums of data containing checksums from being trivial.
+*/
+func maskedChecksum(data []byte) uint32 {
+	var crc = checksum(data)
+
+	return (crc>>15 | crc<<17) + 0xa282ead8
+}
+
+/*
+TFRecordWriter writes records in the TFRecord format used by TensorFlow,
+where every record is framed by its length and checksums of both the length
+and the data. Like RecordWriter, TFRecordWriters are not thread safe.
+*/
+type TFRecordWriter struct {
+	wrappedWriter filesystem.WriteCloser
+	header        [tfRecordHeaderSize]byte
+}
+
+/*
+NewTFRecordWriter creates a new TFRecordWriter wrapped around the specified
+output stream. No actions are performed at the time.
+*/
+func NewTFRecordWriter(writer filesystem.WriteCloser) *TFRecordWriter {
+	return &TFRecordWriter{
+		wrappedWriter: writer,
+	}
+}
+
+/*
+Write writes the specified record to the output stream, and returns the
+number of bytes of record data written.
+*/
+func (w *TFRecordWriter) Write(ctx context.Context, rec []byte) (int, error) {
+	var footer [4]byte
+	var err error
+
+	binary.LittleEndian.PutUint64(w.header[:8], uint64(len(rec)))
+	binary.LittleEndian.PutUint32(w.header[8:], maskedChecksum(w.header[:8]))
+	binary.LittleEndian.PutUint32(footer[:], maskedChecksum(rec))
+
+	if err = writeFull(ctx, w.wrappedWriter, w.header[:]); err != nil {
+		return 0, err
+	}
+	if err = writeFull(ctx, w.wrappedWriter, rec); err != nil {
+		return 0, err
+	}
+	if err = writeFull(ctx, w.wrappedWriter, footer[:]); err != nil {
+		return 0, err
+	}
+	return len(rec), nil
+}
+
+/*
+Close just delegates to the close function of the underlying writer.
+*/
+func (w *TFRecordWriter) Close(ctx context.Context) error {
+	return w.wrappedWriter.Close(ctx)
+}
+
+/*
+TFRecordReader reads records from a file in the TFRecord format used by
+TensorFlow, verifying the checksums of their lengths and data. Only
+WithMaxRecordSize() is taken from the options; TFRecord files have no other
+optional features.
+*/
+type TFRecordReader struct {
+	wrappedReader filesystem.ReadCloser
+	options       options
+	header        [tfRecordHeaderSize]byte
+}
+
+/*
+NewTFRecordReader creates a new TFRecordReader wrapped around the specified
+input stream. No actions are performed at the time.
+*/
+func NewTFRecordReader(reader filesystem.ReadCloser,
+	opts ...Option) *TFRecordReader {
+	return &TFRecordReader{
+		wrappedReader: reader,
+		options:       applyOptions(opts),
+	}
+}
+
+/*
+ReadRecord reads the next record from the input stream. io.EOF is returned
+at the end of the stream, and ErrChecksumMismatch if the length or the data
+of the record are corrupted.
+*/
+func (r *TFRecordReader) ReadRecord(ctx context.Context) ([]byte, error) {
+	var length uint64
+	var rec []byte
+	var err error
+
+	if _, err = readFull(ctx, r.wrappedReader, r.header[:]); err != nil {
+		return nil, err
+	}
+	if binary.LittleEndian.Uint32(r.header[8:]) !=
+		maskedChecksum(r.header[:8]) {
+		return nil, ErrChecksumMismatch
+	}
+
+	// The checksum of the length has been verified, so it is only checked
+	// against the limit before allocating the record.
+	length = binary.LittleEndian.Uint64(r.header[:8])
+	if length > uint64(r.options.maxRecordSize) {
+		return nil, ErrRecordTooLarge
+	}
+
+	rec = make([]byte, length+4)
+	if _, err = readFull(ctx, r.wrappedReader, rec); err != nil {
+		return nil, noEOF(err)
+	}
+	if binary.LittleEndian.Uint32(rec[length:]) !=
+		maskedChecksum(rec[:length]) {
+		return nil, ErrChecksumMismatch
+	}
+	return rec[:length:length], nil
+}
+
+/*
+Close just delegates to the close function of the underlying reader.
+*/
+func (r *TFRecordReader) Close(ctx context.Context) error {
+	return r.wrappedReader.Close(ctx)
+}
@@ -0,0 +1,82 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Records written as TFRecords must be read back, with the checksums of the
+length and the data verified.
+*/
+func TestTFRecordRoundTrip(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewTFRecordWriter(NewIOWriteCloser(&buf))
+	var reader *TFRecordReader
+	var data []byte
+	var rec []byte
+	var err error
+
+	for _, r := range []string{"Hello", "", "World"} {
+		if _, err = writer.Write(ctx, []byte(r)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if buf.Len() != 3*16+10 {
+		t.Error("Unexpected size of TFRecord file: ", buf.Len())
+	}
+	data = buf.Bytes()
+
+	reader = NewTFRecordReader(NewIOReadCloser(bytes.NewReader(data)))
+	for _, r := range []string{"Hello", "", "World"} {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != r {
+			t.Errorf("Unexpected data: got %q, expected %q", rec, r)
+		}
+	}
+	if _, err = reader.ReadRecord(ctx); err != io.EOF {
+		t.Error("Expected EOF, got ", err)
+	}
+
+	reader = NewTFRecordReader(NewIOReadCloser(bytes.NewReader(data)),
+		WithMaxRecordSize(4))
+	if _, err = reader.ReadRecord(ctx); !errors.Is(err, ErrRecordTooLarge) {
+		t.Error("Expected ErrRecordTooLarge, got ", err)
+	}
+
+	reader = NewTFRecordReader(NewIOReadCloser(bytes.NewReader(data[:20])))
+	if _, err = reader.ReadRecord(ctx); err != io.ErrUnexpectedEOF {
+		t.Error("Expected io.ErrUnexpectedEOF, got ", err)
+	}
+}
+
+/*
+Corrupted lengths and data of TFRecords must be detected.
+*/
+func TestTFRecordCorrupted(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewTFRecordWriter(NewIOWriteCloser(&buf))
+	var reader *TFRecordReader
+	var data []byte
+	var err error
+
+	writer.Write(ctx, []byte("Hello"))
+
+	for _, offset := range []int{0, 13} {
+		data = append([]byte(nil), buf.Bytes()...)
+		data[offset] ^= 1
+
+		reader = NewTFRecordReader(NewIOReadCloser(bytes.NewReader(data)))
+		if _, err = reader.ReadRecord(ctx); err != ErrChecksumMismatch {
+			t.Error("Expected ErrChecksumMismatch for corruption at ",
+				offset, ", got ", err)
+		}
+	}
+}
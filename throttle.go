@@ -0,0 +1,124 @@
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"time"
+)
+
+/*
+WithRateLimit limits the throughput of readers and writers to the specified
+number of bytes of record data and number of records per second, e.g. so
+that backfill jobs do not overload shared storage. A limit of 0 disables
+it. Bursts of up to one second worth of data are permitted.
+
+Reads and writes wait as long as needed to stay within the limits before
+accessing the stream. If the context is canceled while waiting, the error
+of the context is returned and nothing has been read or written.
+*/
+func WithRateLimit(bytesPerSecond, recordsPerSecond float64) Option {
+	return func(o *options) {
+		o.bytesPerSecond = bytesPerSecond
+		o.recordsPerSecond = recordsPerSecond
+	}
+}
+
+/*
+rateLimiter implements WithRateLimit() using two token buckets. Taking more
+tokens than available leaves a debt, which the caller waits to be paid off,
+so that records larger than the burst size can still pass.
+*/
+type rateLimiter struct {
+	bytesPerSecond   float64
+	recordsPerSecond float64
+
+	bytes   float64
+	records float64
+	last    time.Time
+}
+
+/*
+newRateLimiter returns a rateLimiter for the limits set in the options, or
+nil if there are none.
+*/
+func newRateLimiter(o options) *rateLimiter {
+	if o.bytesPerSecond <= 0 && o.recordsPerSecond <= 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		bytesPerSecond:   o.bytesPerSecond,
+		recordsPerSecond: o.recordsPerSecond,
+		bytes:            o.bytesPerSecond,
+		records:          o.recordsPerSecond,
+		last:             time.Now(),
+	}
+}
+
+/*
+wait blocks until the specified amount of data and number of records may
+pass. The tokens are returned if the context is canceled in the meantime.
+A nil rateLimiter never blocks.
+*/
+func (l *rateLimiter) wait(ctx context.Context, bytes int64,
+	records int) error {
+	var now time.Time
+	var delay time.Duration
+	var timer *time.Timer
+
+	if l == nil {
+		return nil
+	}
+
+	now = time.Now()
+	l.bytes = refill(l.bytes, l.bytesPerSecond, now.Sub(l.last))
+	l.records = refill(l.records, l.recordsPerSecond, now.Sub(l.last))
+	l.last = now
+
+	if l.bytesPerSecond > 0 {
+		l.bytes -= float64(bytes)
+		delay = debtDelay(l.bytes, l.bytesPerSecond)
+	}
+	if l.recordsPerSecond > 0 {
+		l.records -= float64(records)
+		if d := debtDelay(l.records, l.recordsPerSecond); d > delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer = time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.bytes += float64(bytes)
+		l.records += float64(records)
+		return ctx.Err()
+	}
+}
+
+/*
+refill adds the tokens accumulated over the elapsed time to a bucket,
+limiting it to one second worth of tokens.
+*/
+func refill(tokens, rate float64, elapsed time.Duration) float64 {
+	tokens += rate * elapsed.Seconds()
+	if tokens > rate {
+		return rate
+	}
+	return tokens
+}
+
+/*
+debtDelay returns the time it takes to pay off a negative number of tokens.
+*/
+func debtDelay(tokens, rate float64) time.Duration {
+	if tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tokens / rate * float64(time.Second))
+}
@@ -0,0 +1,65 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"testing"
+	"time"
+)
+
+/*
+Writes beyond the burst must be delayed according to the byte rate.
+*/
+func TestRateLimitBytes(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf, WithRateLimit(1000, 0))
+	var start time.Time
+	var err error
+
+	if _, err = writer.Write(ctx, make([]byte, 1000)); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	start = time.Now()
+	if _, err = writer.Write(ctx, make([]byte, 100)); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Error("Write was not throttled, took ", elapsed)
+	}
+}
+
+/*
+Reads beyond the burst must be delayed according to the record rate, and
+canceling the context while waiting must leave the record to be read.
+*/
+func TestRateLimitRecords(t *testing.T) {
+	var ctx = context.Background()
+	var timeout context.Context
+	var cancel context.CancelFunc
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader = NewIORecordReader(&buf, WithRateLimit(0, 2))
+	var rec []byte
+	var err error
+
+	for _, data := range []string{"One", "Two", "Three"} {
+		writer.Write(ctx, []byte(data))
+	}
+
+	reader.ReadRecord(ctx)
+	reader.ReadRecord(ctx)
+
+	timeout, cancel = context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err = reader.ReadRecord(timeout); !errors.Is(err,
+		context.DeadlineExceeded) {
+		t.Error("Expected deadline to be exceeded, got: ", err)
+	}
+
+	if rec, err = reader.ReadRecord(ctx); err != nil || string(rec) != "Three" {
+		t.Errorf("Unexpected record after cancellation: %q (%v)", rec, err)
+	}
+}
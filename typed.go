@@ -0,0 +1,134 @@
+//go:build go1.18
+
+package recordio
+
+import (
+	"golang.org/x/net/context"
+	"reflect"
+	"sync"
+)
+
+/*
+TypedWriter writes protocol buffers of a single type T to a RecordWriter.
+T must be a pointer to a generated message type, e.g. *mypb.Event.
+*/
+type TypedWriter[T Message] struct {
+	writer *RecordWriter
+}
+
+/*
+NewTypedWriter creates a new TypedWriter writing messages to the specified
+RecordWriter.
+*/
+func NewTypedWriter[T Message](writer *RecordWriter) *TypedWriter[T] {
+	return &TypedWriter[T]{
+		writer: writer,
+	}
+}
+
+/*
+Write serializes the message and writes it as a new record.
+*/
+func (w *TypedWriter[T]) Write(ctx context.Context, pb T) error {
+	return w.writer.WriteMessage(ctx, pb)
+}
+
+/*
+Close closes the underlying RecordWriter.
+*/
+func (w *TypedWriter[T]) Close(ctx context.Context) error {
+	return w.writer.Close(ctx)
+}
+
+/*
+TypedReader reads protocol buffers of a single type T from a RecordReader.
+T must be a pointer to a generated message type, e.g. *mypb.Event.
+*/
+type TypedReader[T Message] struct {
+	reader      *RecordReader
+	messageType reflect.Type
+}
+
+/*
+NewTypedReader creates a new TypedReader reading messages from the specified
+RecordReader.
+*/
+func NewTypedReader[T Message](reader *RecordReader) *TypedReader[T] {
+	var zero T
+
+	return &TypedReader[T]{
+		reader:      reader,
+		messageType: reflect.TypeOf(zero).Elem(),
+	}
+}
+
+/*
+ReadNext reads the next record and returns it parsed as a newly allocated
+message of type T. io.EOF is returned at the end of the stream.
+*/
+func (r *TypedReader[T]) ReadNext(ctx context.Context) (T, error) {
+	var pb = reflect.New(r.messageType).Interface().(T)
+	var zero T
+	var err error
+
+	if err = r.reader.ReadMessage(ctx, pb); err != nil {
+		return zero, err
+	}
+
+	return pb, nil
+}
+
+/*
+Close closes the underlying RecordReader.
+*/
+func (r *TypedReader[T]) Close(ctx context.Context) error {
+	return r.reader.Close(ctx)
+}
+
+/*
+MessagePool keeps messages of type T for reuse, so that scans parsing many
+messages using ReadMessageReuse() do not allocate a new message for each of
+them. T must be a pointer to a generated message type, e.g. *mypb.Event.
+A MessagePool is safe for concurrent use.
+
+Messages obtained from Get() belong to the caller until they are passed to
+Put(), after which neither the message nor anything obtained from its fields
+may be used anymore.
+*/
+type MessagePool[T Message] struct {
+	pool sync.Pool
+}
+
+/*
+NewMessagePool creates a new, empty MessagePool for messages of type T.
+*/
+func NewMessagePool[T Message]() *MessagePool[T] {
+	var zero T
+	var messageType = reflect.TypeOf(zero).Elem()
+
+	return &MessagePool[T]{
+		pool: sync.Pool{
+			New: func() interface{} {
+				return reflect.New(messageType).Interface()
+			},
+		},
+	}
+}
+
+/*
+Get returns an empty message from the pool, allocating a new one if the
+pool is empty.
+*/
+func (p *MessagePool[T]) Get() T {
+	return p.pool.Get().(T)
+}
+
+/*
+Put clears the message and returns it to the pool. Clearing it drops all
+references to its previous contents, so they can be garbage collected and
+do not show up in the message when it is handed out again.
+*/
+func (p *MessagePool[T]) Put(pb T) {
+	pb.Reset()
+	p.pool.Put(pb)
+}
@@ -0,0 +1,83 @@
+//go:build go1.18
+
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Write and read back messages using the typed reader and writer.
+*/
+func TestTypedReaderWriter(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewTypedWriter[*MessageForTest](NewIORecordWriter(&buf))
+	var reader *TypedReader[*MessageForTest]
+	var first, second *MessageForTest
+	var err error
+
+	for _, msg := range []string{"One", "Two"} {
+		if err = writer.Write(ctx, &MessageForTest{Message: msg}); err != nil {
+			t.Error("Error writing message: ", err)
+		}
+	}
+
+	reader = NewTypedReader[*MessageForTest](NewIORecordReader(&buf))
+	if first, err = reader.ReadNext(ctx); err != nil {
+		t.Error("Error reading message: ", err)
+	}
+	if second, err = reader.ReadNext(ctx); err != nil {
+		t.Error("Error reading message: ", err)
+	}
+
+	if first.Message != "One" || second.Message != "Two" {
+		t.Errorf("Unexpected messages: %q, %q", first.Message, second.Message)
+	}
+
+	if _, err = reader.ReadNext(ctx); err != io.EOF {
+		t.Error("Expected EOF, got: ", err)
+	}
+}
+
+/*
+Messages from a MessagePool must be filled by ReadMessageReuse without
+keeping contents from earlier use.
+*/
+func TestMessagePool(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var pool = NewMessagePool[*MessageForTest]()
+	var pb *MessageForTest
+	var err error
+
+	writer.WriteMessage(ctx, &MessageForTest{Message: "One"})
+	writer.WriteMessage(ctx, &MessageForTest{})
+
+	reader = NewIORecordReader(&buf)
+	pb = pool.Get()
+	if err = reader.ReadMessageReuse(ctx, pb); err != nil {
+		t.Error("Error reading message: ", err)
+	}
+	if pb.Message != "One" {
+		t.Errorf("Unexpected message: %v", pb)
+	}
+	pool.Put(pb)
+
+	pb = pool.Get()
+	if pb.Message != "" {
+		t.Errorf("Pooled message was not cleared: %v", pb)
+	}
+	pb.Message = "Stale"
+	if err = reader.ReadMessageReuse(ctx, pb); err != nil {
+		t.Error("Error reading message: ", err)
+	}
+	if pb.Message != "" {
+		t.Errorf("Message was not reset before parsing: %v", pb)
+	}
+}
@@ -0,0 +1,79 @@
+package recordio
+
+import (
+	"encoding"
+	"errors"
+	"golang.org/x/net/context"
+)
+
+/*
+ErrUnsupportedValue is returned by WriteValue() and ReadValue() if the value
+passed in does not implement any of the supported interfaces.
+*/
+var ErrUnsupportedValue = errors.New("Value cannot be converted to a record")
+
+/*
+Marshaler is implemented by types which can convert themselves to the data
+of a record.
+*/
+type Marshaler interface {
+	MarshalRecord() ([]byte, error)
+}
+
+/*
+Unmarshaler is implemented by types which can restore themselves from the
+data of a record written by their MarshalRecord() method.
+*/
+type Unmarshaler interface {
+	UnmarshalRecord(data []byte) error
+}
+
+/*
+valueEncoding is the Encoding used by WriteValue() and ReadValue().
+*/
+type valueEncoding struct{}
+
+func (valueEncoding) Marshal(v interface{}) ([]byte, error) {
+	switch m := v.(type) {
+	case Marshaler:
+		return m.MarshalRecord()
+	case encoding.BinaryMarshaler:
+		return m.MarshalBinary()
+	}
+	return nil, ErrUnsupportedValue
+}
+
+func (valueEncoding) Unmarshal(data []byte, v interface{}) error {
+	switch u := v.(type) {
+	case Unmarshaler:
+		return u.UnmarshalRecord(data)
+	case encoding.BinaryUnmarshaler:
+		return u.UnmarshalBinary(data)
+	}
+	return ErrUnsupportedValue
+}
+
+/*
+WriteValue converts the specified value to a record using its
+MarshalRecord() method and writes it to the underlying output stream. Types
+implementing encoding.BinaryMarshaler instead, such as time.Time or
+url.URL, are converted using their MarshalBinary() method.
+ErrUnsupportedValue is returned if v implements neither.
+
+The same warnings about locking as for Write() apply to this method.
+*/
+func (w *RecordWriter) WriteValue(ctx context.Context, v interface{}) error {
+	return w.WriteEncoded(ctx, valueEncoding{}, v)
+}
+
+/*
+ReadValue reads the next record from the input stream and restores v from it
+using its UnmarshalRecord() method, or its UnmarshalBinary() method for types
+implementing encoding.BinaryUnmarshaler. ErrUnsupportedValue is returned if
+v implements neither; the reader will still be advanced by a record.
+
+All warnings from the ReadRecord() method apply here as well.
+*/
+func (r *RecordReader) ReadValue(ctx context.Context, v interface{}) error {
+	return r.ReadEncoded(ctx, valueEncoding{}, v)
+}
@@ -0,0 +1,85 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/net/context"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+pair is a simple type implementing Marshaler and Unmarshaler.
+*/
+type pair struct {
+	Key, Value string
+}
+
+func (p *pair) MarshalRecord() ([]byte, error) {
+	return []byte(p.Key + "=" + p.Value), nil
+}
+
+func (p *pair) UnmarshalRecord(data []byte) error {
+	var parts = strings.SplitN(string(data), "=", 2)
+
+	if len(parts) != 2 {
+		return errors.New("Not a pair")
+	}
+	p.Key, p.Value = parts[0], parts[1]
+	return nil
+}
+
+/*
+Values implementing Marshaler must round trip through WriteValue() and
+ReadValue().
+*/
+func TestWriteAndReadValue(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var value pair
+	var err error
+
+	if err = writer.WriteValue(ctx, &pair{Key: "a", Value: "b"}); err != nil {
+		t.Error("Error writing value: ", err)
+	}
+	if err = writer.WriteValue(ctx, 42); !errors.Is(err, ErrUnsupportedValue) {
+		t.Error("Expected unsupported value error, got: ", err)
+	}
+
+	reader = NewIORecordReader(&buf)
+	if err = reader.ReadValue(ctx, &value); err != nil {
+		t.Error("Error reading value: ", err)
+	}
+	if value.Key != "a" || value.Value != "b" {
+		t.Error("Unexpected value: ", value)
+	}
+}
+
+/*
+Standard library types implementing encoding.BinaryMarshaler must round trip
+through WriteValue() and ReadValue().
+*/
+func TestBinaryMarshalerValue(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *RecordReader
+	var now = time.Date(2017, 3, 14, 15, 9, 26, 0, time.UTC)
+	var value time.Time
+	var err error
+
+	if err = writer.WriteValue(ctx, now); err != nil {
+		t.Error("Error writing value: ", err)
+	}
+
+	reader = NewIORecordReader(&buf)
+	if err = reader.ReadValue(ctx, &value); err != nil {
+		t.Error("Error reading value: ", err)
+	}
+	if !value.Equal(now) {
+		t.Error("Unexpected value: ", value)
+	}
+}
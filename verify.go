@@ -0,0 +1,182 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+ErrMissingFooter is reported by Verify() if a stream written with
+WithFooter() ends without a footer.
+*/
+var ErrMissingFooter = errors.New("Stream ends without a footer")
+
+/*
+ErrFooterMismatch is reported by Verify() if the number of records or the
+sparse index in the footer do not match the records in the stream.
+*/
+var ErrFooterMismatch = errors.New("Footer does not match records")
+
+/*
+VerifyReport describes the result of verifying a stream using Verify().
+*/
+type VerifyReport struct {
+	/*
+		Records is the number of valid records found before the first
+		error.
+	*/
+	Records int64
+
+	/*
+		Valid is the length of the valid part of the stream, up to the
+		first error, including the footer if the stream is intact.
+	*/
+	Valid int64
+
+	/*
+		FirstError is the first problem found in the stream, or nil if
+		the stream is intact.
+	*/
+	FirstError error
+
+	/*
+		FirstErrorOffset is the offset at which FirstError was found, or
+		-1 if the stream is intact.
+	*/
+	FirstErrorOffset int64
+
+	/*
+		Unreadable is the number of bytes from the first framing or
+		checksum error to the end of the stream, which cannot be read
+		reliably, or -1 if it is unknown because the stream is not
+		seekable.
+	*/
+	Unreadable int64
+}
+
+/*
+OK returns whether no problems were found in the stream.
+*/
+func (v VerifyReport) OK() bool {
+	return v.FirstError == nil
+}
+
+/*
+Verify scans the remainder of the input stream and checks the framing and,
+if checksums are enabled, the checksums of all records, without
+decompressing them. With WithFooter(), it also checks that the footer is
+present and holds the number of records in the stream, and, for seekable
+streams, that the offsets in its sparse index point at the right records.
+Offsets are relative to the position of the input stream when the reader
+was created, and the footer is only checked if the reader started at the
+beginning of the stream.
+
+Scanning stops at the first framing or checksum error, since records cannot
+be found reliably after it; see WithSkipCorrupted() for salvaging them.
+Problems are described in the returned report rather than as an error; only
+ErrClosed and errors of the context are returned. The position of the
+reader is undefined afterwards.
+*/
+func (r *RecordReader) Verify(ctx context.Context) (VerifyReport, error) {
+	var report = VerifyReport{FirstErrorOffset: -1, Unreadable: -1}
+	var checkFooter = r.options.footer && r.position == Position{}
+	var expected *RecordIndex
+	var pos, indexed Position
+	var footerErr error
+	var base int64 = -1
+	var length, crc uint32
+	var ok bool
+	var err error
+
+	if r.closed {
+		return report, recordError(r.position, ErrClosed)
+	}
+
+	// Remember where the stream starts, since the position of the stream
+	// is not known exactly after a failed read.
+	if _, ok = r.wrappedReader.(Seeker); ok {
+		if base, err = r.streamBase(ctx); err != nil {
+			return report, recordError(r.position, err)
+		}
+		if checkFooter {
+			expected, footerErr = r.loadFooter(ctx)
+		}
+		if ctx.Err() != nil {
+			return report, recordError(r.position, ctx.Err())
+		}
+	}
+
+	for {
+		pos = r.position
+		if length, crc, err = r.nextBodyHeader(ctx); err == io.EOF {
+			break
+		} else if err == nil {
+			err = r.verifyBody(ctx, int64(length), crc)
+		}
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+				return report, recordError(pos, noTornTail(ctx, err))
+			}
+			return r.unreadable(ctx, report, base, pos, err), nil
+		}
+
+		if expected != nil {
+			indexed, ok = expected.Record(pos.Index)
+			if ok && indexed.Offset != pos.Offset {
+				report.fail(pos.Offset, ErrFooterMismatch)
+			}
+		}
+
+		r.advance(length)
+		report.Records++
+	}
+
+	report.Valid = r.streamOffset()
+	report.Unreadable = 0
+	if !checkFooter {
+		return report, nil
+	}
+	if expected == nil {
+		expected = r.index
+	}
+	if footerErr != nil {
+		report.fail(r.position.Offset, footerErr)
+	} else if expected == nil {
+		report.fail(r.position.Offset, ErrMissingFooter)
+	} else if expected.Len() != report.Records {
+		report.fail(r.position.Offset, ErrFooterMismatch)
+	}
+	return report, nil
+}
+
+/*
+fail records err as the first error at offset, unless an error has been
+found before.
+*/
+func (v *VerifyReport) fail(offset int64, err error) {
+	if v.FirstError == nil {
+		v.FirstError = err
+		v.FirstErrorOffset = offset
+	}
+}
+
+/*
+unreadable completes the report for a stream whose record at pos could not
+be read due to err. If the absolute offset base at which the reader started
+is known, the rest of the stream is measured.
+*/
+func (r *RecordReader) unreadable(ctx context.Context, report VerifyReport,
+	base int64, pos Position, err error) VerifyReport {
+	var size int64
+
+	report.fail(pos.Offset, err)
+	report.Valid = pos.Offset
+
+	if base >= 0 {
+		if size, err = r.streamSize(ctx); err == nil {
+			report.Unreadable = size - base - pos.Offset
+		}
+	}
+	return report
+}
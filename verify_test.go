@@ -0,0 +1,58 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+Verify must report intact streams as such, and locate checksum errors and
+missing footers.
+*/
+func TestVerify(t *testing.T) {
+	var ctx = context.Background()
+	var data = writeNumbered(t, 10, WithChecksum())
+	var corrupted = append([]byte{}, data...)
+	var footer = writeNumbered(t, 10, WithChecksum(), WithSparseIndex(3))
+	var report VerifyReport
+	var err error
+
+	// Every record takes 8 bytes of header and 5 bytes of data.
+	corrupted[5*13+10] ^= 0xff
+
+	for _, test := range []struct {
+		name     string
+		input    io.Reader
+		opts     []Option
+		expected VerifyReport
+	}{
+		{"Intact", bytes.NewReader(data), []Option{WithChecksum()},
+			VerifyReport{10, 130, nil, -1, 0}},
+		{"Footer", bytes.NewReader(footer),
+			[]Option{WithChecksum(), WithFooter()},
+			VerifyReport{10, int64(len(footer)), nil, -1, 0}},
+		{"MissingFooter", bytes.NewReader(data),
+			[]Option{WithChecksum(), WithFooter()},
+			VerifyReport{10, 130, ErrMissingFooter, 130, 0}},
+		{"Corrupted", bytes.NewReader(corrupted), []Option{WithChecksum()},
+			VerifyReport{5, 65, ErrChecksumMismatch, 65, 65}},
+		{"NotSeekable", bytes.NewBuffer(corrupted), []Option{WithChecksum()},
+			VerifyReport{5, 65, ErrChecksumMismatch, 65, -1}},
+		{"Torn", bytes.NewReader(data[:125]), []Option{WithChecksum()},
+			VerifyReport{9, 117, io.ErrUnexpectedEOF, 117, 8}},
+	} {
+		report, err = NewIORecordReader(test.input, test.opts...).Verify(ctx)
+		if err != nil {
+			t.Error(test.name, ": error verifying: ", err)
+		}
+		if report != test.expected {
+			t.Errorf("%s: unexpected report %+v, expected %+v", test.name,
+				report, test.expected)
+		}
+		if report.OK() != (test.expected.FirstError == nil) {
+			t.Error(test.name, ": unexpected OK result")
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/childoftheuniverse/filesystem"
+	"golang.org/x/net/context"
+	"io"
+)
+
+/*
+ErrInvalidWALRecord is returned when a record of a write-ahead log is
+neither a data record nor a commit marker.
+*/
+var ErrInvalidWALRecord = errors.New("Invalid write-ahead log record")
+
+const (
+	/*
+		walData marks records of a write-ahead log holding data.
+	*/
+	walData = 'D'
+
+	/*
+		walCommit marks commit markers in a write-ahead log.
+	*/
+	walCommit = 'C'
+)
+
+/*
+WALWriter writes a write-ahead log (redo log) on top of a RecordWriter.
+Every record appended to the log is stamped with a sequence number, and
+becomes durable only once it has been committed: Commit() writes a commit
+marker covering all records appended before it and syncs the output stream
+to stable storage. WALReader replays only committed records, so a crash
+in the middle of a transaction leaves no trace of it.
+
+Each entry of the log is a record starting with a type byte, 'D' for data
+and 'C' for commit markers, followed by the sequence number as a varint; for
+data records, the data follows. The options of the RecordWriter apply.
+
+As with RecordWriter, WALWriters are not thread safe.
+*/
+type WALWriter struct {
+	writer    *RecordWriter
+	sequence  uint64
+	committed uint64
+	entry     []byte
+
+	// batch holds the entries appended since BeginBatch(), while batching
+	// is set.
+	batch    [][]byte
+	batching bool
+}
+
+/*
+NewWALWriter creates a new WALWriter writing to the specified output
+stream, which must implement Syncer. The first record appended gets the
+sequence number next; to continue an existing log, pass the sequence number
+following the last one returned by WALReader.Replay(). No actions are
+performed at the time.
+*/
+func NewWALWriter(writer filesystem.WriteCloser, next uint64,
+	opts ...Option) *WALWriter {
+	return &WALWriter{
+		writer:    NewRecordWriter(writer, opts...),
+		sequence:  next,
+		committed: next,
+	}
+}
+
+/*
+Append adds a record to the current transaction of the log and returns its
+sequence number. The record is not durable, and will not be replayed, until
+Commit() has been called.
+*/
+func (w *WALWriter) Append(ctx context.Context, rec []byte) (uint64,
+	error) {
+	var entry []byte
+	var err error
+
+	if w.batching {
+		entry = make([]byte, 0, 1+binary.MaxVarintLen64+len(rec))
+		entry = w.appendEntry(entry, walData, w.sequence)
+		w.batch = append(w.batch, append(entry, rec...))
+		w.sequence++
+		return w.sequence - 1, nil
+	}
+
+	w.entry = w.appendEntry(w.entry[:0], walData, w.sequence)
+	w.entry = append(w.entry, rec...)
+	if _, err = w.writer.Write(ctx, w.entry); err != nil {
+		return 0, err
+	}
+
+	w.sequence++
+	return w.sequence - 1, nil
+}
+
+/*
+Commit ends the current transaction by writing a commit marker for all
+records appended so far, and syncs the output stream to stable storage.
+Once Commit returns successfully, the records survive a crash and will be
+replayed. If nothing has been appended since the last commit, nothing is
+written, but the output stream is still synced. If a batch has been begun,
+it is committed as described for CommitBatch().
+*/
+func (w *WALWriter) Commit(ctx context.Context) error {
+	var err error
+
+	if w.batching {
+		return w.CommitBatch(ctx)
+	}
+
+	if w.sequence > w.committed {
+		w.entry = w.appendEntry(w.entry[:0], walCommit, w.sequence-1)
+		if _, err = w.writer.Write(ctx, w.entry); err != nil {
+			return err
+		}
+		w.committed = w.sequence
+	}
+
+	return w.writer.Sync(ctx)
+}
+
+/*
+BeginBatch starts a group commit: records appended afterwards are only
+collected in memory, until CommitBatch() writes all of them along with a
+single commit marker in one write to the output stream, and syncs it once.
+This gives writers committing at high rates durability without paying for
+a write and a sync per record. Calling BeginBatch while a batch is in
+progress has no effect.
+*/
+func (w *WALWriter) BeginBatch() {
+	w.batching = true
+}
+
+/*
+CommitBatch ends the batch begun by BeginBatch(), writing the records
+appended since, followed by a commit marker covering them, in a single
+write, and syncs the output stream to stable storage, as Commit() does.
+The batch ends even if this fails, and its records are discarded; since
+their commit marker may not have been written completely, they may or may
+not be replayed. Without a batch in progress, CommitBatch is equivalent to
+Commit().
+*/
+func (w *WALWriter) CommitBatch(ctx context.Context) error {
+	var batch = w.batch
+	var err error
+
+	if !w.batching {
+		return w.Commit(ctx)
+	}
+	w.batch = w.batch[:0]
+	w.batching = false
+
+	if w.sequence > w.committed {
+		batch = append(batch, w.appendEntry(nil, walCommit, w.sequence-1))
+		if _, err = w.writer.WriteAll(ctx, batch); err != nil {
+			return err
+		}
+		w.committed = w.sequence
+	}
+
+	return w.writer.Sync(ctx)
+}
+
+/*
+Next returns the sequence number which the next record appended will get.
+*/
+func (w *WALWriter) Next() uint64 {
+	return w.sequence
+}
+
+/*
+Close closes the underlying RecordWriter. Records appended since the last
+commit are not committed, including those of a batch in progress.
+*/
+func (w *WALWriter) Close(ctx context.Context) error {
+	return w.writer.Close(ctx)
+}
+
+/*
+appendEntry appends the type and sequence number of a log entry to dst.
+*/
+func (w *WALWriter) appendEntry(dst []byte, kind byte,
+	sequence uint64) []byte {
+	var scratch [binary.MaxVarintLen64]byte
+
+	dst = append(dst, kind)
+	return append(dst, scratch[:binary.PutUvarint(scratch[:], sequence)]...)
+}
+
+/*
+WALReader replays the committed records of a write-ahead log written by
+WALWriter.
+*/
+type WALReader struct {
+	reader *RecordReader
+}
+
+/*
+NewWALReader creates a new WALReader reading the log from the specified
+input stream. The options must match those used for writing the log.
+*/
+func NewWALReader(reader filesystem.ReadCloser, opts ...Option) *WALReader {
+	return &WALReader{
+		reader: NewRecordReader(reader, opts...),
+	}
+}
+
+/*
+Replay calls apply for every committed record of the log, in order, with
+its sequence number. Records of a transaction are held in memory until its
+commit marker has been read. Records which were never committed, as well
+as a torn or corrupted tail of the log left behind by a crash, are
+ignored; use RecordReader.RecoverTail() on the log to find where to
+truncate it before appending to it again.
+
+The sequence number following the last committed record is returned, to be
+passed to NewWALWriter() for continuing the log. If apply returns an error,
+replaying stops and the error is returned. Other read errors, including
+ErrInvalidWALRecord for records which are not log entries, stop replaying
+as well and are returned wrapped into a RecordError.
+*/
+func (r *WALReader) Replay(ctx context.Context,
+	apply func(sequence uint64, rec []byte) error) (uint64, error) {
+	var next uint64
+	var pending []walEntry
+	var entry walEntry
+	var pos Position
+	var rec []byte
+	var err error
+
+	for {
+		pos = r.reader.position
+		if rec, err = r.reader.ReadRecord(ctx); err == io.EOF {
+			return next, nil
+		} else if err != nil {
+			if ctx.Err() == nil && tornTail(err) {
+				return next, nil
+			}
+			return next, err
+		}
+
+		if entry, err = parseWALEntry(rec); err != nil {
+			return next, recordError(pos, err)
+		}
+		if entry.kind == walData {
+			pending = append(pending, entry)
+			continue
+		}
+
+		for _, data := range pending {
+			if data.sequence > entry.sequence {
+				break
+			}
+			if err = apply(data.sequence, data.data); err != nil {
+				return next, err
+			}
+			next = data.sequence + 1
+		}
+		pending = pending[:0]
+	}
+}
+
+/*
+Close closes the underlying RecordReader.
+*/
+func (r *WALReader) Close(ctx context.Context) error {
+	return r.reader.Close(ctx)
+}
+
+/*
+walEntry is an entry of a write-ahead log.
+*/
+type walEntry struct {
+	kind     byte
+	sequence uint64
+	data     []byte
+}
+
+/*
+parseWALEntry decodes the type and sequence number of a log entry.
+*/
+func parseWALEntry(rec []byte) (walEntry, error) {
+	var entry walEntry
+	var n int
+
+	if len(rec) == 0 || (rec[0] != walData && rec[0] != walCommit) {
+		return entry, ErrInvalidWALRecord
+	}
+	entry.kind = rec[0]
+
+	if entry.sequence, n = binary.Uvarint(rec[1:]); n <= 0 {
+		return entry, ErrInvalidWALRecord
+	}
+	if entry.kind == walCommit && len(rec) != 1+n {
+		return entry, ErrInvalidWALRecord
+	}
+	entry.data = rec[1+n:]
+	return entry, nil
+}
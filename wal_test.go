@@ -0,0 +1,190 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"os"
+	"testing"
+)
+
+/*
+Only committed records of a write-ahead log must be replayed, also when the
+log ends in a torn record, and the log must be continued with the next
+sequence number.
+*/
+func TestWAL(t *testing.T) {
+	var ctx = context.Background()
+	var name = t.TempDir() + "/wal"
+	var file *os.File
+	var writer *WALWriter
+	var replayed []string
+	var next, seq uint64
+	var info os.FileInfo
+	var i int
+	var err error
+
+	if file, err = os.Create(name); err != nil {
+		t.Fatal("Error creating log: ", err)
+	}
+	writer = NewWALWriter(NewIOWriteCloser(file), 0, WithChecksum())
+	for i = 0; i < 5; i++ {
+		if seq, err = writer.Append(ctx, []byte(fmt.Sprint("Rec ", i))); err != nil {
+			t.Error("Error appending record: ", err)
+		} else if seq != uint64(i) {
+			t.Error("Unexpected sequence number: ", seq)
+		}
+		if i == 2 {
+			if err = writer.Commit(ctx); err != nil {
+				t.Error("Error committing: ", err)
+			}
+		}
+	}
+	if err = writer.Close(ctx); err != nil {
+		t.Error("Error closing log: ", err)
+	}
+
+	// Tear the last record apart as if the process had crashed.
+	if info, err = os.Stat(name); err != nil {
+		t.Fatal("Error reading log size: ", err)
+	}
+	if err = os.Truncate(name, info.Size()-2); err != nil {
+		t.Fatal("Error truncating log: ", err)
+	}
+
+	if file, err = os.Open(name); err != nil {
+		t.Fatal("Error opening log: ", err)
+	}
+	next, err = NewWALReader(NewIOReadCloser(file), WithChecksum()).Replay(
+		ctx, func(sequence uint64, rec []byte) error {
+			replayed = append(replayed, fmt.Sprint(sequence, ":", string(rec)))
+			return nil
+		})
+	file.Close()
+	if err != nil {
+		t.Error("Error replaying log: ", err)
+	}
+	if fmt.Sprint(replayed) != "[0:Rec 0 1:Rec 1 2:Rec 2]" {
+		t.Error("Unexpected records replayed: ", replayed)
+	}
+	if next != 3 {
+		t.Error("Unexpected next sequence number: ", next)
+	}
+}
+
+/*
+Commit markers must only be written for new records, and committing must
+fail for streams which cannot be synced.
+*/
+func TestWALCommit(t *testing.T) {
+	var ctx = context.Background()
+	var out = &countingWriter{}
+	var writer = NewWALWriter(NewIOWriteCloser(out), 7)
+	var seq uint64
+	var length int
+	var err error
+
+	if seq, err = writer.Append(ctx, []byte("Hello")); err != nil || seq != 7 {
+		t.Errorf("Unexpected append result: %d (%v)", seq, err)
+	}
+	if err = writer.Commit(ctx); err != ErrSyncUnsupported {
+		t.Error("Expected sync to be unsupported, got: ", err)
+	}
+	length = out.Len()
+	if err = writer.Commit(ctx); err != ErrSyncUnsupported {
+		t.Error("Expected sync to be unsupported, got: ", err)
+	}
+	if out.Len() != length {
+		t.Error("Commit marker written without new records")
+	}
+	if writer.Next() != 8 {
+		t.Error("Unexpected next sequence number: ", writer.Next())
+	}
+}
+
+/*
+A batch must be written in a single write along with its commit marker, and
+be replayed as a whole.
+*/
+func TestWALBatch(t *testing.T) {
+	var ctx = context.Background()
+	var out = &countingWriter{}
+	var writer = NewWALWriter(NewIOWriteCloser(out), 0)
+	var replayed []string
+	var next uint64
+	var err error
+
+	writer.BeginBatch()
+	for i := 0; i < 3; i++ {
+		if _, err = writer.Append(ctx, []byte(fmt.Sprint("Rec ", i))); err != nil {
+			t.Error("Error appending record: ", err)
+		}
+	}
+	if out.writes != 0 {
+		t.Error("Records written before committing the batch: ", out.writes)
+	}
+	if err = writer.CommitBatch(ctx); err != ErrSyncUnsupported {
+		t.Error("Expected sync to be unsupported, got: ", err)
+	}
+	if out.writes != 1 {
+		t.Error("Unexpected number of writes: ", out.writes)
+	}
+
+	// Records appended after the batch are written right away again.
+	if _, err = writer.Append(ctx, []byte("Rec 3")); err != nil {
+		t.Error("Error appending record: ", err)
+	}
+	if out.writes != 2 {
+		t.Error("Unexpected number of writes: ", out.writes)
+	}
+
+	next, err = NewWALReader(NewIOReadCloser(bytes.NewReader(
+		out.Bytes()))).Replay(ctx, func(sequence uint64, rec []byte) error {
+		replayed = append(replayed, fmt.Sprint(sequence, ":", string(rec)))
+		return nil
+	})
+	if err != nil {
+		t.Error("Error replaying log: ", err)
+	}
+	if fmt.Sprint(replayed) != "[0:Rec 0 1:Rec 1 2:Rec 2]" {
+		t.Error("Unexpected records replayed: ", replayed)
+	}
+	if next != 3 {
+		t.Error("Unexpected next sequence number: ", next)
+	}
+}
+
+/*
+Replaying must fail rather than stop silently for records which are not
+log entries and for closed readers.
+*/
+func TestWALReplayErrors(t *testing.T) {
+	var ctx = context.Background()
+	var buf bytes.Buffer
+	var writer = NewIORecordWriter(&buf)
+	var reader *WALReader
+	var recordErr *RecordError
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("Not a log entry")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+	reader = NewWALReader(NewIOReadCloser(bytes.NewReader(buf.Bytes())))
+	_, err = reader.Replay(ctx, func(uint64, []byte) error { return nil })
+	if !errors.Is(err, ErrInvalidWALRecord) {
+		t.Error("Unexpected error for invalid entry: ", err)
+	}
+	if !errors.As(err, &recordErr) || recordErr.Offset != 0 {
+		t.Error("Error not wrapped with the entry position: ", err)
+	}
+
+	reader = NewWALReader(NewIOReadCloser(bytes.NewReader(buf.Bytes())))
+	if err = reader.Close(ctx); err != nil {
+		t.Error("Error closing reader: ", err)
+	}
+	_, err = reader.Replay(ctx, func(uint64, []byte) error { return nil })
+	if !errors.Is(err, ErrClosed) {
+		t.Error("Unexpected error for closed reader: ", err)
+	}
+}
@@ -0,0 +1,96 @@
+package recordio
+
+import (
+	"errors"
+	"golang.org/x/net/context"
+)
+
+/*
+WithWriteBuffer makes writers collect records in an internal buffer and
+pass them to the output stream only once at least size bytes have
+accumulated, so that many small records result in few large writes. This
+matters for output streams where every write is expensive, such as object
+store uploads issuing an RPC per write.
+
+Buffered records are written by Flush(), Sync() and Close(). If writing the
+buffer fails, all records in it are dropped, even though writing them
+appeared to succeed; the position of the writer is moved back to the first
+of them, and the error is returned by the call which attempted the write.
+Write callbacks and the sparse index of the footer only learn about records
+once the buffer holding them has been written.
+*/
+func WithWriteBuffer(size int) Option {
+	return func(o *options) {
+		o.writeBufferSize = size
+	}
+}
+
+/*
+bufferRecord adds an encoded record to the write buffer, and writes the
+buffer to the output stream once it is full. frame holds the buffered data
+followed by the header of the record, and body is its data.
+*/
+func (w *RecordWriter) bufferRecord(ctx context.Context, pos Position,
+	frame, body []byte, crc uint32) (int, error) {
+	var length = len(frame) - len(w.pending) + len(body)
+
+	if len(w.pending) == 0 {
+		w.bufferedFrom = pos
+	}
+	w.pending = append(frame, body...)
+
+	w.position.Offset += int64(length)
+	w.buffered = append(w.buffered, RecordInfo{
+		Index:    pos.Index,
+		Offset:   pos.Offset,
+		Length:   uint32(len(body)),
+		Checksum: crc,
+	})
+	w.position.Index++
+
+	if len(w.pending) >= w.options.writeBufferSize {
+		return length, w.flushBuffer(ctx)
+	}
+	return length, nil
+}
+
+/*
+flushBuffer writes all records in the write buffer to the output stream.
+*/
+func (w *RecordWriter) flushBuffer(ctx context.Context) error {
+	var buffered = w.buffered
+	var n int
+	var err error
+
+	if len(w.pending) == 0 {
+		return nil
+	}
+
+	n, err = w.wrappedWriter.Write(ctx, w.pending)
+	if err == nil && n < len(w.pending) {
+		err = errors.New("Short write")
+	}
+
+	if cap(w.pending) <= maxPooledBuffer {
+		w.pending = w.pending[:0]
+	} else {
+		w.pending = nil
+	}
+
+	w.buffered = w.buffered[:0]
+
+	if err != nil {
+		// Forget about the records which have been lost.
+		w.position = w.bufferedFrom
+		w.position.Offset += int64(n)
+		return recordError(w.bufferedFrom, w.partialWrite(n, err))
+	}
+
+	// Callbacks and the sparse index only learn about records once they
+	// have actually been written.
+	for _, info := range buffered {
+		w.recordWritten(Position{Offset: info.Offset, Index: info.Index},
+			info.Length, info.Checksum)
+	}
+	return nil
+}
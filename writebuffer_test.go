@@ -0,0 +1,118 @@
+package recordio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/context"
+	"testing"
+)
+
+/*
+Small records must be coalesced into few writes, be reported to the write
+callback once written, and be readable once the writer has been flushed.
+*/
+func TestWriteBuffer(t *testing.T) {
+	var ctx = context.Background()
+	var out countingWriter
+	var reported int
+	var writer = NewIORecordWriter(&out, WithWriteBuffer(32),
+		WithWriteCallback(func(RecordInfo) { reported++ }))
+	var reader *RecordReader
+	var rec []byte
+	var i int
+	var err error
+
+	for i = 0; i < 10; i++ {
+		if _, err = writer.Write(ctx, []byte(fmt.Sprint("Rec ", i))); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if out.writes != 2 {
+		t.Error("Unexpected number of writes before flushing: ", out.writes)
+	}
+	if reported != 8 {
+		t.Error("Unexpected number of records reported: ", reported)
+	}
+	if writer.Tell() != (Position{Offset: 90, Index: 10}) {
+		t.Error("Unexpected position: ", writer.Tell())
+	}
+
+	if err = writer.Flush(ctx); err != nil {
+		t.Error("Error flushing writer: ", err)
+	}
+	if out.writes != 3 || out.Len() != 90 {
+		t.Error("Unexpected output after flushing: ", out.writes, " writes, ",
+			out.Len(), " bytes")
+	}
+	if reported != 10 {
+		t.Error("Unexpected number of records reported: ", reported)
+	}
+
+	reader = NewIORecordReader(&out.Buffer)
+	for i = 0; i < 10; i++ {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != fmt.Sprint("Rec ", i) {
+			t.Errorf("Unexpected data: got %q, expected Rec %d", rec, i)
+		}
+	}
+}
+
+/*
+failingWriter accepts a limited number of bytes and fails afterwards.
+*/
+type failingWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.Len()+len(p) > f.limit {
+		n, _ := f.Buffer.Write(p[:f.limit-f.Len()])
+		return n, errors.New("Disk full")
+	}
+	return f.Buffer.Write(p)
+}
+
+/*
+If writing the buffer fails, the buffered records must be dropped, without
+being reported to the write callback, and the position of the writer moved
+back to the first of them.
+*/
+func TestWriteBufferFailure(t *testing.T) {
+	var ctx = context.Background()
+	var out = &failingWriter{limit: 20}
+	var reported []RecordInfo
+	var writer = NewIORecordWriter(out, WithWriteBuffer(1024),
+		WithWriteCallback(func(info RecordInfo) {
+			reported = append(reported, info)
+		}))
+	var partial *PartialWriteError
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three", "Four"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if len(reported) != 0 {
+		t.Error("Buffered records reported before writing: ", reported)
+	}
+
+	if err = writer.Flush(ctx); !errors.As(err, &partial) ||
+		partial.Written != 20 {
+		t.Error("Expected partial write of 20 bytes, got: ", err)
+	}
+	if writer.Tell() != (Position{Offset: 20, Index: 0}) {
+		t.Error("Unexpected position: ", writer.Tell())
+	}
+	if len(reported) != 0 {
+		t.Error("Lost records reported: ", reported)
+	}
+	if _, err = writer.Write(ctx, []byte("Five")); !errors.Is(
+		err, ErrIncompleteRecord) {
+		t.Error("Expected ErrIncompleteRecord, got: ", err)
+	}
+}
@@ -1,10 +1,8 @@
 package recordio
 
 import (
-	"encoding/binary"
 	"errors"
 	"github.com/childoftheuniverse/filesystem"
-	"github.com/golang/protobuf/proto"
 	"golang.org/x/net/context"
 )
 
@@ -20,48 +18,192 @@ they are used in a potentially multi-threaded environment.
 type RecordWriter struct {
 	filesystem.WriteCloser
 	wrappedWriter filesystem.WriteCloser
+	options       options
+	position      Position
+	closed        bool
+	finished      bool
+	incomplete    int64
+	sparseIndex   []int64
+
+	// durable is the offset up to which the stream has been synced.
+	durable int64
+
+	// header is reused for encoding the header of streamed records, and
+	// buf for framing records before writing them.
+	header [maxHeaderLength]byte
+	buf    []byte
+
+	// pending holds records collected by WithWriteBuffer(), the first of
+	// which is at bufferedFrom, and buffered describes them until they
+	// have been written.
+	pending      []byte
+	bufferedFrom Position
+	buffered     []RecordInfo
+
+	limiter *rateLimiter
 }
 
 /*
 NewRecordWriter creates a new RecordWriter wrapped around the specified
-output stream. No actions are performed at the time.
+output stream, with optional features configured by the specified options.
+No actions are performed at the time.
 */
-func NewRecordWriter(writer filesystem.WriteCloser) *RecordWriter {
+func NewRecordWriter(writer filesystem.WriteCloser,
+	opts ...Option) *RecordWriter {
+	var o = applyOptions(opts)
+
 	return &RecordWriter{
-		wrappedWriter: writer,
+		wrappedWriter: wrapWriter(writer, o),
+		options:       o,
+		limiter:       newRateLimiter(o),
 	}
 }
 
+/*
+wrapWriter adds the layers required by the options to the output stream,
+such as retries, preallocation or aligned writes.
+*/
+func wrapWriter(writer filesystem.WriteCloser,
+	o options) filesystem.WriteCloser {
+	writer = retryingWriteCloser(writer, o.retry)
+	writer = preallocating(writer, o.segmentSize)
+	return aligned(writer, o.alignment)
+}
+
+/*
+Reset makes the RecordWriter write to the specified output stream instead,
+resetting the position reported by Tell() and reopening the writer if it
+has been closed. The options of the writer are
+preserved, so pooled writers can be reused for many streams. The previous
+output stream is not closed, and records buffered due to WithWriteBuffer()
+are discarded.
+*/
+func (w *RecordWriter) Reset(writer filesystem.WriteCloser) {
+	w.wrappedWriter = wrapWriter(writer, w.options)
+	w.position = Position{}
+	w.closed = false
+	w.incomplete = 0
+	w.sparseIndex = nil
+	w.pending = w.pending[:0]
+	w.durable = 0
+}
+
 /*
 Write takes the slice of bytes passed in and writes them to the wrapped output
-stream as a new record. This will issue two calls to the Write() method of the
-underlying output stream which might conflict, so use locking as appropriate.
+stream as a new record. The header and the record data are passed to the
+underlying output stream in a single call to its Write() method, so a crash
+cannot leave a header without its data behind unless the output stream
+itself splits the write.
+
+This will add len(rec) + 4 bytes to the output stream (or len(rec) plus the
+length of the varint header when using VarintFraming), plus 4 bytes for the
+checksum if enabled. If a codec is used, the compressed length is used
+instead of len(rec). The number of bytes added to the stream is returned.
 
-This will add len(rec) + 4 bytes to the output stream.
+The context is checked for cancellation before writing the record. If writing
+fails after part of the record has been written, a *PartialWriteError is
+returned.
 */
 func (w *RecordWriter) Write(ctx context.Context, rec []byte) (int, error) {
-	var lengthAsBytes []byte = make([]byte, 4)
-	var headerLength int
-	var bodyLength int
+	var pos = w.position
+	var n int
 	var err error
 
-	binary.BigEndian.PutUint32(lengthAsBytes, uint32(len(rec)))
+	n, err = w.write(ctx, rec)
+	return n, recordError(pos, err)
+}
 
-	headerLength, err = w.wrappedWriter.Write(ctx, lengthAsBytes)
-	if err != nil {
-		return headerLength, err
+/*
+write implements Write() without adding the position of the record to
+errors.
+*/
+func (w *RecordWriter) write(ctx context.Context, rec []byte) (int, error) {
+	var pos = w.position
+	var frame []byte
+	var body []byte
+	var crc uint32
+	var n int
+	var err error
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.incomplete > 0 {
+		return 0, ErrIncompleteRecord
+	}
+
+	if err = ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err = w.limiter.wait(ctx, int64(len(rec)), 1); err != nil {
+		return 0, err
+	}
+
+	if w.options.writeBufferSize > 0 {
+		frame, body, crc, err = w.encodeRecord(w.pending, rec)
+		if err != nil {
+			return 0, err
+		}
+		return w.bufferRecord(ctx, pos, frame, body, crc)
+	}
+
+	if frame, body, crc, err = w.encodeRecord(w.buf[:0], rec); err != nil {
+		return 0, err
+	}
+	frame = append(frame, body...)
+
+	// Keep the buffer for the next record, unless it has grown too large.
+	if cap(frame) <= maxPooledBuffer {
+		w.buf = frame[:0]
 	}
 
-	bodyLength, err = w.wrappedWriter.Write(ctx, rec)
+	n, err = w.wrappedWriter.Write(ctx, frame)
+	w.position.Offset += int64(n)
+	if err == nil && n < len(frame) {
+		err = errors.New("Short write")
+	}
 	if err != nil {
-		return headerLength + bodyLength, err
+		return n, w.partialWrite(n, err)
+	}
+
+	w.recordWritten(pos, uint32(len(body)), crc)
+	w.position.Index++
+	return n, nil
+}
+
+/*
+encodeRecord compresses the record data if required, and returns dst with the
+header appended and the data to be written to the output stream for it,
+along with the checksum of the data if checksums are enabled.
+*/
+func (w *RecordWriter) encodeRecord(dst, rec []byte) ([]byte, []byte, uint32,
+	error) {
+	var body = rec
+	var crc uint32
+	var err error
+
+	if uint64(len(rec)) > uint64(w.options.maxRecordSize) {
+		return nil, nil, 0, ErrRecordTooLarge
+	}
+	if len(rec) == 0 && w.options.segmentSize > 0 {
+		return nil, nil, 0, ErrEmptyRecord
 	}
 
-	if bodyLength < len(rec) {
-		return headerLength + bodyLength, errors.New("Short write")
+	if w.options.codec != nil {
+		body, err = w.options.codec.Compress(rec)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if uint64(len(body)) > uint64(w.options.maxRecordSize) {
+			return nil, nil, 0, ErrRecordTooLarge
+		}
 	}
 
-	return headerLength + bodyLength, nil
+	if w.options.checksum {
+		crc = checksum(body)
+	}
+
+	return w.appendHeader(dst, uint32(len(body)), crc), body, crc, nil
 }
 
 /*
@@ -71,13 +213,13 @@ result as a new record to the underlying output stream.
 The same warnings about locking as for Write() apply to this method.
 */
 func (w *RecordWriter) WriteMessage(
-	ctx context.Context, pb proto.Message) error {
+	ctx context.Context, pb Message) error {
 	var b []byte
 	var err error
 
-	b, err = proto.Marshal(pb)
+	b, err = w.options.marshalMessage(pb)
 	if err != nil {
-		return err
+		return recordError(w.position, err)
 	}
 
 	_, err = w.Write(ctx, b)
@@ -85,9 +227,78 @@ func (w *RecordWriter) WriteMessage(
 }
 
 /*
-Close just delegates to the close function of the underlying writer. No other
-specific action will be taken.
+Close closes the underlying writer, unless WithoutCloseUnderlying() was
+specified. If WithFooter() was specified, the footer is written first,
+unless the stream ends in an incomplete record. Closing a RecordWriter more
+than once has no effect; afterwards, all writes fail with ErrClosed.
 */
 func (w *RecordWriter) Close(ctx context.Context) error {
-	return w.wrappedWriter.Close(ctx)
+	var err error
+
+	if w.closed {
+		return nil
+	}
+
+	err = w.finish(ctx)
+
+	if w.options.keepUnderlyingOpen {
+		if err != nil {
+			return err
+		}
+		if err = w.Flush(ctx); err != nil {
+			return err
+		}
+		w.closed = true
+		w.notifyFlushed(true)
+		return nil
+	}
+
+	w.closed = true
+	if closeErr := w.wrappedWriter.Close(ctx); err == nil {
+		err = closeErr
+	}
+	w.notifyFlushed(true)
+	return err
+}
+
+/*
+finish writes out the buffered records and then the footer, or the padding
+of the last block, as required when closing the writer. Only the first call
+has any effect, so that callers can finish the stream before closing it.
+*/
+func (w *RecordWriter) finish(ctx context.Context) error {
+	var err error
+
+	if w.finished {
+		return nil
+	}
+	w.finished = true
+
+	if err = w.flushBuffer(ctx); err == nil &&
+		w.options.footer && w.incomplete == 0 {
+		err = w.writeFooter(ctx)
+	} else if err == nil && w.incomplete == 0 {
+		err = w.pad(ctx, 0)
+	}
+	return err
+}
+
+/*
+writeFull writes all of buf to the specified writer, treating a short write
+without an error as a failure. Nothing is written if the context has been
+canceled already.
+*/
+func writeFull(ctx context.Context, writer filesystem.WriteCloser,
+	buf []byte) error {
+	var n int
+	var err error
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	n, err = writer.Write(ctx, buf)
+	if err == nil && n < len(buf) {
+		err = errors.New("Short write")
+	}
+	return err
 }
@@ -0,0 +1,104 @@
+package recordio
+
+import (
+	"bytes"
+	"golang.org/x/net/context"
+	"io"
+	"testing"
+)
+
+/*
+A reset writer must write to the new stream with the same options.
+*/
+func TestWriterReset(t *testing.T) {
+	var ctx = context.Background()
+	var first, second bytes.Buffer
+	var writer = NewIORecordWriter(&first, WithFraming(VarintFraming))
+	var rec []byte
+	var err error
+
+	if _, err = writer.Write(ctx, []byte("First")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	writer.Reset(NewIOWriteCloser(&second))
+	if writer.Offset() != 0 {
+		t.Error("Offset was not reset: ", writer.Offset())
+	}
+	if _, err = writer.Write(ctx, []byte("Second")); err != nil {
+		t.Error("Error writing record: ", err)
+	}
+
+	if first.Len() != 6 || second.Len() != 7 {
+		t.Error("Unexpected stream lengths: ", first.Len(), ", ", second.Len())
+	}
+
+	rec, err = NewIORecordReader(&second, WithFraming(VarintFraming)).ReadRecord(ctx)
+	if err != nil {
+		t.Error("Error reading record: ", err)
+	}
+	if string(rec) != "Second" {
+		t.Errorf("Unexpected data: got %q, expected Second", rec)
+	}
+}
+
+/*
+Write small records to a discarding stream, to measure the overhead of
+framing them. Writing should not allocate at all.
+*/
+func BenchmarkWriteSmallRecords(b *testing.B) {
+	var ctx = context.Background()
+	var rec = []byte("Hello")
+
+	for _, opts := range []struct {
+		name    string
+		options []Option
+	}{
+		{"Fixed", nil},
+		{"Varint", []Option{WithFraming(VarintFraming)}},
+		{"Checksum", []Option{WithChecksum()}},
+	} {
+		b.Run(opts.name, func(b *testing.B) {
+			var writer = NewIORecordWriter(io.Discard, opts.options...)
+			var i int
+
+			b.ReportAllocs()
+			for i = 0; i < b.N; i++ {
+				if _, err := writer.Write(ctx, rec); err != nil {
+					b.Fatal("Error writing record: ", err)
+				}
+			}
+		})
+	}
+}
+
+/*
+Every record must be passed to the output stream in a single write.
+*/
+func TestWriteSingleCall(t *testing.T) {
+	var ctx = context.Background()
+	var out countingWriter
+	var writer = NewIORecordWriter(&out, WithChecksum())
+	var reader *RecordReader
+	var rec []byte
+	var err error
+
+	for _, rec := range []string{"One", "Two", "Three"} {
+		if _, err = writer.Write(ctx, []byte(rec)); err != nil {
+			t.Error("Error writing record: ", err)
+		}
+	}
+	if out.writes != 3 {
+		t.Error("Unexpected number of writes: ", out.writes)
+	}
+
+	reader = NewIORecordReader(&out.Buffer, WithChecksum())
+	for _, expected := range []string{"One", "Two", "Three"} {
+		if rec, err = reader.ReadRecord(ctx); err != nil {
+			t.Error("Error reading record: ", err)
+		}
+		if string(rec) != expected {
+			t.Errorf("Unexpected data: got %q, expected %s", rec, expected)
+		}
+	}
+}